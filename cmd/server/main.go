@@ -3,17 +3,21 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/wcygan/llm-json-parse/internal/auth"
 	"github.com/wcygan/llm-json-parse/internal/client"
 	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/limiter"
 	"github.com/wcygan/llm-json-parse/internal/logging"
 	"github.com/wcygan/llm-json-parse/internal/middleware"
 	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/internal/tenant"
 )
 
 func main() {
@@ -23,53 +27,124 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Create structured logger
+	// Create structured logger; its level can be changed at runtime through
+	// the admin endpoint
+	logLevel := new(slog.LevelVar)
 	logger := logging.NewLogger(logging.LogConfig{
-		Level:  cfg.Log.Level,
-		Format: cfg.Log.Format,
+		Level:       cfg.Log.Level,
+		Format:      cfg.Log.Format,
+		FieldPrefix: cfg.Log.FieldPrefix,
+		LevelVar:    logLevel,
 	})
 
 	// Log startup information
-	startupConfig := map[string]interface{}{
-		"address":       cfg.Address(),
-		"llm_server":    cfg.LLM.ServerURL,
-		"cache_size":    cfg.Cache.MaxSize,
-		"log_level":     cfg.Log.Level,
-		"log_format":    cfg.Log.Format,
-		"read_timeout":  cfg.Server.ReadTimeout.String(),
-		"write_timeout": cfg.Server.WriteTimeout.String(),
-		"idle_timeout":  cfg.Server.IdleTimeout.String(),
+	if cfg.Log.StartupConfig {
+		logger.LogStartup(cfg.Redacted())
+	} else {
+		redacted := cfg.Redacted()
+		logger.LogStartup(map[string]interface{}{
+			"address":        cfg.Address(),
+			"llm_server":     redacted.LLM.ServerURL,
+			"cache_size":     cfg.Cache.MaxSize,
+			"cache_eviction": cfg.Cache.EvictionPolicy,
+			"log_level":      cfg.Log.Level,
+			"log_format":     cfg.Log.Format,
+			"read_timeout":   cfg.Server.ReadTimeout.String(),
+			"write_timeout":  cfg.Server.WriteTimeout.String(),
+			"idle_timeout":   cfg.Server.IdleTimeout.String(),
+			"tenants":        len(cfg.Tenants.Tenants),
+			"debug_enabled":  cfg.Debug.Enabled,
+		})
+	}
+
+	// Catch misconfiguration that would fail every request
+	if err := server.CheckStartup(cfg, logger); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
 	}
-	logger.LogStartup(startupConfig)
 
 	// Create LLM client with configuration
-	llmClient := client.NewLlamaServerClientWithTimeout(cfg.LLM.ServerURL, cfg.LLM.Timeout)
+	transport := client.NewTransport(client.TransportConfig{
+		DNSCacheTTL:         cfg.LLM.DNSCacheTTL,
+		KeepAlive:           cfg.LLM.KeepAlive,
+		MaxIdleConnsPerHost: cfg.LLM.MaxIdleConnsPerHost,
+	})
+	llmClient := client.NewLlamaServerClientWithTransport(cfg.LLM.ServerURL, cfg.LLM.Timeout, client.RetryConfig{
+		Attempts:       cfg.LLM.RetryAttempts,
+		Delay:          cfg.LLM.RetryDelay,
+		MaxDelay:       cfg.LLM.MaxRetryDelay,
+		Jitter:         cfg.LLM.RetryJitter,
+		EmptyResponses: cfg.LLM.RetryEmptyResponses,
+		DNSFailures:    cfg.LLM.RetryDNSFailures,
+	}, transport, logger)
+	llmClient.SetExtraRequestFields(cfg.LLM.ExtraRequestFields)
+	llmClient.SetAllowInvalidUTF8(cfg.LLM.AllowInvalidUTF8)
 
 	// Create server with configuration and logger
-	srv := server.NewServerWithConfig(llmClient, cfg.Cache.MaxSize, logger)
+	srv := server.NewServerFromConfigWithTransport(llmClient, cfg, logger, transport)
+	srv.SetLogLevelVar(logLevel)
+	tenants := tenant.NewRegistry(cfg.Tenants)
+	admission := limiter.NewLimiter(cfg.Concurrency.MaxConcurrent, cfg.Concurrency.PriorityLevels)
+	srv.SetAdmissionLimiter(admission)
+	perClient := limiter.NewKeyedLimiter(cfg.Concurrency.MaxPerClient)
+	clientIPs, err := middleware.NewClientIPResolver(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to configure trusted proxies: %v", err)
+	}
 
 	// Setup HTTP server with timeouts
 	httpServer := &http.Server{
-		Addr:         cfg.Address(),
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		IdleTimeout:  cfg.Server.IdleTimeout,
+		Addr:              cfg.Address(),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
 
 	// Register routes with middleware
 	mux := http.NewServeMux()
 	srv.RegisterRoutes(mux)
 
-	// Apply middleware chain
-	handler := middleware.Recovery(logger)(
-		middleware.CORS()(
-			middleware.RequestTimeout(cfg.Server.WriteTimeout)(
-				middleware.ContentType("application/json")(
-					middleware.RequestLogging(logger)(mux),
+	// Apply middleware chain. Compression sits inside request logging so the
+	// logged response size is what went over the wire. Authentication runs
+	// before tenant resolution so an identity's tenant can route the request.
+	// The per-client cap comes first so a client over it is turned away
+	// before any other work is done. Bodies are only buffered once a request
+	// has been admitted.
+	// Each layer is timed under its name; the timings are only recorded and
+	// logged when LOG_MIDDLEWARE_TIMINGS wraps the chain below.
+	timed := middleware.Timed
+	contentType := middleware.ContentType("application/json")
+	if cfg.Server.MissingContentType == "assume" {
+		contentType = middleware.ContentTypeWithDefault("application/json", "application/json")
+	}
+	app := timed("client_concurrency_limit", middleware.ClientConcurrencyLimit(perClient, "/health", "/ready"))(
+		timed("authentication", middleware.Authentication(auth.New(cfg.Auth), "/health", "/ready", "/v1/capabilities"))(
+			timed("tenant_resolution", middleware.TenantResolution(tenants))(
+				timed("concurrency_limit", middleware.ConcurrencyLimit(admission, cfg.Concurrency.PriorityHeader, "/health", "/ready", "/debug/vars"))(
+					timed("buffer_body", middleware.BufferBody(cfg.Server.MaxBodyBytes, cfg.Server.VerifyContentLength))(
+						middleware.TimedHandler("handler", mux),
+					),
+				),
+			),
+		),
+	)
+	if cfg.Server.Compression {
+		app = timed("compress", middleware.CompressWithLevel(cfg.Server.CompressionLevel))(app)
+	}
+	handler := timed("recovery", middleware.Recovery(logger))(
+		timed("cors", middleware.CORS())(
+			timed("request_timeout", middleware.RequestTimeoutWithRoutes(cfg.Server.WriteTimeout, cfg.Server.RouteTimeouts))(
+				timed("content_type", contentType)(
+					timed("client_ip", middleware.ClientIP(clientIPs))(
+						timed("request_logging", middleware.RequestLoggingWithHeaders(logger, cfg.Log.HeaderFields))(app),
+					),
 				),
 			),
 		),
 	)
+	if cfg.Log.MiddlewareTimings {
+		handler = middleware.MiddlewareTimings(logger)(handler)
+	}
 	httpServer.Handler = handler
 
 	// Channel to listen for interrupt signal to terminate gracefully
@@ -101,5 +176,9 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	if err := srv.Close(ctx); err != nil {
+		logger.WithComponent("http_server").WithError(err).Warn("Pending webhook events were not delivered")
+	}
+
 	logger.LogShutdown(true, time.Since(shutdownStart))
 }
@@ -0,0 +1,60 @@
+// Package auth authenticates requests against a configurable backend: a
+// static list of bearer tokens or JWTs verified against a JWKS.
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/config"
+)
+
+var (
+	// ErrMissingCredentials is returned when a request carries no bearer token
+	ErrMissingCredentials = errors.New("missing bearer token")
+	// ErrInvalidCredentials is returned when a bearer token is not accepted
+	ErrInvalidCredentials = errors.New("invalid bearer token")
+)
+
+// Identity is the authenticated caller of a request. TenantID, when set,
+// routes the request to that tenant regardless of the tenant header.
+type Identity struct {
+	Subject  string
+	TenantID string
+}
+
+// Authenticator resolves the identity behind a request's credentials
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// New creates the authenticator selected by cfg.Mode, or nil when requests
+// are not authenticated
+func New(cfg config.AuthConfig) Authenticator {
+	switch cfg.Mode {
+	case "static":
+		return NewStaticAuthenticator(cfg.Tokens)
+	case "jwt":
+		return NewJWTAuthenticator(JWTConfig{
+			JWKSURL:     cfg.JWKSURL,
+			CacheTTL:    cfg.JWKSCacheTTL,
+			Issuer:      cfg.Issuer,
+			Audience:    cfg.Audience,
+			TenantClaim: cfg.TenantClaim,
+			Timeout:     5 * time.Second,
+		})
+	default:
+		return nil
+	}
+}
+
+// bearerToken extracts the token from a request's Authorization header
+func bearerToken(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrMissingCredentials
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksMinRefresh limits how often an unknown key ID triggers a JWKS refetch,
+// so tokens with made-up key IDs cannot hammer the JWKS endpoint
+const jwksMinRefresh = 10 * time.Second
+
+// JWTConfig controls JWT verification. Keys are fetched from JWKSURL and
+// reused for CacheTTL. Issuer and Audience are checked when set; the tenant
+// is read from the string claim named TenantClaim.
+type JWTConfig struct {
+	JWKSURL     string
+	CacheTTL    time.Duration
+	Issuer      string
+	Audience    string
+	TenantClaim string
+	Timeout     time.Duration
+}
+
+// JWTAuthenticator accepts RS256-signed JWTs whose key is published in a JWKS
+type JWTAuthenticator struct {
+	cfg    JWTConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWTAuthenticator creates a JWT authenticator. The JWKS is fetched on
+// first use rather than at startup.
+func NewJWTAuthenticator(cfg JWTConfig) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}
+}
+
+// jwtHeader is the JOSE header of a JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Authenticate verifies the request's bearer JWT and returns its subject and
+// tenant
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed JWT", ErrInvalidCredentials)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidCredentials, err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidCredentials, header.Alg)
+	}
+
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidCredentials, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidCredentials)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidCredentials, err)
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	if a.cfg.TenantClaim != "" {
+		identity.TenantID, _ = claims[a.cfg.TenantClaim].(string)
+	}
+	return identity, nil
+}
+
+// checkClaims enforces expiry, not-before, issuer and audience
+func (a *JWTAuthenticator) checkClaims(claims map[string]interface{}) error {
+	now := float64(a.now().Unix())
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing exp claim", ErrInvalidCredentials)
+	}
+	if now >= exp {
+		return fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return fmt.Errorf("%w: token not yet valid", ErrInvalidCredentials)
+	}
+	if a.cfg.Issuer != "" && claims["iss"] != a.cfg.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+	if a.cfg.Audience != "" && !hasAudience(claims["aud"], a.cfg.Audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// hasAudience reports whether the aud claim, a string or array of strings,
+// contains audience
+func hasAudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, item := range v {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the public key with the given ID, refreshing the JWKS when the
+// cache has expired or the key is unknown
+func (a *JWTAuthenticator) key(kid string) (*rsa.PublicKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	age := a.now().Sub(a.fetchedAt)
+	key, ok := a.keys[kid]
+	if a.keys == nil || age >= a.cfg.CacheTTL || (!ok && age >= jwksMinRefresh) {
+		keys, err := a.fetchKeys()
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+		a.fetchedAt = a.now()
+		key, ok = a.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key ID %q", ErrInvalidCredentials, kid)
+	}
+	return key, nil
+}
+
+// jwks is a JSON Web Key Set; only RSA keys are used
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// fetchKeys downloads the JWKS and returns its RSA signing keys by key ID
+func (a *JWTAuthenticator) fetchKeys() (map[string]*rsa.PublicKey, error) {
+	resp, err := a.client.Get(a.cfg.JWKSURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: server returned status %d", resp.StatusCode)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
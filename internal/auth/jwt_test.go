@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signJWT builds an RS256 JWT with the given key ID and claims
+func signJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newJWKSServer serves the public half of key under the given key ID
+func newJWKSServer(t *testing.T, key *rsa.PrivateKey, kid string) (*httptest.Server, *int32) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksServer, fetches := newJWKSServer(t, key, "key-1")
+	a := NewJWTAuthenticator(JWTConfig{
+		JWKSURL:     jwksServer.URL,
+		CacheTTL:    time.Hour,
+		Issuer:      "https://issuer.example",
+		Audience:    "llm-json-parse",
+		TenantClaim: "tenant_id",
+		Timeout:     time.Second,
+	})
+
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":       "user-1",
+			"iss":       "https://issuer.example",
+			"aud":       []string{"other", "llm-json-parse"},
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"tenant_id": "acme",
+		}
+	}
+
+	authenticate := func(token string) (*Identity, error) {
+		req := httptest.NewRequest("POST", "/v1/validated-query", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return a.Authenticate(req)
+	}
+
+	t.Run("valid_token", func(t *testing.T) {
+		identity, err := authenticate(signJWT(t, key, "key-1", validClaims()))
+		require.NoError(t, err)
+		assert.Equal(t, &Identity{Subject: "user-1", TenantID: "acme"}, identity)
+	})
+
+	t.Run("keys_are_cached", func(t *testing.T) {
+		before := atomic.LoadInt32(fetches)
+		_, err := authenticate(signJWT(t, key, "key-1", validClaims()))
+		require.NoError(t, err)
+		assert.Equal(t, before, atomic.LoadInt32(fetches))
+	})
+
+	t.Run("wrong_signing_key", func(t *testing.T) {
+		_, err := authenticate(signJWT(t, otherKey, "key-1", validClaims()))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("unknown_key_id", func(t *testing.T) {
+		_, err := authenticate(signJWT(t, key, "key-2", validClaims()))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, err := authenticate(signJWT(t, key, "key-1", claims))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("wrong_issuer", func(t *testing.T) {
+		claims := validClaims()
+		claims["iss"] = "https://evil.example"
+		_, err := authenticate(signJWT(t, key, "key-1", claims))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("wrong_audience", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "other"
+		_, err := authenticate(signJWT(t, key, "key-1", claims))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("tampered_claims", func(t *testing.T) {
+		token := signJWT(t, key, "key-1", validClaims())
+		forged, err := json.Marshal(map[string]interface{}{"sub": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+		require.NoError(t, err)
+		parts := strings.Split(token, ".")
+		_, err = authenticate(parts[0] + "." + base64.RawURLEncoding.EncodeToString(forged) + "." + parts[2])
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := authenticate("not-a-jwt")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
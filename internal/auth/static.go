@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/wcygan/llm-json-parse/internal/config"
+)
+
+// StaticAuthenticator accepts a fixed set of bearer tokens
+type StaticAuthenticator struct {
+	tokens []config.StaticToken
+}
+
+// NewStaticAuthenticator creates an authenticator accepting the given tokens
+func NewStaticAuthenticator(tokens []config.StaticToken) *StaticAuthenticator {
+	return &StaticAuthenticator{tokens: tokens}
+}
+
+// Authenticate returns the identity of the request's bearer token. Every
+// configured token is compared in constant time so timing reveals nothing
+// about which tokens exist.
+func (a *StaticAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity *Identity
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+			identity = &Identity{Subject: t.Subject, TenantID: t.TenantID}
+		}
+	}
+	if identity == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return identity, nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+)
+
+func TestStaticAuthenticator(t *testing.T) {
+	a := NewStaticAuthenticator([]config.StaticToken{
+		{Token: "ci-token", Subject: "ci"},
+		{Token: "acme-token", Subject: "acme-app", TenantID: "acme"},
+	})
+
+	authenticate := func(authorization string) (*Identity, error) {
+		req := httptest.NewRequest("POST", "/v1/validated-query", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return a.Authenticate(req)
+	}
+
+	t.Run("valid_token", func(t *testing.T) {
+		identity, err := authenticate("Bearer ci-token")
+		require.NoError(t, err)
+		assert.Equal(t, &Identity{Subject: "ci"}, identity)
+	})
+
+	t.Run("valid_token_with_tenant", func(t *testing.T) {
+		identity, err := authenticate("bearer acme-token")
+		require.NoError(t, err)
+		assert.Equal(t, &Identity{Subject: "acme-app", TenantID: "acme"}, identity)
+	})
+
+	t.Run("unknown_token", func(t *testing.T) {
+		_, err := authenticate("Bearer nope")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("missing_token", func(t *testing.T) {
+		_, err := authenticate("")
+		assert.ErrorIs(t, err, ErrMissingCredentials)
+	})
+
+	t.Run("wrong_scheme", func(t *testing.T) {
+		_, err := authenticate("Basic Y2k6dG9rZW4=")
+		assert.ErrorIs(t, err, ErrMissingCredentials)
+	})
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(config.AuthConfig{Mode: "none"}))
+	assert.IsType(t, &StaticAuthenticator{}, New(config.AuthConfig{Mode: "static"}))
+	assert.IsType(t, &JWTAuthenticator{}, New(config.AuthConfig{Mode: "jwt", JWKSURL: "http://jwks.invalid"}))
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrCallBudgetExhausted is returned when a request has made every LLM call
+// its budget allows
+var ErrCallBudgetExhausted = errors.New("LLM call budget exhausted")
+
+// CallBudget caps the LLM calls made on behalf of a single request, however
+// they arise: the initial call, HTTP retries and validation re-prompts all
+// draw from the same budget. It is safe for concurrent use.
+type CallBudget struct {
+	max  int64
+	used atomic.Int64
+}
+
+// NewCallBudget creates a budget allowing max calls; zero or less is unlimited
+func NewCallBudget(max int) *CallBudget {
+	return &CallBudget{max: int64(max)}
+}
+
+// take claims one call, reporting false once the budget is spent
+func (b *CallBudget) take() bool {
+	if b.max <= 0 {
+		b.used.Add(1)
+		return true
+	}
+	for {
+		used := b.used.Load()
+		if used >= b.max {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+1) {
+			return true
+		}
+	}
+}
+
+// Used returns the number of calls made so far
+func (b *CallBudget) Used() int {
+	return int(b.used.Load())
+}
+
+// Remaining returns the number of calls left, or -1 when unlimited
+func (b *CallBudget) Remaining() int {
+	if b.max <= 0 {
+		return -1
+	}
+	return int(b.max - b.used.Load())
+}
+
+// callBudgetKey carries a request's CallBudget in its context
+type callBudgetKey struct{}
+
+// WithCallBudget returns a context whose LLM calls draw from budget
+func WithCallBudget(ctx context.Context, budget *CallBudget) context.Context {
+	return context.WithValue(ctx, callBudgetKey{}, budget)
+}
+
+// takeCall claims a call from the context's budget, if it has one
+func takeCall(ctx context.Context) bool {
+	budget, ok := ctx.Value(callBudgetKey{}).(*CallBudget)
+	return !ok || budget.take()
+}
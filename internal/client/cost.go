@@ -0,0 +1,30 @@
+package client
+
+import (
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// DefaultPriceModel is the price table entry used for models without their own
+const DefaultPriceModel = "*"
+
+// PriceTable estimates the cost of LLM calls from their token usage
+type PriceTable map[string]config.ModelPrice
+
+// EstimateCost returns the cost of usage at the model's prices, falling back
+// to the DefaultPriceModel entry. It reports false when the backend returned
+// no usage or the model has no price.
+func (p PriceTable) EstimateCost(model string, usage *types.Usage) (float64, bool) {
+	if usage == nil {
+		return 0, false
+	}
+	price, ok := p[model]
+	if !ok {
+		if price, ok = p[DefaultPriceModel]; !ok {
+			return 0, false
+		}
+	}
+	cost := float64(usage.PromptTokens)*price.InputPerMillion +
+		float64(usage.CompletionTokens)*price.OutputPerMillion
+	return cost / 1e6, true
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestPriceTable(t *testing.T) {
+	prices := PriceTable{
+		"gemma-3-4b": {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+		"*":          {InputPerMillion: 1.00, OutputPerMillion: 2.00},
+	}
+	usage := &types.Usage{PromptTokens: 1200, CompletionTokens: 300, TotalTokens: 1500}
+
+	t.Run("model_price", func(t *testing.T) {
+		cost, ok := prices.EstimateCost("gemma-3-4b", usage)
+		assert.True(t, ok)
+		// 1200 * $0.10/M + 300 * $0.40/M
+		assert.InDelta(t, 0.00024, cost, 1e-12)
+	})
+
+	t.Run("default_price", func(t *testing.T) {
+		cost, ok := prices.EstimateCost("unknown-model", usage)
+		assert.True(t, ok)
+		assert.InDelta(t, 0.0018, cost, 1e-12)
+	})
+
+	t.Run("unpriced_model", func(t *testing.T) {
+		_, ok := PriceTable{"gemma-3-4b": {InputPerMillion: 1}}.EstimateCost("other", usage)
+		assert.False(t, ok)
+	})
+
+	t.Run("no_usage", func(t *testing.T) {
+		_, ok := prices.EstimateCost("gemma-3-4b", nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("empty_table", func(t *testing.T) {
+		_, ok := PriceTable(map[string]config.ModelPrice{}).EstimateCost("gemma-3-4b", usage)
+		assert.False(t, ok)
+	})
+}
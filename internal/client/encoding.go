@@ -0,0 +1,45 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is returned when an LLM reply is not valid UTF-8 and the
+// client does not accept such replies
+var ErrInvalidUTF8 = errors.New("LLM response is not valid UTF-8")
+
+// utf8BOM is the byte order mark some backends put before their replies
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// SetAllowInvalidUTF8 accepts replies that are not valid UTF-8, whose invalid
+// bytes are then decoded as U+FFFD, instead of failing them with
+// ErrInvalidUTF8
+func (c *LlamaServerClient) SetAllowInvalidUTF8(allow bool) {
+	c.allowInvalidUTF8 = allow
+}
+
+// normalizeEncoding strips a leading byte order mark from a reply body, which
+// JSON decoding would otherwise reject, and checks the rest is valid UTF-8
+func (c *LlamaServerClient) normalizeEncoding(body []byte) ([]byte, error) {
+	body = bytes.TrimPrefix(body, utf8BOM)
+	if !c.allowInvalidUTF8 && !utf8.Valid(body) {
+		return nil, fmt.Errorf("%w: invalid byte at offset %d", ErrInvalidUTF8, invalidUTF8Offset(body))
+	}
+	return body, nil
+}
+
+// invalidUTF8Offset returns the offset of the first byte that does not start
+// a valid UTF-8 sequence
+func invalidUTF8Offset(body []byte) int {
+	for offset := 0; offset < len(body); {
+		r, size := utf8.DecodeRune(body[offset:])
+		if r == utf8.RuneError && size <= 1 {
+			return offset
+		}
+		offset += size
+	}
+	return len(body)
+}
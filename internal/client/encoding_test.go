@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestResponseEncoding(t *testing.T) {
+	messages := []types.Message{{Role: "user", Content: "hello"}}
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json"})
+
+	send := func(t *testing.T, body string, allowInvalid bool) (*types.ValidatedResponse, error) {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(backend.Close)
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{}, logger)
+		c.SetAllowInvalidUTF8(allowInvalid)
+		return c.SendStructuredQuery(context.Background(), messages, nil)
+	}
+	completion := func(content string) string {
+		return `{"choices": [{"message": {"role": "assistant", "content": ` + content + `}}]}`
+	}
+
+	t.Run("bom_before_body_is_stripped", func(t *testing.T) {
+		resp, err := send(t, "\xef\xbb\xbf"+completion(`"{\"name\": \"Ada\"}"`), false)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "Ada"}`, string(resp.Data))
+	})
+
+	t.Run("bom_before_content_is_stripped", func(t *testing.T) {
+		resp, err := send(t, completion(`"\ufeff{\"name\": \"Ada\"}"`), false)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "Ada"}`, string(resp.Data))
+	})
+
+	t.Run("invalid_utf8_is_rejected", func(t *testing.T) {
+		_, err := send(t, completion("\"{\\\"name\\\": \\\"Ad\xff\\\"}\""), false)
+		require.ErrorIs(t, err, ErrInvalidUTF8)
+		assert.Contains(t, err.Error(), "invalid byte at offset 74")
+	})
+
+	t.Run("invalid_utf8_is_replaced_when_allowed", func(t *testing.T) {
+		resp, err := send(t, completion("\"{\\\"name\\\": \\\"Ad\xff\\\"}\""), true)
+		require.NoError(t, err)
+		assert.JSONEq(t, "{\"name\": \"Ad\uFFFD\"}", string(resp.Data))
+	})
+}
@@ -4,8 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/wcygan/llm-json-parse/internal/logging"
@@ -14,17 +20,91 @@ import (
 
 type LLMClient interface {
 	SendStructuredQuery(ctx context.Context, messages []types.Message, schema json.RawMessage) (*types.ValidatedResponse, error)
+	// SendStructuredQueryStream is SendStructuredQuery with the output
+	// delivered as it is generated. The channel closes once the completion
+	// ends; see StreamDelta.
+	SendStructuredQueryStream(ctx context.Context, messages []types.Message, schema json.RawMessage) (<-chan StreamDelta, error)
+	HealthCheck(ctx context.Context) error
 }
 
+// Paths of the llama-server endpoints, joined to the client's base URL
+const (
+	completionsPath = "/v1/chat/completions"
+	healthPath      = "/health"
+)
+
 type LlamaServerClient struct {
-	baseURL string
-	client  *http.Client
-	logger  *logging.Logger
+	baseURL     string
+	client      *http.Client
+	logger      *logging.Logger
+	retry       RetryConfig
+	extraFields map[string]json.RawMessage
+	// jitterSource, when set, replaces the global random source for retry
+	// jitter so tests can seed it
+	jitterMu     sync.Mutex
+	jitterSource rand.Source
+	// allowInvalidUTF8 decodes replies that are not valid UTF-8 rather than
+	// failing them
+	allowInvalidUTF8 bool
+}
+
+// reservedRequestFields are completion request fields built from the caller's
+// query, which extra request fields may never replace
+var reservedRequestFields = map[string]bool{
+	"messages":        true,
+	"response_format": true,
+	"stream":          true,
+}
+
+// SetExtraRequestFields adds static fields, such as "cache_prompt", to every
+// completion request body. Fields the client sets itself always win.
+func (c *LlamaServerClient) SetExtraRequestFields(fields map[string]json.RawMessage) {
+	c.extraFields = fields
+}
+
+// withExtraFields merges the extra request fields into a marshaled request
+func (c *LlamaServerClient) withExtraFields(reqBody []byte) ([]byte, error) {
+	if len(c.extraFields) == 0 {
+		return reqBody, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(reqBody, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range c.extraFields {
+		if _, set := fields[name]; set || reservedRequestFields[name] {
+			continue
+		}
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
+
+// RetryConfig controls how failed LLM calls are retried. Attempts is the
+// number of retries after the initial call; zero disables retrying. The
+// first retry waits Delay and each later one twice as long as the last, up
+// to MaxDelay when it is set. Jitter waits a random time between zero and
+// that backoff instead, so clients that failed together don't retry in
+// lockstep.
+// EmptyResponses also retries responses with no choices, treating them as
+// a transient backend fault. DNSFailures retries calls whose backend
+// hostname failed to resolve, which is often transient in containerized
+// environments; without it such calls fail at once.
+type RetryConfig struct {
+	Attempts       int
+	Delay          time.Duration
+	MaxDelay       time.Duration
+	Jitter         bool
+	EmptyResponses bool
+	DNSFailures    bool
 }
 
+// ErrEmptyResponse is returned when the LLM server answers with no choices
+var ErrEmptyResponse = errors.New("LLM server returned no response choices")
+
 func NewLlamaServerClient(baseURL string) *LlamaServerClient {
 	return &LlamaServerClient{
-		baseURL: baseURL,
+		baseURL: normalizeBaseURL(baseURL),
 		client:  &http.Client{Timeout: 30 * time.Second},
 		logger:  logging.NewLogger(logging.LogConfig{Level: "info", Format: "json"}),
 	}
@@ -33,7 +113,7 @@ func NewLlamaServerClient(baseURL string) *LlamaServerClient {
 // NewLlamaServerClientWithTimeout creates a new LLM client with custom timeout
 func NewLlamaServerClientWithTimeout(baseURL string, timeout time.Duration) *LlamaServerClient {
 	return &LlamaServerClient{
-		baseURL: baseURL,
+		baseURL: normalizeBaseURL(baseURL),
 		client:  &http.Client{Timeout: timeout},
 		logger:  logging.NewLogger(logging.LogConfig{Level: "info", Format: "json"}),
 	}
@@ -42,31 +122,66 @@ func NewLlamaServerClientWithTimeout(baseURL string, timeout time.Duration) *Lla
 // NewLlamaServerClientWithLogger creates a new LLM client with custom logger
 func NewLlamaServerClientWithLogger(baseURL string, timeout time.Duration, logger *logging.Logger) *LlamaServerClient {
 	return &LlamaServerClient{
-		baseURL: baseURL,
+		baseURL: normalizeBaseURL(baseURL),
 		client:  &http.Client{Timeout: timeout},
 		logger:  logger,
 	}
 }
 
+// normalizeBaseURL trims trailing slashes so joining an endpoint path never
+// yields a double slash, which some servers reject
+func normalizeBaseURL(baseURL string) string {
+	return strings.TrimRight(baseURL, "/")
+}
+
+// endpoint returns the URL of the given endpoint path
+func (c *LlamaServerClient) endpoint(path string) string {
+	return c.baseURL + path
+}
+
+// noRetryKey marks a context whose LLM calls must not be retried
+type noRetryKey struct{}
+
+// WithoutRetry returns a context that disables retries for LLM calls made
+// with it, for callers that handle retries themselves
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// retryDisabled reports whether retries were disabled via WithoutRetry
+func retryDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noRetryKey{}).(bool)
+	return disabled
+}
+
+// NewLlamaServerClientWithRetry creates a new LLM client that retries transient failures
+func NewLlamaServerClientWithRetry(baseURL string, timeout time.Duration, retry RetryConfig, logger *logging.Logger) *LlamaServerClient {
+	return &LlamaServerClient{
+		baseURL: normalizeBaseURL(baseURL),
+		client:  &http.Client{Timeout: timeout},
+		logger:  logger,
+		retry:   retry,
+	}
+}
+
+// NewLlamaServerClientWithTransport creates a new LLM client that retries
+// transient failures and sends requests through the given transport
+func NewLlamaServerClientWithTransport(baseURL string, timeout time.Duration, retry RetryConfig, transport http.RoundTripper, logger *logging.Logger) *LlamaServerClient {
+	return &LlamaServerClient{
+		baseURL: normalizeBaseURL(baseURL),
+		client:  &http.Client{Timeout: timeout, Transport: transport},
+		logger:  logger,
+		retry:   retry,
+	}
+}
+
 func (c *LlamaServerClient) SendStructuredQuery(ctx context.Context, messages []types.Message, schema json.RawMessage) (*types.ValidatedResponse, error) {
 	start := time.Now()
 	logger := c.logger.WithComponent("llm_client").WithOperation("structured_query")
 
-	request := types.LLMRequest{
-		Messages: messages,
-		ResponseFormat: &types.ResponseFormat{
-			Type: "json_schema",
-			JSONSchema: types.JSONSchema{
-				Name:   "response",
-				Strict: true,
-				Schema: schema,
-			},
-		},
-	}
-
 	// Marshal request
 	marshalStart := time.Now()
-	reqBody, err := json.Marshal(request)
+	reqBody, err := c.marshalRequest(structuredRequest(messages, schema))
 	if err != nil {
 		logger.WithError(err).Error("Failed to marshal LLM request")
 		return nil, fmt.Errorf("marshal request: %w", err)
@@ -74,61 +189,30 @@ func (c *LlamaServerClient) SendStructuredQuery(ctx context.Context, messages []
 	marshalDuration := time.Since(marshalStart)
 
 	logger.WithFields(map[string]interface{}{
-		"url":                 c.baseURL + "/v1/chat/completions",
+		"url":                 c.endpoint(completionsPath),
 		"request_size_bytes":  len(reqBody),
 		"schema_size_bytes":   len(schema),
 		"message_count":       len(messages),
 		"marshal_duration_ms": marshalDuration.Milliseconds(),
 	}).Info("Sending structured query to LLM")
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/chat/completions", bytes.NewReader(reqBody))
-	if err != nil {
-		logger.WithError(err).Error("Failed to create HTTP request")
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	// Send HTTP request
+	// Send HTTP request and decode the reply, retrying transient failures
 	httpStart := time.Now()
-	resp, err := c.client.Do(httpReq)
+	llmResponse, err := c.doWithRetry(ctx, logger, reqBody)
 	httpDuration := time.Since(httpStart)
 
 	if err != nil {
 		logger.WithError(err).
 			WithDuration(httpDuration).
 			Error("HTTP request to LLM failed")
-		return nil, fmt.Errorf("http request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		logger.WithFields(map[string]interface{}{
-			"status_code":      resp.StatusCode,
-			"http_duration_ms": httpDuration.Milliseconds(),
-		}).Error("LLM server returned non-200 status")
-		return nil, fmt.Errorf("LLM server returned status %d", resp.StatusCode)
-	}
-
-	// Decode response
-	decodeStart := time.Now()
-	var llmResponse types.LLMResponse
-	if err := json.NewDecoder(resp.Body).Decode(&llmResponse); err != nil {
-		logger.WithError(err).
-			WithDuration(time.Since(decodeStart)).
-			Error("Failed to decode LLM response")
-		return nil, fmt.Errorf("decode response: %w", err)
-	}
-	decodeDuration := time.Since(decodeStart)
-
-	if len(llmResponse.Choices) == 0 {
-		logger.Error("LLM response contains no choices")
-		return nil, fmt.Errorf("no response choices")
+		return nil, err
 	}
 
 	// Validate that content is valid JSON
 	validateStart := time.Now()
 	var temp interface{}
-	content := llmResponse.Choices[0].Message.Content
+	// A byte order mark may also open the content itself
+	content := strings.TrimPrefix(llmResponse.Choices[0].Message.Content, "\ufeff")
 	if err := json.Unmarshal([]byte(content), &temp); err != nil {
 		logger.WithError(err).
 			WithDuration(time.Since(validateStart)).
@@ -146,13 +230,239 @@ func (c *LlamaServerClient) SendStructuredQuery(ctx context.Context, messages []
 			"response_size_bytes":  len(content),
 			"http_duration_ms":     httpDuration.Milliseconds(),
 			"marshal_duration_ms":  marshalDuration.Milliseconds(),
-			"decode_duration_ms":   decodeDuration.Milliseconds(),
 			"validate_duration_ms": validateDuration.Milliseconds(),
 			"llm_success":          true,
 		}).Info("LLM structured query completed successfully")
 
 	// Return as ValidatedResponse with the raw JSON
 	return &types.ValidatedResponse{
-		Data: json.RawMessage(content),
+		Data:  json.RawMessage(content),
+		Usage: llmResponse.Usage,
+		Model: llmResponse.Model,
 	}, nil
 }
+
+// structuredRequest builds the completion request for a structured query
+func structuredRequest(messages []types.Message, schema json.RawMessage) types.LLMRequest {
+	request := types.LLMRequest{
+		Messages: messages,
+	}
+	// Without a schema the backend is queried unconstrained (schemaless pass-through)
+	if len(schema) > 0 {
+		request.ResponseFormat = &types.ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: types.JSONSchema{
+				Name:   "response",
+				Strict: true,
+				Schema: schema,
+			},
+		}
+	}
+	return request
+}
+
+// marshalRequest encodes a completion request with the extra request fields
+func (c *LlamaServerClient) marshalRequest(request types.LLMRequest) ([]byte, error) {
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	return c.withExtraFields(reqBody)
+}
+
+// statusError reports a non-200 response from the LLM server
+type statusError struct {
+	statusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("LLM server returned status %d", e.statusCode)
+}
+
+// decodeError reports a 200 response whose body is not a completion
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return fmt.Sprintf("decode response: %v", e.err)
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// IsTimeout reports whether an LLM call failed because the backend did not
+// answer in time, whether the client timeout or the caller's deadline expired
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryable reports whether a failed attempt may succeed if repeated.
+// Connection errors and 5xx responses are transient; other statuses and
+// undecodable bodies are not. Empty responses are retried when configured.
+func (c *LlamaServerClient) retryable(err error) bool {
+	if errors.Is(err, ErrEmptyResponse) {
+		return c.retry.EmptyResponses
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return c.retry.DNSFailures
+	}
+	var de *decodeError
+	if errors.As(err, &de) {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.statusCode >= 500
+	}
+	return true
+}
+
+// doWithRetry sends the request body to the completions endpoint and decodes
+// the reply, retrying transient failures up to the configured number of
+// attempts. The returned response always has at least one choice.
+func (c *LlamaServerClient) doWithRetry(ctx context.Context, logger *logging.Logger, reqBody []byte) (*types.LLMResponse, error) {
+	maxAttempts := c.retry.Attempts + 1
+	if retryDisabled(ctx) {
+		maxAttempts = 1
+	}
+	var delay time.Duration
+	var lastErr error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		// Every attempt, including retries, draws from the request's call budget
+		if !takeCall(ctx) {
+			if lastErr == nil {
+				return nil, ErrCallBudgetExhausted
+			}
+			logger.LogRetryOutcome(false, attempt-1, lastErr)
+			return nil, fmt.Errorf("%w: %w", ErrCallBudgetExhausted, lastErr)
+		}
+
+		if attempt > 1 {
+			delay = c.retryDelay(attempt)
+			select {
+			case <-ctx.Done():
+				logger.LogRetryOutcome(false, attempt-1, ctx.Err())
+				return nil, fmt.Errorf("http request: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+
+		logger.LogLLMRequest(c.endpoint(completionsPath), c.client.Timeout, attempt-1)
+		resp, err := c.send(ctx, reqBody)
+		final := err == nil || !c.retryable(err) || attempt == maxAttempts
+		logger.LogRetryAttempt(attempt, maxAttempts, delay, err, final)
+
+		if err == nil {
+			if attempt > 1 {
+				logger.LogRetryOutcome(true, attempt, nil)
+			}
+			return resp, nil
+		}
+
+		lastErr = err
+		if !c.retryable(err) {
+			return nil, err
+		}
+	}
+
+	if maxAttempts > 1 {
+		logger.LogRetryOutcome(false, maxAttempts, lastErr)
+	}
+	return nil, lastErr
+}
+
+// retryDelay is the wait before the given attempt, the second being the
+// first retry: its backoff, jittered when configured
+func (c *LlamaServerClient) retryDelay(attempt int) time.Duration {
+	delay := c.backoff(attempt)
+	if !c.retry.Jitter || delay <= 0 {
+		return delay
+	}
+	if c.jitterSource == nil {
+		return rand.N(delay + 1)
+	}
+	c.jitterMu.Lock()
+	defer c.jitterMu.Unlock()
+	return time.Duration(rand.New(c.jitterSource).Int64N(int64(delay) + 1))
+}
+
+// backoff is the longest wait before the given attempt
+func (c *LlamaServerClient) backoff(attempt int) time.Duration {
+	delay := c.retry.Delay
+	for i := 2; i < attempt; i++ {
+		if c.retry.MaxDelay > 0 && delay >= c.retry.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	if c.retry.MaxDelay > 0 && delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	return delay
+}
+
+// send performs a single HTTP call to the completions endpoint and decodes
+// its reply
+func (c *LlamaServerClient) send(ctx context.Context, reqBody []byte) (*types.LLMResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.endpoint(completionsPath), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{statusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	body, err = c.normalizeEncoding(body)
+	if err != nil {
+		return nil, &decodeError{err: err}
+	}
+	var llmResponse types.LLMResponse
+	if err := json.Unmarshal(body, &llmResponse); err != nil {
+		return nil, &decodeError{err: err}
+	}
+	if len(llmResponse.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return &llmResponse, nil
+}
+
+// HealthCheck reports whether the LLM server is up and able to serve requests.
+// It is a single probe of the server's /health endpoint and is never retried.
+func (c *LlamaServerClient) HealthCheck(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.endpoint(healthPath), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{statusCode: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// newFlakyBackend returns a backend that responds with the given status codes
+// in order, then succeeds with content for every later call
+func newFlakyBackend(t *testing.T, content string, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		json.NewEncoder(w).Encode(types.LLMResponse{
+			Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: content}}},
+		})
+	}))
+	t.Cleanup(backend.Close)
+	return backend, &calls
+}
+
+// parseLogLines decodes newline-delimited JSON log output
+func parseLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSendStructuredQueryRetries(t *testing.T) {
+	messages := []types.Message{{Role: "user", Content: "hello"}}
+	schema := json.RawMessage(`{"type":"object"}`)
+
+	t.Run("retries_transient_failures_and_logs_attempts", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{"ok": true}`, http.StatusServiceUnavailable, http.StatusBadGateway)
+
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &buf})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+
+		resp, err := c.SendStructuredQuery(context.Background(), messages, schema)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"ok": true}`, string(resp.Data))
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+		var attempts []map[string]interface{}
+		var outcome map[string]interface{}
+		for _, entry := range parseLogLines(t, &buf) {
+			if entry["msg"] == "LLM call attempt completed" {
+				attempts = append(attempts, entry)
+			}
+			if _, ok := entry["retry_outcome"]; ok {
+				outcome = entry
+			}
+		}
+
+		require.Len(t, attempts, 3)
+		for i, attempt := range attempts {
+			assert.Equal(t, float64(i+1), attempt["retry_attempt"])
+			assert.Equal(t, float64(4), attempt["max_attempts"])
+		}
+		assert.Equal(t, float64(0), attempts[0]["retry_delay_ms"])
+		assert.Contains(t, attempts[0]["error"], "status 503")
+		assert.Equal(t, false, attempts[1]["final_attempt"])
+		assert.Equal(t, true, attempts[2]["final_attempt"])
+		assert.Equal(t, true, attempts[2]["attempt_success"])
+
+		require.NotNil(t, outcome)
+		assert.Equal(t, "succeeded", outcome["retry_outcome"])
+		assert.Equal(t, "LLM call succeeded after 2 retries", outcome["msg"])
+	})
+
+	t.Run("logs_exhausted_retries", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{}`, 500, 500, 500)
+
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &buf})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 2, Delay: time.Millisecond}, logger)
+
+		_, err := c.SendStructuredQuery(context.Background(), messages, schema)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "LLM server returned status 500")
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+		assert.Contains(t, buf.String(), `"retry_outcome":"exhausted"`)
+		assert.Contains(t, buf.String(), `"total_attempts":3`)
+	})
+
+	t.Run("does_not_retry_client_errors", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{}`, http.StatusBadRequest)
+
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &buf})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+
+		_, err := c.SendStructuredQuery(context.Background(), messages, schema)
+		require.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+		assert.Contains(t, buf.String(), `"final_attempt":true`)
+	})
+
+	t.Run("without_retry_context_makes_single_attempt", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{}`, http.StatusServiceUnavailable)
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: &bytes.Buffer{}})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+
+		_, err := c.SendStructuredQuery(WithoutRetry(context.Background()), messages, schema)
+		require.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("call_budget_stops_retries", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{}`, 500, 500, 500)
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: &bytes.Buffer{}})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+
+		budget := NewCallBudget(2)
+		_, err := c.SendStructuredQuery(WithCallBudget(context.Background(), budget), messages, schema)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrCallBudgetExhausted)
+		assert.Contains(t, err.Error(), "status 500")
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+		assert.Equal(t, 2, budget.Used())
+		assert.Equal(t, 0, budget.Remaining())
+
+		_, err = c.SendStructuredQuery(WithCallBudget(context.Background(), budget), messages, schema)
+		assert.ErrorIs(t, err, ErrCallBudgetExhausted)
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+
+	t.Run("unlimited_call_budget_counts_calls", func(t *testing.T) {
+		backend, _ := newFlakyBackend(t, `{}`, 500)
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: &bytes.Buffer{}})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+
+		budget := NewCallBudget(0)
+		_, err := c.SendStructuredQuery(WithCallBudget(context.Background(), budget), messages, schema)
+		require.NoError(t, err)
+		assert.Equal(t, 2, budget.Used())
+		assert.Equal(t, -1, budget.Remaining())
+	})
+
+	t.Run("backs_off_exponentially_up_to_max_delay", func(t *testing.T) {
+		c := NewLlamaServerClientWithRetry("http://llm", time.Second, RetryConfig{Attempts: 5, Delay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}, nil)
+		var delays []time.Duration
+		for attempt := 2; attempt <= 6; attempt++ {
+			delays = append(delays, c.retryDelay(attempt))
+		}
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}, delays)
+
+		uncapped := NewLlamaServerClientWithRetry("http://llm", time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, nil)
+		assert.Equal(t, 4*time.Millisecond, uncapped.retryDelay(4))
+	})
+
+	t.Run("jitter_waits_up_to_backoff", func(t *testing.T) {
+		retry := RetryConfig{Attempts: 5, Delay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond, Jitter: true}
+		newClient := func(seed uint64) *LlamaServerClient {
+			c := NewLlamaServerClientWithRetry("http://llm", time.Second, retry, nil)
+			c.jitterSource = rand.NewPCG(seed, seed)
+			return c
+		}
+
+		first, second := newClient(1), newClient(1)
+		varied := false
+		for attempt := 2; attempt <= 6; attempt++ {
+			delay := first.retryDelay(attempt)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, first.backoff(attempt))
+			assert.Equal(t, delay, second.retryDelay(attempt), "same seed, same delays")
+			varied = varied || delay != first.backoff(attempt)
+		}
+		assert.True(t, varied)
+
+		unseeded := NewLlamaServerClientWithRetry("http://llm", time.Second, retry, nil)
+		assert.LessOrEqual(t, unseeded.retryDelay(3), 200*time.Millisecond)
+	})
+
+	t.Run("logs_each_request_with_its_retry_number", func(t *testing.T) {
+		backend, _ := newFlakyBackend(t, `{}`, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &buf})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}, logger)
+
+		_, err := c.SendStructuredQuery(context.Background(), messages, schema)
+		require.NoError(t, err)
+
+		var requests, attempts []map[string]interface{}
+		for _, entry := range parseLogLines(t, &buf) {
+			switch entry["msg"] {
+			case "LLM request initiated":
+				requests = append(requests, entry)
+			case "LLM call attempt completed":
+				attempts = append(attempts, entry)
+			}
+		}
+		require.Len(t, requests, 3)
+		for i, request := range requests {
+			assert.Equal(t, float64(i), request["retry_attempt"])
+			assert.Equal(t, backend.URL+"/v1/chat/completions", request["llm_url"])
+		}
+		require.Len(t, attempts, 3)
+		assert.Equal(t, float64(1), attempts[1]["retry_delay_ms"])
+		assert.Equal(t, float64(2), attempts[2]["retry_delay_ms"])
+	})
+
+	t.Run("cancellation_aborts_backoff", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{}`, http.StatusServiceUnavailable)
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: &bytes.Buffer{}})
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Hour}, logger)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := c.SendStructuredQuery(ctx, messages, schema)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("no_retries_by_default", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{}`, http.StatusServiceUnavailable)
+
+		c := NewLlamaServerClient(backend.URL)
+		_, err := c.SendStructuredQuery(context.Background(), messages, schema)
+		require.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+}
+
+func TestHealthCheck(t *testing.T) {
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json"})
+
+	t.Run("healthy_backend", func(t *testing.T) {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/health", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer backend.Close()
+
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{}, logger)
+		assert.NoError(t, c.HealthCheck(context.Background()))
+	})
+
+	t.Run("unhealthy_backend", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, "", http.StatusServiceUnavailable)
+
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+		err := c.HealthCheck(context.Background())
+		assert.ErrorContains(t, err, "status 503")
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+}
+
+func TestTrailingSlashBaseURL(t *testing.T) {
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json"})
+	var paths []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/v1/chat/completions" {
+			json.NewEncoder(w).Encode(types.LLMResponse{
+				Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: `{}`}}},
+			})
+		}
+	}))
+	defer backend.Close()
+
+	c := NewLlamaServerClientWithRetry(backend.URL+"//", 5*time.Second, RetryConfig{}, logger)
+	_, err := c.SendStructuredQuery(context.Background(), []types.Message{{Role: "user", Content: "Hi"}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.HealthCheck(context.Background()))
+
+	assert.Equal(t, []string{"/v1/chat/completions", "/health"}, paths)
+}
+
+func TestExtraRequestFields(t *testing.T) {
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json"})
+	var received map[string]json.RawMessage
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(types.LLMResponse{
+			Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: `{}`}}},
+		})
+	}))
+	defer backend.Close()
+
+	c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{}, logger)
+	c.SetExtraRequestFields(map[string]json.RawMessage{
+		"cache_prompt":    json.RawMessage(`true`),
+		"top_k":           json.RawMessage(`40`),
+		"messages":        json.RawMessage(`[]`),
+		"response_format": json.RawMessage(`{"type": "text"}`),
+	})
+
+	messages := []types.Message{{Role: "user", Content: "Hi"}}
+	_, err := c.SendStructuredQuery(context.Background(), messages, json.RawMessage(`{"type": "object"}`))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `true`, string(received["cache_prompt"]))
+	assert.JSONEq(t, `40`, string(received["top_k"]))
+	assert.JSONEq(t, `[{"role": "user", "content": "Hi"}]`, string(received["messages"]))
+	assert.Contains(t, string(received["response_format"]), "json_schema")
+}
+
+func TestEmptyChoices(t *testing.T) {
+	messages := []types.Message{{Role: "user", Content: "hello"}}
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json"})
+
+	// newEmptyThenValidBackend answers with no choices `empties` times, then succeeds
+	newEmptyThenValidBackend := func(t *testing.T, empties int) (*httptest.Server, *int32) {
+		var calls int32
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if int(atomic.AddInt32(&calls, 1)) <= empties {
+				json.NewEncoder(w).Encode(types.LLMResponse{Choices: []types.Choice{}})
+				return
+			}
+			json.NewEncoder(w).Encode(types.LLMResponse{
+				Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: `{"ok": true}`}}},
+			})
+		}))
+		t.Cleanup(backend.Close)
+		return backend, &calls
+	}
+
+	t.Run("not_retried_by_default", func(t *testing.T) {
+		backend, calls := newEmptyThenValidBackend(t, 1)
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+
+		_, err := c.SendStructuredQuery(context.Background(), messages, nil)
+		require.ErrorIs(t, err, ErrEmptyResponse)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("retried_when_configured", func(t *testing.T) {
+		backend, calls := newEmptyThenValidBackend(t, 2)
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second,
+			RetryConfig{Attempts: 3, Delay: time.Millisecond, EmptyResponses: true}, logger)
+
+		resp, err := c.SendStructuredQuery(context.Background(), messages, nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"ok": true}`, string(resp.Data))
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	})
+
+	t.Run("retries_exhausted", func(t *testing.T) {
+		backend, calls := newEmptyThenValidBackend(t, 5)
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second,
+			RetryConfig{Attempts: 2, Delay: time.Millisecond, EmptyResponses: true}, logger)
+
+		_, err := c.SendStructuredQuery(context.Background(), messages, nil)
+		require.ErrorIs(t, err, ErrEmptyResponse)
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	})
+
+	t.Run("undecodable_body_not_retried", func(t *testing.T) {
+		var calls int32
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Write([]byte("not json"))
+		}))
+		defer backend.Close()
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second,
+			RetryConfig{Attempts: 3, Delay: time.Millisecond, EmptyResponses: true}, logger)
+
+		_, err := c.SendStructuredQuery(context.Background(), messages, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "decode response")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestDNSFailureRetry(t *testing.T) {
+	messages := []types.Message{{Role: "user", Content: "hello"}}
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json"})
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(types.LLMResponse{
+			Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: `{"ok": true}`}}},
+		})
+	}))
+	defer backend.Close()
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+
+	// flakyDNSTransport fails to resolve llm.internal on its first dial, then
+	// routes it to the test backend
+	newFlakyDNSTransport := func() (*http.Transport, *int32) {
+		var dials int32
+		var dialer net.Dialer
+		return &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if atomic.AddInt32(&dials, 1) == 1 {
+					return nil, &net.DNSError{Err: "server misbehaving", Name: "llm.internal", IsTemporary: true}
+				}
+				return dialer.DialContext(ctx, network, backendAddr)
+			},
+		}, &dials
+	}
+
+	t.Run("retried_when_configured", func(t *testing.T) {
+		transport, dials := newFlakyDNSTransport()
+		c := NewLlamaServerClientWithTransport("http://llm.internal:8080", 5*time.Second,
+			RetryConfig{Attempts: 2, Delay: time.Millisecond, DNSFailures: true}, transport, logger)
+
+		resp, err := c.SendStructuredQuery(context.Background(), messages, nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"ok": true}`, string(resp.Data))
+		assert.Equal(t, int32(2), atomic.LoadInt32(dials))
+	})
+
+	t.Run("not_retried_when_disabled", func(t *testing.T) {
+		transport, dials := newFlakyDNSTransport()
+		c := NewLlamaServerClientWithTransport("http://llm.internal:8080", 5*time.Second,
+			RetryConfig{Attempts: 2, Delay: time.Millisecond}, transport, logger)
+
+		_, err := c.SendStructuredQuery(context.Background(), messages, nil)
+		var dnsErr *net.DNSError
+		require.ErrorAs(t, err, &dnsErr)
+		assert.Equal(t, int32(1), atomic.LoadInt32(dials))
+	})
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// maxStreamLineBytes bounds one server-sent event line of a streamed
+// completion
+const maxStreamLineBytes = 1 << 20
+
+// StreamDelta is one piece of a streamed completion. Content is the text
+// generated since the previous delta; Model and Usage are set on whichever
+// delta the backend reports them in. A delta with Err set is the last one
+// sent and means the stream broke off.
+type StreamDelta struct {
+	Content string
+	Model   string
+	Usage   *types.Usage
+	Err     error
+}
+
+// streamChunk is one event of a llama-server completion stream
+type streamChunk struct {
+	Model   string `json:"model,omitempty"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *types.Usage `json:"usage,omitempty"`
+}
+
+// SendStructuredQueryStream sends a structured query with stream: true and
+// delivers the completion's deltas as they arrive. Errors before the stream
+// opens, such as a non-200 status, are returned directly. The call draws on
+// the request's call budget but is never retried, since deltas may already
+// have been passed on.
+func (c *LlamaServerClient) SendStructuredQueryStream(ctx context.Context, messages []types.Message, schema json.RawMessage) (<-chan StreamDelta, error) {
+	logger := c.logger.WithComponent("llm_client").WithOperation("structured_query_stream")
+
+	request := structuredRequest(messages, schema)
+	request.Stream = true
+	reqBody, err := c.marshalRequest(request)
+	if err != nil {
+		logger.WithError(err).Error("Failed to marshal LLM request")
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	if !takeCall(ctx) {
+		return nil, ErrCallBudgetExhausted
+	}
+
+	logger.LogLLMRequest(c.endpoint(completionsPath), c.client.Timeout, 0)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.endpoint(completionsPath), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		logger.WithError(err).Error("HTTP request to LLM failed")
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err := &statusError{statusCode: resp.StatusCode}
+		logger.WithError(err).Error("HTTP request to LLM failed")
+		return nil, err
+	}
+
+	deltas := make(chan StreamDelta)
+	go c.readStream(ctx, resp.Body, deltas, logger)
+	return deltas, nil
+}
+
+// readStream forwards the deltas of a completion stream until its [DONE]
+// event or the end of the body, then closes deltas
+func (c *LlamaServerClient) readStream(ctx context.Context, body io.ReadCloser, deltas chan<- StreamDelta, logger *logging.Logger) {
+	defer close(deltas)
+	defer body.Close()
+
+	send := func(delta StreamDelta) bool {
+		select {
+		case deltas <- delta:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	sawChoice := false
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxStreamLineBytes)
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		payload = strings.TrimSpace(payload)
+		if payload == "[DONE]" {
+			break
+		}
+
+		// Decoding would silently replace invalid bytes, so check them first
+		event, err := c.normalizeEncoding([]byte(payload))
+		if err != nil {
+			logger.WithError(err).Error("LLM stream event is not valid UTF-8")
+			send(StreamDelta{Err: &decodeError{err: err}})
+			return
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal(event, &chunk); err != nil {
+			logger.WithError(err).Error("Failed to decode LLM stream event")
+			send(StreamDelta{Err: &decodeError{err: err}})
+			return
+		}
+		delta := StreamDelta{Model: chunk.Model, Usage: chunk.Usage}
+		if len(chunk.Choices) > 0 {
+			sawChoice = true
+			delta.Content = chunk.Choices[0].Delta.Content
+		}
+		if delta.Content == "" && delta.Usage == nil {
+			continue
+		}
+		if !send(delta) {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.WithError(err).Error("LLM stream broke off")
+		send(StreamDelta{Err: fmt.Errorf("read response: %w", err)})
+		return
+	}
+	if !sawChoice {
+		send(StreamDelta{Err: ErrEmptyResponse})
+	}
+}
+
+// CollectStream gathers a completion stream into the response
+// SendStructuredQuery would have returned, passing each piece of content to
+// onDelta as it arrives
+func CollectStream(deltas <-chan StreamDelta, onDelta func(content string)) (*types.ValidatedResponse, error) {
+	var content strings.Builder
+	response := &types.ValidatedResponse{}
+	for delta := range deltas {
+		if delta.Err != nil {
+			return nil, delta.Err
+		}
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			if onDelta != nil {
+				onDelta(delta.Content)
+			}
+		}
+		if delta.Model != "" {
+			response.Model = delta.Model
+		}
+		if delta.Usage != nil {
+			response.Usage = delta.Usage
+		}
+	}
+
+	// A byte order mark may also open the content itself
+	data := strings.TrimPrefix(content.String(), "\ufeff")
+	var temp interface{}
+	if err := json.Unmarshal([]byte(data), &temp); err != nil {
+		return nil, fmt.Errorf("LLM response is not valid JSON: %w", err)
+	}
+	response.Data = json.RawMessage(data)
+	return response, nil
+}
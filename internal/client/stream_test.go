@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// newStreamingBackend returns a backend that streams events as the data
+// lines of a completion stream, recording whether it was asked to stream
+func newStreamingBackend(t *testing.T, events ...string) (*httptest.Server, *types.LLMRequest) {
+	var received types.LLMRequest
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(backend.Close)
+	return backend, &received
+}
+
+func TestSendStructuredQueryStream(t *testing.T) {
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	messages := []types.Message{{Role: "user", Content: "Who?"}}
+	schema := json.RawMessage(`{"type": "object"}`)
+
+	t.Run("delivers_deltas_in_order", func(t *testing.T) {
+		backend, received := newStreamingBackend(t,
+			`{"model": "m", "choices": [{"delta": {"role": "assistant"}}]}`,
+			`{"model": "m", "choices": [{"delta": {"content": "{\"name\": "}}]}`,
+			`{"model": "m", "choices": [{"delta": {"content": "\"Ada\"}"}}]}`,
+			`{"model": "m", "choices": [], "usage": {"prompt_tokens": 3, "completion_tokens": 4, "total_tokens": 7}}`,
+			`[DONE]`,
+		)
+		c := NewLlamaServerClientWithLogger(backend.URL, 5*time.Second, logger)
+
+		deltas, err := c.SendStructuredQueryStream(context.Background(), messages, schema)
+		require.NoError(t, err)
+
+		var pieces []string
+		response, err := CollectStream(deltas, func(content string) { pieces = append(pieces, content) })
+		require.NoError(t, err)
+		assert.True(t, received.Stream)
+		assert.Equal(t, []string{`{"name": `, `"Ada"}`}, pieces)
+		assert.JSONEq(t, `{"name": "Ada"}`, string(response.Data))
+		assert.Equal(t, "m", response.Model)
+		assert.Equal(t, &types.Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7}, response.Usage)
+	})
+
+	t.Run("incomplete_json_fails_collection", func(t *testing.T) {
+		backend, _ := newStreamingBackend(t, `{"choices": [{"delta": {"content": "{\"name\""}}]}`)
+		c := NewLlamaServerClientWithLogger(backend.URL, 5*time.Second, logger)
+
+		deltas, err := c.SendStructuredQueryStream(context.Background(), messages, schema)
+		require.NoError(t, err)
+		_, err = CollectStream(deltas, nil)
+		assert.ErrorContains(t, err, "not valid JSON")
+	})
+
+	t.Run("invalid_utf8_is_rejected", func(t *testing.T) {
+		backend, _ := newStreamingBackend(t, "{\"choices\": [{\"delta\": {\"content\": \"{\\\"name\\\": \\\"Ad\xff\\\"}\"}}]}", `[DONE]`)
+		c := NewLlamaServerClientWithLogger(backend.URL, 5*time.Second, logger)
+
+		deltas, err := c.SendStructuredQueryStream(context.Background(), messages, schema)
+		require.NoError(t, err)
+		_, err = CollectStream(deltas, nil)
+		assert.ErrorIs(t, err, ErrInvalidUTF8)
+	})
+
+	t.Run("invalid_utf8_is_replaced_when_allowed", func(t *testing.T) {
+		backend, _ := newStreamingBackend(t, "{\"choices\": [{\"delta\": {\"content\": \"{\\\"name\\\": \\\"Ad\xff\\\"}\"}}]}", `[DONE]`)
+		c := NewLlamaServerClientWithLogger(backend.URL, 5*time.Second, logger)
+		c.SetAllowInvalidUTF8(true)
+
+		deltas, err := c.SendStructuredQueryStream(context.Background(), messages, schema)
+		require.NoError(t, err)
+		response, err := CollectStream(deltas, nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, "{\"name\": \"Ad\uFFFD\"}", string(response.Data))
+	})
+
+	t.Run("stream_without_choices_is_empty", func(t *testing.T) {
+		backend, _ := newStreamingBackend(t, `[DONE]`)
+		c := NewLlamaServerClientWithLogger(backend.URL, 5*time.Second, logger)
+
+		deltas, err := c.SendStructuredQueryStream(context.Background(), messages, schema)
+		require.NoError(t, err)
+		_, err = CollectStream(deltas, nil)
+		assert.ErrorIs(t, err, ErrEmptyResponse)
+	})
+
+	t.Run("error_status_returned_before_streaming", func(t *testing.T) {
+		backend, calls := newFlakyBackend(t, `{}`, http.StatusServiceUnavailable)
+		c := NewLlamaServerClientWithRetry(backend.URL, 5*time.Second, RetryConfig{Attempts: 2, Delay: time.Millisecond}, logger)
+
+		_, err := c.SendStructuredQueryStream(context.Background(), messages, schema)
+		assert.ErrorContains(t, err, "status 503")
+		assert.Equal(t, int32(1), *calls, "streamed calls are not retried")
+	})
+}
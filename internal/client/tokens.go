@@ -0,0 +1,38 @@
+package client
+
+import (
+	"unicode/utf8"
+
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// TokenEstimator estimates how many prompt tokens a set of messages will consume
+type TokenEstimator interface {
+	EstimateTokens(messages []types.Message) int
+}
+
+// CharTokenEstimator approximates tokens from character counts, which is
+// close enough for most English text when a real tokenizer isn't available
+type CharTokenEstimator struct {
+	CharsPerToken int
+}
+
+// NewCharTokenEstimator creates an estimator using the common ~4 characters per token heuristic
+func NewCharTokenEstimator() *CharTokenEstimator {
+	return &CharTokenEstimator{CharsPerToken: 4}
+}
+
+// EstimateTokens returns the estimated token count, rounding each message up
+func (e *CharTokenEstimator) EstimateTokens(messages []types.Message) int {
+	charsPerToken := e.CharsPerToken
+	if charsPerToken <= 0 {
+		charsPerToken = 4
+	}
+
+	total := 0
+	for _, msg := range messages {
+		chars := utf8.RuneCountInString(msg.Content)
+		total += (chars + charsPerToken - 1) / charsPerToken
+	}
+	return total
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestCharTokenEstimator(t *testing.T) {
+	estimator := NewCharTokenEstimator()
+
+	t.Run("rounds_up_per_message", func(t *testing.T) {
+		messages := []types.Message{
+			{Role: "system", Content: strings.Repeat("a", 8)},
+			{Role: "user", Content: strings.Repeat("b", 9)},
+		}
+		assert.Equal(t, 2+3, estimator.EstimateTokens(messages))
+	})
+
+	t.Run("counts_runes_not_bytes", func(t *testing.T) {
+		messages := []types.Message{{Role: "user", Content: "héllo wörld"}}
+		assert.Equal(t, 3, estimator.EstimateTokens(messages))
+	})
+
+	t.Run("empty_messages", func(t *testing.T) {
+		assert.Equal(t, 0, estimator.EstimateTokens(nil))
+	})
+}
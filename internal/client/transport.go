@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TransportConfig tunes connections to the LLM backend. DNSCacheTTL caches
+// resolved backend addresses for that long; zero resolves on every dial as
+// Go does by default. KeepAlive and MaxIdleConnsPerHost control reuse of
+// connections, which matters because all traffic goes to a single host.
+type TransportConfig struct {
+	DNSCacheTTL         time.Duration
+	KeepAlive           time.Duration
+	MaxIdleConnsPerHost int
+}
+
+// Resolver looks up the addresses of a host; *net.Resolver satisfies it
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// NewTransport creates an HTTP transport for LLM backends using cfg
+func NewTransport(cfg TransportConfig) *http.Transport {
+	return newTransport(cfg, net.DefaultResolver)
+}
+
+func newTransport(cfg TransportConfig, resolver Resolver) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if cfg.DNSCacheTTL > 0 {
+		transport.DialContext = newDNSCache(resolver, cfg.DNSCacheTTL).dialer(dialer)
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	return transport
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// dnsCache remembers host lookups for a fixed TTL. Failed lookups are not
+// cached so an outage is retried on the next dial.
+type dnsCache struct {
+	resolver Resolver
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+func newDNSCache(resolver Resolver, ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		resolver: resolver,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]dnsEntry),
+	}
+}
+
+// lookup returns the cached addresses for host, resolving it when missing or expired
+func (d *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	entry, ok := d.entries[host]
+	d.mu.Unlock()
+	if ok && d.now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.entries[host] = dnsEntry{addrs: addrs, expires: d.now().Add(d.ttl)}
+	d.mu.Unlock()
+	return addrs, nil
+}
+
+// dialer returns a DialContext that resolves hosts through the cache and
+// tries each resolved address in turn
+func (d *dnsCache) dialer(base *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return base.DialContext(ctx, network, address)
+		}
+
+		addrs, err := d.lookup(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", host, err)
+		}
+
+		var lastErr error
+		for _, addr := range addrs {
+			conn, err := base.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no addresses for %s", host)
+		}
+		return nil, lastErr
+	}
+}
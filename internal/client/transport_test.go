@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingResolver resolves every host to loopback and counts lookups
+type countingResolver struct {
+	lookups atomic.Int32
+	err     error
+}
+
+func (r *countingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups.Add(1)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []string{"127.0.0.1"}, nil
+}
+
+func TestDNSCache(t *testing.T) {
+	t.Run("reuses_lookups_within_ttl", func(t *testing.T) {
+		resolver := &countingResolver{}
+		cache := newDNSCache(resolver, time.Minute)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+
+		for i := 0; i < 10; i++ {
+			addrs, err := cache.lookup(context.Background(), "llm.internal")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"127.0.0.1"}, addrs)
+		}
+		assert.Equal(t, int32(1), resolver.lookups.Load())
+
+		now = now.Add(time.Minute)
+		_, err := cache.lookup(context.Background(), "llm.internal")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), resolver.lookups.Load())
+	})
+
+	t.Run("failures_are_not_cached", func(t *testing.T) {
+		resolver := &countingResolver{err: errors.New("no such host")}
+		cache := newDNSCache(resolver, time.Minute)
+
+		_, err := cache.lookup(context.Background(), "llm.internal")
+		assert.Error(t, err)
+		_, err = cache.lookup(context.Background(), "llm.internal")
+		assert.Error(t, err)
+		assert.Equal(t, int32(2), resolver.lookups.Load())
+	})
+}
+
+func TestTransportDNSLookups(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	_, port, err := net.SplitHostPort(backend.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// Disable keep-alives so every request dials and would otherwise resolve
+	run := func(ttl time.Duration) int32 {
+		resolver := &countingResolver{}
+		transport := newTransport(TransportConfig{DNSCacheTTL: ttl}, resolver)
+		transport.DisableKeepAlives = true
+		if ttl == 0 {
+			// Route uncached dials through the same resolver for comparison
+			transport.DialContext = newDNSCache(resolver, 0).dialer(&net.Dialer{})
+		}
+		httpClient := &http.Client{Transport: transport}
+
+		for i := 0; i < 20; i++ {
+			resp, err := httpClient.Get("http://llm.internal:" + port + "/health")
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+		return resolver.lookups.Load()
+	}
+
+	assert.Equal(t, int32(20), run(0))
+	assert.Equal(t, int32(1), run(time.Minute))
+}
+
+func BenchmarkDNSCacheLookup(b *testing.B) {
+	cache := newDNSCache(&countingResolver{}, time.Minute)
+	for i := 0; i < b.N; i++ {
+		cache.lookup(context.Background(), "llm.internal")
+	}
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -10,10 +16,21 @@ import (
 
 // Config represents the complete application configuration
 type Config struct {
-	Server ServerConfig `json:"server"`
-	LLM    LLMConfig    `json:"llm"`
-	Cache  CacheConfig  `json:"cache"`
-	Log    LogConfig    `json:"log"`
+	Server        ServerConfig        `json:"server"`
+	LLM           LLMConfig           `json:"llm"`
+	Cache         CacheConfig         `json:"cache"`
+	Log           LogConfig           `json:"log"`
+	Tenants       TenantsConfig       `json:"tenants"`
+	Debug         DebugConfig         `json:"debug"`
+	Admin         AdminConfig         `json:"admin"`
+	Validation    ValidationConfig    `json:"validation"`
+	Concurrency   ConcurrencyConfig   `json:"concurrency"`
+	Health        HealthConfig        `json:"health"`
+	Output        OutputConfig        `json:"output"`
+	ResponseCache ResponseCacheConfig `json:"response_cache"`
+	Webhook       WebhookConfig       `json:"webhook"`
+	Registry      RegistryConfig      `json:"registry"`
+	Auth          AuthConfig          `json:"auth"`
 }
 
 // ServerConfig contains HTTP server configuration
@@ -23,6 +40,40 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `json:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout"`
 	IdleTimeout  time.Duration `json:"idle_timeout"`
+	// ReadHeaderTimeout bounds reading request headers, so a client
+	// trickling them in (slowloris) cannot hold a connection for the whole
+	// ReadTimeout
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout"`
+	// Compression gzips responses for clients that accept it, at
+	// CompressionLevel: 1 (fastest) to 9 (smallest), 0 for none, -1 for
+	// gzip's default or -2 for Huffman-only
+	Compression      bool `json:"compression"`
+	CompressionLevel int  `json:"compression_level"`
+	// StrictStartup refuses to start when the LLM server URL looks like a
+	// default or placeholder, instead of only warning
+	StrictStartup bool `json:"strict_startup"`
+	// TrustedProxies lists the CIDRs of proxies whose X-Forwarded-For hops
+	// are believed when resolving the client IP
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+	// MaxBodyBytes caps request bodies, which are buffered in memory so
+	// handlers can read them more than once; zero means no limit
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// MaxMessageBytes caps the content of each message in a validated
+	// query, so one huge message cannot bloat the prompt; zero means no limit
+	MaxMessageBytes int64 `json:"max_message_bytes"`
+	// VerifyContentLength rejects bodies whose size differs from their
+	// declared Content-Length, e.g. uploads truncated in transit
+	VerifyContentLength bool `json:"verify_content_length"`
+	// MaxRequestDuration caps the whole handling of a validated query,
+	// across LLM calls, retries, re-prompts and validation; zero means no cap
+	MaxRequestDuration time.Duration `json:"max_request_duration"`
+	// RouteTimeouts replaces WriteTimeout as the request timeout of the
+	// listed paths, e.g. to give batch endpoints a larger budget
+	RouteTimeouts map[string]time.Duration `json:"route_timeouts,omitempty"`
+	// MissingContentType decides what happens to a POST, PUT or PATCH sent
+	// without a Content-Type: "reject" answers 415, "assume" treats the body
+	// as JSON, for clients that omit the header
+	MissingContentType string `json:"missing_content_type"`
 }
 
 // LLMConfig contains LLM client configuration
@@ -32,45 +83,624 @@ type LLMConfig struct {
 	RetryAttempts int           `json:"retry_attempts"`
 	RetryDelay    time.Duration `json:"retry_delay"`
 	MaxRetryDelay time.Duration `json:"max_retry_delay"`
+	// RetryJitter waits a random part of each retry's backoff, so requests
+	// that failed together don't all retry at once
+	RetryJitter     bool `json:"retry_jitter"`
+	MaxPromptTokens int  `json:"max_prompt_tokens"`
+	// RetryEmptyResponses retries replies with no choices like other
+	// transient backend failures
+	RetryEmptyResponses bool `json:"retry_empty_responses"`
+	// RetryDNSFailures retries calls whose backend hostname failed to
+	// resolve; otherwise they fail without retrying
+	RetryDNSFailures bool `json:"retry_dns_failures"`
+	// AllowInvalidUTF8 accepts replies that are not valid UTF-8, decoding
+	// invalid bytes as U+FFFD, instead of failing them
+	AllowInvalidUTF8 bool `json:"allow_invalid_utf8"`
+	// SchemaHints spells out enum, format and pattern constraints as
+	// descriptions in the schema sent to the LLM; validation still uses
+	// the schema as given
+	SchemaHints bool `json:"schema_hints"`
+	// MaxCallsPerRequest caps LLM calls per request across retries and
+	// repair re-prompts; zero means no cap
+	MaxCallsPerRequest int `json:"max_calls_per_request"`
+
+	// FallbackURL is a backend, typically serving a smaller, faster model,
+	// that retries a validated query once the default backend times out.
+	// FallbackModel is sent as its completion request's "model" when set.
+	FallbackURL   string `json:"fallback_url,omitempty"`
+	FallbackModel string `json:"fallback_model,omitempty"`
+
+	// Connection tuning; DNSCacheTTL of zero resolves the backend on every dial
+	DNSCacheTTL         time.Duration `json:"dns_cache_ttl"`
+	KeepAlive           time.Duration `json:"keep_alive"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host"`
+
+	// BackendOverride lets trusted clients pick an allowlisted backend per request
+	BackendOverride BackendOverrideConfig `json:"backend_override"`
+
+	// Prices maps model names, or "*" for any other model, to token prices
+	// used to estimate each request's cost; empty disables cost estimates
+	Prices map[string]ModelPrice `json:"prices,omitempty"`
+
+	// ExtraRequestFields are static fields merged into every completion
+	// request body, e.g. {"cache_prompt": true}; they never replace the
+	// messages or response format
+	ExtraRequestFields map[string]json.RawMessage `json:"extra_request_fields,omitempty"`
+}
+
+// ModelPrice is a model's price in USD per million input (prompt) and output
+// (completion) tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
 }
 
-// CacheConfig contains schema cache configuration
+// BackendOverrideConfig contains the trusted-mode X-LLM-Backend override.
+// It must stay disabled unless every client of the gateway is trusted.
+type BackendOverrideConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Allowlist []string `json:"allowlist,omitempty"`
+}
+
+// CacheConfig contains schema cache configuration. ResultMaxSize bounds the
+// cache of /v1/validate and batch validation reports, keyed by schema and
+// data and kept for ResultTTL; zero disables it.
 type CacheConfig struct {
-	MaxSize int           `json:"max_size"`
-	TTL     time.Duration `json:"ttl"`
+	MaxSize        int           `json:"max_size"`
+	TTL            time.Duration `json:"ttl"`
+	EvictionPolicy string        `json:"eviction_policy"`
+	ResultMaxSize  int           `json:"result_max_size"`
+	ResultTTL      time.Duration `json:"result_ttl"`
 }
 
 // LogConfig contains logging configuration
 type LogConfig struct {
-	Level  string `json:"level"`
-	Format string `json:"format"`
+	Level         string `json:"level"`
+	Format        string `json:"format"`
+	StartupConfig bool   `json:"startup_config"`
+	// FieldPrefix namespaces structured log keys, e.g. "llmjp" for "llmjp.component"
+	FieldPrefix string `json:"field_prefix"`
+	// HeaderFields maps request header names to log field names added to
+	// every log line of a request, e.g. {"X-Feature-Flag": "feature_flag"}
+	HeaderFields map[string]string `json:"header_fields,omitempty"`
+	// MiddlewareTimings logs, at debug level, how long each middleware and
+	// the final handler took for every request
+	MiddlewareTimings bool `json:"middleware_timings"`
+}
+
+// maxLogHeaderFields bounds how many headers are copied into request logs
+const maxLogHeaderFields = 16
+
+// ValidationConfig contains request and response validation behavior.
+// Timeout bounds validating a single response; zero means no limit.
+// DuplicateKeys decides what happens when the LLM repeats a key within an
+// object: "allow" it, "warn" about it, or "reject" the response.
+// SchemaInjection likewise decides what happens to a schema whose strings,
+// such as descriptions, contain likely prompt-injection phrases.
+// MaxRepairAttempts re-prompts the LLM with the validation error up to that
+// many times when its response fails the schema; zero returns 422 at once.
+// RepairDeterministicOnly limits those re-prompts to requests that set
+// "deterministic", so free-form answers are not re-rolled at extra cost.
+// ApplyDefaults fills absent properties from their schema "default" after
+// validation; it changes the returned data, so it is off by default.
+// AssertFormats makes "format" an assertion even for schemas declaring a
+// draft that treats it as an annotation; RequireObjectRoot and
+// RejectPermissive reject schemas whose root is not an object or that
+// constrain nothing beyond the type. Preset records the strictness preset
+// the other fields started from, if any. NullPolicy decides what happens to
+// a null in a property whose schema doesn't allow it: "reject" it, "strip"
+// it so "required" decides, or replace it with the schema "default".
+// StrictIntegers rejects integral numbers written as 25.0 or 2.5e1 where the
+// schema expects an integer, which JSON Schema itself accepts.
+// BatchMaxPayloads caps the payloads
+// in one /v1/validate/batch call and BatchConcurrency how many of them are
+// validated at once. A validated response smaller than MinResponseBytes
+// (compacted) or with fewer than MinResponseProperties top-level properties
+// is logged, and reported as a warning, as a likely refusal; zero disables
+// either check.
+type ValidationConfig struct {
+	Preset                  string        `json:"preset,omitempty"`
+	AllowSchemaless         bool          `json:"allow_schemaless"`
+	ReportWarnings          bool          `json:"report_warnings"`
+	Timeout                 time.Duration `json:"timeout"`
+	DuplicateKeys           string        `json:"duplicate_keys"`
+	SchemaInjection         string        `json:"schema_injection"`
+	MaxRepairAttempts       int           `json:"max_repair_attempts"`
+	RepairDeterministicOnly bool          `json:"repair_deterministic_only"`
+	ApplyDefaults           bool          `json:"apply_defaults"`
+	AssertFormats           bool          `json:"assert_formats"`
+	RequireObjectRoot       bool          `json:"require_object_root"`
+	RejectPermissive        bool          `json:"reject_permissive"`
+	NullPolicy              string        `json:"null_policy"`
+	StrictIntegers          bool          `json:"strict_integers"`
+	BatchMaxPayloads        int           `json:"batch_max_payloads"`
+	BatchConcurrency        int           `json:"batch_concurrency"`
+	// Response size thresholds below which a warning is raised
+	MinResponseBytes      int `json:"min_response_bytes"`
+	MinResponseProperties int `json:"min_response_properties"`
+}
+
+// Validation strictness presets accepted by ApplyPreset
+var validationPresets = []string{"lenient", "standard", "strict"}
+
+// ApplyPreset sets the strictness flags to a named preset:
+//
+//   - lenient: schemaless requests allowed; formats left to the schema; no
+//     warnings; duplicate keys allowed; any schema accepted
+//   - standard: a schema is required; formats are asserted; warnings are
+//     reported; duplicate keys warned about; any schema accepted
+//   - strict: as standard, but duplicate keys are rejected and schemas must
+//     have an object root that constrains more than the type
+//
+// Flags set afterwards, e.g. from the environment, override the preset.
+func (v *ValidationConfig) ApplyPreset(name string) error {
+	switch name {
+	case "lenient":
+		v.AllowSchemaless, v.AssertFormats, v.ReportWarnings = true, false, false
+		v.DuplicateKeys, v.RequireObjectRoot, v.RejectPermissive = "allow", false, false
+	case "standard":
+		v.AllowSchemaless, v.AssertFormats, v.ReportWarnings = false, true, true
+		v.DuplicateKeys, v.RequireObjectRoot, v.RejectPermissive = "warn", false, false
+	case "strict":
+		v.AllowSchemaless, v.AssertFormats, v.ReportWarnings = false, true, true
+		v.DuplicateKeys, v.RequireObjectRoot, v.RejectPermissive = "reject", true, true
+	default:
+		return fmt.Errorf("validation preset must be one of %v, got %s", validationPresets, name)
+	}
+	v.Preset = name
+	return nil
+}
+
+// ConcurrencyConfig contains request admission limits. PriorityLevels names
+// the priorities accepted in PriorityHeader from highest to lowest; with no
+// levels requests are admitted in arrival order. MaxPerClient caps the
+// requests a single client IP may have in flight; zero means no cap.
+type ConcurrencyConfig struct {
+	MaxConcurrent  int      `json:"max_concurrent"`
+	MaxPerClient   int      `json:"max_per_client"`
+	PriorityHeader string   `json:"priority_header"`
+	PriorityLevels []string `json:"priority_levels,omitempty"`
+}
+
+// HealthConfig contains readiness probe configuration. A backend health check
+// result is reused for CacheTTL; zero checks the backend on every probe.
+type HealthConfig struct {
+	CacheTTL time.Duration `json:"cache_ttl"`
+}
+
+// OutputConfig contains response shaping applied after validation. KeyCase
+// rekeys response objects to "camel" or "snake" case; empty leaves keys as-is.
+// DefaultRepresentation is used when a request's Accept header is absent or a
+// wildcard: "json", "pretty" (indented JSON) or "problem" (errors as
+// application/problem+json). Envelope wraps successful data as
+// {"data": ...}; RequestIDInBody adds the request ID to that envelope,
+// which otherwise is only sent in the X-Request-ID header. ErrorVerbosity
+// "sanitized" strips backend URLs and addresses from error details sent to
+// clients; "full" returns them as logged. CostHeader returns the estimated
+// request cost in X-Estimated-Cost when LLM prices are configured.
+// DiagnosticHeaders adds X-Schema-Hash, X-LLM-Backend and X-Cache to
+// validated-query responses. Canonical encodes successful validated-query
+// bodies as RFC 8785 canonical JSON so equivalent data is byte-identical.
+// PreserveKeyOrder keeps the LLM's object key order when data is re-encoded
+// after validation, e.g. to rewrite nulls or fill defaults. EscapeHTML
+// escapes <, > and & in successful response bodies as \u003c, \u003e and
+// \u0026, which is safe to embed in HTML but mangles URLs and code.
+// ErrorSchema adds the applied schema to the context of 422 validation
+// errors: "none", its "id" (the schema hash, which is also its registry ID)
+// or the "full" schema alongside the ID. ReportTransformations lists the
+// transformations applied to the data, such as rewritten nulls or rekeying,
+// in the envelope's metadata.
+type OutputConfig struct {
+	KeyCase               string `json:"key_case"`
+	DefaultRepresentation string `json:"default_representation"`
+	Envelope              bool   `json:"envelope"`
+	RequestIDInBody       bool   `json:"request_id_in_body"`
+	ErrorVerbosity        string `json:"error_verbosity"`
+	CostHeader            bool   `json:"cost_header"`
+	DiagnosticHeaders     bool   `json:"diagnostic_headers"`
+	Canonical             bool   `json:"canonical"`
+	PreserveKeyOrder      bool   `json:"preserve_key_order"`
+	EscapeHTML            bool   `json:"escape_html"`
+	ErrorSchema           string `json:"error_schema"`
+	ReportTransformations bool   `json:"report_transformations"`
+}
+
+// ResponseCacheConfig contains how validated responses are identified and
+// stored for reuse. OrderSensitiveKeys treats the same messages in a
+// different order as a different request, which is right whenever messages
+// form a conversation. Backend is "none", "memory" (per replica, holding at
+// most MaxEntries) or "redis" (shared through RedisAddr); entries live for TTL.
+type ResponseCacheConfig struct {
+	OrderSensitiveKeys bool          `json:"order_sensitive_keys"`
+	Backend            string        `json:"backend"`
+	TTL                time.Duration `json:"ttl"`
+	MaxEntries         int           `json:"max_entries"`
+	RedisAddr          string        `json:"redis_addr,omitempty"`
+	RedisPassword      string        `json:"redis_password,omitempty"`
+	RedisTimeout       time.Duration `json:"redis_timeout"`
+}
+
+// WebhookConfig contains completion event delivery settings. Events are
+// only sent when URL is set.
+type WebhookConfig struct {
+	URL           string        `json:"url"`
+	QueueSize     int           `json:"queue_size"`
+	RetryAttempts int           `json:"retry_attempts"`
+	RetryDelay    time.Duration `json:"retry_delay"`
+	Timeout       time.Duration `json:"timeout"`
+}
+
+// RegistryConfig bounds the in-memory schema registry. FullPolicy decides what
+// happens when a new schema is registered into a full registry: "reject" it
+// or evict the least recently used schema ("lru"). MaxSchemaBytes caps the
+// size of each registered schema. Schemas may be registered
+// by URL only from hosts in FetchAllowlist; an empty allowlist disables it.
+// Store "redis" shares registered schemas with every replica through
+// RedisAddr; "local" keeps them to this process.
+type RegistryConfig struct {
+	MaxEntries     int           `json:"max_entries"`
+	FullPolicy     string        `json:"full_policy"`
+	MaxSchemaBytes int64         `json:"max_schema_bytes"`
+	FetchAllowlist []string      `json:"fetch_allowlist"`
+	FetchTimeout   time.Duration `json:"fetch_timeout"`
+	FetchMaxBytes  int64         `json:"fetch_max_bytes"`
+	Store          string        `json:"store"`
+	RedisAddr      string        `json:"redis_addr,omitempty"`
+	RedisPassword  string        `json:"redis_password,omitempty"`
+	RedisTimeout   time.Duration `json:"redis_timeout"`
+}
+
+// AuthConfig selects how requests are authenticated. Mode "none" admits every
+// request; "static" accepts the bearer tokens in Tokens; "jwt" accepts RS256
+// JWTs signed by a key published at JWKSURL, checking Issuer and Audience
+// when set. An identity's tenant, from a static token or the JWT's
+// TenantClaim, takes precedence over the tenant header.
+type AuthConfig struct {
+	Mode         string        `json:"mode"`
+	Tokens       []StaticToken `json:"tokens,omitempty"`
+	JWKSURL      string        `json:"jwks_url"`
+	JWKSCacheTTL time.Duration `json:"jwks_cache_ttl"`
+	Issuer       string        `json:"issuer"`
+	Audience     string        `json:"audience"`
+	TenantClaim  string        `json:"tenant_claim"`
+}
+
+// StaticToken is a bearer token accepted by the "static" auth mode and the
+// identity it authenticates as
+type StaticToken struct {
+	Token    string `json:"token"`
+	Subject  string `json:"subject"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// DebugConfig contains configuration for diagnostic endpoints.
+// ErrorRateWindow is the sliding window over which the failure rate of
+// validated queries is reported; zero disables tracking it.
+type DebugConfig struct {
+	Enabled         bool          `json:"enabled"`
+	Token           string        `json:"token"`
+	ErrorRateWindow time.Duration `json:"error_rate_window"`
+}
+
+// AdminConfig contains configuration for runtime administration endpoints.
+// Callers must be authenticated; when Subjects is set, only those identity
+// subjects are allowed. RecentRequests keeps the last that many validated
+// queries in memory for /admin/requests, zero disabling it;
+// RecentRequestBodies keeps their request bodies too, which may hold
+// sensitive prompts.
+type AdminConfig struct {
+	Enabled             bool     `json:"enabled"`
+	Subjects            []string `json:"subjects"`
+	RecentRequests      int      `json:"recent_requests"`
+	RecentRequestBodies bool     `json:"recent_request_bodies"`
+}
+
+// maxRecentRequests bounds the admin recent-requests buffer
+const maxRecentRequests = 10000
+
+// TenantsConfig contains multi-tenant isolation configuration
+type TenantsConfig struct {
+	Header   string         `json:"header"`
+	Required bool           `json:"required"`
+	Tenants  []TenantConfig `json:"tenants"`
+}
+
+// TenantConfig contains the per-tenant overrides applied to a request.
+// SchemaAllowlist holds schema hashes as produced by schema.Hash; an empty
+// allowlist permits any schema.
+type TenantConfig struct {
+	ID                string   `json:"id"`
+	LLMServerURL      string   `json:"llm_server_url,omitempty"`
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty"`
+	SchemaAllowlist   []string `json:"schema_allowlist,omitempty"`
+}
+
+// Default returns the configuration used when no environment overrides are set
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8081,
+			Host:              "",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxBodyBytes:      10 << 20,
+			// Balances speed against ratio
+			CompressionLevel:   6,
+			MissingContentType: "reject",
+		},
+		LLM: LLMConfig{
+			ServerURL:     "http://localhost:8080",
+			Timeout:       30 * time.Second,
+			RetryAttempts: 3,
+			RetryDelay:    1 * time.Second,
+			MaxRetryDelay: 10 * time.Second,
+			RetryJitter:   true,
+			KeepAlive:     30 * time.Second,
+			// Lookups failing in containers are usually transient
+			RetryDNSFailures: true,
+			// All traffic goes to one backend, so keep more than Go's default of 2 idle
+			MaxIdleConnsPerHost: 16,
+		},
+		Cache: CacheConfig{
+			MaxSize:        100,
+			TTL:            1 * time.Hour,
+			EvictionPolicy: "lru",
+			ResultTTL:      5 * time.Minute,
+		},
+		Log: LogConfig{
+			Level:         "info",
+			Format:        "json",
+			StartupConfig: true,
+		},
+		Tenants: TenantsConfig{
+			Header: "X-Tenant-ID",
+		},
+		Concurrency: ConcurrencyConfig{
+			PriorityHeader: "X-Priority",
+		},
+		Debug: DebugConfig{
+			ErrorRateWindow: 5 * time.Minute,
+		},
+		Health: HealthConfig{
+			CacheTTL: 5 * time.Second,
+		},
+		Validation: ValidationConfig{
+			DuplicateKeys:    "allow",
+			SchemaInjection:  "allow",
+			NullPolicy:       "reject",
+			BatchMaxPayloads: 100,
+			BatchConcurrency: 4,
+		},
+		Output: OutputConfig{
+			DefaultRepresentation: "json",
+			ErrorVerbosity:        "sanitized",
+			ErrorSchema:           "none",
+			EscapeHTML:            true,
+		},
+		ResponseCache: ResponseCacheConfig{
+			OrderSensitiveKeys: true,
+			Backend:            "none",
+			TTL:                5 * time.Minute,
+			MaxEntries:         1000,
+			RedisTimeout:       time.Second,
+		},
+		Registry: RegistryConfig{
+			MaxEntries:     1000,
+			FullPolicy:     "reject",
+			MaxSchemaBytes: 1 << 20,
+			FetchTimeout:   5 * time.Second,
+			FetchMaxBytes:  1 << 20,
+			Store:          "local",
+			RedisTimeout:   time.Second,
+		},
+		Webhook: WebhookConfig{
+			QueueSize:     100,
+			RetryAttempts: 3,
+			RetryDelay:    1 * time.Second,
+			Timeout:       5 * time.Second,
+		},
+		Auth: AuthConfig{
+			Mode:         "none",
+			JWKSCacheTTL: 1 * time.Hour,
+			TenantClaim:  "tenant_id",
+		},
+	}
 }
 
 // LoadConfig loads configuration from environment variables with defaults
 func LoadConfig() (*Config, error) {
+	d := Default()
+	// A preset replaces the validation defaults; individual variables still win
+	if preset := os.Getenv("VALIDATION_PRESET"); preset != "" {
+		if err := d.Validation.ApplyPreset(preset); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
 	config := &Config{
 		Server: ServerConfig{
-			Port:         getEnvInt("PORT", 8081),
-			Host:         getEnvString("HOST", ""),
-			ReadTimeout:  getEnvDuration("READ_TIMEOUT", 30*time.Second),
-			WriteTimeout: getEnvDuration("WRITE_TIMEOUT", 30*time.Second),
-			IdleTimeout:  getEnvDuration("IDLE_TIMEOUT", 120*time.Second),
+			Port:                getEnvInt("PORT", d.Server.Port),
+			Host:                getEnvString("HOST", d.Server.Host),
+			ReadTimeout:         getEnvDuration("READ_TIMEOUT", d.Server.ReadTimeout),
+			ReadHeaderTimeout:   getEnvDuration("READ_HEADER_TIMEOUT", d.Server.ReadHeaderTimeout),
+			WriteTimeout:        getEnvDuration("WRITE_TIMEOUT", d.Server.WriteTimeout),
+			MaxRequestDuration:  getEnvDuration("MAX_TOTAL_REQUEST_DURATION", d.Server.MaxRequestDuration),
+			IdleTimeout:         getEnvDuration("IDLE_TIMEOUT", d.Server.IdleTimeout),
+			Compression:         getEnvBool("COMPRESSION_ENABLED", d.Server.Compression),
+			CompressionLevel:    getEnvInt("COMPRESSION_LEVEL", d.Server.CompressionLevel),
+			StrictStartup:       getEnvBool("STRICT_STARTUP", d.Server.StrictStartup),
+			TrustedProxies:      getEnvList("TRUSTED_PROXIES", d.Server.TrustedProxies),
+			MaxBodyBytes:        int64(getEnvInt("MAX_REQUEST_BODY_BYTES", int(d.Server.MaxBodyBytes))),
+			MaxMessageBytes:     int64(getEnvInt("MAX_MESSAGE_BYTES", int(d.Server.MaxMessageBytes))),
+			VerifyContentLength: getEnvBool("VERIFY_CONTENT_LENGTH", d.Server.VerifyContentLength),
+			MissingContentType:  getEnvString("MISSING_CONTENT_TYPE", d.Server.MissingContentType),
 		},
 		LLM: LLMConfig{
-			ServerURL:     getEnvString("LLM_SERVER_URL", "http://localhost:8080"),
-			Timeout:       getEnvDuration("LLM_TIMEOUT", 30*time.Second),
-			RetryAttempts: getEnvInt("LLM_RETRY_ATTEMPTS", 3),
-			RetryDelay:    getEnvDuration("LLM_RETRY_DELAY", 1*time.Second),
-			MaxRetryDelay: getEnvDuration("LLM_MAX_RETRY_DELAY", 10*time.Second),
+			ServerURL:           getEnvString("LLM_SERVER_URL", d.LLM.ServerURL),
+			FallbackURL:         getEnvString("LLM_FALLBACK_URL", d.LLM.FallbackURL),
+			FallbackModel:       getEnvString("LLM_FALLBACK_MODEL", d.LLM.FallbackModel),
+			Timeout:             getEnvDuration("LLM_TIMEOUT", d.LLM.Timeout),
+			RetryAttempts:       getEnvInt("LLM_RETRY_ATTEMPTS", d.LLM.RetryAttempts),
+			RetryDelay:          getEnvDuration("LLM_RETRY_DELAY", d.LLM.RetryDelay),
+			MaxRetryDelay:       getEnvDuration("LLM_MAX_RETRY_DELAY", d.LLM.MaxRetryDelay),
+			RetryJitter:         getEnvBool("LLM_RETRY_JITTER", d.LLM.RetryJitter),
+			RetryEmptyResponses: getEnvBool("LLM_RETRY_EMPTY_RESPONSES", d.LLM.RetryEmptyResponses),
+			RetryDNSFailures:    getEnvBool("LLM_RETRY_DNS_FAILURES", d.LLM.RetryDNSFailures),
+			AllowInvalidUTF8:    getEnvBool("LLM_ALLOW_INVALID_UTF8", d.LLM.AllowInvalidUTF8),
+			SchemaHints:         getEnvBool("LLM_SCHEMA_HINTS", d.LLM.SchemaHints),
+			MaxPromptTokens:     getEnvInt("LLM_MAX_PROMPT_TOKENS", d.LLM.MaxPromptTokens),
+			MaxCallsPerRequest:  getEnvInt("LLM_MAX_CALLS_PER_REQUEST", d.LLM.MaxCallsPerRequest),
+			DNSCacheTTL:         getEnvDuration("LLM_DNS_CACHE_TTL", d.LLM.DNSCacheTTL),
+			KeepAlive:           getEnvDuration("LLM_KEEP_ALIVE", d.LLM.KeepAlive),
+			MaxIdleConnsPerHost: getEnvInt("LLM_MAX_IDLE_CONNS_PER_HOST", d.LLM.MaxIdleConnsPerHost),
+			BackendOverride: BackendOverrideConfig{
+				Enabled:   getEnvBool("LLM_BACKEND_OVERRIDE_ENABLED", d.LLM.BackendOverride.Enabled),
+				Allowlist: getEnvList("LLM_BACKEND_ALLOWLIST", d.LLM.BackendOverride.Allowlist),
+			},
 		},
 		Cache: CacheConfig{
-			MaxSize: getEnvInt("SCHEMA_CACHE_SIZE", 100),
-			TTL:     getEnvDuration("SCHEMA_CACHE_TTL", 1*time.Hour),
+			MaxSize:        getEnvInt("SCHEMA_CACHE_SIZE", d.Cache.MaxSize),
+			TTL:            getEnvDuration("SCHEMA_CACHE_TTL", d.Cache.TTL),
+			EvictionPolicy: getEnvString("SCHEMA_CACHE_EVICTION", d.Cache.EvictionPolicy),
+			ResultMaxSize:  getEnvInt("VALIDATION_RESULT_CACHE_SIZE", d.Cache.ResultMaxSize),
+			ResultTTL:      getEnvDuration("VALIDATION_RESULT_CACHE_TTL", d.Cache.ResultTTL),
 		},
 		Log: LogConfig{
-			Level:  getEnvString("LOG_LEVEL", "info"),
-			Format: getEnvString("LOG_FORMAT", "json"),
+			Level:             getEnvString("LOG_LEVEL", d.Log.Level),
+			Format:            getEnvString("LOG_FORMAT", d.Log.Format),
+			StartupConfig:     getEnvBool("LOG_STARTUP_CONFIG", d.Log.StartupConfig),
+			FieldPrefix:       getEnvString("LOG_FIELD_PREFIX", d.Log.FieldPrefix),
+			MiddlewareTimings: getEnvBool("LOG_MIDDLEWARE_TIMINGS", d.Log.MiddlewareTimings),
+		},
+		Tenants: TenantsConfig{
+			Header:   getEnvString("TENANT_HEADER", d.Tenants.Header),
+			Required: getEnvBool("TENANT_REQUIRED", d.Tenants.Required),
+		},
+		Debug: DebugConfig{
+			Enabled:         getEnvBool("DEBUG_ENDPOINTS_ENABLED", d.Debug.Enabled),
+			Token:           getEnvString("DEBUG_TOKEN", d.Debug.Token),
+			ErrorRateWindow: getEnvDuration("ERROR_RATE_WINDOW", d.Debug.ErrorRateWindow),
 		},
+		Admin: AdminConfig{
+			Enabled:             getEnvBool("ADMIN_ENDPOINTS_ENABLED", d.Admin.Enabled),
+			Subjects:            getEnvList("ADMIN_SUBJECTS", d.Admin.Subjects),
+			RecentRequests:      getEnvInt("ADMIN_RECENT_REQUESTS", d.Admin.RecentRequests),
+			RecentRequestBodies: getEnvBool("ADMIN_RECENT_REQUEST_BODIES", d.Admin.RecentRequestBodies),
+		},
+		Validation: ValidationConfig{
+			Preset:                  d.Validation.Preset,
+			AllowSchemaless:         getEnvBool("ALLOW_SCHEMALESS", d.Validation.AllowSchemaless),
+			ReportWarnings:          getEnvBool("VALIDATION_WARNINGS", d.Validation.ReportWarnings),
+			Timeout:                 getEnvDuration("VALIDATION_TIMEOUT", d.Validation.Timeout),
+			DuplicateKeys:           getEnvString("VALIDATION_DUPLICATE_KEYS", d.Validation.DuplicateKeys),
+			SchemaInjection:         getEnvString("VALIDATION_SCHEMA_INJECTION", d.Validation.SchemaInjection),
+			MaxRepairAttempts:       getEnvInt("VALIDATION_MAX_REPAIR_ATTEMPTS", d.Validation.MaxRepairAttempts),
+			RepairDeterministicOnly: getEnvBool("VALIDATION_REPAIR_DETERMINISTIC_ONLY", d.Validation.RepairDeterministicOnly),
+			ApplyDefaults:           getEnvBool("VALIDATION_APPLY_DEFAULTS", d.Validation.ApplyDefaults),
+			AssertFormats:           getEnvBool("VALIDATION_ASSERT_FORMATS", d.Validation.AssertFormats),
+			RequireObjectRoot:       getEnvBool("VALIDATION_REQUIRE_OBJECT_ROOT", d.Validation.RequireObjectRoot),
+			RejectPermissive:        getEnvBool("VALIDATION_REJECT_PERMISSIVE", d.Validation.RejectPermissive),
+			NullPolicy:              getEnvString("VALIDATION_NULL_POLICY", d.Validation.NullPolicy),
+			StrictIntegers:          getEnvBool("VALIDATION_STRICT_INTEGERS", d.Validation.StrictIntegers),
+			BatchMaxPayloads:        getEnvInt("VALIDATION_BATCH_MAX_PAYLOADS", d.Validation.BatchMaxPayloads),
+			BatchConcurrency:        getEnvInt("VALIDATION_BATCH_CONCURRENCY", d.Validation.BatchConcurrency),
+			MinResponseBytes:        getEnvInt("VALIDATION_MIN_RESPONSE_BYTES", d.Validation.MinResponseBytes),
+			MinResponseProperties:   getEnvInt("VALIDATION_MIN_RESPONSE_PROPERTIES", d.Validation.MinResponseProperties),
+		},
+		Concurrency: ConcurrencyConfig{
+			MaxConcurrent:  getEnvInt("MAX_CONCURRENT_REQUESTS", d.Concurrency.MaxConcurrent),
+			MaxPerClient:   getEnvInt("MAX_CONCURRENT_PER_CLIENT", d.Concurrency.MaxPerClient),
+			PriorityHeader: getEnvString("PRIORITY_HEADER", d.Concurrency.PriorityHeader),
+			PriorityLevels: getEnvList("PRIORITY_LEVELS", d.Concurrency.PriorityLevels),
+		},
+		Health: HealthConfig{
+			CacheTTL: getEnvDuration("HEALTH_CHECK_CACHE_TTL", d.Health.CacheTTL),
+		},
+		Output: OutputConfig{
+			KeyCase:               getEnvString("OUTPUT_KEY_CASE", d.Output.KeyCase),
+			DefaultRepresentation: getEnvString("OUTPUT_DEFAULT_REPRESENTATION", d.Output.DefaultRepresentation),
+			Envelope:              getEnvBool("OUTPUT_ENVELOPE", d.Output.Envelope),
+			RequestIDInBody:       getEnvBool("OUTPUT_REQUEST_ID_IN_BODY", d.Output.RequestIDInBody),
+			ErrorVerbosity:        getEnvString("ERROR_VERBOSITY", d.Output.ErrorVerbosity),
+			CostHeader:            getEnvBool("OUTPUT_COST_HEADER", d.Output.CostHeader),
+			DiagnosticHeaders:     getEnvBool("OUTPUT_DIAGNOSTIC_HEADERS", d.Output.DiagnosticHeaders),
+			Canonical:             getEnvBool("OUTPUT_CANONICAL_JSON", d.Output.Canonical),
+			PreserveKeyOrder:      getEnvBool("OUTPUT_PRESERVE_KEY_ORDER", d.Output.PreserveKeyOrder),
+			EscapeHTML:            getEnvBool("OUTPUT_ESCAPE_HTML", d.Output.EscapeHTML),
+			ErrorSchema:           getEnvString("OUTPUT_ERROR_SCHEMA", d.Output.ErrorSchema),
+			ReportTransformations: getEnvBool("OUTPUT_REPORT_TRANSFORMATIONS", d.Output.ReportTransformations),
+		},
+		ResponseCache: ResponseCacheConfig{
+			OrderSensitiveKeys: getEnvBool("RESPONSE_CACHE_ORDER_SENSITIVE", d.ResponseCache.OrderSensitiveKeys),
+			Backend:            getEnvString("RESPONSE_CACHE_BACKEND", d.ResponseCache.Backend),
+			TTL:                getEnvDuration("RESPONSE_CACHE_TTL", d.ResponseCache.TTL),
+			MaxEntries:         getEnvInt("RESPONSE_CACHE_MAX_ENTRIES", d.ResponseCache.MaxEntries),
+			RedisAddr:          getEnvString("RESPONSE_CACHE_REDIS_ADDR", d.ResponseCache.RedisAddr),
+			RedisPassword:      getEnvString("RESPONSE_CACHE_REDIS_PASSWORD", d.ResponseCache.RedisPassword),
+			RedisTimeout:       getEnvDuration("RESPONSE_CACHE_REDIS_TIMEOUT", d.ResponseCache.RedisTimeout),
+		},
+		Registry: RegistryConfig{
+			MaxEntries:     getEnvInt("SCHEMA_REGISTRY_MAX_ENTRIES", d.Registry.MaxEntries),
+			FullPolicy:     getEnvString("SCHEMA_REGISTRY_FULL_POLICY", d.Registry.FullPolicy),
+			MaxSchemaBytes: int64(getEnvInt("SCHEMA_REGISTRY_MAX_SCHEMA_BYTES", int(d.Registry.MaxSchemaBytes))),
+			FetchAllowlist: getEnvList("SCHEMA_FETCH_ALLOWLIST", d.Registry.FetchAllowlist),
+			FetchTimeout:   getEnvDuration("SCHEMA_FETCH_TIMEOUT", d.Registry.FetchTimeout),
+			FetchMaxBytes:  int64(getEnvInt("SCHEMA_FETCH_MAX_BYTES", int(d.Registry.FetchMaxBytes))),
+			Store:          getEnvString("SCHEMA_REGISTRY_STORE", d.Registry.Store),
+			RedisAddr:      getEnvString("SCHEMA_REGISTRY_REDIS_ADDR", d.Registry.RedisAddr),
+			RedisPassword:  getEnvString("SCHEMA_REGISTRY_REDIS_PASSWORD", d.Registry.RedisPassword),
+			RedisTimeout:   getEnvDuration("SCHEMA_REGISTRY_REDIS_TIMEOUT", d.Registry.RedisTimeout),
+		},
+		Webhook: WebhookConfig{
+			URL:           getEnvString("WEBHOOK_URL", d.Webhook.URL),
+			QueueSize:     getEnvInt("WEBHOOK_QUEUE_SIZE", d.Webhook.QueueSize),
+			RetryAttempts: getEnvInt("WEBHOOK_RETRY_ATTEMPTS", d.Webhook.RetryAttempts),
+			RetryDelay:    getEnvDuration("WEBHOOK_RETRY_DELAY", d.Webhook.RetryDelay),
+			Timeout:       getEnvDuration("WEBHOOK_TIMEOUT", d.Webhook.Timeout),
+		},
+		Auth: AuthConfig{
+			Mode:         getEnvString("AUTH_MODE", d.Auth.Mode),
+			JWKSURL:      getEnvString("AUTH_JWKS_URL", d.Auth.JWKSURL),
+			JWKSCacheTTL: getEnvDuration("AUTH_JWKS_CACHE_TTL", d.Auth.JWKSCacheTTL),
+			Issuer:       getEnvString("AUTH_JWT_ISSUER", d.Auth.Issuer),
+			Audience:     getEnvString("AUTH_JWT_AUDIENCE", d.Auth.Audience),
+			TenantClaim:  getEnvString("AUTH_JWT_TENANT_CLAIM", d.Auth.TenantClaim),
+		},
+	}
+
+	// Tenants are supplied as a JSON array since they don't map onto flat variables
+	if value := os.Getenv("TENANTS"); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.Tenants.Tenants); err != nil {
+			return nil, fmt.Errorf("invalid configuration: parse TENANTS: %w", err)
+		}
+	}
+	if value := os.Getenv("LLM_PRICES"); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.LLM.Prices); err != nil {
+			return nil, fmt.Errorf("invalid configuration: parse LLM_PRICES: %w", err)
+		}
+	}
+	if value := os.Getenv("LOG_HEADER_FIELDS"); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.Log.HeaderFields); err != nil {
+			return nil, fmt.Errorf("invalid configuration: parse LOG_HEADER_FIELDS: %w", err)
+		}
+	}
+	if value := os.Getenv("ROUTE_TIMEOUTS"); value != "" {
+		routeTimeouts, err := parseRouteTimeouts(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: parse ROUTE_TIMEOUTS: %w", err)
+		}
+		config.Server.RouteTimeouts = routeTimeouts
+	}
+	if value := os.Getenv("LLM_EXTRA_REQUEST_FIELDS"); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.LLM.ExtraRequestFields); err != nil {
+			return nil, fmt.Errorf("invalid configuration: parse LLM_EXTRA_REQUEST_FIELDS: %w", err)
+		}
+	}
+	if value := os.Getenv("AUTH_TOKENS"); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.Auth.Tokens); err != nil {
+			return nil, fmt.Errorf("invalid configuration: parse AUTH_TOKENS: %w", err)
+		}
 	}
 
 	if err := config.Validate(); err != nil {
@@ -89,12 +719,44 @@ func (c *Config) Validate() error {
 	if c.Server.ReadTimeout <= 0 {
 		return fmt.Errorf("server read timeout must be positive, got %v", c.Server.ReadTimeout)
 	}
+	if c.Server.ReadHeaderTimeout <= 0 || c.Server.ReadHeaderTimeout > c.Server.ReadTimeout {
+		return fmt.Errorf("server read header timeout must be positive and at most the read timeout (%v), got %v", c.Server.ReadTimeout, c.Server.ReadHeaderTimeout)
+	}
 	if c.Server.WriteTimeout <= 0 {
 		return fmt.Errorf("server write timeout must be positive, got %v", c.Server.WriteTimeout)
 	}
+	if c.Server.MaxRequestDuration < 0 {
+		return fmt.Errorf("max request duration must be non-negative, got %v", c.Server.MaxRequestDuration)
+	}
 	if c.Server.IdleTimeout <= 0 {
 		return fmt.Errorf("server idle timeout must be positive, got %v", c.Server.IdleTimeout)
 	}
+	if c.Server.CompressionLevel < gzip.HuffmanOnly || c.Server.CompressionLevel > gzip.BestCompression {
+		return fmt.Errorf("compression level must be between %d and %d, got %d", gzip.HuffmanOnly, gzip.BestCompression, c.Server.CompressionLevel)
+	}
+	validContentTypePolicies := []string{"reject", "assume"}
+	if !contains(validContentTypePolicies, c.Server.MissingContentType) {
+		return fmt.Errorf("missing content type policy must be one of %v, got %s", validContentTypePolicies, c.Server.MissingContentType)
+	}
+	if c.Server.MaxBodyBytes < 0 {
+		return fmt.Errorf("max request body bytes must be non-negative, got %d", c.Server.MaxBodyBytes)
+	}
+	if c.Server.MaxMessageBytes < 0 {
+		return fmt.Errorf("max message bytes must be non-negative, got %d", c.Server.MaxMessageBytes)
+	}
+	for path, timeout := range c.Server.RouteTimeouts {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("route timeout path must start with /, got %q", path)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("route timeout for %s must be positive, got %v", path, timeout)
+		}
+	}
+	for _, cidr := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+			return fmt.Errorf("trusted proxy must be a CIDR or IP address, got %s", cidr)
+		}
+	}
 
 	// LLM validation
 	if c.LLM.ServerURL == "" {
@@ -103,6 +765,9 @@ func (c *Config) Validate() error {
 	if c.LLM.Timeout <= 0 {
 		return fmt.Errorf("LLM timeout must be positive, got %v", c.LLM.Timeout)
 	}
+	if c.LLM.FallbackModel != "" && c.LLM.FallbackURL == "" {
+		return fmt.Errorf("LLM fallback model requires a fallback URL")
+	}
 	if c.LLM.RetryAttempts < 0 {
 		return fmt.Errorf("LLM retry attempts must be non-negative, got %d", c.LLM.RetryAttempts)
 	}
@@ -113,6 +778,32 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("LLM max retry delay must be >= retry delay, got %v < %v", c.LLM.MaxRetryDelay, c.LLM.RetryDelay)
 	}
 
+	if c.LLM.MaxPromptTokens < 0 {
+		return fmt.Errorf("LLM max prompt tokens must be non-negative, got %d", c.LLM.MaxPromptTokens)
+	}
+	if c.LLM.MaxCallsPerRequest < 0 {
+		return fmt.Errorf("LLM max calls per request must be non-negative, got %d", c.LLM.MaxCallsPerRequest)
+	}
+	if c.LLM.DNSCacheTTL < 0 {
+		return fmt.Errorf("LLM DNS cache TTL must be non-negative, got %v", c.LLM.DNSCacheTTL)
+	}
+	if c.LLM.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("LLM max idle connections per host must be non-negative, got %d", c.LLM.MaxIdleConnsPerHost)
+	}
+	if c.LLM.BackendOverride.Enabled && len(c.LLM.BackendOverride.Allowlist) == 0 {
+		return fmt.Errorf("LLM backend override requires a non-empty allowlist")
+	}
+	for model, price := range c.LLM.Prices {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return fmt.Errorf("LLM price for model %q must be non-negative", model)
+		}
+	}
+	for _, name := range []string{"messages", "response_format", "stream"} {
+		if _, ok := c.LLM.ExtraRequestFields[name]; ok {
+			return fmt.Errorf("LLM extra request fields cannot override %q", name)
+		}
+	}
+
 	// Cache validation
 	if c.Cache.MaxSize <= 0 {
 		return fmt.Errorf("cache max size must be positive, got %d", c.Cache.MaxSize)
@@ -120,6 +811,33 @@ func (c *Config) Validate() error {
 	if c.Cache.TTL <= 0 {
 		return fmt.Errorf("cache TTL must be positive, got %v", c.Cache.TTL)
 	}
+	validPolicies := []string{"lru", "clear", "cost"}
+	if c.Cache.EvictionPolicy != "" && !contains(validPolicies, c.Cache.EvictionPolicy) {
+		return fmt.Errorf("cache eviction policy must be one of %v, got %s", validPolicies, c.Cache.EvictionPolicy)
+	}
+	if c.Cache.ResultMaxSize < 0 {
+		return fmt.Errorf("validation result cache size must be non-negative, got %d", c.Cache.ResultMaxSize)
+	}
+	if c.Cache.ResultMaxSize > 0 && c.Cache.ResultTTL <= 0 {
+		return fmt.Errorf("validation result cache TTL must be positive, got %v", c.Cache.ResultTTL)
+	}
+
+	// Response cache validation
+	validCacheBackends := []string{"none", "memory", "redis"}
+	if c.ResponseCache.Backend != "" && !contains(validCacheBackends, c.ResponseCache.Backend) {
+		return fmt.Errorf("response cache backend must be one of %v, got %s", validCacheBackends, c.ResponseCache.Backend)
+	}
+	if c.ResponseCache.Backend == "memory" || c.ResponseCache.Backend == "redis" {
+		if c.ResponseCache.TTL <= 0 {
+			return fmt.Errorf("response cache TTL must be positive, got %v", c.ResponseCache.TTL)
+		}
+		if c.ResponseCache.Backend == "memory" && c.ResponseCache.MaxEntries <= 0 {
+			return fmt.Errorf("response cache max entries must be positive, got %d", c.ResponseCache.MaxEntries)
+		}
+		if c.ResponseCache.Backend == "redis" && c.ResponseCache.RedisAddr == "" {
+			return fmt.Errorf("response cache redis address is required for the redis backend")
+		}
+	}
 
 	// Log validation
 	validLevels := []string{"debug", "info", "warn", "error", "fatal"}
@@ -130,6 +848,193 @@ func (c *Config) Validate() error {
 	if !contains(validFormats, strings.ToLower(c.Log.Format)) {
 		return fmt.Errorf("log format must be one of %v, got %s", validFormats, c.Log.Format)
 	}
+	if len(c.Log.HeaderFields) > maxLogHeaderFields {
+		return fmt.Errorf("log header fields must map at most %d headers, got %d", maxLogHeaderFields, len(c.Log.HeaderFields))
+	}
+	for header, field := range c.Log.HeaderFields {
+		if header == "" || field == "" {
+			return fmt.Errorf("log header fields need a header and a field name, got %q: %q", header, field)
+		}
+	}
+
+	// Tenant validation
+	if len(c.Tenants.Tenants) > 0 && c.Tenants.Header == "" {
+		return fmt.Errorf("tenant header cannot be empty when tenants are configured")
+	}
+	seenTenants := make(map[string]bool)
+	for i, t := range c.Tenants.Tenants {
+		if t.ID == "" {
+			return fmt.Errorf("tenant %d: id cannot be empty", i)
+		}
+		if seenTenants[t.ID] {
+			return fmt.Errorf("tenant %q: duplicate id", t.ID)
+		}
+		seenTenants[t.ID] = true
+		if t.RequestsPerMinute < 0 {
+			return fmt.Errorf("tenant %q: requests per minute must be non-negative, got %d", t.ID, t.RequestsPerMinute)
+		}
+	}
+
+	// Concurrency validation
+	if c.Concurrency.MaxConcurrent < 0 {
+		return fmt.Errorf("max concurrent requests must be non-negative, got %d", c.Concurrency.MaxConcurrent)
+	}
+	if c.Concurrency.MaxPerClient < 0 {
+		return fmt.Errorf("max concurrent requests per client must be non-negative, got %d", c.Concurrency.MaxPerClient)
+	}
+	seenLevels := make(map[string]bool)
+	for _, level := range c.Concurrency.PriorityLevels {
+		if seenLevels[level] {
+			return fmt.Errorf("priority level %q: duplicate level", level)
+		}
+		seenLevels[level] = true
+	}
+
+	// Output validation
+	validKeyCases := []string{"camel", "snake"}
+	if c.Output.KeyCase != "" && !contains(validKeyCases, c.Output.KeyCase) {
+		return fmt.Errorf("output key case must be one of %v, got %s", validKeyCases, c.Output.KeyCase)
+	}
+	validRepresentations := []string{"json", "pretty", "problem"}
+	if c.Output.DefaultRepresentation != "" && !contains(validRepresentations, c.Output.DefaultRepresentation) {
+		return fmt.Errorf("output default representation must be one of %v, got %s", validRepresentations, c.Output.DefaultRepresentation)
+	}
+	validVerbosities := []string{"full", "sanitized"}
+	if c.Output.ErrorVerbosity != "" && !contains(validVerbosities, c.Output.ErrorVerbosity) {
+		return fmt.Errorf("error verbosity must be one of %v, got %s", validVerbosities, c.Output.ErrorVerbosity)
+	}
+	validErrorSchemas := []string{"none", "id", "full"}
+	if c.Output.ErrorSchema != "" && !contains(validErrorSchemas, c.Output.ErrorSchema) {
+		return fmt.Errorf("output error schema must be one of %v, got %s", validErrorSchemas, c.Output.ErrorSchema)
+	}
+	if c.Output.RequestIDInBody && !c.Output.Envelope {
+		return fmt.Errorf("output request ID in body requires envelope mode")
+	}
+	if c.Output.ReportTransformations && !c.Output.Envelope {
+		return fmt.Errorf("output transformation reporting requires envelope mode")
+	}
+	if c.Output.PreserveKeyOrder && c.Output.Canonical {
+		return fmt.Errorf("output key order preservation cannot be combined with canonical JSON, which sorts keys")
+	}
+
+	// Registry validation
+	if c.Registry.MaxEntries <= 0 {
+		return fmt.Errorf("schema registry max entries must be positive, got %d", c.Registry.MaxEntries)
+	}
+	if c.Registry.MaxSchemaBytes <= 0 {
+		return fmt.Errorf("schema registry max schema bytes must be positive, got %d", c.Registry.MaxSchemaBytes)
+	}
+
+	validRegistryPolicies := []string{"reject", "lru"}
+
+	if !contains(validRegistryPolicies, c.Registry.FullPolicy) {
+		return fmt.Errorf("schema registry full policy must be one of %v, got %s", validRegistryPolicies, c.Registry.FullPolicy)
+	}
+	if len(c.Registry.FetchAllowlist) > 0 {
+		if c.Registry.FetchTimeout <= 0 {
+			return fmt.Errorf("schema fetch timeout must be positive, got %v", c.Registry.FetchTimeout)
+		}
+		if c.Registry.FetchMaxBytes <= 0 {
+			return fmt.Errorf("schema fetch max bytes must be positive, got %d", c.Registry.FetchMaxBytes)
+		}
+	}
+	validRegistryStores := []string{"local", "redis"}
+	if c.Registry.Store != "" && !contains(validRegistryStores, c.Registry.Store) {
+		return fmt.Errorf("schema registry store must be one of %v, got %s", validRegistryStores, c.Registry.Store)
+	}
+	if c.Registry.Store == "redis" && c.Registry.RedisAddr == "" {
+		return fmt.Errorf("schema registry redis address is required for the redis store")
+	}
+
+	// Webhook validation
+	if c.Webhook.URL != "" {
+		if c.Webhook.QueueSize <= 0 {
+			return fmt.Errorf("webhook queue size must be positive, got %d", c.Webhook.QueueSize)
+		}
+		if c.Webhook.RetryAttempts < 0 {
+			return fmt.Errorf("webhook retry attempts must be non-negative, got %d", c.Webhook.RetryAttempts)
+		}
+		if c.Webhook.Timeout <= 0 {
+			return fmt.Errorf("webhook timeout must be positive, got %v", c.Webhook.Timeout)
+		}
+	}
+
+	if c.Validation.Timeout < 0 {
+		return fmt.Errorf("validation timeout must be non-negative, got %v", c.Validation.Timeout)
+	}
+	if c.Validation.MaxRepairAttempts < 0 {
+		return fmt.Errorf("max repair attempts must be non-negative, got %d", c.Validation.MaxRepairAttempts)
+	}
+	if c.Validation.BatchMaxPayloads < 1 {
+		return fmt.Errorf("validation batch max payloads must be at least 1, got %d", c.Validation.BatchMaxPayloads)
+	}
+	if c.Validation.BatchConcurrency < 1 {
+		return fmt.Errorf("validation batch concurrency must be at least 1, got %d", c.Validation.BatchConcurrency)
+	}
+	if c.Validation.MinResponseBytes < 0 {
+		return fmt.Errorf("validation min response bytes must be non-negative, got %d", c.Validation.MinResponseBytes)
+	}
+	if c.Validation.MinResponseProperties < 0 {
+		return fmt.Errorf("validation min response properties must be non-negative, got %d", c.Validation.MinResponseProperties)
+	}
+	if c.Validation.Preset != "" && !contains(validationPresets, c.Validation.Preset) {
+		return fmt.Errorf("validation preset must be one of %v, got %s", validationPresets, c.Validation.Preset)
+	}
+	validNullPolicies := []string{"reject", "strip", "default"}
+	if c.Validation.NullPolicy != "" && !contains(validNullPolicies, c.Validation.NullPolicy) {
+		return fmt.Errorf("null policy must be one of %v, got %s", validNullPolicies, c.Validation.NullPolicy)
+	}
+	validDuplicateKeyPolicies := []string{"allow", "warn", "reject"}
+	if c.Validation.DuplicateKeys != "" && !contains(validDuplicateKeyPolicies, c.Validation.DuplicateKeys) {
+		return fmt.Errorf("duplicate keys policy must be one of %v, got %s", validDuplicateKeyPolicies, c.Validation.DuplicateKeys)
+	}
+	validSchemaInjectionPolicies := []string{"allow", "warn", "reject"}
+	if c.Validation.SchemaInjection != "" && !contains(validSchemaInjectionPolicies, c.Validation.SchemaInjection) {
+		return fmt.Errorf("schema injection policy must be one of %v, got %s", validSchemaInjectionPolicies, c.Validation.SchemaInjection)
+	}
+
+	// Auth validation
+	switch c.Auth.Mode {
+	case "", "none":
+	case "static":
+		if len(c.Auth.Tokens) == 0 {
+			return fmt.Errorf("static auth requires at least one token")
+		}
+		seenTokens := make(map[string]bool)
+		for i, tok := range c.Auth.Tokens {
+			if tok.Token == "" {
+				return fmt.Errorf("auth token %d: token cannot be empty", i)
+			}
+			if seenTokens[tok.Token] {
+				return fmt.Errorf("auth token %d: duplicate token", i)
+			}
+			seenTokens[tok.Token] = true
+		}
+	case "jwt":
+		if c.Auth.JWKSURL == "" {
+			return fmt.Errorf("jwt auth requires a JWKS URL")
+		}
+		if c.Auth.JWKSCacheTTL <= 0 {
+			return fmt.Errorf("JWKS cache TTL must be positive, got %v", c.Auth.JWKSCacheTTL)
+		}
+	default:
+		return fmt.Errorf("auth mode must be one of [none static jwt], got %s", c.Auth.Mode)
+	}
+
+	if c.Admin.Enabled && (c.Auth.Mode == "" || c.Auth.Mode == "none") {
+		return fmt.Errorf("admin endpoints require an auth mode other than none")
+	}
+	if c.Admin.RecentRequests < 0 || c.Admin.RecentRequests > maxRecentRequests {
+		return fmt.Errorf("admin recent requests must be between 0 and %d, got %d", maxRecentRequests, c.Admin.RecentRequests)
+	}
+	if c.Debug.ErrorRateWindow < 0 {
+		return fmt.Errorf("error rate window must be non-negative, got %v", c.Debug.ErrorRateWindow)
+	}
+
+	// Health validation
+	if c.Health.CacheTTL < 0 {
+		return fmt.Errorf("health check cache TTL must be non-negative, got %v", c.Health.CacheTTL)
+	}
 
 	return nil
 }
@@ -142,8 +1047,102 @@ func (c *Config) Address() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
 
+// redactedValue replaces secrets in Redacted output
+const redactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the configuration that is safe to log, with
+// secrets masked. Unset secrets stay empty so logs show whether they are set.
+func (c *Config) Redacted() Config {
+	redacted := *c
+
+	// Copy maps and slices so the caller cannot alter c through the copy
+	redacted.Server.TrustedProxies = slices.Clone(c.Server.TrustedProxies)
+	redacted.Server.RouteTimeouts = maps.Clone(c.Server.RouteTimeouts)
+	redacted.LLM.Prices = maps.Clone(c.LLM.Prices)
+	redacted.Log.HeaderFields = maps.Clone(c.Log.HeaderFields)
+	redacted.Concurrency.PriorityLevels = slices.Clone(c.Concurrency.PriorityLevels)
+	redacted.Registry.FetchAllowlist = slices.Clone(c.Registry.FetchAllowlist)
+	redacted.Admin.Subjects = slices.Clone(c.Admin.Subjects)
+
+	if redacted.Debug.Token != "" {
+		redacted.Debug.Token = redactedValue
+	}
+	if redacted.ResponseCache.RedisPassword != "" {
+		redacted.ResponseCache.RedisPassword = redactedValue
+	}
+	if redacted.Registry.RedisPassword != "" {
+		redacted.Registry.RedisPassword = redactedValue
+	}
+	// Webhook URLs often carry their credential in the path or query
+	if redacted.Webhook.URL != "" {
+		redacted.Webhook.URL = redactedValue
+	}
+	redacted.Auth.Tokens = nil
+	for _, tok := range c.Auth.Tokens {
+		tok.Token = redactedValue
+		redacted.Auth.Tokens = append(redacted.Auth.Tokens, tok)
+	}
+
+	// Backend URLs may embed credentials as user:password@host
+	redacted.LLM.ServerURL = stripUserinfo(c.LLM.ServerURL)
+	redacted.LLM.FallbackURL = stripUserinfo(c.LLM.FallbackURL)
+	redacted.Auth.JWKSURL = stripUserinfo(c.Auth.JWKSURL)
+	redacted.LLM.BackendOverride.Allowlist = nil
+	for _, backend := range c.LLM.BackendOverride.Allowlist {
+		redacted.LLM.BackendOverride.Allowlist = append(redacted.LLM.BackendOverride.Allowlist, stripUserinfo(backend))
+	}
+	redacted.Tenants.Tenants = nil
+	for _, tenant := range c.Tenants.Tenants {
+		tenant.LLMServerURL = stripUserinfo(tenant.LLMServerURL)
+		tenant.SchemaAllowlist = slices.Clone(tenant.SchemaAllowlist)
+		redacted.Tenants.Tenants = append(redacted.Tenants.Tenants, tenant)
+	}
+
+	// Extra request fields can hold backend API keys; only their names are kept
+	if c.LLM.ExtraRequestFields != nil {
+		redacted.LLM.ExtraRequestFields = make(map[string]json.RawMessage, len(c.LLM.ExtraRequestFields))
+		for name := range c.LLM.ExtraRequestFields {
+			redacted.LLM.ExtraRequestFields[name] = json.RawMessage(`"` + redactedValue + `"`)
+		}
+	}
+
+	return redacted
+}
+
+// stripUserinfo removes the user and password from a URL. A value that does
+// not parse is masked entirely, since its credentials cannot be located.
+func stripUserinfo(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redactedValue
+	}
+	u.User = nil
+	return u.String()
+}
+
 // Helper functions for environment variable parsing
 
+// parseRouteTimeouts parses a JSON object of paths to duration strings, e.g.
+// {"/v1/validate/batch": "2m"}
+func parseRouteTimeouts(value string) (map[string]time.Duration, error) {
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, err
+	}
+	timeouts := make(map[string]time.Duration, len(raw))
+	for path, duration := range raw {
+		timeout, err := time.ParseDuration(duration)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", path, err)
+		}
+		timeouts[path] = timeout
+	}
+	return timeouts, nil
+}
+
 func getEnvString(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -160,6 +1159,28 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := time.ParseDuration(value); err == nil {
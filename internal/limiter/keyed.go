@@ -0,0 +1,56 @@
+package limiter
+
+import "sync"
+
+// KeyedLimiter caps how many requests each key, such as a client IP, may
+// have in flight at once. Unlike Limiter it never queues: a key at its cap is
+// refused immediately, since the caller is the one holding its slots.
+type KeyedLimiter struct {
+	mu     sync.Mutex
+	max    int
+	active map[string]int
+}
+
+// NewKeyedLimiter creates a limiter admitting maxPerKey requests per key
+func NewKeyedLimiter(maxPerKey int) *KeyedLimiter {
+	return &KeyedLimiter{
+		max:    maxPerKey,
+		active: make(map[string]int),
+	}
+}
+
+// Enabled reports whether the limiter restricts concurrency at all
+func (l *KeyedLimiter) Enabled() bool {
+	return l != nil && l.max > 0
+}
+
+// TryAcquire takes a slot for key, reporting false when key is at its cap.
+// Every successful TryAcquire must be paired with a Release.
+func (l *KeyedLimiter) TryAcquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[key] >= l.max {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+// Release frees a slot held by key. Keys are forgotten once idle so the map
+// only holds clients with requests in flight.
+func (l *KeyedLimiter) Release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[key] <= 1 {
+		delete(l.active, key)
+		return
+	}
+	l.active[key]--
+}
+
+// Active returns the number of requests key has in flight
+func (l *KeyedLimiter) Active(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active[key]
+}
@@ -0,0 +1,33 @@
+package limiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	t.Run("caps_each_key_separately", func(t *testing.T) {
+		l := NewKeyedLimiter(2)
+		assert.True(t, l.TryAcquire("10.0.0.1"))
+		assert.True(t, l.TryAcquire("10.0.0.1"))
+		assert.False(t, l.TryAcquire("10.0.0.1"))
+		assert.True(t, l.TryAcquire("10.0.0.2"))
+
+		l.Release("10.0.0.1")
+		assert.True(t, l.TryAcquire("10.0.0.1"))
+	})
+
+	t.Run("idle_keys_forgotten", func(t *testing.T) {
+		l := NewKeyedLimiter(1)
+		assert.True(t, l.TryAcquire("10.0.0.1"))
+		l.Release("10.0.0.1")
+		assert.Equal(t, 0, l.Active("10.0.0.1"))
+		assert.Empty(t, l.active)
+	})
+
+	t.Run("zero_disables", func(t *testing.T) {
+		assert.False(t, NewKeyedLimiter(0).Enabled())
+		assert.True(t, NewKeyedLimiter(1).Enabled())
+	})
+}
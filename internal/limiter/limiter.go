@@ -0,0 +1,144 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/metrics"
+)
+
+// Limiter bounds the number of requests processed concurrently. When all slots
+// are taken, waiters are admitted highest priority first and in arrival order
+// within a priority, so a single priority level behaves as a FIFO semaphore.
+type Limiter struct {
+	mu     sync.Mutex
+	max    int
+	active int
+	levels map[string]int
+	queues [][]chan struct{} // index 0 is the highest priority
+
+	maxQueued int
+	waits     *metrics.Histogram
+}
+
+// NewLimiter creates a limiter admitting maxConcurrent requests at once.
+// Levels names the priorities from highest to lowest; with no levels every
+// request shares a single priority.
+func NewLimiter(maxConcurrent int, levels []string) *Limiter {
+	l := &Limiter{
+		max:    maxConcurrent,
+		levels: make(map[string]int, len(levels)),
+		queues: make([][]chan struct{}, max(len(levels), 1)),
+		waits:  metrics.NewHistogram(metrics.QueueWaitBuckets),
+	}
+	for i, level := range levels {
+		l.levels[level] = i
+	}
+	return l
+}
+
+// Enabled reports whether the limiter restricts concurrency at all
+func (l *Limiter) Enabled() bool {
+	return l != nil && l.max > 0
+}
+
+// Priority maps a priority name to its level. Unknown or empty names get the
+// lowest priority so callers must opt in to being served first.
+func (l *Limiter) Priority(name string) int {
+	if level, ok := l.levels[name]; ok {
+		return level
+	}
+	return len(l.queues) - 1
+}
+
+// Acquire blocks until a slot is available or ctx is done. Every successful
+// Acquire must be paired with a Release.
+func (l *Limiter) Acquire(ctx context.Context, priority int) error {
+	l.mu.Lock()
+	if l.active < l.max && l.queued() == 0 {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	l.queues[priority] = append(l.queues[priority], ready)
+	l.maxQueued = max(l.maxQueued, l.queued())
+	l.mu.Unlock()
+
+	queuedAt := time.Now()
+	defer func() { l.waits.Observe(time.Since(queuedAt).Seconds()) }()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !l.remove(priority, ready) {
+			// A slot was handed over while we were giving up; pass it on
+			l.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot, handing it to the highest-priority waiter if any
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.release()
+}
+
+// Queued returns the number of requests waiting for a slot
+func (l *Limiter) Queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queued()
+}
+
+// QueueStats returns the current and maximum queue depth and the
+// distribution of time requests spent queued
+func (l *Limiter) QueueStats() metrics.QueueSnapshot {
+	l.mu.Lock()
+	depth, maxDepth := l.queued(), l.maxQueued
+	l.mu.Unlock()
+	return metrics.QueueSnapshot{
+		Depth:       depth,
+		MaxDepth:    maxDepth,
+		WaitSeconds: l.waits.Snapshot(),
+	}
+}
+
+func (l *Limiter) release() {
+	for i, queue := range l.queues {
+		if len(queue) > 0 {
+			// The slot moves straight to the waiter, so active is unchanged
+			close(queue[0])
+			l.queues[i] = queue[1:]
+			return
+		}
+	}
+	l.active--
+}
+
+func (l *Limiter) queued() int {
+	total := 0
+	for _, queue := range l.queues {
+		total += len(queue)
+	}
+	return total
+}
+
+// remove drops a waiter from its queue, reporting whether it was still queued
+func (l *Limiter) remove(priority int, ready chan struct{}) bool {
+	queue := l.queues[priority]
+	for i, waiter := range queue {
+		if waiter == ready {
+			l.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enqueue starts a waiter and blocks until it is queued, reporting admission on the returned channel
+func enqueue(t *testing.T, l *Limiter, priority int, name string, admitted chan<- string) {
+	t.Helper()
+	before := l.Queued()
+	go func() {
+		if err := l.Acquire(context.Background(), priority); err == nil {
+			admitted <- name
+		}
+	}()
+	require.Eventually(t, func() bool { return l.Queued() == before+1 }, time.Second, time.Millisecond)
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("admits_up_to_limit", func(t *testing.T) {
+		l := NewLimiter(2, nil)
+		require.NoError(t, l.Acquire(context.Background(), 0))
+		require.NoError(t, l.Acquire(context.Background(), 0))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, l.Acquire(ctx, 0), context.DeadlineExceeded)
+		assert.Equal(t, 0, l.Queued())
+	})
+
+	t.Run("high_priority_admitted_before_queued_low_priority", func(t *testing.T) {
+		l := NewLimiter(1, []string{"high", "low"})
+		require.NoError(t, l.Acquire(context.Background(), l.Priority("low")))
+
+		admitted := make(chan string, 2)
+		enqueue(t, l, l.Priority("low"), "low", admitted)
+		enqueue(t, l, l.Priority("high"), "high", admitted)
+
+		l.Release()
+		assert.Equal(t, "high", <-admitted)
+		l.Release()
+		assert.Equal(t, "low", <-admitted)
+	})
+
+	t.Run("single_priority_is_fifo", func(t *testing.T) {
+		l := NewLimiter(1, nil)
+		require.NoError(t, l.Acquire(context.Background(), 0))
+
+		admitted := make(chan string, 2)
+		enqueue(t, l, l.Priority("high"), "first", admitted)
+		enqueue(t, l, l.Priority(""), "second", admitted)
+
+		l.Release()
+		assert.Equal(t, "first", <-admitted)
+		l.Release()
+		assert.Equal(t, "second", <-admitted)
+	})
+
+	t.Run("unknown_priority_is_lowest", func(t *testing.T) {
+		l := NewLimiter(1, []string{"high", "low"})
+		assert.Equal(t, 0, l.Priority("high"))
+		assert.Equal(t, 1, l.Priority("bogus"))
+		assert.Equal(t, 1, l.Priority(""))
+	})
+
+	t.Run("reports_queue_depth_and_waits", func(t *testing.T) {
+		l := NewLimiter(1, nil)
+		require.NoError(t, l.Acquire(context.Background(), 0))
+
+		admitted := make(chan string, 2)
+		enqueue(t, l, 0, "first", admitted)
+		enqueue(t, l, 0, "second", admitted)
+
+		stats := l.QueueStats()
+		assert.Equal(t, 2, stats.Depth)
+		assert.Equal(t, 2, stats.MaxDepth)
+		assert.Equal(t, int64(0), stats.WaitSeconds.Count)
+
+		l.Release()
+		l.Release()
+		<-admitted
+		<-admitted
+		require.Eventually(t, func() bool { return l.QueueStats().WaitSeconds.Count == 2 }, time.Second, time.Millisecond)
+
+		stats = l.QueueStats()
+		assert.Equal(t, 0, stats.Depth)
+		assert.Equal(t, 2, stats.MaxDepth)
+	})
+
+	t.Run("disabled_without_limit", func(t *testing.T) {
+		assert.False(t, NewLimiter(0, nil).Enabled())
+		assert.True(t, NewLimiter(1, nil).Enabled())
+	})
+}
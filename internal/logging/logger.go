@@ -2,6 +2,7 @@ package logging
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
@@ -19,6 +20,11 @@ type LogConfig struct {
 	Level  string
 	Format string
 	Output io.Writer
+	// FieldPrefix namespaces every attribute key as "<prefix>.<key>"
+	FieldPrefix string
+	// LevelVar, when set, is initialized to Level and consulted on every log
+	// call, so changing it adjusts every logger sharing it at runtime
+	LevelVar *slog.LevelVar
 }
 
 // NewLogger creates a new structured logger based on configuration
@@ -35,6 +41,10 @@ func NewLogger(config LogConfig) *Logger {
 		Level:     level,
 		AddSource: true,
 	}
+	if config.LevelVar != nil {
+		config.LevelVar.Set(level)
+		opts.Level = config.LevelVar
+	}
 
 	switch strings.ToLower(config.Format) {
 	case "json":
@@ -44,6 +54,9 @@ func NewLogger(config LogConfig) *Logger {
 	default:
 		handler = slog.NewJSONHandler(output, opts)
 	}
+	if config.FieldPrefix != "" {
+		handler = newPrefixHandler(handler, config.FieldPrefix)
+	}
 
 	return &Logger{
 		Logger: slog.New(handler),
@@ -111,21 +124,35 @@ func (l *Logger) LogRequest(method, path, userAgent string, startTime time.Time)
 
 // LogResponse logs HTTP response information
 func (l *Logger) LogResponse(statusCode int, duration time.Duration, size int64) {
-	level := slog.LevelInfo
-	if statusCode >= 400 {
-		level = slog.LevelWarn
-	}
-	if statusCode >= 500 {
-		level = slog.LevelError
-	}
-
-	l.Logger.Log(context.Background(), level, "HTTP request completed",
+	l.Logger.Log(context.Background(), responseLevel(statusCode), "HTTP request completed",
 		"status_code", statusCode,
 		"duration_ms", duration.Milliseconds(),
 		"response_size_bytes", size,
 	)
 }
 
+// LogCompressedResponse logs HTTP response information for a compressed
+// response. response_size_bytes is the compressed size sent over the wire.
+func (l *Logger) LogCompressedResponse(statusCode int, duration time.Duration, compressedSize, uncompressedSize int64) {
+	l.Logger.Log(context.Background(), responseLevel(statusCode), "HTTP request completed",
+		"status_code", statusCode,
+		"duration_ms", duration.Milliseconds(),
+		"response_size_bytes", compressedSize,
+		"uncompressed_size_bytes", uncompressedSize,
+	)
+}
+
+// responseLevel picks the log level for a response status code
+func responseLevel(statusCode int) slog.Level {
+	if statusCode >= 500 {
+		return slog.LevelError
+	}
+	if statusCode >= 400 {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
 // LogCacheOperation logs cache operations
 func (l *Logger) LogCacheOperation(operation string, hit bool, key string, size int) {
 	l.Logger.Debug("Cache operation",
@@ -175,8 +202,46 @@ func (l *Logger) LogLLMResponse(statusCode int, responseSize int, duration time.
 	)
 }
 
+// LogRetryAttempt logs the outcome of a single LLM call attempt
+func (l *Logger) LogRetryAttempt(attempt, maxAttempts int, delay time.Duration, err error, final bool) {
+	level := slog.LevelDebug
+	args := []interface{}{
+		"retry_attempt", attempt,
+		"max_attempts", maxAttempts,
+		"retry_delay_ms", delay.Milliseconds(),
+		"final_attempt", final,
+		"attempt_success", err == nil,
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		args = append(args, "error", err.Error())
+	}
+
+	l.Logger.Log(context.Background(), level, "LLM call attempt completed", args...)
+}
+
+// LogRetryOutcome logs a summary once an LLM call that needed retries finishes
+func (l *Logger) LogRetryOutcome(success bool, attempts int, err error) {
+	if success {
+		l.Logger.Info(fmt.Sprintf("LLM call succeeded after %d retries", attempts-1),
+			"retry_outcome", "succeeded",
+			"total_attempts", attempts,
+		)
+		return
+	}
+
+	args := []interface{}{
+		"retry_outcome", "exhausted",
+		"total_attempts", attempts,
+	}
+	if err != nil {
+		args = append(args, "error", err.Error())
+	}
+	l.Logger.Error("LLM call exhausted retries", args...)
+}
+
 // LogStartup logs application startup information
-func (l *Logger) LogStartup(config map[string]interface{}) {
+func (l *Logger) LogStartup(config interface{}) {
 	l.Logger.Info("Application starting",
 		"config", config,
 		"startup_time", time.Now().UTC().Format(time.RFC3339),
@@ -193,6 +258,17 @@ func (l *Logger) LogShutdown(graceful bool, duration time.Duration) {
 }
 
 // parseLogLevel converts string log level to slog.Level
+// ParseLevel parses a log level name, rejecting names parseLogLevel would
+// silently treat as info
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error":
+		return parseLogLevel(level), nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
 func parseLogLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
@@ -3,6 +3,8 @@ package logging
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"strings"
 	"testing"
 	"time"
@@ -361,6 +363,100 @@ func TestSpecializedLoggingMethods(t *testing.T) {
 		assert.Contains(t, output, "2000")
 		assert.Contains(t, output, "Application shutdown")
 	})
+
+	t.Run("log_retry_attempt", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewLogger(LogConfig{Level: "debug", Format: "json", Output: &buf})
+
+		logger.LogRetryAttempt(2, 3, 500*time.Millisecond, errors.New("connection reset"), false)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "WARN", entry["level"])
+		assert.Equal(t, float64(2), entry["retry_attempt"])
+		assert.Equal(t, float64(3), entry["max_attempts"])
+		assert.Equal(t, float64(500), entry["retry_delay_ms"])
+		assert.Equal(t, false, entry["final_attempt"])
+		assert.Equal(t, "connection reset", entry["error"])
+	})
+
+	t.Run("log_retry_outcome", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewLogger(LogConfig{Level: "info", Format: "json", Output: &buf})
+
+		logger.LogRetryOutcome(true, 3, nil)
+		logger.LogRetryOutcome(false, 4, errors.New("status 503"))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "LLM call succeeded after 2 retries")
+		assert.Contains(t, lines[0], `"retry_outcome":"succeeded"`)
+		assert.Contains(t, lines[1], "LLM call exhausted retries")
+		assert.Contains(t, lines[1], `"total_attempts":4`)
+	})
+}
+
+func TestFieldPrefix(t *testing.T) {
+	newPrefixedLogger := func(buf *bytes.Buffer) *Logger {
+		return NewLogger(LogConfig{Level: "info", Format: "json", Output: buf, FieldPrefix: "llmjp"})
+	}
+
+	t.Run("prefixes_context_and_record_fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newPrefixedLogger(&buf)
+
+		logger.WithRequestID("req-1").
+			WithComponent("validator").
+			WithError(assert.AnError).
+			WithFields(map[string]interface{}{"schema_size_bytes": 42}).
+			Info("validated", "duration_ms", 5)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "req-1", entry["llmjp.request_id"])
+		assert.Equal(t, "validator", entry["llmjp.component"])
+		assert.Equal(t, assert.AnError.Error(), entry["llmjp.error"])
+		assert.Equal(t, float64(42), entry["llmjp.schema_size_bytes"])
+		assert.Equal(t, float64(5), entry["llmjp.duration_ms"])
+		assert.NotContains(t, entry, "component")
+		assert.NotContains(t, entry, "error")
+
+		// Built-in keys keep their standard names
+		assert.Equal(t, "validated", entry["msg"])
+		assert.Contains(t, entry, "time")
+		assert.Contains(t, entry, "level")
+	})
+
+	t.Run("group_fields_prefixed_once", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newPrefixedLogger(&buf)
+
+		logger.WithGroup("llm").With("status", 200).Info("call")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, map[string]interface{}{"status": float64(200)}, entry["llmjp.llm"])
+	})
+
+	t.Run("specialized_methods_prefixed", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newPrefixedLogger(&buf)
+
+		logger.LogResponse(200, 10*time.Millisecond, 128)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, float64(200), entry["llmjp.status_code"])
+		assert.Equal(t, float64(128), entry["llmjp.response_size_bytes"])
+	})
+
+	t.Run("no_prefix_by_default", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := NewLogger(LogConfig{Level: "info", Format: "json", Output: &buf})
+
+		logger.WithComponent("validator").Info("validated")
+		assert.Contains(t, buf.String(), `"component":"validator"`)
+	})
 }
 
 func TestParseLogLevel(t *testing.T) {
@@ -386,6 +482,27 @@ func TestParseLogLevel(t *testing.T) {
 	}
 }
 
+func TestLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	levelVar := new(slog.LevelVar)
+	logger := NewLogger(LogConfig{Level: "warn", Format: "json", Output: &buf, LevelVar: levelVar})
+	derived := logger.WithComponent("validator")
+
+	assert.Equal(t, slog.LevelWarn, levelVar.Level())
+	derived.Debug("hidden")
+	assert.Empty(t, buf.String())
+
+	levelVar.Set(slog.LevelDebug)
+	derived.Debug("shown")
+	assert.Contains(t, buf.String(), "shown")
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+	level, err := ParseLevel("WARNING")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+}
+
 func TestLoggerChaining(t *testing.T) {
 	t.Run("multiple_context_methods", func(t *testing.T) {
 		var buf bytes.Buffer
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// prefixHandler namespaces attribute keys, turning "component" into
+// "<prefix>.component", so this service's fields don't collide with other
+// services' in a shared log store. The built-in time, level, msg and source
+// keys are left alone. Attributes inside a group are reached through the
+// already-prefixed group name and are not prefixed again.
+type prefixHandler struct {
+	inner   slog.Handler
+	prefix  string
+	grouped bool
+}
+
+func newPrefixHandler(inner slog.Handler, prefix string) slog.Handler {
+	return &prefixHandler{inner: inner, prefix: prefix + "."}
+}
+
+func (h *prefixHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *prefixHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.grouped {
+		return h.inner.Handle(ctx, r)
+	}
+	prefixed := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		prefixed.AddAttrs(h.prefixAttr(a))
+		return true
+	})
+	return h.inner.Handle(ctx, prefixed)
+}
+
+func (h *prefixHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if !h.grouped {
+		prefixed := make([]slog.Attr, len(attrs))
+		for i, a := range attrs {
+			prefixed[i] = h.prefixAttr(a)
+		}
+		attrs = prefixed
+	}
+	return &prefixHandler{inner: h.inner.WithAttrs(attrs), prefix: h.prefix, grouped: h.grouped}
+}
+
+func (h *prefixHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	if !h.grouped {
+		name = h.prefix + name
+	}
+	return &prefixHandler{inner: h.inner.WithGroup(name), prefix: h.prefix, grouped: true}
+}
+
+func (h *prefixHandler) prefixAttr(a slog.Attr) slog.Attr {
+	if a.Key == "" {
+		return a
+	}
+	return slog.Attr{Key: h.prefix + a.Key, Value: a.Value}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import "sync"
+
+// QueueWaitBuckets are the upper bounds, in seconds, of the admission queue
+// wait histogram
+var QueueWaitBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Histogram counts observations into fixed buckets
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []int64
+	count  int64
+	sum    float64
+}
+
+// Bucket is the number of observations less than or equal to UpperBound
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      int64   `json:"count"`
+}
+
+// HistogramSnapshot contains a histogram's cumulative bucket counts at a
+// point in time. Observations above the last bound are only in Count.
+type HistogramSnapshot struct {
+	Buckets []Bucket `json:"buckets"`
+	Count   int64    `json:"count"`
+	Sum     float64  `json:"sum"`
+}
+
+// NewHistogram creates a histogram with the given ascending upper bounds
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)),
+	}
+}
+
+// Observe records one value
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += v
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+			return
+		}
+	}
+}
+
+// Snapshot returns the current bucket counts
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make([]Bucket, len(h.bounds))
+	var cumulative int64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		buckets[i] = Bucket{UpperBound: bound, Count: cumulative}
+	}
+	return HistogramSnapshot{Buckets: buckets, Count: h.count, Sum: h.sum}
+}
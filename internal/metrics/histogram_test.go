@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{0.1, 1, 10})
+	for _, v := range []float64{0.05, 0.1, 0.5, 5, 50} {
+		h.Observe(v)
+	}
+
+	snapshot := h.Snapshot()
+	assert.Equal(t, []Bucket{
+		{UpperBound: 0.1, Count: 2},
+		{UpperBound: 1, Count: 3},
+		{UpperBound: 10, Count: 4},
+	}, snapshot.Buckets)
+	assert.Equal(t, int64(5), snapshot.Count)
+	assert.InDelta(t, 55.65, snapshot.Sum, 1e-9)
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Stats collects race-safe request counters for the debug endpoint
+type Stats struct {
+	totalRequests atomic.Int64
+	inFlight      atomic.Int64
+	llmErrors     atomic.Int64
+
+	mu             sync.Mutex
+	failuresByCode map[string]int64
+}
+
+// CacheSnapshot contains schema cache counters at a point in time
+type CacheSnapshot struct {
+	Size    int     `json:"size"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// QueueSnapshot contains admission queue counters at a point in time. Depth
+// is the number of requests waiting now and MaxDepth the most ever waiting
+// at once. WaitSeconds covers every request that left the queue, whether
+// admitted or abandoned; requests admitted without queueing are not in it.
+type QueueSnapshot struct {
+	Depth       int               `json:"depth"`
+	MaxDepth    int               `json:"max_depth"`
+	WaitSeconds HistogramSnapshot `json:"wait_seconds"`
+}
+
+// Snapshot contains all counters at a point in time. Queue is only set when
+// concurrency is limited, and ErrorRates when an error rate window is set.
+type Snapshot struct {
+	TotalRequests  int64              `json:"total_requests"`
+	InFlight       int64              `json:"in_flight"`
+	LLMErrors      int64              `json:"llm_errors"`
+	FailuresByCode map[string]int64   `json:"failures_by_code"`
+	Cache          CacheSnapshot      `json:"cache"`
+	Queue          *QueueSnapshot     `json:"queue,omitempty"`
+	ErrorRates     *ErrorRateSnapshot `json:"error_rates,omitempty"`
+}
+
+// NewStats creates an empty set of counters
+func NewStats() *Stats {
+	return &Stats{
+		failuresByCode: make(map[string]int64),
+	}
+}
+
+// RequestStarted records a request entering the server
+func (s *Stats) RequestStarted() {
+	s.totalRequests.Add(1)
+	s.inFlight.Add(1)
+}
+
+// RequestFinished records a request leaving the server
+func (s *Stats) RequestFinished() {
+	s.inFlight.Add(-1)
+}
+
+// RecordFailure records a request that failed with the given error code
+func (s *Stats) RecordFailure(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failuresByCode[code]++
+}
+
+// RecordLLMError records a failed call to the LLM backend
+func (s *Stats) RecordLLMError() {
+	s.llmErrors.Add(1)
+}
+
+// Snapshot returns the current counter values with the given cache counters
+func (s *Stats) Snapshot(cache CacheSnapshot) Snapshot {
+	s.mu.Lock()
+	failures := make(map[string]int64, len(s.failuresByCode))
+	for code, count := range s.failuresByCode {
+		failures[code] = count
+	}
+	s.mu.Unlock()
+
+	if lookups := cache.Hits + cache.Misses; lookups > 0 {
+		cache.HitRate = float64(cache.Hits) / float64(lookups)
+	}
+
+	return Snapshot{
+		TotalRequests:  s.totalRequests.Load(),
+		InFlight:       s.inFlight.Load(),
+		LLMErrors:      s.llmErrors.Load(),
+		FailuresByCode: failures,
+		Cache:          cache,
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	t.Run("counts_requests_and_failures", func(t *testing.T) {
+		stats := NewStats()
+
+		stats.RequestStarted()
+		stats.RequestStarted()
+		stats.RequestFinished()
+		stats.RecordFailure("INVALID_SCHEMA")
+		stats.RecordFailure("INVALID_SCHEMA")
+		stats.RecordFailure("LLM_ERROR")
+		stats.RecordLLMError()
+
+		snapshot := stats.Snapshot(CacheSnapshot{})
+		assert.Equal(t, int64(2), snapshot.TotalRequests)
+		assert.Equal(t, int64(1), snapshot.InFlight)
+		assert.Equal(t, int64(1), snapshot.LLMErrors)
+		assert.Equal(t, int64(2), snapshot.FailuresByCode["INVALID_SCHEMA"])
+		assert.Equal(t, int64(1), snapshot.FailuresByCode["LLM_ERROR"])
+	})
+
+	t.Run("computes_cache_hit_rate", func(t *testing.T) {
+		snapshot := NewStats().Snapshot(CacheSnapshot{Size: 2, Hits: 3, Misses: 1})
+		assert.Equal(t, 0.75, snapshot.Cache.HitRate)
+
+		empty := NewStats().Snapshot(CacheSnapshot{})
+		assert.Equal(t, 0.0, empty.Cache.HitRate)
+	})
+
+	t.Run("concurrent_updates", func(t *testing.T) {
+		stats := NewStats()
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stats.RequestStarted()
+				stats.RecordFailure("VALIDATION_FAILED")
+				stats.RequestFinished()
+			}()
+		}
+		wg.Wait()
+
+		snapshot := stats.Snapshot(CacheSnapshot{})
+		assert.Equal(t, int64(50), snapshot.TotalRequests)
+		assert.Equal(t, int64(0), snapshot.InFlight)
+		assert.Equal(t, int64(50), snapshot.FailuresByCode["VALIDATION_FAILED"])
+	})
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// windowSlots is how many slots a window is divided into. Requests age out
+// of the window a slot at a time, so it slides in steps of window/windowSlots.
+const windowSlots = 60
+
+// windowSlot counts the requests that completed during one slot
+type windowSlot struct {
+	index    int64 // which slot-length period since the epoch this counts
+	requests int64
+	failures map[string]int64
+}
+
+// ErrorWindow tracks what fraction of requests failed, by category, over a
+// sliding window, so operators can alert on rates such as "validation
+// failures above 20% over 5m" without aggregating logs elsewhere
+type ErrorWindow struct {
+	mu      sync.Mutex
+	window  time.Duration
+	slotLen time.Duration
+	slots   [windowSlots]windowSlot
+	now     func() time.Time
+}
+
+// ErrorRateSnapshot contains the requests and failures seen within the
+// window. FailureRate is the fraction of requests that failed, and
+// RatesByCategory the fraction that failed with each category.
+type ErrorRateSnapshot struct {
+	WindowSeconds   float64            `json:"window_seconds"`
+	Requests        int64              `json:"requests"`
+	Failures        int64              `json:"failures"`
+	FailureRate     float64            `json:"failure_rate"`
+	RatesByCategory map[string]float64 `json:"rates_by_category"`
+}
+
+// NewErrorWindow creates a tracker over the given window
+func NewErrorWindow(window time.Duration) *ErrorWindow {
+	slotLen := window / windowSlots
+	if slotLen <= 0 {
+		slotLen = 1
+	}
+	return &ErrorWindow{
+		window:  window,
+		slotLen: slotLen,
+		now:     time.Now,
+	}
+}
+
+// Record counts a completed request; category names why it failed, or is
+// empty when it succeeded
+func (e *ErrorWindow) Record(category string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	index := e.now().UnixNano() / int64(e.slotLen)
+	slot := &e.slots[index%windowSlots]
+	if slot.index != index {
+		*slot = windowSlot{index: index}
+	}
+	slot.requests++
+	if category != "" {
+		if slot.failures == nil {
+			slot.failures = make(map[string]int64)
+		}
+		slot.failures[category]++
+	}
+}
+
+// Snapshot returns the rates over the window ending now
+func (e *ErrorWindow) Snapshot() ErrorRateSnapshot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	snapshot := ErrorRateSnapshot{
+		WindowSeconds:   e.window.Seconds(),
+		RatesByCategory: make(map[string]float64),
+	}
+	failures := make(map[string]int64)
+	current := e.now().UnixNano() / int64(e.slotLen)
+	for _, slot := range e.slots {
+		if slot.index <= current-windowSlots || slot.index > current {
+			continue
+		}
+		snapshot.Requests += slot.requests
+		for category, count := range slot.failures {
+			failures[category] += count
+			snapshot.Failures += count
+		}
+	}
+
+	if snapshot.Requests > 0 {
+		snapshot.FailureRate = float64(snapshot.Failures) / float64(snapshot.Requests)
+		for category, count := range failures {
+			snapshot.RatesByCategory[category] = float64(count) / float64(snapshot.Requests)
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorWindow(t *testing.T) {
+	newWindow := func(window time.Duration) (*ErrorWindow, *time.Time) {
+		now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		e := NewErrorWindow(window)
+		e.now = func() time.Time { return now }
+		return e, &now
+	}
+
+	t.Run("reports_known_ratio", func(t *testing.T) {
+		e, _ := newWindow(5 * time.Minute)
+		for i := 0; i < 6; i++ {
+			e.Record("")
+		}
+		for i := 0; i < 3; i++ {
+			e.Record("VALIDATION_FAILED")
+		}
+		e.Record("LLM_ERROR")
+
+		snapshot := e.Snapshot()
+		assert.Equal(t, float64(300), snapshot.WindowSeconds)
+		assert.Equal(t, int64(10), snapshot.Requests)
+		assert.Equal(t, int64(4), snapshot.Failures)
+		assert.InDelta(t, 0.4, snapshot.FailureRate, 1e-9)
+		assert.InDelta(t, 0.3, snapshot.RatesByCategory["VALIDATION_FAILED"], 1e-9)
+		assert.InDelta(t, 0.1, snapshot.RatesByCategory["LLM_ERROR"], 1e-9)
+	})
+
+	t.Run("old_requests_leave_window", func(t *testing.T) {
+		e, now := newWindow(time.Minute)
+		e.Record("VALIDATION_FAILED")
+
+		*now = now.Add(30 * time.Second)
+		e.Record("")
+		e.Record("")
+		snapshot := e.Snapshot()
+		assert.Equal(t, int64(3), snapshot.Requests)
+		assert.Equal(t, int64(1), snapshot.Failures)
+
+		*now = now.Add(45 * time.Second)
+		snapshot = e.Snapshot()
+		assert.Equal(t, int64(2), snapshot.Requests)
+		assert.Equal(t, int64(0), snapshot.Failures)
+		assert.Zero(t, snapshot.FailureRate)
+
+		*now = now.Add(time.Hour)
+		assert.Equal(t, int64(0), e.Snapshot().Requests)
+	})
+
+	t.Run("empty_window_reports_zero", func(t *testing.T) {
+		e, _ := newWindow(time.Minute)
+		snapshot := e.Snapshot()
+		assert.Zero(t, snapshot.Requests)
+		assert.Zero(t, snapshot.FailureRate)
+		assert.Empty(t, snapshot.RatesByCategory)
+	})
+
+	t.Run("concurrent_records", func(t *testing.T) {
+		e := NewErrorWindow(time.Minute)
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if i%4 == 0 {
+					e.Record("VALIDATION_FAILED")
+				} else {
+					e.Record("")
+				}
+				e.Snapshot()
+			}(i)
+		}
+		wg.Wait()
+
+		snapshot := e.Snapshot()
+		assert.Equal(t, int64(100), snapshot.Requests)
+		assert.InDelta(t, 0.25, snapshot.FailureRate, 1e-9)
+	})
+}
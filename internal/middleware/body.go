@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// ContextKeyRequestBody is the context key for the buffered request body
+const ContextKeyRequestBody ContextKey = "request_body"
+
+// BufferBody creates a middleware that reads the request body once into
+// memory, rejecting it with 413 when it exceeds maxBytes (zero means no
+// limit). r.Body is replaced with a reader over the buffer, r.GetBody returns
+// a fresh one, and the raw bytes are stored in the context, so downstream
+// handlers may read the body as often as they need. With verifyLength, a body
+// whose size differs from its declared Content-Length, such as an upload
+// truncated by a proxy, is rejected with 400 before any handler decodes it.
+func BufferBody(maxBytes int64, verifyLength bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := r.Body
+			if maxBytes > 0 {
+				body = http.MaxBytesReader(w, body, maxBytes)
+			}
+			buf, err := io.ReadAll(body)
+			r.Body.Close()
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, r, http.StatusRequestEntityTooLarge, types.ErrorCodeRequestTooLarge,
+						"Request body too large", "request body exceeds the configured limit")
+					return
+				}
+				if verifyLength && errors.Is(err, io.ErrUnexpectedEOF) {
+					writeLengthMismatch(w, r, r.ContentLength, len(buf))
+					return
+				}
+				if ctxLogger := GetLogger(r.Context()); ctxLogger != nil {
+					ctxLogger.WithComponent("body_middleware").WithError(err).Warn("Failed to read request body")
+				}
+				writeError(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+					"Failed to read request body", err.Error())
+				return
+			}
+
+			if verifyLength && r.ContentLength >= 0 && r.ContentLength != int64(len(buf)) {
+				writeLengthMismatch(w, r, r.ContentLength, len(buf))
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), ContextKeyRequestBody, buf))
+			r.Body = io.NopCloser(bytes.NewReader(buf))
+			r.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(buf)), nil
+			}
+			r.ContentLength = int64(len(buf))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeLengthMismatch rejects a body whose size differs from its declared length
+func writeLengthMismatch(w http.ResponseWriter, r *http.Request, declared int64, read int) {
+	if ctxLogger := GetLogger(r.Context()); ctxLogger != nil {
+		ctxLogger.WithComponent("body_middleware").WithFields(map[string]interface{}{
+			"content_length": declared,
+			"bytes_read":     read,
+		}).Warn("Request body size does not match Content-Length")
+	}
+	writeError(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+		"Request body does not match Content-Length",
+		fmt.Sprintf("Content-Length declared %d bytes but %d were received", declared, read))
+}
+
+// GetRequestBody retrieves the body buffered by BufferBody. It reports false
+// when the body was not buffered, e.g. for requests without one.
+func GetRequestBody(ctx context.Context) ([]byte, bool) {
+	body, ok := ctx.Value(ContextKeyRequestBody).([]byte)
+	return body, ok
+}
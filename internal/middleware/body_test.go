@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferBody(t *testing.T) {
+	const payload = `{"schema": {"type": "object"}, "messages": []}`
+
+	t.Run("body_readable_many_times", func(t *testing.T) {
+		var reads []string
+		handler := BufferBody(1024, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// A decoder consumes the body, as a handler validating an envelope would
+			var envelope map[string]json.RawMessage
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&envelope))
+			assert.Contains(t, envelope, "schema")
+
+			raw, ok := GetRequestBody(r.Context())
+			require.True(t, ok)
+			reads = append(reads, string(raw))
+
+			fresh, err := r.GetBody()
+			require.NoError(t, err)
+			again, err := io.ReadAll(fresh)
+			require.NoError(t, err)
+			reads = append(reads, string(again))
+
+			assert.Equal(t, int64(len(payload)), r.ContentLength)
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		req := httptest.NewRequest("POST", "/v1/validated-query", strings.NewReader(payload))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{payload, payload}, reads)
+	})
+
+	t.Run("oversized_body_rejected", func(t *testing.T) {
+		called := false
+		handler := BufferBody(10, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+
+		req := httptest.NewRequest("POST", "/v1/validated-query", strings.NewReader(payload))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.False(t, called)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Contains(t, rr.Body.String(), "REQUEST_TOO_LARGE")
+	})
+
+	t.Run("zero_limit_unbounded", func(t *testing.T) {
+		handler := BufferBody(0, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, ok := GetRequestBody(r.Context())
+			assert.True(t, ok)
+			assert.Len(t, raw, 1<<16)
+		}))
+
+		req := httptest.NewRequest("POST", "/v1/validated-query", strings.NewReader(strings.Repeat("a", 1<<16)))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("no_body_passes_through", func(t *testing.T) {
+		handler := BufferBody(10, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := GetRequestBody(r.Context())
+			assert.False(t, ok)
+		}))
+
+		req := httptest.NewRequest("GET", "/health", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("content_length_mismatch", func(t *testing.T) {
+		serve := func(verify bool, declared int64) *httptest.ResponseRecorder {
+			handler := BufferBody(1024, verify)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest("POST", "/v1/validated-query", strings.NewReader(payload))
+			req.ContentLength = declared
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			return rr
+		}
+
+		rr := serve(true, int64(len(payload))+20)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "does not match Content-Length")
+
+		assert.Equal(t, http.StatusOK, serve(true, int64(len(payload))).Code)
+		assert.Equal(t, http.StatusOK, serve(true, -1).Code, "unknown length, e.g. chunked")
+		assert.Equal(t, http.StatusOK, serve(false, int64(len(payload))+20).Code)
+	})
+
+	t.Run("truncated_upload_over_the_wire", func(t *testing.T) {
+		server := httptest.NewServer(BufferBody(1024, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+		defer server.Close()
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// Declare more bytes than are sent, then stop sending
+		fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: test\r\nContent-Length: %d\r\n\r\n%s", len(payload)+20, payload)
+		require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
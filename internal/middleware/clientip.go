@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIPResolver finds the originating client of a request. X-Forwarded-For
+// is only believed for hops added by trusted proxies, since any client can
+// send the header with whatever addresses it likes.
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver creates a resolver trusting proxies in the given CIDRs.
+// Bare IP addresses are accepted as single-address ranges.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	c := &ClientIPResolver{}
+	for _, cidr := range trustedProxies {
+		network, err := parseTrustedProxy(cidr)
+		if err != nil {
+			return nil, err
+		}
+		c.trusted = append(c.trusted, network)
+	}
+	return c, nil
+}
+
+// parseTrustedProxy parses a trusted proxy CIDR or bare IP address
+func parseTrustedProxy(cidr string) (*net.IPNet, error) {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+		}
+		bits := 8 * net.IPv4len
+		if ip.To4() == nil {
+			bits = 8 * net.IPv6len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+	}
+	return network, nil
+}
+
+func (c *ClientIPResolver) isTrusted(ip net.IP) bool {
+	for _, network := range c.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address of a request. Starting from the
+// immediate peer, X-Forwarded-For is walked from right to left while the
+// current hop is a trusted proxy; the first untrusted hop is the client.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	client := net.ParseIP(peer)
+	if client == nil || !c.isTrusted(client) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// A malformed hop can't be trusted; stop at the last proxy we know
+			break
+		}
+		client = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// ClientIP creates a middleware that resolves the client address and stores
+// it in the request context, where request logging picks it up
+func ClientIP(resolver *ClientIPResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ContextKeyClientIP, resolver.ClientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientIP retrieves the resolved client address from context
+func GetClientIP(ctx context.Context) string {
+	if clientIP, ok := ctx.Value(ContextKeyClientIP).(string); ok {
+		return clientIP
+	}
+	return ""
+}
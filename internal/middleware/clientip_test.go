@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	resolve := func(remoteAddr string, forwardedFor ...string) string {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = remoteAddr
+		for _, header := range forwardedFor {
+			req.Header.Add("X-Forwarded-For", header)
+		}
+		return resolver.ClientIP(req)
+	}
+
+	t.Run("untrusted_peer_without_header", func(t *testing.T) {
+		assert.Equal(t, "203.0.113.7", resolve("203.0.113.7:5123"))
+	})
+
+	t.Run("untrusted_peer_spoofed_header_ignored", func(t *testing.T) {
+		assert.Equal(t, "203.0.113.7", resolve("203.0.113.7:5123", "198.51.100.1"))
+	})
+
+	t.Run("trusted_peer_uses_forwarded_client", func(t *testing.T) {
+		assert.Equal(t, "198.51.100.1", resolve("10.0.0.2:443", "198.51.100.1"))
+	})
+
+	t.Run("trusted_chain_walked_right_to_left", func(t *testing.T) {
+		assert.Equal(t, "198.51.100.1", resolve("10.0.0.2:443", "198.51.100.1, 192.168.1.1, 10.0.0.3"))
+	})
+
+	t.Run("spoofed_hops_left_of_client_ignored", func(t *testing.T) {
+		// The client prepended a fake address; only the hop our proxy added counts
+		assert.Equal(t, "198.51.100.1", resolve("10.0.0.2:443", "1.2.3.4, 198.51.100.1"))
+	})
+
+	t.Run("multiple_headers_combined", func(t *testing.T) {
+		assert.Equal(t, "198.51.100.1", resolve("10.0.0.2:443", "198.51.100.1", "10.0.0.3"))
+	})
+
+	t.Run("malformed_hop_stops_at_last_proxy", func(t *testing.T) {
+		assert.Equal(t, "10.0.0.3", resolve("10.0.0.2:443", "garbage, 10.0.0.3"))
+	})
+
+	t.Run("trusted_peer_without_header", func(t *testing.T) {
+		assert.Equal(t, "10.0.0.2", resolve("10.0.0.2:443"))
+	})
+
+	t.Run("invalid_cidr_rejected", func(t *testing.T) {
+		_, err := NewClientIPResolver([]string{"10.0.0.0/33"})
+		assert.Error(t, err)
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := logging.NewLogger(logging.LogConfig{Level: "info", Format: "json", Output: &buf})
+
+	var captured string
+	handler := ClientIP(resolver)(RequestLogging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = GetClientIP(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.2:443"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "198.51.100.1", captured)
+	assert.Contains(t, buf.String(), `"client_ip":"198.51.100.1"`)
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// countingWriter counts the bytes passed to the underlying writer
+type countingWriter struct {
+	w     http.ResponseWriter
+	count int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.count += int64(n)
+	return n, err
+}
+
+// compressWriter gzips a response body, tracking both the bytes the handler
+// wrote and the compressed bytes sent to the client
+type compressWriter struct {
+	http.ResponseWriter
+	gz           *gzip.Writer
+	out          *countingWriter
+	uncompressed int64
+	wroteHeader  bool
+}
+
+func newCompressWriter(w http.ResponseWriter, level int) *compressWriter {
+	out := &countingWriter{w: w}
+	gz, err := gzip.NewWriterLevel(out, level)
+	if err != nil {
+		gz = gzip.NewWriter(out)
+	}
+	return &compressWriter{
+		ResponseWriter: w,
+		gz:             gz,
+		out:            out,
+	}
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	h := cw.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	h.Add("Vary", "Accept-Encoding")
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	n, err := cw.gz.Write(b)
+	cw.uncompressed += int64(n)
+	return n, err
+}
+
+// Flush sends the data compressed so far to the client
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.gz.Flush()
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close flushes the remaining compressed data to the client
+func (cw *compressWriter) Close() error {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.gz.Close()
+}
+
+// CompressedBytes returns the number of compressed bytes written to the client
+func (cw *compressWriter) CompressedBytes() int64 {
+	return cw.out.count
+}
+
+// UncompressedBytes returns the number of bytes written by the handler
+func (cw *compressWriter) UncompressedBytes() int64 {
+	return cw.uncompressed
+}
+
+// Compress creates a middleware that gzips responses for clients that accept
+// it. Place it inside RequestLogging so logged sizes reflect compressed output.
+func Compress() func(http.Handler) http.Handler {
+	return CompressWithLevel(gzip.DefaultCompression)
+}
+
+// CompressWithLevel is Compress at the given gzip level, from
+// gzip.HuffmanOnly to gzip.BestCompression; lower levels trade ratio for
+// speed. An invalid level falls back to gzip.DefaultCompression.
+func CompressWithLevel(level int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := newCompressWriter(w, level)
+			if rw, ok := w.(*responseWriter); ok {
+				rw.compression = cw
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
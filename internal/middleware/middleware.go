@@ -2,11 +2,20 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/wcygan/llm-json-parse/internal/auth"
+	"github.com/wcygan/llm-json-parse/internal/limiter"
 	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/tenant"
+	"github.com/wcygan/llm-json-parse/pkg/types"
 )
 
 // ContextKey represents keys for context values
@@ -19,6 +28,14 @@ const (
 	ContextKeyLogger ContextKey = "logger"
 	// ContextKeyStartTime is the context key for request start time
 	ContextKeyStartTime ContextKey = "start_time"
+	// ContextKeyTenant is the context key for the resolved tenant
+	ContextKeyTenant ContextKey = "tenant"
+	// ContextKeyIdentity is the context key for the authenticated identity
+	ContextKeyIdentity ContextKey = "identity"
+	// ContextKeyClientIP is the context key for the resolved client address
+	ContextKeyClientIP ContextKey = "client_ip"
+	// ContextKeyTimings is the context key for the middleware timing recorder
+	ContextKeyTimings ContextKey = "middleware_timings"
 )
 
 // responseWriter wraps http.ResponseWriter to capture response details
@@ -26,6 +43,8 @@ type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	size       int64
+	// compression is set by Compress when it gzips this response
+	compression *compressWriter
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -39,8 +58,24 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// maxLogHeaderValue bounds the bytes of a header value copied into logs
+const maxLogHeaderValue = 256
+
 // RequestLogging creates a middleware that logs HTTP requests and responses
 func RequestLogging(logger *logging.Logger) func(http.Handler) http.Handler {
+	return RequestLoggingWithHeaders(logger, nil)
+}
+
+// RequestLoggingWithHeaders is RequestLogging that also adds the values of
+// the mapped request headers to every log line of the request, under their
+// log field names. Values are truncated to maxLogHeaderValue bytes.
+func RequestLoggingWithHeaders(logger *logging.Logger, headerFields map[string]string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Generate request ID if not present
@@ -53,6 +88,12 @@ func RequestLogging(logger *logging.Logger) func(http.Handler) http.Handler {
 			requestLogger := logger.
 				WithRequestID(requestID).
 				WithComponent("http_server")
+			if clientIP := GetClientIP(r.Context()); clientIP != "" {
+				requestLogger = requestLogger.WithFields(map[string]interface{}{"client_ip": clientIP})
+			}
+			if fields := headerLogFields(r, headerFields); len(fields) > 0 {
+				requestLogger = requestLogger.WithFields(fields)
+			}
 
 			// Record start time
 			startTime := time.Now()
@@ -80,11 +121,35 @@ func RequestLogging(logger *logging.Logger) func(http.Handler) http.Handler {
 
 			// Calculate duration and log response
 			duration := time.Since(startTime)
-			requestLogger.LogResponse(rw.statusCode, duration, rw.size)
+			if rw.compression != nil {
+				requestLogger.LogCompressedResponse(rw.statusCode, duration,
+					rw.compression.CompressedBytes(), rw.compression.UncompressedBytes())
+			} else {
+				requestLogger.LogResponse(rw.statusCode, duration, rw.size)
+			}
 		})
 	}
 }
 
+// headerLogFields returns the log fields for the mapped headers present on r
+func headerLogFields(r *http.Request, headerFields map[string]string) map[string]interface{} {
+	var fields map[string]interface{}
+	for header, field := range headerFields {
+		value := r.Header.Get(header)
+		if value == "" {
+			continue
+		}
+		if len(value) > maxLogHeaderValue {
+			value = value[:maxLogHeaderValue]
+		}
+		if fields == nil {
+			fields = make(map[string]interface{}, len(headerFields))
+		}
+		fields[field] = value
+	}
+	return fields
+}
+
 // Recovery creates a middleware that recovers from panics
 func Recovery(logger *logging.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -122,7 +187,7 @@ func CORS() func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID, X-No-Retry, X-Priority, X-LLM-Backend")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -136,8 +201,22 @@ func CORS() func(http.Handler) http.Handler {
 
 // RequestTimeout creates a middleware that enforces request timeouts
 func RequestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return RequestTimeoutWithRoutes(timeout, nil)
+}
+
+// RequestTimeoutWithRoutes is RequestTimeout with a timeout of its own for
+// each path in routes, e.g. a longer one for batch endpoints. A route's
+// timeout also moves the connection's write deadline, so a route may run
+// longer than the server-wide write timeout.
+func RequestTimeoutWithRoutes(timeout time.Duration, routes map[string]time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := timeout
+			if routeTimeout, ok := routes[r.URL.Path]; ok {
+				timeout = routeTimeout
+				// Writers that cannot move their deadline keep the server's
+				http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+			}
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer cancel()
 
@@ -147,19 +226,37 @@ func RequestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
 	}
 }
 
-// ContentType creates a middleware that validates content type for specific methods
+// ContentType creates a middleware that validates content type for specific
+// methods. Only the media type is compared, case-insensitively, so
+// "application/json; charset=utf-8" satisfies "application/json".
 func ContentType(requiredTypes ...string) func(http.Handler) http.Handler {
+	return ContentTypeWithDefault("", requiredTypes...)
+}
+
+// ContentTypeWithDefault is ContentType for clients that omit the header: a
+// request without a Content-Type is treated as defaultType, which is also
+// set on the request for later handlers. An empty defaultType rejects such
+// requests as ContentType does.
+func ContentTypeWithDefault(defaultType string, requiredTypes ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Only check content type for methods that have a body
 			if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
 				contentType := r.Header.Get("Content-Type")
+				if contentType == "" && defaultType != "" {
+					contentType = defaultType
+					r.Header.Set("Content-Type", defaultType)
+				}
 
+				// Parameters such as charset don't change the media type, and
+				// media types are case-insensitive
 				valid := false
-				for _, reqType := range requiredTypes {
-					if contentType == reqType {
-						valid = true
-						break
+				if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+					for _, reqType := range requiredTypes {
+						if strings.EqualFold(mediaType, reqType) {
+							valid = true
+							break
+						}
 					}
 				}
 
@@ -185,6 +282,198 @@ func ContentType(requiredTypes ...string) func(http.Handler) http.Handler {
 	}
 }
 
+// Authentication creates a middleware that authenticates requests with the
+// given authenticator and stores the identity in the request context. A nil
+// authenticator admits every request; exempt paths such as health probes are
+// never authenticated.
+func Authentication(authenticator auth.Authenticator, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if authenticator == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, path := range exempt {
+				if r.URL.Path == path {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			identity, err := authenticator.Authenticate(r)
+			if err != nil {
+				// Anything but a rejected credential means the backend itself failed
+				unavailable := !errors.Is(err, auth.ErrMissingCredentials) && !errors.Is(err, auth.ErrInvalidCredentials)
+				if ctxLogger := GetLogger(r.Context()); ctxLogger != nil {
+					ctxLogger = ctxLogger.WithComponent("auth_middleware").WithError(err)
+					if unavailable {
+						ctxLogger.Error("Authentication backend unavailable")
+					} else {
+						ctxLogger.Warn("Authentication failed")
+					}
+				}
+				if unavailable {
+					writeError(w, r, http.StatusServiceUnavailable, types.ErrorCodeInternalError,
+						"Authentication unavailable", "credentials could not be verified")
+					return
+				}
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, r, http.StatusUnauthorized, types.ErrorCodeUnauthorized,
+					"Unauthorized", err.Error())
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextKeyIdentity, identity)
+			if ctxLogger := GetLogger(ctx); ctxLogger != nil {
+				ctx = context.WithValue(ctx, ContextKeyLogger, ctxLogger.WithFields(map[string]interface{}{
+					"auth_subject": identity.Subject,
+				}))
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TenantResolution creates a middleware that identifies the tenant and stores
+// it in the request context. An authenticated identity's tenant takes
+// precedence over the configured header, which may then only repeat it.
+func TenantResolution(registry *tenant.Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !registry.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tenantID := r.Header.Get(registry.Header())
+			if identity := GetIdentity(r.Context()); identity != nil && identity.TenantID != "" {
+				if tenantID != "" && tenantID != identity.TenantID {
+					writeError(w, r, http.StatusForbidden, types.ErrorCodeUnknownTenant,
+						"Tenant not permitted", "tenant "+tenantID+" does not match the authenticated identity")
+					return
+				}
+				tenantID = identity.TenantID
+			}
+			if tenantID == "" {
+				if registry.Required() {
+					writeError(w, r, http.StatusForbidden, types.ErrorCodeUnknownTenant,
+						"Tenant ID required", "missing "+registry.Header()+" header")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			t, ok := registry.Lookup(tenantID)
+			if !ok {
+				if ctxLogger := GetLogger(r.Context()); ctxLogger != nil {
+					ctxLogger.
+						WithComponent("tenant_middleware").
+						WithFields(map[string]interface{}{
+							"tenant_id": tenantID,
+						}).
+						Warn("Unknown tenant")
+				}
+				writeError(w, r, http.StatusForbidden, types.ErrorCodeUnknownTenant,
+					"Unknown tenant", "tenant "+tenantID+" is not configured")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextKeyTenant, t)
+			if ctxLogger := GetLogger(ctx); ctxLogger != nil {
+				ctx = context.WithValue(ctx, ContextKeyLogger, ctxLogger.WithFields(map[string]interface{}{
+					"tenant_id": t.ID,
+				}))
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ConcurrencyLimit creates a middleware that admits requests through the
+// limiter, ordering queued requests by the priority named in header. Requests
+// to exempt paths, such as health probes and the debug endpoint, bypass the
+// limiter so they still answer while it is saturated.
+func ConcurrencyLimit(l *limiter.Limiter, header string, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !l.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, path := range exempt {
+				if r.URL.Path == path {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			priority := l.Priority(r.Header.Get(header))
+			if err := l.Acquire(r.Context(), priority); err != nil {
+				if ctxLogger := GetLogger(r.Context()); ctxLogger != nil {
+					ctxLogger.
+						WithComponent("concurrency_middleware").
+						WithError(err).
+						WithFields(map[string]interface{}{
+							"priority": priority,
+						}).
+						Warn("Request abandoned while queued")
+				}
+				writeError(w, r, http.StatusServiceUnavailable, types.ErrorCodeOverloaded,
+					"Server overloaded", "request was not admitted before it was cancelled")
+				return
+			}
+			defer l.Release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientConcurrencyLimit creates a middleware that rejects a request with 429
+// when its client already has the maximum number of requests in flight, so
+// one client's long-lived requests cannot take every slot. Clients are told
+// apart by the address stored by the ClientIP middleware, falling back to the
+// peer address. Requests to exempt paths, such as health probes, are not
+// counted.
+func ClientConcurrencyLimit(l *limiter.KeyedLimiter, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !l.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, path := range exempt {
+				if r.URL.Path == path {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			clientIP := GetClientIP(r.Context())
+			if clientIP == "" {
+				clientIP = r.RemoteAddr
+				if host, _, err := net.SplitHostPort(clientIP); err == nil {
+					clientIP = host
+				}
+			}
+			if !l.TryAcquire(clientIP) {
+				if ctxLogger := GetLogger(r.Context()); ctxLogger != nil {
+					ctxLogger.
+						WithComponent("concurrency_middleware").
+						WithFields(map[string]interface{}{
+							"client_ip": clientIP,
+						}).
+						Warn("Client exceeded concurrent request limit")
+				}
+				writeError(w, r, http.StatusTooManyRequests, types.ErrorCodeRateLimited,
+					"Too many concurrent requests", "client has too many requests in flight")
+				return
+			}
+			defer l.Release(clientIP)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetRequestID retrieves request ID from context
 func GetRequestID(ctx context.Context) string {
 	if requestID, ok := ctx.Value(ContextKeyRequestID).(string); ok {
@@ -209,6 +498,31 @@ func GetStartTime(ctx context.Context) time.Time {
 	return time.Time{}
 }
 
+// GetTenant retrieves the resolved tenant from context
+func GetTenant(ctx context.Context) *tenant.Tenant {
+	if t, ok := ctx.Value(ContextKeyTenant).(*tenant.Tenant); ok {
+		return t
+	}
+	return nil
+}
+
+// GetIdentity retrieves the authenticated identity from context
+func GetIdentity(ctx context.Context) *auth.Identity {
+	if identity, ok := ctx.Value(ContextKeyIdentity).(*auth.Identity); ok {
+		return identity
+	}
+	return nil
+}
+
+// writeError writes a standardized JSON error response from a middleware
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message, details string) {
+	errorResp := types.NewErrorResponse(code, message, details).WithRequestID(GetRequestID(r.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResp)
+}
+
 // generateRequestID creates a simple request ID
 func generateRequestID() string {
 	return strconv.FormatInt(time.Now().UnixNano(), 36)
@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -12,7 +16,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/auth"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/limiter"
 	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/tenant"
 )
 
 func TestRequestLogging(t *testing.T) {
@@ -59,6 +67,58 @@ func TestRequestLogging(t *testing.T) {
 		assert.Equal(t, float64(13), responseLog["response_size_bytes"]) // "test response"
 	})
 
+	t.Run("adds_configured_header_fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{
+			Level:  "info",
+			Format: "json",
+			Output: &buf,
+		})
+
+		headerFields := map[string]string{"X-Feature-Flag": "feature_flag", "X-Team": "team"}
+		handler := RequestLoggingWithHeaders(logger, headerFields)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			GetLogger(r.Context()).Info("handler log")
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Feature-Flag", "new-parser")
+		req.Header.Set("X-Unmapped", "ignored")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		for _, line := range lines {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			assert.Equal(t, "new-parser", entry["feature_flag"])
+			assert.NotContains(t, entry, "team")
+			assert.NotContains(t, entry, "X-Unmapped")
+		}
+	})
+
+	t.Run("truncates_long_header_values", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{
+			Level:  "info",
+			Format: "json",
+			Output: &buf,
+		})
+
+		handler := RequestLoggingWithHeaders(logger, map[string]string{"X-Feature-Flag": "feature_flag"})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Feature-Flag", strings.Repeat("a", 1000))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(strings.Split(buf.String(), "\n")[0]), &entry))
+		assert.Len(t, entry["feature_flag"], maxLogHeaderValue)
+	})
+
 	t.Run("adds_request_id_to_context", func(t *testing.T) {
 		var buf bytes.Buffer
 		logger := logging.NewLogger(logging.LogConfig{
@@ -108,6 +168,114 @@ func TestRequestLogging(t *testing.T) {
 	})
 }
 
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"name": "John", "age": 25}`, 100)
+
+	serve := func(acceptEncoding string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{
+			Level:  "info",
+			Format: "json",
+			Output: &buf,
+		})
+
+		handler := RequestLogging(logger)(Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		})))
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		var responseLog map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &responseLog))
+		return rr, responseLog
+	}
+
+	t.Run("logs_compressed_size", func(t *testing.T) {
+		rr, responseLog := serve("gzip, deflate")
+
+		assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, float64(rr.Body.Len()), responseLog["response_size_bytes"])
+		assert.Equal(t, float64(len(body)), responseLog["uncompressed_size_bytes"])
+		assert.Less(t, rr.Body.Len(), len(body))
+
+		gz, err := gzip.NewReader(rr.Body)
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(decompressed))
+	})
+
+	t.Run("uncompressed_without_accept_encoding", func(t *testing.T) {
+		rr, responseLog := serve("")
+
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, body, rr.Body.String())
+		assert.Equal(t, float64(len(body)), responseLog["response_size_bytes"])
+		assert.NotContains(t, responseLog, "uncompressed_size_bytes")
+	})
+
+	t.Run("flush_passes_through_compression", func(t *testing.T) {
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: &bytes.Buffer{}})
+		handler := RequestLogging(logger)(Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+			w.(http.Flusher).Flush()
+		})))
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.True(t, rr.Flushed)
+		gz, err := gzip.NewReader(rr.Body)
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(decompressed))
+	})
+
+	t.Run("levels_trade_size_for_speed", func(t *testing.T) {
+		var text strings.Builder
+		for i := 0; i < 500; i++ {
+			fmt.Fprintf(&text, `{"id": %d, "name": "user-%d", "score": %d}`, i, i*7919%1000, i*31%97)
+		}
+		payload := text.String()
+
+		sizes := map[int]int{}
+		for _, level := range []int{gzip.NoCompression, gzip.BestSpeed, gzip.BestCompression} {
+			handler := CompressWithLevel(level)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(payload))
+			}))
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			sizes[level] = rr.Body.Len()
+			gz, err := gzip.NewReader(rr.Body)
+			require.NoError(t, err)
+			decompressed, err := io.ReadAll(gz)
+			require.NoError(t, err)
+			assert.Equal(t, payload, string(decompressed), "level %d", level)
+		}
+
+		assert.Greater(t, sizes[gzip.NoCompression], sizes[gzip.BestSpeed])
+		assert.Greater(t, sizes[gzip.BestSpeed], sizes[gzip.BestCompression])
+	})
+
+	t.Run("gzip_refused_with_zero_quality", func(t *testing.T) {
+		rr, _ := serve("gzip;q=0")
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	})
+}
+
 func TestRecovery(t *testing.T) {
 	t.Run("recovers_from_panic", func(t *testing.T) {
 		var buf bytes.Buffer
@@ -231,6 +399,38 @@ func TestRequestTimeout(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rr.Code)
 		assert.Equal(t, "success", rr.Body.String())
 	})
+
+	t.Run("routes_enforce_their_own_timeout", func(t *testing.T) {
+		handler := RequestTimeoutWithRoutes(time.Second, map[string]time.Duration{
+			"/v1/validate/batch": time.Minute,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, ok := r.Context().Deadline()
+			require.True(t, ok)
+			w.Write([]byte(time.Until(deadline).Round(time.Second).String()))
+		}))
+
+		for path, want := range map[string]string{
+			"/v1/validated-query": "1s",
+			"/v1/validate/batch":  "1m0s",
+			"/v1/validate":        "1s",
+		} {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("POST", path, nil))
+			assert.Equal(t, want, rr.Body.String(), path)
+		}
+	})
+
+	t.Run("route_timeout_cancels_request", func(t *testing.T) {
+		handler := RequestTimeoutWithRoutes(time.Minute, map[string]time.Duration{
+			"/slow": 50 * time.Millisecond,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+
+		start := time.Now()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+		assert.Less(t, time.Since(start), time.Second)
+	})
 }
 
 func TestContentType(t *testing.T) {
@@ -272,6 +472,34 @@ func TestContentType(t *testing.T) {
 		assert.Contains(t, rr.Body.String(), "Unsupported Media Type")
 	})
 
+	t.Run("ignores_parameters_and_case", func(t *testing.T) {
+		handler := ContentType("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		for _, contentType := range []string{"application/json; charset=utf-8", "application/JSON", "Application/Json ; charset=UTF-8"} {
+			req := httptest.NewRequest("POST", "/test", strings.NewReader("{}"))
+			req.Header.Set("Content-Type", contentType)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code, contentType)
+		}
+	})
+
+	t.Run("rejects_malformed_or_different_base_type", func(t *testing.T) {
+		handler := ContentType("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Should not call next handler")
+		}))
+
+		for _, contentType := range []string{"", "application/jsonx", "application/json; charset", "text/json; charset=utf-8"} {
+			req := httptest.NewRequest("POST", "/test", strings.NewReader("{}"))
+			req.Header.Set("Content-Type", contentType)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code, contentType)
+		}
+	})
+
 	t.Run("ignores_get_requests", func(t *testing.T) {
 		handler := ContentType("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -288,6 +516,34 @@ func TestContentType(t *testing.T) {
 		assert.Equal(t, "success", rr.Body.String())
 	})
 
+	t.Run("missing_content_type_by_policy", func(t *testing.T) {
+		var seen string
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = r.Header.Get("Content-Type")
+			w.WriteHeader(http.StatusOK)
+		})
+
+		// Rejected by default
+		req := httptest.NewRequest("POST", "/test", strings.NewReader("{}"))
+		rr := httptest.NewRecorder()
+		ContentType("application/json")(next).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+		// Assumed to be the default type when one is given
+		req = httptest.NewRequest("POST", "/test", strings.NewReader("{}"))
+		rr = httptest.NewRecorder()
+		ContentTypeWithDefault("application/json", "application/json")(next).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", seen)
+
+		// A wrong type is still rejected
+		req = httptest.NewRequest("POST", "/test", strings.NewReader("data"))
+		req.Header.Set("Content-Type", "text/plain")
+		rr = httptest.NewRecorder()
+		ContentTypeWithDefault("application/json", "application/json")(next).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
 	t.Run("accepts_multiple_content_types", func(t *testing.T) {
 		handler := ContentType("application/json", "application/xml")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -309,6 +565,234 @@ func TestContentType(t *testing.T) {
 	})
 }
 
+func TestTenantResolution(t *testing.T) {
+	registry := tenant.NewRegistry(config.TenantsConfig{
+		Header:   "X-Tenant-ID",
+		Required: true,
+		Tenants:  []config.TenantConfig{{ID: "acme"}},
+	})
+
+	var capturedTenant *tenant.Tenant
+	handler := TenantResolution(registry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedTenant = GetTenant(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("known_tenant_added_to_context", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Tenant-ID", "acme")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, capturedTenant)
+		assert.Equal(t, "acme", capturedTenant.ID)
+	})
+
+	t.Run("unknown_tenant_rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Tenant-ID", "globex")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Body.String(), "UNKNOWN_TENANT")
+	})
+
+	t.Run("missing_tenant_rejected_when_required", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("identity_tenant_used_without_header", func(t *testing.T) {
+		capturedTenant = nil
+		req := httptest.NewRequest("GET", "/test", nil)
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyIdentity, &auth.Identity{Subject: "app", TenantID: "acme"}))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, capturedTenant)
+		assert.Equal(t, "acme", capturedTenant.ID)
+	})
+
+	t.Run("header_conflicting_with_identity_rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Tenant-ID", "globex")
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyIdentity, &auth.Identity{Subject: "app", TenantID: "acme"}))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "does not match the authenticated identity")
+	})
+
+	t.Run("passthrough_when_no_tenants_configured", func(t *testing.T) {
+		capturedTenant = nil
+		handler := TenantResolution(tenant.NewRegistry(config.TenantsConfig{Header: "X-Tenant-ID"}))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				capturedTenant = GetTenant(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Tenant-ID", "acme")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Nil(t, capturedTenant)
+	})
+}
+
+// failingAuthenticator simulates an authentication backend outage
+type failingAuthenticator struct{}
+
+func (failingAuthenticator) Authenticate(r *http.Request) (*auth.Identity, error) {
+	return nil, errors.New("jwks unreachable")
+}
+
+func TestAuthentication(t *testing.T) {
+	authenticator := auth.NewStaticAuthenticator([]config.StaticToken{{Token: "s3cret", Subject: "ci"}})
+
+	var capturedIdentity *auth.Identity
+	handler := Authentication(authenticator, "/health")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedIdentity = GetIdentity(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("valid_credentials_add_identity", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/v1/validated-query", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, capturedIdentity)
+		assert.Equal(t, "ci", capturedIdentity.Subject)
+	})
+
+	t.Run("invalid_credentials_rejected", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/v1/validated-query", nil)
+		req.Header.Set("Authorization", "Bearer wrong")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, rr.Body.String(), "UNAUTHORIZED")
+	})
+
+	t.Run("missing_credentials_rejected", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/validated-query", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("exempt_path_skips_authentication", func(t *testing.T) {
+		capturedIdentity = nil
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Nil(t, capturedIdentity)
+	})
+
+	t.Run("backend_failure_is_unavailable", func(t *testing.T) {
+		handler := Authentication(failingAuthenticator{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		req := httptest.NewRequest("POST", "/v1/validated-query", nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	})
+
+	t.Run("nil_authenticator_admits_all", func(t *testing.T) {
+		handler := Authentication(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/validated-query", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestClientConcurrencyLimit(t *testing.T) {
+	perClient := limiter.NewKeyedLimiter(2)
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	handler := ClientIP(resolver)(ClientConcurrencyLimit(perClient, "/health")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	// Requests arrive through a trusted proxy, so the forwarded address is the client
+	request := func(path, client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		req.RemoteAddr = "10.0.0.1:4000"
+		req.Header.Set("X-Forwarded-For", client)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	results := make(chan int, 3)
+	for i := 0; i < 2; i++ {
+		go func() { results <- request("/v1/validated-query", "203.0.113.7").Code }()
+	}
+	require.Eventually(t, func() bool { return perClient.Active("203.0.113.7") == 2 }, time.Second, time.Millisecond)
+
+	t.Run("client_over_cap_rejected", func(t *testing.T) {
+		rr := request("/v1/validated-query", "203.0.113.7")
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Contains(t, rr.Body.String(), "RATE_LIMITED")
+	})
+
+	t.Run("exempt_path_not_counted", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("/health", "203.0.113.7").Code)
+	})
+
+	t.Run("other_clients_unaffected", func(t *testing.T) {
+		go func() { results <- request("/v1/validated-query", "198.51.100.2").Code }()
+		require.Eventually(t, func() bool { return perClient.Active("198.51.100.2") == 1 }, time.Second, time.Millisecond)
+	})
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, <-results)
+	}
+	assert.Equal(t, 0, perClient.Active("203.0.113.7"))
+
+	t.Run("slot_freed_after_completion", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("/v1/validated-query", "203.0.113.7").Code)
+	})
+}
+
 func TestContextHelpers(t *testing.T) {
 	t.Run("get_request_id", func(t *testing.T) {
 		ctx := context.WithValue(context.Background(), ContextKeyRequestID, "test-123")
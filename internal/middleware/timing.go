@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/logging"
+)
+
+// timedLayer is one named layer of a request's middleware chain and when
+// the request entered and left it
+type timedLayer struct {
+	name  string
+	enter time.Time
+	exit  time.Time
+}
+
+// chainTimings collects the timed layers a request passed through, outermost
+// first. Layers nest, so each one's own time is what remains after taking
+// out the layer inside it.
+type chainTimings struct {
+	mu     sync.Mutex
+	layers []timedLayer
+}
+
+func (c *chainTimings) enter(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.layers = append(c.layers, timedLayer{name: name, enter: time.Now()})
+	return len(c.layers) - 1
+}
+
+func (c *chainTimings) exit(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.layers[i].exit = time.Now()
+}
+
+// breakdown returns each layer's own time in microseconds, keyed by name
+func (c *chainTimings) breakdown() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	self := make(map[string]int64, len(c.layers))
+	for i, layer := range c.layers {
+		d := layer.exit.Sub(layer.enter)
+		if i+1 < len(c.layers) {
+			inner := c.layers[i+1]
+			d -= inner.exit.Sub(inner.enter)
+		}
+		self[layer.name] += d.Microseconds()
+	}
+	return self
+}
+
+// MiddlewareTimings records how long each layer wrapped with Timed or
+// TimedHandler inside it took, excluding the layers it wraps, and logs the
+// breakdown at debug level once the request completes. It belongs outermost
+// in the chain.
+func MiddlewareTimings(logger *logging.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timings := &chainTimings{}
+			start := time.Now()
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ContextKeyTimings, timings)))
+
+			// The request ID is assigned further in, so it is read back from
+			// the response
+			logger.
+				WithRequestID(w.Header().Get("X-Request-ID")).
+				WithComponent("http_server").
+				WithDuration(time.Since(start)).
+				WithFields(map[string]interface{}{
+					"method":        r.Method,
+					"path":          r.URL.Path,
+					"middleware_us": timings.breakdown(),
+				}).
+				Debug("Middleware timings")
+		})
+	}
+}
+
+// Timed records the time spent in mw under name for requests running inside
+// MiddlewareTimings; other requests pass through mw untouched
+func Timed(name string, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return TimedHandler(name, mw(next))
+	}
+}
+
+// TimedHandler is Timed for the handler at the end of the chain
+func TimedHandler(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timings, ok := r.Context().Value(ContextKeyTimings).(*chainTimings)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		i := timings.enter(name)
+		defer timings.exit(i)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+)
+
+func TestMiddlewareTimings(t *testing.T) {
+	newChain := func(logger *logging.Logger) http.Handler {
+		return Timed("recovery", Recovery(logger))(
+			Timed("cors", CORS())(
+				Timed("request_logging", RequestLogging(logger))(
+					TimedHandler("handler", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusNoContent)
+					})),
+				),
+			),
+		)
+	}
+
+	t.Run("logs_breakdown_for_every_layer", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &buf})
+		handler := MiddlewareTimings(logger)(newChain(logger))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		var entry map[string]interface{}
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var candidate map[string]interface{}
+			require.NoError(t, json.Unmarshal(line, &candidate))
+			if candidate["msg"] == "Middleware timings" {
+				entry = candidate
+			}
+		}
+		require.NotNil(t, entry, "no timing breakdown logged")
+		assert.Equal(t, rr.Header().Get("X-Request-ID"), entry["request_id"])
+		assert.Equal(t, "/test", entry["path"])
+
+		breakdown, ok := entry["middleware_us"].(map[string]interface{})
+		require.True(t, ok)
+		for _, name := range []string{"recovery", "cors", "request_logging", "handler"} {
+			assert.Contains(t, breakdown, name)
+			assert.GreaterOrEqual(t, breakdown[name].(float64), 0.0)
+		}
+	})
+
+	t.Run("short_circuit_records_only_layers_reached", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &buf})
+		handler := MiddlewareTimings(logger)(newChain(logger))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, "/test", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, buf.String(), `"cors"`)
+		assert.NotContains(t, buf.String(), `"handler"`)
+	})
+
+	t.Run("timed_layers_pass_through_without_recorder", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &buf})
+
+		rr := httptest.NewRecorder()
+		newChain(logger).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.NotContains(t, buf.String(), "Middleware timings")
+	})
+
+	t.Run("silent_above_debug", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "info", Format: "json", Output: &buf})
+		handler := MiddlewareTimings(logger)(newChain(logger))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.NotContains(t, buf.String(), "Middleware timings")
+	})
+}
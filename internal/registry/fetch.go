@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrHostNotAllowed is returned when a schema URL's host is not allowlisted
+	ErrHostNotAllowed = errors.New("schema URL host is not allowed")
+	// ErrSchemaTooLarge is returned when a fetched schema exceeds the size limit
+	ErrSchemaTooLarge = errors.New("fetched schema is too large")
+)
+
+// FetchConfig controls fetching schemas by URL. Only http and https URLs
+// whose host appears in AllowedHosts are fetched, since the gateway would
+// otherwise make requests to arbitrary addresses on a client's behalf.
+type FetchConfig struct {
+	AllowedHosts []string
+	Timeout      time.Duration
+	MaxBytes     int64
+}
+
+// Fetcher downloads schemas from allowlisted URLs
+type Fetcher struct {
+	allowed  map[string]bool
+	maxBytes int64
+	client   *http.Client
+}
+
+// NewFetcher creates a fetcher. Redirects are followed only to allowlisted hosts.
+func NewFetcher(cfg FetchConfig) *Fetcher {
+	f := &Fetcher{
+		allowed:  make(map[string]bool, len(cfg.AllowedHosts)),
+		maxBytes: cfg.MaxBytes,
+	}
+	for _, host := range cfg.AllowedHosts {
+		f.allowed[strings.ToLower(host)] = true
+	}
+	f.client = &http.Client{
+		Timeout: cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+// Enabled reports whether any host is allowlisted
+func (f *Fetcher) Enabled() bool {
+	return len(f.allowed) > 0
+}
+
+// checkURL rejects URLs that are not http(s) or whose host is not allowlisted.
+// Entries match either the bare hostname or host:port.
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrHostNotAllowed, u.Scheme)
+	}
+	if !f.allowed[strings.ToLower(u.Hostname())] && !f.allowed[strings.ToLower(u.Host)] {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, u.Host)
+	}
+	return nil
+}
+
+// Fetch downloads the schema at rawURL, checking it against the allowlist
+// and size limit. The result is guaranteed to be valid JSON.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (json.RawMessage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema URL: %w", err)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch schema: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch schema: server returned status %d", resp.StatusCode)
+	}
+
+	// Read one byte past the limit to detect oversized bodies
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read schema: %w", err)
+	}
+	if int64(len(body)) > f.maxBytes {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrSchemaTooLarge, f.maxBytes)
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("fetched schema is not valid JSON")
+	}
+	return json.RawMessage(body), nil
+}
@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetcher(t *testing.T) {
+	schemaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/person.json":
+			w.Write([]byte(`{"type": "object"}`))
+		case "/large.json":
+			w.Write([]byte(`{"description": "` + strings.Repeat("x", 100) + `"}`))
+		case "/invalid.json":
+			w.Write([]byte(`not json`))
+		case "/redirect.json":
+			http.Redirect(w, r, "http://schemas.invalid/person.json", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer schemaServer.Close()
+
+	serverURL, err := url.Parse(schemaServer.URL)
+	require.NoError(t, err)
+
+	newFetcher := func(hosts ...string) *Fetcher {
+		return NewFetcher(FetchConfig{AllowedHosts: hosts, Timeout: time.Second, MaxBytes: 64})
+	}
+
+	t.Run("allowlisted_host", func(t *testing.T) {
+		schemaBytes, err := newFetcher(serverURL.Hostname()).Fetch(context.Background(), schemaServer.URL+"/person.json")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type": "object"}`, string(schemaBytes))
+	})
+
+	t.Run("allowlisted_host_and_port", func(t *testing.T) {
+		_, err := newFetcher(serverURL.Host).Fetch(context.Background(), schemaServer.URL+"/person.json")
+		assert.NoError(t, err)
+	})
+
+	t.Run("host_not_allowlisted", func(t *testing.T) {
+		_, err := newFetcher("schemas.example.com").Fetch(context.Background(), schemaServer.URL+"/person.json")
+		assert.ErrorIs(t, err, ErrHostNotAllowed)
+	})
+
+	t.Run("unsupported_scheme", func(t *testing.T) {
+		_, err := newFetcher("localhost").Fetch(context.Background(), "file://localhost/etc/passwd")
+		assert.ErrorIs(t, err, ErrHostNotAllowed)
+	})
+
+	t.Run("redirect_to_other_host", func(t *testing.T) {
+		_, err := newFetcher(serverURL.Hostname()).Fetch(context.Background(), schemaServer.URL+"/redirect.json")
+		assert.ErrorIs(t, err, ErrHostNotAllowed)
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		_, err := newFetcher(serverURL.Hostname()).Fetch(context.Background(), schemaServer.URL+"/large.json")
+		assert.ErrorIs(t, err, ErrSchemaTooLarge)
+	})
+
+	t.Run("invalid_json", func(t *testing.T) {
+		_, err := newFetcher(serverURL.Hostname()).Fetch(context.Background(), schemaServer.URL+"/invalid.json")
+		assert.Error(t, err)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := newFetcher(serverURL.Hostname()).Fetch(context.Background(), schemaServer.URL+"/missing.json")
+		assert.Error(t, err)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		assert.False(t, newFetcher().Enabled())
+		assert.True(t, newFetcher("schemas.example.com").Enabled())
+	})
+}
@@ -0,0 +1,320 @@
+// Package registry stores schemas registered by clients so requests can refer
+// to them by ID instead of sending the full schema each time.
+package registry
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// Policies applied when registering a new schema into a full registry
+const (
+	PolicyReject = "reject"
+	PolicyLRU    = "lru"
+)
+
+// ErrFull is returned by Register when the registry is full under PolicyReject
+var ErrFull = errors.New("schema registry is full")
+
+// ErrTooLarge is returned by Register for a schema over the per-entry size cap
+var ErrTooLarge = errors.New("schema exceeds the registry's maximum entry size")
+
+// ErrStoreUnavailable is returned by Register when the shared store could not
+// record a new schema
+var ErrStoreUnavailable = errors.New("shared schema store unavailable")
+
+// ErrCachePolicyConflict is returned when a schema is registered again with a
+// cache policy other than the one it already has
+var ErrCachePolicyConflict = errors.New("schema is already registered with a different cache policy")
+
+// Store shares registered schemas between replicas. Each value holds the raw
+// schema and the cache policy it was registered with: callers register
+// schemas that already compiled, and each replica compiles its own copy on
+// use. Get reports an unknown ID with ok false.
+type Store interface {
+	Get(ctx context.Context, id string) (value json.RawMessage, ok bool, err error)
+	Set(ctx context.Context, id string, value json.RawMessage) error
+}
+
+// CachePolicy controls response caching for requests using one schema.
+// Schemas whose data is meant to vary, such as creative content, should not
+// be cached at all; deterministic ones may warrant a TTL of their own. A zero
+// TTL means the response cache's own.
+type CachePolicy struct {
+	Cacheable bool          `json:"cacheable"`
+	TTL       time.Duration `json:"ttl,omitempty"`
+}
+
+// storedSchema is the value a Store holds for each registered schema
+type storedSchema struct {
+	Schema      json.RawMessage `json:"schema"`
+	CachePolicy *CachePolicy    `json:"cache_policy,omitempty"`
+}
+
+type entry struct {
+	id     string
+	schema json.RawMessage
+	// cachePolicy overrides global response caching when set. It is shared
+	// through the store along with the schema.
+	cachePolicy *CachePolicy
+
+	// Validation counters; they live and die with the entry, so tracking
+	// is bounded by the registry size
+	valid         int64
+	invalid       int64
+	responseBytes int64
+}
+
+// Registry is a bounded, race-safe schema store keyed by schema.Hash
+type Registry struct {
+	mu         sync.Mutex
+	maxEntries int
+	policy     string
+	// maxSchemaBytes caps each registered schema; zero means no cap
+	maxSchemaBytes int64
+	entries        map[string]*list.Element
+	order          *list.List // front is most recently used
+	// store, when set, makes schemas registered on any replica visible here
+	store Store
+}
+
+// NewRegistry creates a registry holding at most maxEntries schemas
+func NewRegistry(maxEntries int, policy string) *Registry {
+	return &Registry{
+		maxEntries: maxEntries,
+		policy:     policy,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// SetStore shares the registry through store. Registrations are written
+// through to it, and IDs unknown locally are looked up in it.
+func (r *Registry) SetStore(store Store) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store = store
+}
+
+// SetMaxSchemaBytes caps the size of each schema registered from now on.
+// Zero removes the cap.
+func (r *Registry) SetMaxSchemaBytes(maxBytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxSchemaBytes = maxBytes
+}
+
+// Register stores a schema and returns its ID. Registering a schema that is
+// already present returns the existing ID and never fails for lack of space.
+// With a shared store, a new schema is written there before it is kept
+// locally, so an ID is only returned once every replica can resolve it.
+func (r *Registry) Register(schemaBytes json.RawMessage) (string, error) {
+	return r.RegisterWithCachePolicy(schemaBytes, nil)
+}
+
+// RegisterWithCachePolicy registers a schema like Register along with the
+// response caching policy for requests using it; a nil policy leaves them to
+// global caching. The policy is stored with the schema, so it outlives local
+// eviction and applies on every replica. A schema keeps the policy it was
+// first registered with: registering it again without one is allowed, but
+// asking for a different one fails with ErrCachePolicyConflict.
+func (r *Registry) RegisterWithCachePolicy(schemaBytes json.RawMessage, policy *CachePolicy) (string, error) {
+	id := schema.Hash(schemaBytes)
+
+	r.mu.Lock()
+	if r.maxSchemaBytes > 0 && int64(len(schemaBytes)) > r.maxSchemaBytes {
+		r.mu.Unlock()
+		return "", fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, len(schemaBytes), r.maxSchemaBytes)
+	}
+	if elem, ok := r.entries[id]; ok {
+		if policyConflicts(elem.Value.(*entry).cachePolicy, policy) {
+			r.mu.Unlock()
+			return "", ErrCachePolicyConflict
+		}
+		r.order.MoveToFront(elem)
+		r.mu.Unlock()
+		return id, nil
+	}
+	if len(r.entries) >= r.maxEntries && r.policy != PolicyLRU {
+		r.mu.Unlock()
+		return "", ErrFull
+	}
+	store := r.store
+	r.mu.Unlock()
+
+	// The store is used without holding the lock, which would otherwise
+	// stall every lookup behind a network round trip. Another replica may
+	// already have registered the schema, in which case its policy stands.
+	if store != nil {
+		stored, ok, err := loadStored(store, id)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+		}
+		if ok {
+			if policyConflicts(stored.CachePolicy, policy) {
+				return "", ErrCachePolicyConflict
+			}
+			policy = stored.CachePolicy
+		} else {
+			value, err := json.Marshal(storedSchema{Schema: schemaBytes, CachePolicy: policy})
+			if err != nil {
+				return "", fmt.Errorf("encode schema: %w", err)
+			}
+			if err := store.Set(context.Background(), id, value); err != nil {
+				return "", fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.add(id, schemaBytes, policy) {
+		return "", ErrFull
+	}
+	return id, nil
+}
+
+// policyConflicts reports whether registering with requested would change
+// the existing policy. Registering without a policy never does.
+func policyConflicts(existing, requested *CachePolicy) bool {
+	return requested != nil && (existing == nil || *existing != *requested)
+}
+
+// loadStored reads the schema and cache policy the store holds for id
+func loadStored(store Store, id string) (storedSchema, bool, error) {
+	value, ok, err := store.Get(context.Background(), id)
+	if err != nil || !ok {
+		return storedSchema{}, false, err
+	}
+	var stored storedSchema
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return storedSchema{}, false, fmt.Errorf("decode stored schema %s: %w", id, err)
+	}
+	return stored, true, nil
+}
+
+// add keeps a schema and its cache policy locally, evicting under PolicyLRU
+// when full. It reports false when the registry is full under PolicyReject.
+// The caller holds r.mu.
+func (r *Registry) add(id string, schemaBytes json.RawMessage, policy *CachePolicy) bool {
+	if elem, ok := r.entries[id]; ok {
+		r.order.MoveToFront(elem)
+		return true
+	}
+
+	if len(r.entries) >= r.maxEntries {
+		if r.policy != PolicyLRU {
+			return false
+		}
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*entry).id)
+	}
+
+	stored := append(json.RawMessage(nil), schemaBytes...)
+	r.entries[id] = r.order.PushFront(&entry{id: id, schema: stored, cachePolicy: policy})
+	return true
+}
+
+// Get returns the schema registered under id, falling back to the shared
+// store for schemas registered on another replica or evicted here, which
+// restores their cache policy too. A store that cannot be reached is treated
+// as not having the schema.
+func (r *Registry) Get(id string) (json.RawMessage, bool) {
+	r.mu.Lock()
+	if elem, ok := r.entries[id]; ok {
+		r.order.MoveToFront(elem)
+		schemaBytes := elem.Value.(*entry).schema
+		r.mu.Unlock()
+		return schemaBytes, true
+	}
+	store := r.store
+	r.mu.Unlock()
+
+	if store == nil {
+		return nil, false
+	}
+	stored, ok, err := loadStored(store, id)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	// A full registry under PolicyReject still serves the schema, it just
+	// does not keep a local copy
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.add(id, stored.Schema, stored.CachePolicy)
+	return stored.Schema, true
+}
+
+// CachePolicy returns the caching policy of the schema registered under id,
+// with ok false when the schema is not held locally or was registered
+// without one
+func (r *Registry) CachePolicy(id string) (CachePolicy, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[id]
+	if !ok || elem.Value.(*entry).cachePolicy == nil {
+		return CachePolicy{}, false
+	}
+	return *elem.Value.(*entry).cachePolicy, true
+}
+
+// RecordValidation counts one validation of data against the schema
+// registered under id. Validations against unregistered schemas are ignored.
+// Recording does not count as use for LRU eviction.
+func (r *Registry) RecordValidation(id string, valid bool, responseBytes int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[id]
+	if !ok {
+		return
+	}
+	e := elem.Value.(*entry)
+	if valid {
+		e.valid++
+	} else {
+		e.invalid++
+	}
+	e.responseBytes += int64(responseBytes)
+}
+
+// Stats returns the validation counters of the schema registered under id
+func (r *Registry) Stats(id string) (types.SchemaStats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[id]
+	if !ok {
+		return types.SchemaStats{}, false
+	}
+	e := elem.Value.(*entry)
+	stats := types.SchemaStats{
+		ID:          id,
+		Validations: e.valid + e.invalid,
+		Valid:       e.valid,
+		Invalid:     e.invalid,
+	}
+	if stats.Validations > 0 {
+		stats.PassRate = float64(e.valid) / float64(stats.Validations)
+		stats.AvgResponseBytes = float64(e.responseBytes) / float64(stats.Validations)
+	}
+	return stats, true
+}
+
+// Len returns the number of registered schemas
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
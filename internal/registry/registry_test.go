@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func testSchema(i int) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"type": "object", "title": "schema-%d"}`, i))
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("register_and_get", func(t *testing.T) {
+		r := NewRegistry(10, PolicyReject)
+		id, err := r.Register(testSchema(1))
+		require.NoError(t, err)
+
+		stored, ok := r.Get(id)
+		assert.True(t, ok)
+		assert.JSONEq(t, string(testSchema(1)), string(stored))
+
+		_, ok = r.Get("missing")
+		assert.False(t, ok)
+	})
+
+	t.Run("reregistering_is_idempotent", func(t *testing.T) {
+		r := NewRegistry(1, PolicyReject)
+		first, err := r.Register(testSchema(1))
+		require.NoError(t, err)
+		second, err := r.Register(testSchema(1))
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.Equal(t, 1, r.Len())
+	})
+
+	t.Run("reject_policy_refuses_when_full", func(t *testing.T) {
+		r := NewRegistry(3, PolicyReject)
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := r.Register(testSchema(i))
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+
+		_, err := r.Register(testSchema(3))
+		assert.ErrorIs(t, err, ErrFull)
+		assert.Equal(t, 3, r.Len())
+		for _, id := range ids {
+			_, ok := r.Get(id)
+			assert.True(t, ok)
+		}
+	})
+
+	t.Run("oversized_schema_rejected", func(t *testing.T) {
+		r := NewRegistry(10, PolicyReject)
+		r.SetMaxSchemaBytes(int64(len(testSchema(1))))
+
+		_, err := r.Register(testSchema(1))
+		require.NoError(t, err)
+
+		_, err = r.Register(json.RawMessage(`{"type": "object", "description": "far too long for the cap"}`))
+		assert.ErrorIs(t, err, ErrTooLarge)
+		assert.Equal(t, 1, r.Len())
+	})
+
+	t.Run("lru_policy_evicts_least_recently_used", func(t *testing.T) {
+		r := NewRegistry(3, PolicyLRU)
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := r.Register(testSchema(i))
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+		// Touch the oldest so the second schema becomes least recently used
+		r.Get(ids[0])
+
+		newID, err := r.Register(testSchema(3))
+		require.NoError(t, err)
+		assert.Equal(t, 3, r.Len())
+
+		_, ok := r.Get(ids[1])
+		assert.False(t, ok)
+		for _, id := range []string{ids[0], ids[2], newID} {
+			_, ok := r.Get(id)
+			assert.True(t, ok)
+		}
+	})
+
+	t.Run("validation_stats", func(t *testing.T) {
+		r := NewRegistry(1, PolicyLRU)
+		id, err := r.Register(testSchema(1))
+		require.NoError(t, err)
+
+		r.RecordValidation(id, true, 100)
+		r.RecordValidation(id, true, 50)
+		r.RecordValidation(id, false, 30)
+		r.RecordValidation("unregistered", true, 10)
+
+		stats, ok := r.Stats(id)
+		require.True(t, ok)
+		assert.Equal(t, types.SchemaStats{
+			ID:               id,
+			Validations:      3,
+			Valid:            2,
+			Invalid:          1,
+			PassRate:         2.0 / 3.0,
+			AvgResponseBytes: 60,
+		}, stats)
+
+		_, ok = r.Stats("unregistered")
+		assert.False(t, ok)
+
+		// Stats are dropped with the evicted schema
+		_, err = r.Register(testSchema(2))
+		require.NoError(t, err)
+		_, ok = r.Stats(id)
+		assert.False(t, ok)
+	})
+
+	t.Run("cache_policy", func(t *testing.T) {
+		r := NewRegistry(2, PolicyLRU)
+		id, err := r.Register(testSchema(1))
+		require.NoError(t, err)
+
+		_, ok := r.CachePolicy(id)
+		assert.False(t, ok, "no policy unless registered with one")
+
+		policy := CachePolicy{Cacheable: true, TTL: time.Minute}
+		id, err = r.RegisterWithCachePolicy(testSchema(2), &policy)
+		require.NoError(t, err)
+		got, ok := r.CachePolicy(id)
+		require.True(t, ok)
+		assert.Equal(t, policy, got)
+
+		_, ok = r.CachePolicy("unregistered")
+		assert.False(t, ok)
+	})
+
+	t.Run("reregistering_with_another_cache_policy_conflicts", func(t *testing.T) {
+		r := NewRegistry(2, PolicyLRU)
+		_, err := r.RegisterWithCachePolicy(testSchema(1), &CachePolicy{Cacheable: false})
+		require.NoError(t, err)
+
+		_, err = r.RegisterWithCachePolicy(testSchema(1), &CachePolicy{Cacheable: true})
+		assert.ErrorIs(t, err, ErrCachePolicyConflict)
+
+		// The same policy, or none, keeps the existing one
+		id, err := r.RegisterWithCachePolicy(testSchema(1), &CachePolicy{Cacheable: false})
+		require.NoError(t, err)
+		_, err = r.Register(testSchema(1))
+		require.NoError(t, err)
+		policy, ok := r.CachePolicy(id)
+		require.True(t, ok)
+		assert.False(t, policy.Cacheable)
+	})
+}
@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// mapStore is an in-process Store standing in for a shared backend
+type mapStore struct {
+	mu      sync.Mutex
+	schemas map[string]json.RawMessage
+	err     error
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{schemas: map[string]json.RawMessage{}}
+}
+
+func (m *mapStore) Get(_ context.Context, id string) (json.RawMessage, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, false, m.err
+	}
+	s, ok := m.schemas[id]
+	return s, ok, nil
+}
+
+func (m *mapStore) Set(_ context.Context, id string, s json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.schemas[id] = s
+	return nil
+}
+
+func TestSharedStore(t *testing.T) {
+	t.Run("schema_registered_on_one_replica_validates_on_another", func(t *testing.T) {
+		store := newMapStore()
+		first := NewRegistry(10, PolicyReject)
+		first.SetStore(store)
+		second := NewRegistry(10, PolicyReject)
+		second.SetStore(store)
+
+		personSchema := json.RawMessage(`{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`)
+		id, err := first.Register(personSchema)
+		require.NoError(t, err)
+
+		shared, ok := second.Get(id)
+		require.True(t, ok)
+		assert.Equal(t, 1, second.Len())
+
+		// The second replica compiles its own copy
+		validator := schema.NewValidator()
+		require.NoError(t, validator.ValidateSchema(shared))
+		assert.NoError(t, validator.ValidateResponse(shared, &types.ValidatedResponse{Data: json.RawMessage(`{"name": "Ada"}`)}))
+		assert.Error(t, validator.ValidateResponse(shared, &types.ValidatedResponse{Data: json.RawMessage(`{}`)}))
+	})
+
+	t.Run("full_reject_registry_still_serves_shared_schema", func(t *testing.T) {
+		store := newMapStore()
+		writer := NewRegistry(10, PolicyReject)
+		writer.SetStore(store)
+		id, err := writer.Register(testSchema(1))
+		require.NoError(t, err)
+
+		reader := NewRegistry(1, PolicyReject)
+		reader.SetStore(store)
+		_, err = reader.Register(testSchema(2))
+		require.NoError(t, err)
+
+		_, ok := reader.Get(id)
+		assert.True(t, ok)
+		assert.Equal(t, 1, reader.Len())
+	})
+
+	t.Run("cache_policy_survives_eviction", func(t *testing.T) {
+		store := newMapStore()
+		r := NewRegistry(1, PolicyLRU)
+		r.SetStore(store)
+
+		policy := CachePolicy{Cacheable: true, TTL: time.Minute}
+		id, err := r.RegisterWithCachePolicy(testSchema(1), &policy)
+		require.NoError(t, err)
+		_, err = r.Register(testSchema(2))
+		require.NoError(t, err)
+		_, ok := r.CachePolicy(id)
+		require.False(t, ok, "evicted locally")
+
+		_, ok = r.Get(id)
+		require.True(t, ok)
+		got, ok := r.CachePolicy(id)
+		require.True(t, ok)
+		assert.Equal(t, policy, got)
+	})
+
+	t.Run("cache_policy_is_shared_between_replicas", func(t *testing.T) {
+		store := newMapStore()
+		first := NewRegistry(10, PolicyReject)
+		first.SetStore(store)
+		second := NewRegistry(10, PolicyReject)
+		second.SetStore(store)
+
+		id, err := first.RegisterWithCachePolicy(testSchema(1), &CachePolicy{Cacheable: false})
+		require.NoError(t, err)
+
+		_, ok := second.Get(id)
+		require.True(t, ok)
+		policy, ok := second.CachePolicy(id)
+		require.True(t, ok)
+		assert.False(t, policy.Cacheable)
+
+		// A replica that never saw the schema still sees its policy
+		_, err = second.RegisterWithCachePolicy(testSchema(1), &CachePolicy{Cacheable: true})
+		assert.ErrorIs(t, err, ErrCachePolicyConflict)
+		third := NewRegistry(10, PolicyReject)
+		third.SetStore(store)
+		_, err = third.RegisterWithCachePolicy(testSchema(1), &CachePolicy{Cacheable: true})
+		assert.ErrorIs(t, err, ErrCachePolicyConflict)
+		_, err = third.Register(testSchema(1))
+		require.NoError(t, err)
+		policy, ok = third.CachePolicy(id)
+		require.True(t, ok)
+		assert.False(t, policy.Cacheable)
+	})
+
+	t.Run("store_failure_fails_registration", func(t *testing.T) {
+		store := newMapStore()
+		store.err = errors.New("connection refused")
+		r := NewRegistry(10, PolicyReject)
+		r.SetStore(store)
+
+		_, err := r.Register(testSchema(1))
+		assert.ErrorIs(t, err, ErrStoreUnavailable)
+		assert.Equal(t, 0, r.Len())
+	})
+
+	t.Run("store_failure_is_a_miss", func(t *testing.T) {
+		store := newMapStore()
+		store.err = errors.New("connection refused")
+		r := NewRegistry(10, PolicyReject)
+		r.SetStore(store)
+
+		_, ok := r.Get(schema.Hash(testSchema(1)))
+		assert.False(t, ok)
+	})
+}
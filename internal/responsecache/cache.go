@@ -0,0 +1,25 @@
+package responsecache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Backends a response cache can be stored in
+const (
+	BackendNone   = "none"
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// ResponseCache stores validated response data by request key. Entries
+// expire after the TTL the cache was created with, or the one passed to
+// SetWithTTL. Get reports a miss with
+// ok false; errors mean the backend could not be reached, and callers should
+// treat them as a miss rather than fail the request.
+type ResponseCache interface {
+	Get(ctx context.Context, key string) (data json.RawMessage, ok bool, err error)
+	Set(ctx context.Context, key string, data json.RawMessage) error
+	SetWithTTL(ctx context.Context, key string, data json.RawMessage, ttl time.Duration) error
+}
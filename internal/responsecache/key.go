@@ -0,0 +1,65 @@
+// Package responsecache identifies and stores validated responses so that
+// identical requests can be answered without another LLM call.
+package responsecache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// keyMaterial is the canonical form of a request that is hashed into a key
+type keyMaterial struct {
+	Schema   json.RawMessage `json:"schema"`
+	Messages []types.Message `json:"messages"`
+	KeyCase  string          `json:"key_case,omitempty"`
+}
+
+// Key returns a deterministic identifier for a request. Insignificant
+// whitespace in the schema never affects the key.
+//
+// With orderSensitive set, messages must appear in the same order for two
+// requests to share a key. This is almost always what you want: a prompt's
+// meaning depends on message order, so the same messages reordered can yield
+// a different answer. Without it, messages are sorted by role and content
+// before hashing, so any permutation of the same messages shares a key; only
+// use this when messages are independent facts rather than a conversation.
+func Key(req types.ValidatedQueryRequest, orderSensitive bool) string {
+	material := keyMaterial{
+		Schema:   compactJSON(req.Schema),
+		Messages: req.Messages,
+		KeyCase:  req.KeyCase,
+	}
+
+	if !orderSensitive {
+		material.Messages = append([]types.Message(nil), req.Messages...)
+		sort.SliceStable(material.Messages, func(i, j int) bool {
+			a, b := material.Messages[i], material.Messages[j]
+			if a.Role != b.Role {
+				return a.Role < b.Role
+			}
+			return a.Content < b.Content
+		})
+	}
+
+	// Marshalling a struct of strings and compacted JSON cannot fail
+	encoded, _ := json.Marshal(material)
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
+}
+
+// compactJSON strips insignificant whitespace, leaving invalid JSON unchanged
+func compactJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
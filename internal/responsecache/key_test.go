@@ -0,0 +1,66 @@
+package responsecache
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestKey(t *testing.T) {
+	schema := json.RawMessage(`{"type": "object"}`)
+	ordered := types.ValidatedQueryRequest{
+		Schema: schema,
+		Messages: []types.Message{
+			{Role: "system", Content: "Extract a person"},
+			{Role: "user", Content: "Jane is 30"},
+			{Role: "user", Content: "She lives in Paris"},
+		},
+	}
+	reordered := types.ValidatedQueryRequest{
+		Schema: schema,
+		Messages: []types.Message{
+			{Role: "user", Content: "She lives in Paris"},
+			{Role: "system", Content: "Extract a person"},
+			{Role: "user", Content: "Jane is 30"},
+		},
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		assert.Equal(t, Key(ordered, true), Key(ordered, true))
+		assert.Len(t, Key(ordered, true), 64)
+	})
+
+	t.Run("order_sensitive_distinguishes_permutations", func(t *testing.T) {
+		assert.NotEqual(t, Key(ordered, true), Key(reordered, true))
+	})
+
+	t.Run("order_insensitive_matches_permutations", func(t *testing.T) {
+		assert.Equal(t, Key(ordered, false), Key(reordered, false))
+	})
+
+	t.Run("order_insensitive_leaves_request_untouched", func(t *testing.T) {
+		Key(reordered, false)
+		assert.Equal(t, "She lives in Paris", reordered.Messages[0].Content)
+	})
+
+	t.Run("different_content_differs_in_both_modes", func(t *testing.T) {
+		other := ordered
+		other.Messages = []types.Message{{Role: "user", Content: "Bob is 40"}}
+		assert.NotEqual(t, Key(ordered, true), Key(other, true))
+		assert.NotEqual(t, Key(ordered, false), Key(other, false))
+	})
+
+	t.Run("schema_whitespace_ignored", func(t *testing.T) {
+		spaced := ordered
+		spaced.Schema = json.RawMessage("{\n  \"type\":   \"object\"\n}")
+		assert.Equal(t, Key(ordered, true), Key(spaced, true))
+	})
+
+	t.Run("key_case_affects_key", func(t *testing.T) {
+		camel := ordered
+		camel.KeyCase = "camel"
+		assert.NotEqual(t, Key(ordered, true), Key(camel, true))
+	})
+}
@@ -0,0 +1,93 @@
+package responsecache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key       string
+	data      json.RawMessage
+	expiresAt time.Time
+}
+
+// MemoryCache is a bounded in-process ResponseCache that evicts the least
+// recently used entry when full. Hits are not shared between replicas.
+type MemoryCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // front is most recently used
+	now        func() time.Time
+}
+
+// NewMemoryCache creates an in-memory cache holding at most maxEntries
+// responses for ttl each
+func NewMemoryCache(maxEntries int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Get returns the unexpired data stored under key
+func (c *MemoryCache) Get(_ context.Context, key string) (json.RawMessage, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := elem.Value.(*memoryEntry)
+	if !c.now().Before(e.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return e.data, true, nil
+}
+
+// Set stores data under key, evicting the least recently used entry if full
+func (c *MemoryCache) Set(ctx context.Context, key string, data json.RawMessage) error {
+	return c.SetWithTTL(ctx, key, data, c.ttl)
+}
+
+// SetWithTTL is Set with an entry lifetime other than the cache's
+func (c *MemoryCache) SetWithTTL(_ context.Context, key string, data json.RawMessage, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := append(json.RawMessage(nil), data...)
+	expiresAt := c.now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*memoryEntry)
+		e.data, e.expiresAt = stored, expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, data: stored, expiresAt: expiresAt})
+	return nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet
+// evicted
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
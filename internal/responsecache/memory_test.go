@@ -0,0 +1,69 @@
+package responsecache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores_and_returns_data", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+		require.NoError(t, c.Set(ctx, "k", json.RawMessage(`{"name": "Jane"}`)))
+
+		data, ok, err := c.Get(ctx, "k")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.JSONEq(t, `{"name": "Jane"}`, string(data))
+
+		_, ok, err = c.Get(ctx, "other")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("entries_expire", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		require.NoError(t, c.Set(ctx, "k", json.RawMessage(`{}`)))
+
+		now = now.Add(time.Minute)
+		_, ok, _ := c.Get(ctx, "k")
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("entry_ttl_overrides_cache_ttl", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		require.NoError(t, c.SetWithTTL(ctx, "short", json.RawMessage(`{}`), time.Second))
+		require.NoError(t, c.SetWithTTL(ctx, "long", json.RawMessage(`{}`), time.Hour))
+
+		now = now.Add(2 * time.Minute)
+		_, ok, _ := c.Get(ctx, "short")
+		assert.False(t, ok)
+		_, ok, _ = c.Get(ctx, "long")
+		assert.True(t, ok)
+	})
+
+	t.Run("evicts_least_recently_used", func(t *testing.T) {
+		c := NewMemoryCache(2, time.Minute)
+		c.Set(ctx, "a", json.RawMessage(`1`))
+		c.Set(ctx, "b", json.RawMessage(`2`))
+		c.Get(ctx, "a")
+		c.Set(ctx, "c", json.RawMessage(`3`))
+
+		_, okA, _ := c.Get(ctx, "a")
+		_, okB, _ := c.Get(ctx, "b")
+		assert.True(t, okA)
+		assert.False(t, okB)
+		assert.Equal(t, 2, c.Len())
+	})
+}
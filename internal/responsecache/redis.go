@@ -0,0 +1,190 @@
+package responsecache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisKeyPrefix namespaces response cache keys in a shared Redis
+// unless RedisConfig.Prefix overrides it
+const redisKeyPrefix = "llm-json-parse:response:"
+
+// redisMaxIdle bounds the connections kept open between commands
+const redisMaxIdle = 8
+
+// RedisConfig locates the Redis server backing a RedisCache
+type RedisConfig struct {
+	Addr     string
+	Password string
+	// Timeout bounds dialing and each command; zero means no limit
+	Timeout time.Duration
+	// Prefix namespaces keys, so other stores can share the same Redis
+	Prefix string
+}
+
+// RedisCache is a ResponseCache stored in Redis, so every replica of the
+// gateway shares hits. Entries expire through Redis key TTLs. It speaks the
+// few RESP commands it needs directly rather than pulling in a client library.
+type RedisCache struct {
+	cfg  RedisConfig
+	ttl  time.Duration
+	idle chan *redisConn
+}
+
+// NewRedisCache creates a Redis-backed cache whose entries live for ttl, or
+// never expire when ttl is zero. Connections are opened on first use.
+func NewRedisCache(cfg RedisConfig, ttl time.Duration) *RedisCache {
+	if cfg.Prefix == "" {
+		cfg.Prefix = redisKeyPrefix
+	}
+	return &RedisCache{cfg: cfg, ttl: ttl, idle: make(chan *redisConn, redisMaxIdle)}
+}
+
+// Get returns the data stored under key
+func (c *RedisCache) Get(ctx context.Context, key string) (json.RawMessage, bool, error) {
+	reply, err := c.do(ctx, "GET", c.cfg.Prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	return json.RawMessage(reply), true, nil
+}
+
+// Set stores data under key with the cache's TTL
+func (c *RedisCache) Set(ctx context.Context, key string, data json.RawMessage) error {
+	return c.SetWithTTL(ctx, key, data, c.ttl)
+}
+
+// SetWithTTL stores data under key for ttl, or without expiry when ttl is zero
+func (c *RedisCache) SetWithTTL(ctx context.Context, key string, data json.RawMessage, ttl time.Duration) error {
+	args := []string{"SET", c.cfg.Prefix + key, string(data)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+// do runs one command on a pooled connection. A connection that failed is
+// closed rather than returned to the pool.
+func (c *RedisCache) do(ctx context.Context, args ...string) ([]byte, error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(ctx, c.cfg.Timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+// conn returns an idle connection or dials and authenticates a new one
+func (c *RedisCache) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.cfg.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis dial: %w", err)
+	}
+	conn := &redisConn{Conn: netConn, r: bufio.NewReader(netConn)}
+	if c.cfg.Password != "" {
+		if _, err := conn.do(ctx, c.cfg.Timeout, "AUTH", c.cfg.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply from Redis; the connection remains usable
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do sends a command as a RESP array of bulk strings and reads its reply.
+// Nil replies are returned as nil bytes.
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, args ...string) ([]byte, error) {
+	deadline, ok := ctx.Deadline()
+	if timeout > 0 && (!ok || time.Until(deadline) > timeout) {
+		deadline, ok = time.Now().Add(timeout), true
+	}
+	if ok {
+		c.SetDeadline(deadline)
+	} else {
+		c.SetDeadline(time.Time{})
+	}
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, cmd.String()); err != nil {
+		return nil, fmt.Errorf("redis write: %w", err)
+	}
+	return c.readReply()
+}
+
+// readReply reads one RESP reply
+func (c *redisConn) readReply() ([]byte, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis read: empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis read: bad bulk length %q", line[1:])
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, fmt.Errorf("redis read: %w", err)
+		}
+		return buf[:size], nil
+	default:
+		return nil, fmt.Errorf("redis read: unexpected reply %q", line)
+	}
+}
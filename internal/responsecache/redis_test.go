@@ -0,0 +1,187 @@
+package responsecache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is a minimal in-process Redis speaking the commands RedisCache
+// uses: AUTH, GET and SET with PX
+type fakeRedis struct {
+	password string
+
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]time.Duration
+	dials  int
+}
+
+func newFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	f := &fakeRedis{password: password, values: map[string]string{}, ttls: map[string]time.Duration{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.dials++
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f, listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] == f.password {
+				authed, reply = true, "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case "GET":
+			value, ok := f.values[args[1]]
+			switch {
+			case !authed:
+				reply = "-NOAUTH Authentication required.\r\n"
+			case ok:
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			default:
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			if !authed {
+				reply = "-NOAUTH Authentication required.\r\n"
+				break
+			}
+			f.values[args[1]] = args[2]
+			if len(args) == 5 && strings.EqualFold(args[3], "PX") {
+				ms, _ := strconv.Atoi(args[4])
+				f.ttls[args[1]] = time.Duration(ms) * time.Millisecond
+			}
+			reply = "+OK\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+		io.WriteString(conn, reply)
+	}
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header)[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores_with_ttl_and_reuses_connections", func(t *testing.T) {
+		fake, addr := newFakeRedis(t, "")
+		c := NewRedisCache(RedisConfig{Addr: addr, Timeout: time.Second}, 5*time.Minute)
+
+		data := json.RawMessage("{\"note\": \"line one\\r\\nline two\"}")
+		require.NoError(t, c.Set(ctx, "k", data))
+
+		got, ok, err := c.Get(ctx, "k")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, string(data), string(got))
+
+		_, ok, err = c.Get(ctx, "missing")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		assert.Equal(t, 5*time.Minute, fake.ttls[redisKeyPrefix+"k"])
+		assert.Equal(t, 1, fake.dials)
+	})
+
+	t.Run("custom_prefix_without_expiry", func(t *testing.T) {
+		fake, addr := newFakeRedis(t, "")
+		c := NewRedisCache(RedisConfig{Addr: addr, Timeout: time.Second, Prefix: "schemas:"}, 0)
+		require.NoError(t, c.Set(ctx, "k", json.RawMessage(`{}`)))
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		assert.Equal(t, "{}", fake.values["schemas:k"])
+		_, expires := fake.ttls["schemas:k"]
+		assert.False(t, expires)
+	})
+
+	t.Run("authenticates", func(t *testing.T) {
+		_, addr := newFakeRedis(t, "s3cret")
+
+		c := NewRedisCache(RedisConfig{Addr: addr, Password: "s3cret", Timeout: time.Second}, time.Minute)
+		require.NoError(t, c.Set(ctx, "k", json.RawMessage(`{}`)))
+
+		wrong := NewRedisCache(RedisConfig{Addr: addr, Password: "nope", Timeout: time.Second}, time.Minute)
+		_, _, err := wrong.Get(ctx, "k")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "WRONGPASS")
+	})
+
+	t.Run("unreachable_server_errors", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+
+		c := NewRedisCache(RedisConfig{Addr: addr, Timeout: time.Second}, time.Minute)
+		_, _, err = c.Get(ctx, "k")
+		assert.Error(t, err)
+	})
+}
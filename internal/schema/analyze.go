@@ -0,0 +1,241 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// maxEnumSuggestion is the most distinct string values suggested as an enum
+const maxEnumSuggestion = 5
+
+// shapeKeywords constrain a value's type on their own, so a node using one
+// is never told to add "type"
+var shapeKeywords = []string{"type", "enum", "const", "$ref", "allOf", "anyOf", "oneOf", "not", "if"}
+
+// Analyze suggests ways to tighten a schema that the samples, data it is
+// expected to accept, show to be safe. Suggestions are keyed by the JSON
+// pointer of the data they concern, with "*" standing for every array item,
+// and cover undeclared or closable properties, properties present in every
+// sample, missing types and strings drawn from a small set of values.
+func Analyze(schemaBytes json.RawMessage, samples []json.RawMessage) ([]types.SchemaSuggestion, error) {
+	var schemaObj interface{}
+	if err := json.Unmarshal(schemaBytes, &schemaObj); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	values := make([]interface{}, 0, len(samples))
+	for i, sample := range samples {
+		var value interface{}
+		if err := json.Unmarshal(sample, &value); err != nil {
+			return nil, fmt.Errorf("sample %d: invalid JSON: %w", i, err)
+		}
+		values = append(values, value)
+	}
+
+	suggestions := []types.SchemaSuggestion{}
+	analyzeNode(schemaObj, values, "", &suggestions)
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Path != suggestions[j].Path {
+			return suggestions[i].Path < suggestions[j].Path
+		}
+		return suggestions[i].Keyword < suggestions[j].Keyword
+	})
+	return suggestions, nil
+}
+
+// analyzeNode inspects one schema node against every value seen at its path
+func analyzeNode(schemaNode interface{}, values []interface{}, path string, suggestions *[]types.SchemaSuggestion) {
+	schemaMap, ok := schemaNode.(map[string]interface{})
+	if !ok || len(values) == 0 {
+		return
+	}
+
+	if !hasAnyKeyword(schemaMap, shapeKeywords) {
+		if typeName := commonType(values); typeName != "" {
+			*suggestions = append(*suggestions, types.SchemaSuggestion{
+				Path:      pathOrRoot(path),
+				Keyword:   "type",
+				Message:   fmt.Sprintf("every sample has type %s; consider declaring it", typeName),
+				Suggested: typeName,
+			})
+		}
+	}
+	if schemaMap["type"] == "string" && !hasAnyKeyword(schemaMap, []string{"enum", "const", "format", "pattern"}) {
+		if enum := enumCandidate(values); enum != nil {
+			*suggestions = append(*suggestions, types.SchemaSuggestion{
+				Path:      pathOrRoot(path),
+				Keyword:   "enum",
+				Message:   fmt.Sprintf("samples only use %d distinct values; consider an enum", len(enum)),
+				Suggested: enum,
+			})
+		}
+	}
+
+	var objects []map[string]interface{}
+	var items []interface{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			objects = append(objects, v)
+		case []interface{}:
+			items = append(items, v...)
+		}
+	}
+	if len(objects) > 0 {
+		analyzeObject(schemaMap, objects, path, suggestions)
+	}
+	if len(items) > 0 {
+		analyzeNode(schemaMap["items"], items, path+"/*", suggestions)
+	}
+}
+
+// analyzeObject looks for properties that could be declared, closed off or
+// required
+func analyzeObject(schemaMap map[string]interface{}, objects []map[string]interface{}, path string, suggestions *[]types.SchemaSuggestion) {
+	properties, _ := schemaMap["properties"].(map[string]interface{})
+	if properties == nil {
+		return
+	}
+
+	required := map[string]bool{}
+	if list, ok := schemaMap["required"].([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	seen := map[string]int{}
+	undeclared := map[string]bool{}
+	for _, obj := range objects {
+		for key := range obj {
+			seen[key]++
+			if _, declared := properties[key]; !declared {
+				undeclared[key] = true
+			}
+		}
+	}
+
+	if _, set := schemaMap["additionalProperties"]; !set {
+		if len(undeclared) == 0 {
+			*suggestions = append(*suggestions, types.SchemaSuggestion{
+				Path:      pathOrRoot(path),
+				Keyword:   "additionalProperties",
+				Message:   "samples only use declared properties; consider setting additionalProperties to false",
+				Suggested: false,
+			})
+		} else {
+			for key := range undeclared {
+				*suggestions = append(*suggestions, types.SchemaSuggestion{
+					Path:    path + "/" + escapePointer(key),
+					Keyword: "properties",
+					Message: "property appears in samples but is not declared",
+				})
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := path + "/" + escapePointer(name)
+		if !required[name] && seen[name] == len(objects) {
+			*suggestions = append(*suggestions, types.SchemaSuggestion{
+				Path:      childPath,
+				Keyword:   "required",
+				Message:   "property is present in every sample; consider marking it required",
+				Suggested: name,
+			})
+		}
+
+		var values []interface{}
+		for _, obj := range objects {
+			if value, ok := obj[name]; ok {
+				values = append(values, value)
+			}
+		}
+		analyzeNode(properties[name], values, childPath, suggestions)
+	}
+}
+
+// hasAnyKeyword reports whether the schema uses any of keywords
+func hasAnyKeyword(schemaMap map[string]interface{}, keywords []string) bool {
+	for _, keyword := range keywords {
+		if _, ok := schemaMap[keyword]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// commonType returns the JSON Schema type shared by every value, or "" when
+// they differ. Whole numbers are reported as "integer" unless a fraction
+// appears among them.
+func commonType(values []interface{}) string {
+	common := ""
+	for _, value := range values {
+		typeName := jsonTypeName(value)
+		switch {
+		case common == "" || common == typeName:
+			common = typeName
+		case common == "integer" && typeName == "number", common == "number" && typeName == "integer":
+			common = "number"
+		default:
+			return ""
+		}
+	}
+	return common
+}
+
+// jsonTypeName returns the JSON Schema type of a decoded JSON value
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// enumCandidate returns the sorted distinct strings among values when there
+// are few enough of them, each seen at least twice on average, to suggest an
+// enum; otherwise nil
+func enumCandidate(values []interface{}) []string {
+	distinct := map[string]bool{}
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		distinct[s] = true
+	}
+	if len(distinct) > maxEnumSuggestion || len(values) < 2*len(distinct) {
+		return nil
+	}
+
+	enum := make([]string, 0, len(distinct))
+	for s := range distinct {
+		enum = append(enum, s)
+	}
+	sort.Strings(enum)
+	return enum
+}
@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestAnalyze(t *testing.T) {
+	samples := func(raw ...string) []json.RawMessage {
+		out := make([]json.RawMessage, len(raw))
+		for i, r := range raw {
+			out[i] = json.RawMessage(r)
+		}
+		return out
+	}
+	find := func(suggestions []types.SchemaSuggestion, path, keyword string) (types.SchemaSuggestion, bool) {
+		for _, s := range suggestions {
+			if s.Path == path && s.Keyword == keyword {
+				return s, true
+			}
+		}
+		return types.SchemaSuggestion{}, false
+	}
+
+	t.Run("permissive_schema_with_consistent_samples", func(t *testing.T) {
+		schemaJSON := json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"age": {},
+				"status": {"type": "string"},
+				"nickname": {"type": "string"}
+			}
+		}`)
+		suggestions, err := Analyze(schemaJSON, samples(
+			`{"name": "Ada", "age": 36, "status": "active"}`,
+			`{"name": "Alan", "age": 41, "status": "active", "nickname": "Al"}`,
+			`{"name": "Grace", "age": 85, "status": "retired"}`,
+			`{"name": "Edsger", "age": 72, "status": "active"}`,
+		))
+		require.NoError(t, err)
+
+		closed, ok := find(suggestions, "/", "additionalProperties")
+		require.True(t, ok)
+		assert.Equal(t, false, closed.Suggested)
+
+		for _, name := range []string{"name", "age", "status"} {
+			_, ok := find(suggestions, "/"+name, "required")
+			assert.True(t, ok, name)
+		}
+		_, ok = find(suggestions, "/nickname", "required")
+		assert.False(t, ok, "nickname is missing from some samples")
+
+		ageType, ok := find(suggestions, "/age", "type")
+		require.True(t, ok)
+		assert.Equal(t, "integer", ageType.Suggested)
+
+		statusEnum, ok := find(suggestions, "/status", "enum")
+		require.True(t, ok)
+		assert.Equal(t, []string{"active", "retired"}, statusEnum.Suggested)
+		_, ok = find(suggestions, "/name", "enum")
+		assert.False(t, ok, "every name is distinct")
+	})
+
+	t.Run("undeclared_properties_and_array_items", func(t *testing.T) {
+		schemaJSON := json.RawMessage(`{
+			"type": "object",
+			"properties": {"tags": {"type": "array", "items": {"type": "object", "properties": {"id": {"type": "integer"}}}}},
+			"required": ["tags"]
+		}`)
+		suggestions, err := Analyze(schemaJSON, samples(
+			`{"tags": [{"id": 1}, {"id": 2}], "extra": true}`,
+		))
+		require.NoError(t, err)
+
+		_, ok := find(suggestions, "/extra", "properties")
+		assert.True(t, ok)
+		_, ok = find(suggestions, "/", "additionalProperties")
+		assert.False(t, ok, "samples use an undeclared property")
+		_, ok = find(suggestions, "/tags", "required")
+		assert.False(t, ok, "already required")
+		_, ok = find(suggestions, "/tags/*/id", "required")
+		assert.True(t, ok)
+		_, ok = find(suggestions, "/tags/*", "additionalProperties")
+		assert.True(t, ok)
+	})
+
+	t.Run("strict_schema_has_no_suggestions", func(t *testing.T) {
+		schemaJSON := json.RawMessage(`{
+			"type": "object",
+			"properties": {"name": {"type": "string"}},
+			"required": ["name"],
+			"additionalProperties": false
+		}`)
+		suggestions, err := Analyze(schemaJSON, samples(`{"name": "Ada"}`, `{"name": "Alan"}`))
+		require.NoError(t, err)
+		assert.Empty(t, suggestions)
+	})
+
+	t.Run("invalid_sample", func(t *testing.T) {
+		_, err := Analyze(json.RawMessage(`{"type": "object"}`), samples(`{`))
+		assert.ErrorContains(t, err, "sample 0")
+	})
+}
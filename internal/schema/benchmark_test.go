@@ -2,8 +2,12 @@ package schema
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"testing"
+	"time"
 
+	"github.com/wcygan/llm-json-parse/internal/logging"
 	"github.com/wcygan/llm-json-parse/pkg/types"
 )
 
@@ -39,6 +43,41 @@ func BenchmarkValidatorWithCache(b *testing.B) {
 	}
 }
 
+// BenchmarkValidatorCacheOverflow validates against maxSize+10 distinct
+// schemas, alternating a hot set that fits in the cache with a rotating cold
+// one, and reports the schema cache hit rate of each eviction policy. LRU
+// keeps the hot schemas cached; clearing the full cache recompiles them too.
+func BenchmarkValidatorCacheOverflow(b *testing.B) {
+	const maxSize = 100
+	schemas := make([]json.RawMessage, maxSize+10)
+	for i := range schemas {
+		schemas[i] = json.RawMessage(fmt.Sprintf(`{"type": "object", "properties": {"field%d": {"type": "string"}}}`, i))
+	}
+	hot, cold := schemas[:maxSize/2], schemas[maxSize/2:]
+	response := &types.ValidatedResponse{Data: json.RawMessage(`{}`)}
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+
+	for _, policy := range []string{EvictionLRU, EvictionClear} {
+		b.Run(policy, func(b *testing.B) {
+			validator := NewValidatorWithCache(NewSchemaCacheWithPolicy(maxSize, policy), logger)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				schemaJSON := hot[i/2%len(hot)]
+				if i%2 == 1 {
+					schemaJSON = cold[i/2%len(cold)]
+				}
+				if err := validator.ValidateResponse(schemaJSON, response); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			_, hits, misses := validator.CacheStats()
+			b.ReportMetric(float64(hits)/float64(hits+misses), "hit-rate")
+		})
+	}
+}
+
 func BenchmarkValidatorWithoutCache(b *testing.B) {
 	// Create a validator that doesn't use caching by compiling fresh each time
 	schemaJSON := json.RawMessage(`{
@@ -73,3 +112,26 @@ func BenchmarkValidatorWithoutCache(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkResultCacheHit(b *testing.B) {
+	schemaJSON := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"age": {"type": "number"},
+			"email": {"type": "string", "format": "email"}
+		},
+		"required": ["name", "age"]
+	}`)
+	data := json.RawMessage(`{"name": "John Doe", "age": 30, "email": "john@example.com"}`)
+
+	cache := NewResultCache(100, time.Hour)
+	cache.Set(ResultKey(schemaJSON, data), &types.ValidationReport{Valid: true})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := cache.Get(ResultKey(schemaJSON, data)); !ok {
+			b.Fatal("expected a cached report")
+		}
+	}
+}
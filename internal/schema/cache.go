@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Eviction policies supported by SchemaCache
+const (
+	// EvictionLRU evicts the least recently used entry when the cache is full
+	EvictionLRU = "lru"
+	// EvictionClear empties the whole cache when it reaches capacity
+	EvictionClear = "clear"
+	// EvictionCost evicts the cheapest-to-recompile entry among the least
+	// recently used half of the cache
+	EvictionCost = "cost"
+)
+
+// cacheEntry is a compiled schema with the bookkeeping used for eviction
+type cacheEntry struct {
+	key    string
+	schema *jsonschema.Schema
+	cost   time.Duration
+}
+
+// SchemaCache provides thread-safe caching of compiled JSON schemas
+type SchemaCache struct {
+	mu      sync.Mutex
+	schemas map[string]*list.Element
+	order   *list.List // front is most recently used
+	maxSize int
+	policy  string
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewSchemaCache creates a new schema cache with the given maximum size
+func NewSchemaCache(maxSize int) *SchemaCache {
+	return NewSchemaCacheWithPolicy(maxSize, EvictionLRU)
+}
+
+// NewSchemaCacheWithPolicy creates a schema cache using the given eviction policy
+func NewSchemaCacheWithPolicy(maxSize int, policy string) *SchemaCache {
+	return &SchemaCache{
+		schemas: make(map[string]*list.Element),
+		order:   list.New(),
+		maxSize: maxSize,
+		policy:  policy,
+	}
+}
+
+// Get retrieves a compiled schema from the cache, marking it most recently used
+func (sc *SchemaCache) Get(key string) (*jsonschema.Schema, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	elem, exists := sc.schemas[key]
+	if !exists {
+		sc.misses.Add(1)
+		return nil, false
+	}
+	sc.hits.Add(1)
+	sc.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).schema, true
+}
+
+// Set stores a compiled schema in the cache
+func (sc *SchemaCache) Set(key string, schema *jsonschema.Schema) {
+	sc.SetWithCost(key, schema, 0)
+}
+
+// SetWithCost stores a compiled schema along with how long it took to compile
+func (sc *SchemaCache) SetWithCost(key string, schema *jsonschema.Schema, cost time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if elem, exists := sc.schemas[key]; exists {
+		entry := elem.Value.(*cacheEntry)
+		entry.schema, entry.cost = schema, cost
+		sc.order.MoveToFront(elem)
+		return
+	}
+	if sc.maxSize <= 0 {
+		return
+	}
+	if len(sc.schemas) >= sc.maxSize {
+		sc.evict()
+	}
+	sc.schemas[key] = sc.order.PushFront(&cacheEntry{key: key, schema: schema, cost: cost})
+}
+
+// evict makes room for a new entry according to the cache's policy.
+// The caller must hold the lock.
+func (sc *SchemaCache) evict() {
+	switch sc.policy {
+	case EvictionClear:
+		sc.schemas = make(map[string]*list.Element)
+		sc.order.Init()
+		return
+	case EvictionCost:
+		// Only the colder half is eligible, so a hot schema is never evicted
+		// just because it happened to compile quickly
+		victim := sc.order.Back()
+		elem := victim.Prev()
+		for i := 1; i < (sc.order.Len()+1)/2; i++ {
+			if elem.Value.(*cacheEntry).cost < victim.Value.(*cacheEntry).cost {
+				victim = elem
+			}
+			elem = elem.Prev()
+		}
+		sc.remove(victim)
+	default:
+		sc.remove(sc.order.Back())
+	}
+}
+
+// remove drops one entry. The caller must hold the lock.
+func (sc *SchemaCache) remove(elem *list.Element) {
+	sc.order.Remove(elem)
+	delete(sc.schemas, elem.Value.(*cacheEntry).key)
+}
+
+// Size returns the current number of cached schemas
+func (sc *SchemaCache) Size() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return len(sc.schemas)
+}
+
+// Stats returns the number of cache hits and misses recorded by Get
+func (sc *SchemaCache) Stats() (hits, misses int64) {
+	return sc.hits.Load(), sc.misses.Load()
+}
@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ApplyDefaults fills properties that are absent from data with the value of
+// their schema "default" keyword. Present properties are never changed, even
+// when null. Local references are followed as they are reached, and
+// properties declared in allOf branches get their defaults too; when several
+// schemas give a property a default, the one declaring the property wins over
+// the schemas it references. Data is returned unchanged when no default
+// applies; otherwise it is re-encoded with sorted keys.
+func ApplyDefaults(schemaBytes, data json.RawMessage) (json.RawMessage, error) {
+	var root interface{}
+	if err := json.Unmarshal(schemaBytes, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid response JSON: %w", err)
+	}
+
+	if !applyDefaults(root, []interface{}{root}, value) {
+		return data, nil
+	}
+	out, err := encodeData(value)
+	if err != nil {
+		return nil, fmt.Errorf("encode response: %w", err)
+	}
+	return out, nil
+}
+
+// encodeData re-encodes response data without HTML escaping, leaving that
+// choice to the encoder that writes the response
+func encodeData(value interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// applyDefaults walks the schemas' properties and items alongside the data,
+// reporting whether any default was added
+func applyDefaults(root interface{}, schemaNodes []interface{}, data interface{}) bool {
+	schemas := appliedSchemas(root, schemaNodes)
+
+	changed := false
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, propSchemas := range propertySchemas(schemas) {
+			if child, present := value[key]; present {
+				changed = applyDefaults(root, propSchemas, child) || changed
+				continue
+			}
+			if def, hasDefault := defaultOf(root, propSchemas); hasDefault {
+				value[key] = def
+				changed = true
+			}
+		}
+	case []interface{}:
+		items := itemSchemas(schemas)
+		for _, item := range value {
+			changed = applyDefaults(root, items, item) || changed
+		}
+	}
+	return changed
+}
+
+// defaultOf returns the "default" of the outermost schema that has one among
+// those applying to a value
+func defaultOf(root interface{}, schemaNodes []interface{}) (interface{}, bool) {
+	schemas := appliedSchemas(root, schemaNodes)
+	for i := len(schemas) - 1; i >= 0; i-- {
+		if def, ok := schemas[i]["default"]; ok {
+			return def, true
+		}
+	}
+	return nil, false
+}
+
+// appliedSchemas returns the schema objects that apply to one data value:
+// the given schemas, the targets of their local references and their allOf
+// branches, each after the schemas it reaches. References are resolved as
+// they are reached and each is followed once, so repeated and recursive
+// references cost no more than the schema's size.
+func appliedSchemas(root interface{}, schemaNodes []interface{}) []map[string]interface{} {
+	var schemas []map[string]interface{}
+	followed := make(map[string]bool)
+
+	var collect func(node interface{})
+	collect = func(node interface{}) {
+		schemaMap, ok := node.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if ref, ok := schemaMap["$ref"].(string); ok && strings.HasPrefix(ref, "#") && !followed[ref] {
+			followed[ref] = true
+			if target, ok := resolvePointer(root, strings.TrimPrefix(ref, "#")); ok {
+				collect(target)
+			}
+		}
+		if allOf, ok := schemaMap["allOf"].([]interface{}); ok {
+			for _, branch := range allOf {
+				collect(branch)
+			}
+		}
+		schemas = append(schemas, schemaMap)
+	}
+	for _, node := range schemaNodes {
+		collect(node)
+	}
+	return schemas
+}
+
+// propertySchemas groups the "properties" entries of the schemas by name
+func propertySchemas(schemas []map[string]interface{}) map[string][]interface{} {
+	byName := make(map[string][]interface{})
+	for _, schemaMap := range schemas {
+		properties, _ := schemaMap["properties"].(map[string]interface{})
+		for key, propSchema := range properties {
+			byName[key] = append(byName[key], propSchema)
+		}
+	}
+	return byName
+}
+
+// itemSchemas returns the "items" entries of the schemas
+func itemSchemas(schemas []map[string]interface{}) []interface{} {
+	var items []interface{}
+	for _, schemaMap := range schemas {
+		if item, ok := schemaMap["items"]; ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
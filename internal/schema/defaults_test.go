@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	schemaBytes := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"status": {"type": "string", "default": "active"},
+			"retries": {"type": "integer", "default": 3},
+			"address": {"$ref": "#/$defs/address"},
+			"tags": {"type": "array", "items": {"type": "object", "properties": {"weight": {"default": 1}}}}
+		},
+		"$defs": {
+			"address": {"type": "object", "properties": {"country": {"type": "string", "default": "US"}}}
+		}
+	}`)
+
+	apply := func(t *testing.T, data string) map[string]interface{} {
+		out, err := ApplyDefaults(schemaBytes, json.RawMessage(data))
+		require.NoError(t, err)
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(out, &decoded))
+		return decoded
+	}
+
+	t.Run("missing_defaulted_field_filled", func(t *testing.T) {
+		out := apply(t, `{"name": "Ada"}`)
+		assert.Equal(t, "Ada", out["name"])
+		assert.Equal(t, "active", out["status"])
+		assert.Equal(t, float64(3), out["retries"])
+		assert.NotContains(t, out, "address")
+	})
+
+	t.Run("present_field_untouched", func(t *testing.T) {
+		out := apply(t, `{"name": "Ada", "status": "suspended", "retries": null}`)
+		assert.Equal(t, "suspended", out["status"])
+		assert.Nil(t, out["retries"])
+		assert.Contains(t, out, "retries")
+	})
+
+	t.Run("nested_and_referenced_defaults", func(t *testing.T) {
+		out := apply(t, `{"address": {}, "tags": [{}, {"weight": 5}]}`)
+		assert.Equal(t, map[string]interface{}{"country": "US"}, out["address"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"weight": float64(1)},
+			map[string]interface{}{"weight": float64(5)},
+		}, out["tags"])
+	})
+
+	t.Run("unchanged_data_returned_verbatim", func(t *testing.T) {
+		data := json.RawMessage(`{"status": "x", "retries": 1.50}`)
+		out, err := ApplyDefaults(json.RawMessage(`{"properties": {"status": {"default": "y"}}}`), data)
+		require.NoError(t, err)
+		assert.Equal(t, string(data), string(out))
+	})
+
+	t.Run("large_numbers_preserved", func(t *testing.T) {
+		out, err := ApplyDefaults(schemaBytes, json.RawMessage(`{"id": 12345678901234567890}`))
+		require.NoError(t, err)
+		assert.Contains(t, string(out), `"id":12345678901234567890`)
+	})
+	t.Run("repeated_refs_walked_lazily", func(t *testing.T) {
+		// Inlining this schema would take 2^40 copies of the last definition
+		data := `{}`
+		for i := 0; i < 39; i++ {
+			data = `{"a": ` + data + `}`
+		}
+		out, err := ApplyDefaults(doublingSchema(40), json.RawMessage(`{"root": `+data+`}`))
+		require.NoError(t, err)
+
+		assert.Contains(t, string(out), `{"a":1,"b":1}`)
+	})
+
+	t.Run("declaring_schema_default_wins", func(t *testing.T) {
+		out, err := ApplyDefaults(json.RawMessage(`{
+			"properties": {"status": {"$ref": "#/$defs/status", "default": "new"}},
+			"$defs": {"status": {"type": "string", "default": "active"}}
+		}`), json.RawMessage(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, `{"status":"new"}`, string(out))
+	})
+}
@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Policies for LLM output that repeats a key within an object
+const (
+	DuplicateKeysAllow  = "allow"
+	DuplicateKeysWarn   = "warn"
+	DuplicateKeysReject = "reject"
+)
+
+// DuplicateKeys returns the JSON pointer of every repeated key in data, in
+// document order. encoding/json silently keeps the last occurrence, so this
+// scans the raw tokens instead of decoded values.
+func DuplicateKeys(data json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var duplicates []string
+	if err := scanDuplicates(dec, "", &duplicates); err != nil {
+		return nil, fmt.Errorf("scan for duplicate keys: %w", err)
+	}
+	return duplicates, nil
+}
+
+// scanDuplicates consumes one JSON value from dec, recording repeated keys
+func scanDuplicates(dec *json.Decoder, path string, duplicates *[]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := keyTok.(string)
+			childPath := path + "/" + escapePointer(key)
+			if seen[key] {
+				*duplicates = append(*duplicates, childPath)
+			}
+			seen[key] = true
+			if err := scanDuplicates(dec, childPath, duplicates); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := scanDuplicates(dec, path+"/"+strconv.Itoa(i), duplicates); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+	return nil
+}
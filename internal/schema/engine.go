@@ -0,0 +1,41 @@
+package schema
+
+import (
+	"runtime/debug"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// engineModule is the module path of the JSON Schema implementation
+const engineModule = "github.com/santhosh-tekuri/jsonschema/v5"
+
+// engine is read from the build once; it cannot change while running
+var engine = sync.OnceValue(func() types.SchemaEngine {
+	info := types.SchemaEngine{
+		Library:      engineModule,
+		Version:      "unknown",
+		DefaultDraft: jsonschema.NewCompiler().Draft.URL(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range build.Deps {
+			if dep.Path != engineModule {
+				continue
+			}
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			info.Version = dep.Version
+		}
+	}
+	return info
+})
+
+// Engine describes the JSON Schema implementation schemas are compiled with:
+// its module version, taken from the build, and the draft assumed for
+// schemas without "$schema". Deployments that disagree about a validation
+// can compare it. Version is "unknown" when the binary carries no build info.
+func Engine() types.SchemaEngine {
+	return engine()
+}
@@ -0,0 +1,14 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine(t *testing.T) {
+	engine := Engine()
+	assert.Equal(t, "github.com/santhosh-tekuri/jsonschema/v5", engine.Library)
+	assert.Regexp(t, `^v5\.`, engine.Version)
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", engine.DefaultDraft)
+}
@@ -0,0 +1,213 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FragmentRefPrefix marks a "$ref" naming a registered schema fragment, as
+// in {"$ref": "registry:<id>"} or {"$ref": "registry:<id>#/$defs/money"}
+const FragmentRefPrefix = "registry:"
+
+var (
+	// ErrUnknownFragment is returned when a schema includes a fragment, or a
+	// location within one, that is not registered
+	ErrUnknownFragment = errors.New("unknown schema fragment")
+	// ErrFragmentCycle is returned when fragments include each other
+	ErrFragmentCycle = errors.New("schema fragments include each other")
+)
+
+// FragmentLookup returns the registered schema with the given ID
+type FragmentLookup func(id string) (json.RawMessage, bool)
+
+// ResolveFragments makes a schema that includes registered fragments compile
+// without the registry. Each included fragment is copied once into the
+// schema's "$defs" under "registry:<id>", with its own "#/..." references
+// rewritten to point into that copy, and every registry "$ref" becomes a
+// local reference to it. Fragments may include further fragments, which are
+// copied alongside, but not each other. Keywords beside a registry "$ref"
+// are kept and combined with the reference through allOf. References inside
+// a fragment's subschemas that declare their own "$id" are left alone. A
+// schema without registry references is returned unchanged.
+func ResolveFragments(schemaBytes json.RawMessage, lookup FragmentLookup) (json.RawMessage, error) {
+	if !bytes.Contains(schemaBytes, []byte(FragmentRefPrefix)) {
+		return schemaBytes, nil
+	}
+
+	root, err := decodeSchemaNode(schemaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	included := make(map[string]interface{})
+	resolved, changed, err := resolveFragments(root, lookup, included, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return schemaBytes, nil
+	}
+
+	rootMap, ok := resolved.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema including fragments must be an object")
+	}
+	defs, ok := rootMap["$defs"].(map[string]interface{})
+	if !ok {
+		defs = make(map[string]interface{}, len(included))
+		rootMap["$defs"] = defs
+	}
+	for id, fragment := range included {
+		defs[FragmentRefPrefix+id] = fragment
+	}
+
+	out, err := json.Marshal(rootMap)
+	if err != nil {
+		return nil, fmt.Errorf("encode schema: %w", err)
+	}
+	return out, nil
+}
+
+// decodeSchemaNode decodes a schema keeping numbers exactly as written
+func decodeSchemaNode(schemaBytes json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(schemaBytes))
+	dec.UseNumber()
+	var node interface{}
+	if err := dec.Decode(&node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// resolveFragments returns a copy of node with registry references replaced
+// by local ones, reporting whether any were found. included collects the
+// fragments to copy into "$defs" by ID; expanding holds the fragments
+// currently being included so cycles are detected.
+func resolveFragments(node interface{}, lookup FragmentLookup, included map[string]interface{}, expanding map[string]bool) (interface{}, bool, error) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		ref, isRef := value["$ref"].(string)
+		if isRef && strings.HasPrefix(ref, FragmentRefPrefix) {
+			localRef, err := includeFragment(strings.TrimPrefix(ref, FragmentRefPrefix), lookup, included, expanding)
+			if err != nil {
+				return nil, false, err
+			}
+
+			siblings := make(map[string]interface{}, len(value))
+			for key, child := range value {
+				if key == "$ref" {
+					continue
+				}
+				resolved, _, err := resolveFragments(child, lookup, included, expanding)
+				if err != nil {
+					return nil, false, err
+				}
+				siblings[key] = resolved
+			}
+			fragmentRef := map[string]interface{}{"$ref": localRef}
+			if len(siblings) == 0 {
+				return fragmentRef, true, nil
+			}
+			allOf, _ := siblings["allOf"].([]interface{})
+			siblings["allOf"] = append(allOf, fragmentRef)
+			return siblings, true, nil
+		}
+
+		changed := false
+		out := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			resolved, childChanged, err := resolveFragments(child, lookup, included, expanding)
+			if err != nil {
+				return nil, false, err
+			}
+			out[key] = resolved
+			changed = changed || childChanged
+		}
+		return out, changed, nil
+	case []interface{}:
+		changed := false
+		out := make([]interface{}, len(value))
+		for i, child := range value {
+			resolved, childChanged, err := resolveFragments(child, lookup, included, expanding)
+			if err != nil {
+				return nil, false, err
+			}
+			out[i] = resolved
+			changed = changed || childChanged
+		}
+		return out, changed, nil
+	default:
+		return value, false, nil
+	}
+}
+
+// includeFragment adds the fragment named by "<id>" or "<id>#<pointer>" to
+// included, unless it is there already, and returns the local reference to
+// the named location in its copy
+func includeFragment(target string, lookup FragmentLookup, included map[string]interface{}, expanding map[string]bool) (string, error) {
+	id, pointer, _ := strings.Cut(target, "#")
+	if expanding[id] {
+		return "", fmt.Errorf("%w: %s", ErrFragmentCycle, id)
+	}
+	base := "#/$defs/" + escapePointer(FragmentRefPrefix+id)
+
+	fragment, ok := included[id]
+	if !ok {
+		raw, found := lookup(id)
+		if !found {
+			return "", fmt.Errorf("%w: %s", ErrUnknownFragment, id)
+		}
+		root, err := decodeSchemaNode(raw)
+		if err != nil {
+			return "", fmt.Errorf("fragment %s: invalid JSON: %w", id, err)
+		}
+		if rootMap, ok := root.(map[string]interface{}); ok {
+			// An included fragment is no longer a document of its own
+			delete(rootMap, "$id")
+			delete(rootMap, "$schema")
+		}
+
+		expanding[id] = true
+		fragment, _, err = resolveFragments(rebaseLocalRefs(root, base), lookup, included, expanding)
+		delete(expanding, id)
+		if err != nil {
+			return "", err
+		}
+		included[id] = fragment
+	}
+
+	if _, ok := resolvePointer(fragment, pointer); !ok {
+		return "", fmt.Errorf("%w: %s has no #%s", ErrUnknownFragment, id, pointer)
+	}
+	return base + pointer, nil
+}
+
+// rebaseLocalRefs returns a copy of node whose "#" and "#/..." references
+// point below base instead of the document root. Subschemas with an "$id"
+// start a document of their own, so their references are kept.
+func rebaseLocalRefs(node interface{}, base string) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if _, ok := value["$id"]; ok {
+			return value
+		}
+		out := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			out[key] = rebaseLocalRefs(child, base)
+		}
+		if ref, ok := value["$ref"].(string); ok && (ref == "#" || strings.HasPrefix(ref, "#/")) {
+			out["$ref"] = base + strings.TrimPrefix(ref, "#")
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, child := range value {
+			out[i] = rebaseLocalRefs(child, base)
+		}
+		return out
+	default:
+		return value
+	}
+}
@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveFragments(t *testing.T) {
+	fragments := map[string]json.RawMessage{
+		"address": json.RawMessage(`{"$id": "https://example.com/address", "type": "object", "properties": {"zip": {"$ref": "#/$defs/zip"}}, "$defs": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}}`),
+		"money":   json.RawMessage(`{"$defs": {"amount": {"type": "number", "multipleOf": 0.01}}}`),
+		"loop_a":  json.RawMessage(`{"$ref": "registry:loop_b"}`),
+		"loop_b":  json.RawMessage(`{"$ref": "registry:loop_a"}`),
+		"tree":    json.RawMessage(`{"$ref": "#/$defs/node", "$defs": {"node": {"type": "object", "properties": {"child": {"$ref": "#/$defs/node"}}}}}`),
+		"dag":     doublingSchema(40),
+	}
+	lookup := func(id string) (json.RawMessage, bool) {
+		fragment, ok := fragments[id]
+		return fragment, ok
+	}
+
+	t.Run("copies_fragments_into_defs", func(t *testing.T) {
+		out, err := ResolveFragments(json.RawMessage(`{"type": "object", "properties": {
+			"home": {"$ref": "registry:address"},
+			"work": {"$ref": "registry:address"},
+			"price": {"$ref": "registry:money#/$defs/amount", "minimum": 0}
+		}}`), lookup)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"type": "object", "properties": {
+			"home": {"$ref": "#/$defs/registry:address"},
+			"work": {"$ref": "#/$defs/registry:address"},
+			"price": {"minimum": 0, "allOf": [{"$ref": "#/$defs/registry:money/$defs/amount"}]}
+		}, "$defs": {
+			"registry:address": {"type": "object", "properties": {"zip": {"$ref": "#/$defs/registry:address/$defs/zip"}},
+				"$defs": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}},
+			"registry:money": {"$defs": {"amount": {"type": "number", "multipleOf": 0.01}}}
+		}}`, string(out))
+
+		v := NewValidator()
+		require.NoError(t, v.ValidateSchema(out))
+		normalized, err := v.Normalize(out)
+		require.NoError(t, err)
+		assert.Contains(t, string(normalized), `"zip":{"pattern":"^[0-9]{5}$","type":"string"}`)
+	})
+
+	t.Run("recursive_fragments_included", func(t *testing.T) {
+		out, err := ResolveFragments(json.RawMessage(`{"type": "object", "properties": {"root": {"$ref": "registry:tree"}}}`), lookup)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"type": "object", "properties": {"root": {"$ref": "#/$defs/registry:tree"}}, "$defs": {
+			"registry:tree": {"$ref": "#/$defs/registry:tree/$defs/node", "$defs": {"node": {"type": "object",
+				"properties": {"child": {"$ref": "#/$defs/registry:tree/$defs/node"}}}}}
+		}}`, string(out))
+		assert.NoError(t, NewValidator().ValidateSchema(out))
+	})
+
+	t.Run("repeated_refs_not_expanded", func(t *testing.T) {
+		// Inlining this fragment would take 2^40 copies of its last definition
+		out, err := ResolveFragments(json.RawMessage(`{"type": "object", "properties": {"dag": {"$ref": "registry:dag"}}}`), lookup)
+		require.NoError(t, err)
+		assert.Less(t, len(out), 2*len(fragments["dag"]))
+	})
+
+	t.Run("schema_without_fragments_unchanged", func(t *testing.T) {
+		in := json.RawMessage(`{"type": "object",  "maximum": 1e400}`)
+		out, err := ResolveFragments(in, lookup)
+		require.NoError(t, err)
+		assert.Equal(t, string(in), string(out))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := ResolveFragments(json.RawMessage(`{"$ref": "registry:missing"}`), lookup)
+		assert.ErrorIs(t, err, ErrUnknownFragment)
+		_, err = ResolveFragments(json.RawMessage(`{"$ref": "registry:money#/$defs/missing"}`), lookup)
+		assert.ErrorIs(t, err, ErrUnknownFragment)
+		_, err = ResolveFragments(json.RawMessage(`{"$ref": "registry:loop_a"}`), lookup)
+		assert.ErrorIs(t, err, ErrFragmentCycle)
+	})
+}
@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// hintSubschemaMaps are keywords whose value maps names to subschemas
+var hintSubschemaMaps = []string{"properties", "patternProperties", "$defs", "definitions"}
+
+// hintSubschemaLists are keywords whose value is a list of subschemas
+var hintSubschemaLists = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+
+// hintSubschemas are keywords whose value is a single subschema
+var hintSubschemas = []string{"items", "additionalProperties", "not", "if", "then", "else"}
+
+// AddFormatHints returns a copy of the schema in which every node using
+// enum, format or pattern has that constraint spelled out in its
+// "description", appended to any description already there. It is meant for
+// the schema shown to the LLM, which follows prose more reliably than
+// keywords; responses are still validated against the original. The schema
+// is returned unchanged when nothing needs a hint; otherwise it is
+// re-encoded with sorted keys.
+func AddFormatHints(schemaBytes json.RawMessage) (json.RawMessage, error) {
+	var root interface{}
+	if err := json.Unmarshal(schemaBytes, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if !addFormatHints(root) {
+		return schemaBytes, nil
+	}
+	out, err := encodeData(root)
+	if err != nil {
+		return nil, fmt.Errorf("encode schema: %w", err)
+	}
+	return out, nil
+}
+
+// addFormatHints describes the constraints of a node and its subschemas,
+// reporting whether any description was added
+func addFormatHints(schemaNode interface{}) bool {
+	schemaMap, ok := schemaNode.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	changed := false
+	if hint := constraintHint(schemaMap); hint != "" {
+		if description, ok := schemaMap["description"].(string); ok && description != "" {
+			schemaMap["description"] = strings.TrimRight(description, " ") + " " + hint
+		} else {
+			schemaMap["description"] = hint
+		}
+		changed = true
+	}
+
+	for _, keyword := range hintSubschemaMaps {
+		if children, ok := schemaMap[keyword].(map[string]interface{}); ok {
+			for _, child := range children {
+				changed = addFormatHints(child) || changed
+			}
+		}
+	}
+	for _, keyword := range hintSubschemaLists {
+		if children, ok := schemaMap[keyword].([]interface{}); ok {
+			for _, child := range children {
+				changed = addFormatHints(child) || changed
+			}
+		}
+	}
+	for _, keyword := range hintSubschemas {
+		changed = addFormatHints(schemaMap[keyword]) || changed
+	}
+	return changed
+}
+
+// constraintHint describes a node's enum, format and pattern, or returns ""
+// when it has none
+func constraintHint(schemaMap map[string]interface{}) string {
+	var parts []string
+	if enum, ok := schemaMap["enum"].([]interface{}); ok && len(enum) > 0 {
+		values := make([]string, 0, len(enum))
+		for _, value := range enum {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			values = append(values, string(encoded))
+		}
+		parts = append(parts, "Must be one of: "+strings.Join(values, ", ")+".")
+	}
+	if format, ok := schemaMap["format"].(string); ok && format != "" {
+		parts = append(parts, fmt.Sprintf("Must be a valid %s.", format))
+	}
+	if pattern, ok := schemaMap["pattern"].(string); ok && pattern != "" {
+		parts = append(parts, fmt.Sprintf("Must match the regular expression %s.", pattern))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddFormatHints(t *testing.T) {
+	hint := func(t *testing.T, schemaJSON string) map[string]interface{} {
+		out, err := AddFormatHints(json.RawMessage(schemaJSON))
+		require.NoError(t, err)
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(out, &decoded))
+		return decoded
+	}
+	description := func(node map[string]interface{}, path ...string) interface{} {
+		for _, key := range path {
+			node = node[key].(map[string]interface{})
+		}
+		return node["description"]
+	}
+
+	t.Run("constrained_fields_described", func(t *testing.T) {
+		out := hint(t, `{
+			"type": "object",
+			"properties": {
+				"status": {"type": "string", "enum": ["active", "closed"]},
+				"email": {"type": "string", "format": "email", "description": "Contact address"},
+				"code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+				"name": {"type": "string"}
+			}
+		}`)
+
+		assert.Equal(t, `Must be one of: "active", "closed".`, description(out, "properties", "status"))
+		assert.Equal(t, "Contact address Must be a valid email.", description(out, "properties", "email"))
+		assert.Equal(t, "Must match the regular expression ^[A-Z]{3}$.", description(out, "properties", "code"))
+		assert.Nil(t, description(out, "properties", "name"))
+		assert.Nil(t, out["description"])
+	})
+
+	t.Run("nested_subschemas_described", func(t *testing.T) {
+		out := hint(t, `{
+			"type": "array",
+			"items": {"$ref": "#/$defs/tag"},
+			"$defs": {"tag": {"anyOf": [{"type": "string", "format": "uuid"}, {"enum": [1, 2]}]}}
+		}`)
+
+		branches := out["$defs"].(map[string]interface{})["tag"].(map[string]interface{})["anyOf"].([]interface{})
+		assert.Equal(t, "Must be a valid uuid.", branches[0].(map[string]interface{})["description"])
+		assert.Equal(t, "Must be one of: 1, 2.", branches[1].(map[string]interface{})["description"])
+	})
+
+	t.Run("unconstrained_schema_unchanged", func(t *testing.T) {
+		original := json.RawMessage(`{"type": "object",  "properties": {"name": {"type": "string"}}}`)
+		out, err := AddFormatHints(original)
+		require.NoError(t, err)
+		assert.Equal(t, string(original), string(out))
+	})
+
+	t.Run("invalid_json", func(t *testing.T) {
+		_, err := AddFormatHints(json.RawMessage(`{`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Policies for schemas whose text looks like a prompt-injection attempt
+const (
+	InjectionAllow  = "allow"
+	InjectionWarn   = "warn"
+	InjectionReject = "reject"
+)
+
+// injectionPatterns match phrases that address the model rather than
+// describe data. They are heuristics: they catch the obvious attempts, not
+// a determined attacker.
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,20}\b(previous|prior|above|earlier|all|your|system)\b.{0,20}\b(instructions?|prompts?|rules|messages|context)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|repeat|output|show)\b.{0,20}\b(system prompt|your instructions|hidden instructions)\b`),
+	regexp.MustCompile(`(?i)\byou are now\b`),
+	regexp.MustCompile(`(?i)\bnew instructions\s*:`),
+	regexp.MustCompile(`(?i)<\|?(system|im_start|im_end)\|?>`),
+}
+
+// InjectionSuspects returns the JSON pointer of every string in a schema,
+// such as a "description" or "title", that matches a known prompt-injection
+// phrase, sorted by path
+func InjectionSuspects(schemaBytes json.RawMessage) ([]string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(schemaBytes, &doc); err != nil {
+		return nil, fmt.Errorf("scan for prompt injection: %w", err)
+	}
+	var suspects []string
+	scanInjection(doc, "", &suspects)
+	sort.Strings(suspects)
+	return suspects, nil
+}
+
+// scanInjection walks decoded JSON, recording strings that look like injections
+func scanInjection(node interface{}, path string, suspects *[]string) {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for key, value := range node {
+			scanInjection(value, path+"/"+escapePointer(key), suspects)
+		}
+	case []interface{}:
+		for i, value := range node {
+			scanInjection(value, path+"/"+strconv.Itoa(i), suspects)
+		}
+	case string:
+		for _, pattern := range injectionPatterns {
+			if pattern.MatchString(node) {
+				*suspects = append(*suspects, pathOrRoot(path))
+				return
+			}
+		}
+	}
+}
+
+// pathOrRoot returns "/" for the empty pointer of a document's root
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// IntegerLiteralError reports a number written with a fraction or exponent,
+// such as 25.0, where the schema expects an integer. JSON Schema accepts
+// such numbers when they are integral; strict integer mode does not.
+type IntegerLiteralError struct {
+	Path    string
+	Literal string
+}
+
+func (e *IntegerLiteralError) Error() string {
+	return fmt.Sprintf("%s: expected an integer literal, got %s", pathOrRoot(e.Path), e.Literal)
+}
+
+// checkIntegerLiterals finds the first value in data whose schema type is
+// integer but whose literal is not. Local references and allOf branches are
+// followed as in ApplyDefaults.
+func checkIntegerLiterals(schemaBytes, data json.RawMessage) error {
+	var root interface{}
+	if err := json.Unmarshal(schemaBytes, &root); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("invalid response JSON: %w", err)
+	}
+
+	return findNonIntegerLiteral(root, []interface{}{root}, value, "")
+}
+
+// findNonIntegerLiteral walks the schemas' properties and items alongside
+// the data
+func findNonIntegerLiteral(root interface{}, schemaNodes []interface{}, data interface{}, path string) error {
+	schemas := appliedSchemas(root, schemaNodes)
+
+	switch value := data.(type) {
+	case json.Number:
+		if !strings.ContainsAny(value.String(), ".eE") {
+			return nil
+		}
+		for _, schemaMap := range schemas {
+			if requiresInteger(schemaMap["type"]) {
+				return &IntegerLiteralError{Path: path, Literal: value.String()}
+			}
+		}
+	case map[string]interface{}:
+		for key, propSchemas := range propertySchemas(schemas) {
+			if child, present := value[key]; present {
+				if err := findNonIntegerLiteral(root, propSchemas, child, path+"/"+escapePointer(key)); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		items := itemSchemas(schemas)
+		for i, item := range value {
+			if err := findNonIntegerLiteral(root, items, item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// requiresInteger reports whether a "type" keyword allows integers but not
+// other numbers
+func requiresInteger(typeValue interface{}) bool {
+	switch t := typeValue.(type) {
+	case string:
+		return t == "integer"
+	case []interface{}:
+		integer := false
+		for _, name := range t {
+			if name == "number" {
+				return false
+			}
+			integer = integer || name == "integer"
+		}
+		return integer
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestStrictIntegers(t *testing.T) {
+	schemaBytes := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"age": {"type": "integer"},
+			"score": {"type": "number"},
+			"counts": {"type": "array", "items": {"$ref": "#/$defs/count"}}
+		},
+		"$defs": {"count": {"type": ["integer", "null"]}}
+	}`)
+
+	validate := func(t *testing.T, strict bool, data string) error {
+		v := NewValidatorWithLogger(10, logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard}))
+		v.SetPolicy(Policy{StrictIntegers: strict})
+		return v.ValidateResponse(schemaBytes, &types.ValidatedResponse{Data: json.RawMessage(data)})
+	}
+
+	tests := []struct {
+		name    string
+		age     string
+		lenient bool
+		strict  bool
+	}{
+		{name: "integer_literal", age: "25", lenient: true, strict: true},
+		{name: "integral_float_literal", age: "25.0", lenient: true, strict: false},
+		{name: "integral_exponent_literal", age: "2.5e1", lenient: true, strict: false},
+		{name: "fractional", age: "25.5", lenient: false, strict: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `{"age": ` + tt.age + `}`
+			assert.Equal(t, tt.lenient, validate(t, false, data) == nil, "lenient")
+			assert.Equal(t, tt.strict, validate(t, true, data) == nil, "strict")
+		})
+	}
+
+	t.Run("number_fields_keep_fractions", func(t *testing.T) {
+		assert.NoError(t, validate(t, true, `{"age": 25, "score": 25.0}`))
+	})
+
+	t.Run("reports_path_of_nested_literal", func(t *testing.T) {
+		data := json.RawMessage(`{"age": 25, "counts": [1, null, 3.0]}`)
+		err := validate(t, true, string(data))
+		var literalErr *IntegerLiteralError
+		require.ErrorAs(t, err, &literalErr)
+		assert.Equal(t, "/counts/2", literalErr.Path)
+		assert.Equal(t, "3.0", literalErr.Literal)
+
+		violations := Violations(err, schemaBytes, data)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "/counts/2", violations[0].Path)
+		assert.Equal(t, "integer", violations[0].Expected)
+	})
+	t.Run("repeated_refs_walked_lazily", func(t *testing.T) {
+		// Inlining this schema would take 2^40 copies of the last definition
+		data := `{"a": 1, "b": 2.0}`
+		for i := 0; i < 39; i++ {
+			data = `{"a": ` + data + `}`
+		}
+		err := checkIntegerLiterals(doublingSchema(40), json.RawMessage(`{"root": `+data+`}`))
+		var literalErr *IntegerLiteralError
+		require.ErrorAs(t, err, &literalErr)
+		assert.Equal(t, "/root"+strings.Repeat("/a", 39)+"/b", literalErr.Path)
+	})
+}
@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxInlinedNodes bounds the nodes inlineRefs may produce. A reference reused
+// by every definition of a chain doubles the expansion per link, so a small
+// schema can otherwise inline to an exponential size.
+const maxInlinedNodes = 100000
+
+// ErrSchemaTooLarge is returned when inlining a schema's references would
+// exceed maxInlinedNodes
+var ErrSchemaTooLarge = errors.New("schema is too large once references are inlined")
+
+// Normalize returns the schema the gateway effectively validates against as
+// canonical JSON: keys are sorted, whitespace is removed and local "#/..."
+// references are inlined. Recursive references are left in place, along with
+// the definitions they point to, since they cannot be expanded finitely.
+// The schema must compile; invalid schemas are rejected as by ValidateSchema,
+// and schemas whose inlined form is too large fail with ErrSchemaTooLarge.
+func (v *Validator) Normalize(schemaBytes json.RawMessage) (json.RawMessage, error) {
+	if _, err := v.compileSchema(schemaBytes); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(schemaBytes, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	normalized, err := inlineRefs(root)
+	if err != nil {
+		return nil, err
+	}
+	if rootMap, ok := normalized.(map[string]interface{}); ok && !hasLocalRef(rootMap) {
+		delete(rootMap, "$defs")
+		delete(rootMap, "definitions")
+	}
+
+	// encoding/json writes map keys in sorted order
+	out, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("encode schema: %w", err)
+	}
+	return out, nil
+}
+
+// inlineRefs returns a copy of root with local references replaced by their
+// targets, failing with ErrSchemaTooLarge past maxInlinedNodes nodes
+func inlineRefs(root interface{}) (interface{}, error) {
+	budget := maxInlinedNodes
+	return inlineRefsIn(root, root, map[string]bool{}, &budget)
+}
+
+// inlineRefsIn inlines the references of node. expanding holds the
+// references currently being inlined so cycles are detected; budget counts
+// down the nodes still allowed.
+func inlineRefsIn(root, node interface{}, expanding map[string]bool, budget *int) (interface{}, error) {
+	if *budget--; *budget < 0 {
+		return nil, ErrSchemaTooLarge
+	}
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		ref, isRef := value["$ref"].(string)
+		if isRef && strings.HasPrefix(ref, "#") && !expanding[ref] {
+			if target, ok := resolvePointer(root, strings.TrimPrefix(ref, "#")); ok {
+				expanding[ref] = true
+				resolved, err := inlineRefsIn(root, target, expanding, budget)
+				delete(expanding, ref)
+				if err != nil {
+					return nil, err
+				}
+
+				siblings := make(map[string]interface{}, len(value))
+				for key, child := range value {
+					if key != "$ref" {
+						if siblings[key], err = inlineRefsIn(root, child, expanding, budget); err != nil {
+							return nil, err
+						}
+					}
+				}
+				if len(siblings) == 0 {
+					return resolved, nil
+				}
+				// Keywords beside $ref still apply, so combine rather than replace
+				allOf, _ := siblings["allOf"].([]interface{})
+				siblings["allOf"] = append(allOf, resolved)
+				return siblings, nil
+			}
+		}
+
+		out := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			inlined, err := inlineRefsIn(root, child, expanding, budget)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = inlined
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, child := range value {
+			inlined, err := inlineRefsIn(root, child, expanding, budget)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = inlined
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// hasLocalRef reports whether any "#..." reference remains after inlining
+func hasLocalRef(node interface{}) bool {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := value["$ref"].(string); ok && strings.HasPrefix(ref, "#") {
+			return true
+		}
+		for _, child := range value {
+			if hasLocalRef(child) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if hasLocalRef(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Null policies decide what happens to a null the schema doesn't allow
+const (
+	// NullsReject leaves nulls alone, so they fail type validation
+	NullsReject = "reject"
+	// NullsStrip treats such a null as an absent property, so "required"
+	// decides whether the response is valid
+	NullsStrip = "strip"
+	// NullsDefault replaces such a null with the property's schema
+	// "default", stripping it when there is none
+	NullsDefault = "default"
+)
+
+// rewriteNulls applies a null policy to the object properties of data whose
+// schema does not accept null. Local references and allOf branches are
+// followed as in ApplyDefaults, and a replacing default is chosen as there. Data is returned unchanged when nothing was
+// rewritten; otherwise it is re-encoded with sorted keys.
+func rewriteNulls(schemaBytes, data json.RawMessage, policy string) (json.RawMessage, bool, error) {
+	var root interface{}
+	if err := json.Unmarshal(schemaBytes, &root); err != nil {
+		return nil, false, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, false, fmt.Errorf("invalid response JSON: %w", err)
+	}
+
+	if !rewriteNullsIn(root, []interface{}{root}, value, policy) {
+		return data, false, nil
+	}
+	out, err := encodeData(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("encode response: %w", err)
+	}
+	return out, true, nil
+}
+
+// rewriteNullsIn walks the schemas' properties and items alongside the
+// data, reporting whether any null was rewritten
+func rewriteNullsIn(root interface{}, schemaNodes []interface{}, data interface{}, policy string) bool {
+	schemas := appliedSchemas(root, schemaNodes)
+
+	changed := false
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, propSchemas := range propertySchemas(schemas) {
+			child, present := value[key]
+			if !present {
+				continue
+			}
+			if child != nil || acceptsNull(root, propSchemas) {
+				changed = rewriteNullsIn(root, propSchemas, child, policy) || changed
+				continue
+			}
+			if def, hasDefault := defaultOf(root, propSchemas); hasDefault && policy == NullsDefault {
+				value[key] = def
+			} else {
+				delete(value, key)
+			}
+			changed = true
+		}
+	case []interface{}:
+		items := itemSchemas(schemas)
+		for _, item := range value {
+			changed = rewriteNullsIn(root, items, item, policy) || changed
+		}
+	}
+	return changed
+}
+
+// acceptsNull reports whether all of the schemas may accept null, judged by
+// their type, enum and const keywords, their local references and their
+// allOf, anyOf and oneOf branches. Anything it can't rule out counts as
+// accepting null, so valid nulls are never touched.
+func acceptsNull(root interface{}, schemaNodes []interface{}) bool {
+	// Each reference is judged once, so repeated references stay cheap
+	judged := make(map[string]bool)
+	for _, node := range schemaNodes {
+		if !acceptsNullIn(root, node, judged) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsNullIn judges one schema, recording referenced schemas in judged
+func acceptsNullIn(root, schemaNode interface{}, judged map[string]bool) bool {
+	schemaMap, ok := schemaNode.(map[string]interface{})
+	if !ok {
+		// true accepts everything and false nothing, null included
+		accept, isBool := schemaNode.(bool)
+		return !isBool || accept
+	}
+
+	if ref, ok := schemaMap["$ref"].(string); ok && strings.HasPrefix(ref, "#") {
+		accept, seen := judged[ref]
+		if !seen {
+			// A reference back to a schema being judged cannot rule null out
+			judged[ref] = true
+			target, found := resolvePointer(root, strings.TrimPrefix(ref, "#"))
+			accept = !found || acceptsNullIn(root, target, judged)
+			judged[ref] = accept
+		}
+		if !accept {
+			return false
+		}
+	}
+
+	if allOf, ok := schemaMap["allOf"].([]interface{}); ok {
+		for _, branch := range allOf {
+			if !acceptsNullIn(root, branch, judged) {
+				return false
+			}
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		if branches, ok := schemaMap[keyword].([]interface{}); ok {
+			nullable := false
+			for _, branch := range branches {
+				nullable = nullable || acceptsNullIn(root, branch, judged)
+			}
+			if !nullable {
+				return false
+			}
+		}
+	}
+
+	if constValue, ok := schemaMap["const"]; ok && constValue != nil {
+		return false
+	}
+	if enum, ok := schemaMap["enum"].([]interface{}); ok {
+		nullable := false
+		for _, item := range enum {
+			nullable = nullable || item == nil
+		}
+		if !nullable {
+			return false
+		}
+	}
+
+	switch typ := schemaMap["type"].(type) {
+	case string:
+		return typ == "null"
+	case []interface{}:
+		for _, t := range typ {
+			if t == "null" {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestNullPolicy(t *testing.T) {
+	schemaBytes := json.RawMessage(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string"},
+			"nickname": {"type": "string"},
+			"status": {"type": "string", "default": "active"},
+			"note": {"type": ["string", "null"]},
+			"address": {"$ref": "#/$defs/address"}
+		},
+		"$defs": {
+			"address": {"type": "object", "properties": {"city": {"type": "string"}}}
+		}
+	}`)
+
+	validate := func(t *testing.T, nulls, data string) (*types.ValidatedResponse, error) {
+		v := NewValidatorWithLogger(10, logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard}))
+		v.SetPolicy(Policy{Nulls: nulls})
+		response := &types.ValidatedResponse{Data: json.RawMessage(data)}
+		return response, v.ValidateResponse(schemaBytes, response)
+	}
+	decode := func(t *testing.T, response *types.ValidatedResponse) map[string]interface{} {
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(response.Data, &decoded))
+		return decoded
+	}
+
+	t.Run("reject_fails_null_in_optional_field", func(t *testing.T) {
+		for _, nulls := range []string{"", NullsReject} {
+			response, err := validate(t, nulls, `{"name": "Ada", "nickname": null}`)
+			assert.Error(t, err)
+			assert.JSONEq(t, `{"name": "Ada", "nickname": null}`, string(response.Data))
+		}
+	})
+
+	t.Run("strip_removes_null_in_optional_field", func(t *testing.T) {
+		response, err := validate(t, NullsStrip, `{"name": "Ada", "nickname": null, "status": null, "address": {"city": null}}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "Ada", "address": {}}`, string(response.Data))
+	})
+
+	t.Run("strip_leaves_required_to_decide", func(t *testing.T) {
+		response, err := validate(t, NullsStrip, `{"name": null}`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing properties")
+		assert.JSONEq(t, `{"name": null}`, string(response.Data))
+	})
+
+	t.Run("default_replaces_null_in_optional_field", func(t *testing.T) {
+		response, err := validate(t, NullsDefault, `{"name": "Ada", "nickname": null, "status": null}`)
+		require.NoError(t, err)
+		out := decode(t, response)
+		assert.Equal(t, "active", out["status"])
+		// Without a default the null is stripped
+		assert.NotContains(t, out, "nickname")
+	})
+
+	t.Run("repeated_refs_walked_lazily", func(t *testing.T) {
+		// Inlining this schema would take 2^40 copies of the last definition
+		data := `{"a": null, "b": 2}`
+		for i := 0; i < 39; i++ {
+			data = `{"a": ` + data + `}`
+		}
+		out, changed, err := rewriteNulls(doublingSchema(40), json.RawMessage(`{"root": `+data+`}`), NullsDefault)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Contains(t, string(out), `{"a":1,"b":2}`)
+	})
+
+	t.Run("allowed_nulls_untouched", func(t *testing.T) {
+		for _, nulls := range []string{NullsReject, NullsStrip, NullsDefault} {
+			data := `{"name": "Ada", "note": null}`
+			response, err := validate(t, nulls, data)
+			require.NoError(t, err)
+			assert.Equal(t, data, string(response.Data))
+		}
+	})
+}
+
+func TestAcceptsNull(t *testing.T) {
+	for schemaJSON, want := range map[string]bool{
+		`{}`:                            true,
+		`true`:                          true,
+		`false`:                         false,
+		`{"type": "string"}`:            false,
+		`{"type": "null"}`:              true,
+		`{"type": ["integer", "null"]}`: true,
+		`{"enum": ["a", "b"]}`:          false,
+		`{"enum": ["a", null]}`:         true,
+		`{"const": 1}`:                  false,
+		`{"anyOf": [{"type": "string"}, {"type": "null"}]}`:                                       true,
+		`{"oneOf": [{"type": "string"}, {"type": "number"}]}`:                                     false,
+		`{"allOf": [{"type": "string"}]}`:                                                         false,
+		`{"$ref": "#/$defs/s", "$defs": {"s": {"type": "string"}}}`:                               false,
+		`{"$ref": "#/$defs/s", "$defs": {"s": {"anyOf": [{"$ref": "#/$defs/s"}, {"const": 1}]}}}`: true,
+	} {
+		var node interface{}
+		require.NoError(t, json.Unmarshal([]byte(schemaJSON), &node))
+		assert.Equal(t, want, acceptsNull(node, []interface{}{node}), schemaJSON)
+	}
+}
@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// resultEntry is a cached validation report and when it stops being served
+type resultEntry struct {
+	key       string
+	report    *types.ValidationReport
+	expiresAt time.Time
+}
+
+// ResultCache holds validation reports by schema and data, so validating the
+// same data against the same schema again skips the work. It is bounded,
+// evicting the least recently used report when full, and reports expire
+// after a TTL. Cached reports are shared and must not be modified.
+type ResultCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	order      *list.List // front is most recently used
+	now        func() time.Time
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewResultCache creates a cache holding at most maxEntries reports for ttl each
+func NewResultCache(maxEntries int, ttl time.Duration) *ResultCache {
+	return &ResultCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// ResultKey identifies a validation of data against a schema
+func ResultKey(schemaBytes, data json.RawMessage) string {
+	hash := sha256.Sum256(data)
+	return Hash(schemaBytes) + ":" + fmt.Sprintf("%x", hash[:16])
+}
+
+// Get returns the unexpired report stored under key
+func (c *ResultCache) Get(key string) (*types.ValidationReport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	e := elem.Value.(*resultEntry)
+	if !c.now().Before(e.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	c.hits.Add(1)
+	return e.report, true
+}
+
+// Set stores a report under key, evicting the least recently used if full
+func (c *ResultCache) Set(key string, report *types.ValidationReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*resultEntry)
+		e.report, e.expiresAt = report, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resultEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(&resultEntry{key: key, report: report, expiresAt: expiresAt})
+}
+
+// Stats returns the cache's hit and miss counts
+func (c *ResultCache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestResultCache(t *testing.T) {
+	schemaJSON := json.RawMessage(`{"type": "object"}`)
+
+	t.Run("key_depends_on_schema_and_data", func(t *testing.T) {
+		key := ResultKey(schemaJSON, json.RawMessage(`{"a": 1}`))
+		assert.Equal(t, key, ResultKey(schemaJSON, json.RawMessage(`{"a": 1}`)))
+		assert.NotEqual(t, key, ResultKey(schemaJSON, json.RawMessage(`{"a": 2}`)))
+		assert.NotEqual(t, key, ResultKey(json.RawMessage(`{"type": "array"}`), json.RawMessage(`{"a": 1}`)))
+	})
+
+	t.Run("second_lookup_is_a_hit", func(t *testing.T) {
+		c := NewResultCache(10, time.Minute)
+		key := ResultKey(schemaJSON, json.RawMessage(`{}`))
+
+		_, ok := c.Get(key)
+		assert.False(t, ok)
+		report := &types.ValidationReport{Valid: true}
+		c.Set(key, report)
+
+		cached, ok := c.Get(key)
+		assert.True(t, ok)
+		assert.Same(t, report, cached)
+		hits, misses := c.Stats()
+		assert.Equal(t, int64(1), hits)
+		assert.Equal(t, int64(1), misses)
+	})
+
+	t.Run("entries_expire", func(t *testing.T) {
+		now := time.Now()
+		c := NewResultCache(10, time.Minute)
+		c.now = func() time.Time { return now }
+		c.Set("k", &types.ValidationReport{})
+
+		now = now.Add(time.Minute)
+		_, ok := c.Get("k")
+		assert.False(t, ok)
+	})
+
+	t.Run("evicts_least_recently_used", func(t *testing.T) {
+		c := NewResultCache(2, time.Minute)
+		c.Set("a", &types.ValidationReport{})
+		c.Set("b", &types.ValidationReport{})
+		c.Get("a")
+		c.Set("c", &types.ValidationReport{})
+
+		_, ok := c.Get("b")
+		assert.False(t, ok)
+		_, ok = c.Get("a")
+		assert.True(t, ok)
+	})
+}
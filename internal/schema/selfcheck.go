@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Canary schema and documents used by SelfCheck. The schema exercises the
+// keywords most requests rely on so a regression in any of them is caught.
+const (
+	canarySchema = `{
+		"type": "object",
+		"required": ["name", "tags"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+		},
+		"additionalProperties": false
+	}`
+	canaryValid   = `{"name": "canary", "age": 1, "tags": ["a", "b"]}`
+	canaryInvalid = `{"name": "", "age": -1, "tags": ["a", "a"], "extra": true}`
+)
+
+// SelfCheck verifies the validator works end to end by compiling a canary
+// schema and checking that it accepts a valid document and rejects an invalid
+// one. It bypasses the schema cache so the compiler itself is exercised.
+func (v *Validator) SelfCheck() error {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("https://example.com/canary.json", strings.NewReader(canarySchema)); err != nil {
+		return fmt.Errorf("add canary schema: %w", err)
+	}
+	compiled, err := compiler.Compile("https://example.com/canary.json")
+	if err != nil {
+		return fmt.Errorf("compile canary schema: %w", err)
+	}
+
+	var valid, invalid interface{}
+	if err := json.Unmarshal([]byte(canaryValid), &valid); err != nil {
+		return fmt.Errorf("parse canary document: %w", err)
+	}
+	if err := json.Unmarshal([]byte(canaryInvalid), &invalid); err != nil {
+		return fmt.Errorf("parse canary document: %w", err)
+	}
+
+	if err := compiled.Validate(valid); err != nil {
+		return fmt.Errorf("canary document rejected: %w", err)
+	}
+	if err := compiled.Validate(invalid); err == nil {
+		return fmt.Errorf("invalid canary document accepted")
+	}
+	return nil
+}
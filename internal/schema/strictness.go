@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+var (
+	// ErrRootNotObject is returned when a policy requires object schemas and
+	// the schema's root "type" is not "object"
+	ErrRootNotObject = errors.New(`schema root must have "type": "object"`)
+	// ErrPermissiveSchema is returned when a policy rejects schemas that place
+	// no constraint on the response beyond its type
+	ErrPermissiveSchema = errors.New("schema does not constrain the response")
+)
+
+// Policy controls how strictly schemas are applied. AssertFormats makes
+// "format" an assertion for every schema; otherwise the validator library's
+// default applies, which asserts it for schemas without "$schema" and for
+// drafts before 2019-09 but treats it as an annotation for later drafts.
+// RequireObjectRoot rejects schemas whose root type is not "object", and
+// RejectPermissive rejects schemas with no keywords beyond "type" and
+// annotations such as "title", which accept any value of that type. Nulls
+// is one of NullsReject (the default when empty), NullsStrip or
+// NullsDefault and decides how nulls in non-nullable properties are treated.
+// StrictIntegers rejects numbers written with a fraction or exponent, such
+// as 25.0, where the schema's type is integer.
+type Policy struct {
+	AssertFormats     bool
+	RequireObjectRoot bool
+	RejectPermissive  bool
+	Nulls             string
+	StrictIntegers    bool
+}
+
+// applyFormatPolicy configures how a compiler treats the "format" keyword
+func (v *Validator) applyFormatPolicy(compiler *jsonschema.Compiler) {
+	if v.policy.AssertFormats {
+		compiler.AssertFormat = true
+	}
+}
+
+// annotationKeywords describe a schema without constraining instances
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$defs": true, "definitions": true,
+	"title": true, "description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// SetPolicy sets the schema policy. Schemas compiled before the change keep
+// their format behavior while cached, so set it before serving requests.
+func (v *Validator) SetPolicy(policy Policy) {
+	v.policy = policy
+}
+
+// checkPolicy applies the root-type and permissiveness rules to a schema
+// that has already compiled
+func (v *Validator) checkPolicy(schemaBytes json.RawMessage) error {
+	if !v.policy.RequireObjectRoot && !v.policy.RejectPermissive {
+		return nil
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(schemaBytes, &root); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	rootMap, isObject := root.(map[string]interface{})
+
+	if v.policy.RequireObjectRoot && (!isObject || !typeIsObject(rootMap["type"])) {
+		return ErrRootNotObject
+	}
+	if v.policy.RejectPermissive {
+		if !isObject {
+			// A boolean schema either accepts or rejects everything
+			return ErrPermissiveSchema
+		}
+		for keyword := range rootMap {
+			if keyword != "type" && !annotationKeywords[keyword] {
+				return nil
+			}
+		}
+		return ErrPermissiveSchema
+	}
+	return nil
+}
+
+// typeIsObject reports whether a "type" keyword value allows only objects
+func typeIsObject(value interface{}) bool {
+	switch t := value.(type) {
+	case string:
+		return t == "object"
+	case []interface{}:
+		return len(t) == 1 && t[0] == "object"
+	}
+	return false
+}
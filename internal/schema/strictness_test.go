@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestPolicy(t *testing.T) {
+	newValidator := func(policy Policy) *Validator {
+		v := NewValidatorWithLogger(10, logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard}))
+		v.SetPolicy(policy)
+		return v
+	}
+
+	t.Run("default_policy_accepts_any_schema", func(t *testing.T) {
+		v := newValidator(Policy{})
+		assert.NoError(t, v.ValidateSchema(json.RawMessage(`{}`)))
+		assert.NoError(t, v.ValidateSchema(json.RawMessage(`{"type": "array"}`)))
+	})
+
+	t.Run("require_object_root", func(t *testing.T) {
+		v := newValidator(Policy{RequireObjectRoot: true})
+		assert.NoError(t, v.ValidateSchema(json.RawMessage(`{"type": "object"}`)))
+		assert.ErrorIs(t, v.ValidateSchema(json.RawMessage(`{"type": "array"}`)), ErrRootNotObject)
+		assert.ErrorIs(t, v.ValidateSchema(json.RawMessage(`{"type": ["object", "null"]}`)), ErrRootNotObject)
+		assert.ErrorIs(t, v.ValidateSchema(json.RawMessage(`true`)), ErrRootNotObject)
+	})
+
+	t.Run("reject_permissive", func(t *testing.T) {
+		v := newValidator(Policy{RejectPermissive: true})
+		assert.ErrorIs(t, v.ValidateSchema(json.RawMessage(`{}`)), ErrPermissiveSchema)
+		assert.ErrorIs(t, v.ValidateSchema(json.RawMessage(`{"type": "object", "title": "Anything"}`)), ErrPermissiveSchema)
+		assert.ErrorIs(t, v.ValidateSchema(json.RawMessage(`true`)), ErrPermissiveSchema)
+		assert.NoError(t, v.ValidateSchema(json.RawMessage(`{"type": "object", "required": ["name"]}`)))
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		response := &types.ValidatedResponse{Data: json.RawMessage(`{"email": "not-an-email"}`)}
+		undeclared := json.RawMessage(`{"type": "object", "properties": {"email": {"type": "string", "format": "email"}}}`)
+		// The 2020-12 meta-schema makes format an annotation unless asserted
+		declared := json.RawMessage(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object", "properties": {"email": {"type": "string", "format": "email"}}}`)
+
+		// The default policy keeps the library's behavior
+		assert.Error(t, newValidator(Policy{}).ValidateResponse(undeclared, response))
+		assert.NoError(t, newValidator(Policy{}).ValidateResponse(declared, response))
+
+		assert.Error(t, newValidator(Policy{AssertFormats: true}).ValidateResponse(undeclared, response))
+		assert.Error(t, newValidator(Policy{AssertFormats: true}).ValidateResponse(declared, response))
+	})
+}
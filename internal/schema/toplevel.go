@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// TopLevelTypeError reports a response whose top-level JSON type is not one
+// the schema allows, such as an array where an object is expected. It is
+// returned before full validation, whose errors for this case are noisier.
+type TopLevelTypeError struct {
+	Expected []string
+	Actual   string
+}
+
+func (e *TopLevelTypeError) Error() string {
+	return fmt.Sprintf("expected %s, got %s", strings.Join(e.Expected, " or "), e.Actual)
+}
+
+// checkTopLevelType compares the data's type with the schema's root "type"
+// keyword. Schemas without one, e.g. a root $ref, are left to full validation.
+func checkTopLevelType(schema *jsonschema.Schema, data interface{}) error {
+	if len(schema.Types) == 0 {
+		return nil
+	}
+	actual := jsonType(data)
+	for _, allowed := range schema.Types {
+		if allowed == actual || (allowed == "number" && actual == "integer") {
+			return nil
+		}
+	}
+	return &TopLevelTypeError{Expected: schema.Types, Actual: actual}
+}
+
+// jsonType names the JSON Schema type of decoded JSON, reporting whole
+// numbers as "integer"
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
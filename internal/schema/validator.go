@@ -3,9 +3,9 @@ package schema
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -13,53 +13,22 @@ import (
 	"github.com/wcygan/llm-json-parse/pkg/types"
 )
 
-// SchemaCache provides thread-safe caching of compiled JSON schemas
-type SchemaCache struct {
-	mu      sync.RWMutex
-	schemas map[string]*jsonschema.Schema
-	maxSize int
-}
-
-// NewSchemaCache creates a new schema cache with the given maximum size
-func NewSchemaCache(maxSize int) *SchemaCache {
-	return &SchemaCache{
-		schemas: make(map[string]*jsonschema.Schema),
-		maxSize: maxSize,
-	}
-}
-
-// Get retrieves a compiled schema from the cache
-func (sc *SchemaCache) Get(key string) (*jsonschema.Schema, bool) {
-	sc.mu.RLock()
-	defer sc.mu.RUnlock()
-	schema, exists := sc.schemas[key]
-	return schema, exists
-}
-
-// Set stores a compiled schema in the cache
-func (sc *SchemaCache) Set(key string, schema *jsonschema.Schema) {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-
-	// Simple eviction: if at capacity, clear the cache
-	// This is simple but effective for most use cases
-	if len(sc.schemas) >= sc.maxSize {
-		sc.schemas = make(map[string]*jsonschema.Schema)
-	}
-
-	sc.schemas[key] = schema
+type Validator struct {
+	cache     *SchemaCache
+	logger    *logging.Logger
+	namespace string
+	timeout   time.Duration
+	policy    Policy
 }
 
-// Size returns the current number of cached schemas
-func (sc *SchemaCache) Size() int {
-	sc.mu.RLock()
-	defer sc.mu.RUnlock()
-	return len(sc.schemas)
-}
+// ErrValidationTimeout is returned when validating a response takes longer
+// than the validator's timeout
+var ErrValidationTimeout = errors.New("validation timed out")
 
-type Validator struct {
-	cache  *SchemaCache
-	logger *logging.Logger
+// validateInstance validates data against a compiled schema; tests replace
+// it to simulate slow validation
+var validateInstance = func(schema *jsonschema.Schema, data interface{}) error {
+	return schema.Validate(data)
 }
 
 func NewValidator() *Validator {
@@ -77,6 +46,14 @@ func NewValidatorWithCacheSize(cacheSize int) *Validator {
 	}
 }
 
+// NewValidatorWithCache creates a validator backed by the given schema cache
+func NewValidatorWithCache(cache *SchemaCache, logger *logging.Logger) *Validator {
+	return &Validator{
+		cache:  cache,
+		logger: logger,
+	}
+}
+
 // NewValidatorWithLogger creates a validator with custom logger
 func NewValidatorWithLogger(cacheSize int, logger *logging.Logger) *Validator {
 	return &Validator{
@@ -85,7 +62,72 @@ func NewValidatorWithLogger(cacheSize int, logger *logging.Logger) *Validator {
 	}
 }
 
+// CacheStats returns the schema cache size and its hit and miss counts
+func (v *Validator) CacheStats() (size int, hits, misses int64) {
+	hits, misses = v.cache.Stats()
+	return v.cache.Size(), hits, misses
+}
+
+// SetTimeout bounds how long validating a single response may take; zero
+// means no limit. The underlying validator isn't cancellable, so a timed-out
+// validation keeps running in the background until it finishes.
+func (v *Validator) SetTimeout(timeout time.Duration) {
+	v.timeout = timeout
+}
+
+// WithNamespace returns a validator that shares this validator's cache but
+// keys its entries under the given namespace, isolating them from other namespaces
+func (v *Validator) WithNamespace(namespace string) *Validator {
+	nv := *v
+	nv.namespace = namespace
+	return &nv
+}
+
+// WithPolicy returns a validator that shares this validator's cache but
+// applies policy, e.g. a stricter one for a single request. Schemas compiled
+// under a different format policy are cached apart from this validator's.
+func (v *Validator) WithPolicy(policy Policy) *Validator {
+	nv := *v
+	if policy.AssertFormats != v.policy.AssertFormats {
+		nv.namespace = fmt.Sprintf("%s#assert-formats=%t", v.namespace, policy.AssertFormats)
+	}
+	nv.policy = policy
+	return &nv
+}
+
+// Hash returns the identifier used to key a schema in caches and allowlists
+func Hash(schemaBytes json.RawMessage) string {
+	hash := sha256.Sum256(schemaBytes)
+	return fmt.Sprintf("%x", hash[:16]) // Use first 16 bytes for shorter key
+}
+
+// ValidateResponse validates response.Data against the schema. When it fails
+// and the policy's null handling is strip or default, nulls the schema
+// doesn't allow are rewritten and the data validated again; on success
+// response.Data is replaced with the rewritten data.
 func (v *Validator) ValidateResponse(schemaBytes json.RawMessage, response *types.ValidatedResponse) error {
+	err := v.validateResponse(schemaBytes, response)
+	if err == nil || v.policy.Nulls == "" || v.policy.Nulls == NullsReject || errors.Is(err, ErrValidationTimeout) {
+		return err
+	}
+
+	rewritten, changed, rewriteErr := rewriteNulls(schemaBytes, response.Data, v.policy.Nulls)
+	if rewriteErr != nil || !changed {
+		return err
+	}
+	if err := v.validateResponse(schemaBytes, &types.ValidatedResponse{Data: rewritten}); err != nil {
+		return err
+	}
+	v.logger.WithComponent("schema_validator").
+		WithFields(map[string]interface{}{
+			"null_policy": v.policy.Nulls,
+		}).
+		Debug("Response valid after rewriting disallowed nulls")
+	response.Data = rewritten
+	return nil
+}
+
+func (v *Validator) validateResponse(schemaBytes json.RawMessage, response *types.ValidatedResponse) error {
 	start := time.Now()
 	schema, err := v.compileSchema(schemaBytes)
 	if err != nil {
@@ -113,8 +155,21 @@ func (v *Validator) ValidateResponse(schemaBytes json.RawMessage, response *type
 	}
 	parseDuration := time.Since(parseStart)
 
+	// A wrong top-level type gets a concise error instead of a full validation
+	if err := checkTopLevelType(schema, responseData); err != nil {
+		v.logger.WithComponent("schema_validator").
+			WithError(err).
+			WithDuration(time.Since(start)).
+			WithFields(map[string]interface{}{
+				"response_size_bytes": len(response.Data),
+				"validation_success":  false,
+			}).
+			Warn("Response has the wrong top-level type")
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
 	validateStart := time.Now()
-	if err := schema.Validate(responseData); err != nil {
+	if err := v.validateWithTimeout(schema, responseData); err != nil {
 		validateDuration := time.Since(validateStart)
 		totalDuration := time.Since(start)
 
@@ -132,6 +187,20 @@ func (v *Validator) ValidateResponse(schemaBytes json.RawMessage, response *type
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
+	if v.policy.StrictIntegers {
+		if err := checkIntegerLiterals(schemaBytes, response.Data); err != nil {
+			v.logger.WithComponent("schema_validator").
+				WithError(err).
+				WithDuration(time.Since(start)).
+				WithFields(map[string]interface{}{
+					"response_size_bytes": len(response.Data),
+					"validation_success":  false,
+				}).
+				Warn("Response has a non-integer literal for an integer")
+			return fmt.Errorf("validation failed: %w", err)
+		}
+	}
+
 	// Success
 	totalDuration := time.Since(start)
 	validateDuration := time.Since(validateStart)
@@ -149,6 +218,28 @@ func (v *Validator) ValidateResponse(schemaBytes json.RawMessage, response *type
 	return nil
 }
 
+// validateWithTimeout runs schema validation, giving up after v.timeout
+func (v *Validator) validateWithTimeout(schema *jsonschema.Schema, data interface{}) error {
+	if v.timeout <= 0 {
+		return validateInstance(schema, data)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- validateInstance(schema, data)
+	}()
+
+	timer := time.NewTimer(v.timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w after %v", ErrValidationTimeout, v.timeout)
+	}
+}
+
 func (v *Validator) ValidateSchema(schemaBytes json.RawMessage) error {
 	start := time.Now()
 	_, err := v.compileSchema(schemaBytes)
@@ -162,6 +253,15 @@ func (v *Validator) ValidateSchema(schemaBytes json.RawMessage) error {
 			Error("Schema validation failed")
 		return fmt.Errorf("invalid schema: %w", err)
 	}
+	if err := v.checkPolicy(schemaBytes); err != nil {
+		v.logger.WithComponent("schema_validator").
+			WithError(err).
+			WithFields(map[string]interface{}{
+				"schema_size_bytes": len(schemaBytes),
+			}).
+			Warn("Schema rejected by validation policy")
+		return fmt.Errorf("invalid schema: %w", err)
+	}
 	v.logger.WithComponent("schema_validator").
 		WithDuration(time.Since(start)).
 		WithFields(map[string]interface{}{
@@ -173,8 +273,11 @@ func (v *Validator) ValidateSchema(schemaBytes json.RawMessage) error {
 
 func (v *Validator) compileSchema(schemaBytes json.RawMessage) (*jsonschema.Schema, error) {
 	// Generate cache key based on schema content
-	hash := sha256.Sum256(schemaBytes)
-	cacheKey := fmt.Sprintf("%x", hash[:16]) // Use first 16 bytes for shorter key
+	schemaHash := Hash(schemaBytes)
+	cacheKey := schemaHash
+	if v.namespace != "" {
+		cacheKey = v.namespace + ":" + schemaHash
+	}
 
 	// Check cache first
 	if schema, exists := v.cache.Get(cacheKey); exists {
@@ -199,9 +302,10 @@ func (v *Validator) compileSchema(schemaBytes json.RawMessage) (*jsonschema.Sche
 
 	// Create a new compiler for each validation to avoid conflicts
 	compiler := jsonschema.NewCompiler()
+	v.applyFormatPolicy(compiler)
 
 	// Generate unique URL based on schema content
-	schemaURL := fmt.Sprintf("https://example.com/schema-%s.json", cacheKey[:8])
+	schemaURL := fmt.Sprintf("https://example.com/schema-%s.json", schemaHash[:8])
 
 	// Add the schema as a resource to the compiler
 	if err := compiler.AddResource(schemaURL, strings.NewReader(string(schemaBytes))); err != nil {
@@ -224,8 +328,8 @@ func (v *Validator) compileSchema(schemaBytes json.RawMessage) (*jsonschema.Sche
 		return nil, fmt.Errorf("compile schema: %w", err)
 	}
 
-	// Store in cache for future use
-	v.cache.Set(cacheKey, schema)
+	// Store in cache for future use, weighted by how long it took to build
+	v.cache.SetWithCost(cacheKey, schema, compileDuration)
 
 	v.logger.WithComponent("schema_validator").
 		WithDuration(compileDuration).
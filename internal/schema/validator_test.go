@@ -2,8 +2,12 @@ package schema
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/wcygan/llm-json-parse/pkg/types"
@@ -159,10 +163,577 @@ func TestCacheEviction(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 2, validator.cache.Size())
 
-	// Adding third schema should trigger cache eviction (simple clear strategy)
+	// Adding third schema evicts only the least recently used one
 	err = validator.ValidateResponse(schemas[2], responses[2])
 	require.NoError(t, err)
 
-	// After eviction and adding new schema, size should be 1
+	assert.Equal(t, 2, validator.cache.Size())
+	_, exists := validator.cache.Get(Hash(schemas[0]))
+	assert.False(t, exists)
+	_, exists = validator.cache.Get(Hash(schemas[1]))
+	assert.True(t, exists)
+}
+
+func TestLRUEviction(t *testing.T) {
+	cache := NewSchemaCache(2)
+	compiled := jsonschema.MustCompileString("test.json", `{"type": "object"}`)
+
+	cache.Set("a", compiled)
+	cache.Set("b", compiled)
+
+	// Reading "a" makes "b" the least recently used
+	_, exists := cache.Get("a")
+	require.True(t, exists)
+	cache.Set("c", compiled)
+
+	assert.Equal(t, 2, cache.Size())
+	_, exists = cache.Get("b")
+	assert.False(t, exists)
+	_, exists = cache.Get("a")
+	assert.True(t, exists)
+	_, exists = cache.Get("c")
+	assert.True(t, exists)
+}
+
+func TestClearEviction(t *testing.T) {
+	cache := NewSchemaCacheWithPolicy(2, EvictionClear)
+	compiled := jsonschema.MustCompileString("test.json", `{"type": "object"}`)
+
+	cache.Set("a", compiled)
+	cache.Set("b", compiled)
+	cache.Set("c", compiled)
+
+	assert.Equal(t, 1, cache.Size())
+	_, exists := cache.Get("c")
+	assert.True(t, exists)
+}
+
+func TestValidatorNamespaces(t *testing.T) {
+	validator := NewValidator()
+	schemaJSON := json.RawMessage(`{"type": "object", "properties": {"name": {"type": "string"}}}`)
+
+	require.NoError(t, validator.ValidateSchema(schemaJSON))
 	assert.Equal(t, 1, validator.cache.Size())
+
+	// The same schema in another namespace gets its own cache entry
+	tenantValidator := validator.WithNamespace("acme")
+	require.NoError(t, tenantValidator.ValidateSchema(schemaJSON))
+	assert.Equal(t, 2, validator.cache.Size())
+
+	_, exists := validator.cache.Get("acme:" + Hash(schemaJSON))
+	assert.True(t, exists)
+}
+
+func TestCostWeightedEviction(t *testing.T) {
+	cache := NewSchemaCacheWithPolicy(3, EvictionCost)
+	compiled := jsonschema.MustCompileString("test.json", `{"type": "object"}`)
+
+	cache.SetWithCost("expensive", compiled, 200*time.Millisecond)
+	cache.SetWithCost("cheap", compiled, 1*time.Millisecond)
+	cache.SetWithCost("hot", compiled, 1*time.Millisecond)
+
+	// Keep "hot" recently used; "expensive" and "cheap" are the cold half
+	_, exists := cache.Get("hot")
+	require.True(t, exists)
+
+	// Inserting under pressure evicts the cheap cold entry even though the
+	// expensive one is older
+	cache.SetWithCost("new", compiled, 5*time.Millisecond)
+
+	assert.Equal(t, 3, cache.Size())
+	_, exists = cache.Get("expensive")
+	assert.True(t, exists, "expensive cold schema should survive eviction")
+	_, exists = cache.Get("cheap")
+	assert.False(t, exists, "cheap cold schema should be evicted first")
+	_, exists = cache.Get("hot")
+	assert.True(t, exists)
+	_, exists = cache.Get("new")
+	assert.True(t, exists)
+}
+
+func TestCostWeightedEvictionSparesHotEntries(t *testing.T) {
+	cache := NewSchemaCacheWithPolicy(2, EvictionCost)
+	compiled := jsonschema.MustCompileString("test.json", `{"type": "object"}`)
+
+	cache.SetWithCost("cold-expensive", compiled, 100*time.Millisecond)
+	cache.SetWithCost("hot-cheap", compiled, 1*time.Millisecond)
+	cache.Get("hot-cheap")
+
+	// The cheap entry is hot, so the only cold candidate is evicted
+	cache.SetWithCost("new", compiled, 1*time.Millisecond)
+
+	_, exists := cache.Get("hot-cheap")
+	assert.True(t, exists)
+	_, exists = cache.Get("cold-expensive")
+	assert.False(t, exists)
+}
+
+func TestCollectWarnings(t *testing.T) {
+	validator := NewValidator()
+	schemaJSON := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"nickname": {"type": "string", "deprecated": true},
+			"tags": {"type": "array", "items": {"type": "object", "properties": {"label": {"type": "string"}}}}
+		}
+	}`)
+
+	t.Run("advisory_issues_are_warnings_not_errors", func(t *testing.T) {
+		response := &types.ValidatedResponse{Data: json.RawMessage(`{"name": "Jane", "nickname": "JJ", "extra": 1, "tags": [{"label": "a", "color": "red"}]}`)}
+
+		require.NoError(t, validator.ValidateResponse(schemaJSON, response))
+
+		warnings, err := validator.CollectWarnings(schemaJSON, response)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"/extra: property is not declared in schema",
+			"/nickname: property is deprecated",
+			"/tags/0/color: property is not declared in schema",
+		}, warnings)
+	})
+
+	t.Run("clean_response_has_no_warnings", func(t *testing.T) {
+		response := &types.ValidatedResponse{Data: json.RawMessage(`{"name": "Jane", "tags": [{"label": "a"}]}`)}
+
+		warnings, err := validator.CollectWarnings(schemaJSON, response)
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("explicit_additional_properties_still_warns", func(t *testing.T) {
+		openSchema := json.RawMessage(`{"type": "object", "properties": {"name": {"type": "string"}}, "additionalProperties": true}`)
+		response := &types.ValidatedResponse{Data: json.RawMessage(`{"name": "Jane", "extra": 1}`)}
+
+		warnings, err := validator.CollectWarnings(openSchema, response)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/extra: property is not declared in schema"}, warnings)
+	})
+}
+
+func TestViolations(t *testing.T) {
+	validator := NewValidator()
+	schemaJSON := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"tags": {"type": "array", "items": {"type": "string"}, "minItems": 2, "maxItems": 3, "uniqueItems": true}
+		}
+	}`)
+
+	violationsFor := func(t *testing.T, data string) []types.Violation {
+		response := &types.ValidatedResponse{Data: json.RawMessage(data)}
+		err := validator.ValidateResponse(schemaJSON, response)
+		require.Error(t, err)
+		return Violations(err, schemaJSON, response.Data)
+	}
+
+	t.Run("too_short_array", func(t *testing.T) {
+		violations := violationsFor(t, `{"tags": ["go"]}`)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "/tags", violations[0].Path)
+		assert.Equal(t, "minItems", violations[0].Keyword)
+		assert.Equal(t, 1, violations[0].Actual)
+		assert.Equal(t, 2, violations[0].Expected)
+		assert.Equal(t, "array at /tags has 1 items, minimum is 2", violations[0].Message)
+	})
+
+	t.Run("too_long_array", func(t *testing.T) {
+		violations := violationsFor(t, `{"tags": ["a", "b", "c", "d"]}`)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "maxItems", violations[0].Keyword)
+		assert.Equal(t, 4, violations[0].Actual)
+		assert.Equal(t, 3, violations[0].Expected)
+	})
+
+	t.Run("duplicate_items", func(t *testing.T) {
+		violations := violationsFor(t, `{"tags": ["go", "rust", "go"]}`)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "/tags", violations[0].Path)
+		assert.Equal(t, "uniqueItems", violations[0].Keyword)
+		assert.Equal(t, []int{0, 2}, violations[0].DuplicateIndices)
+		assert.Equal(t, "array at /tags has duplicate items at indices 0 and 2", violations[0].Message)
+	})
+
+	t.Run("other_keywords_keep_library_message", func(t *testing.T) {
+		violations := violationsFor(t, `{"tags": ["go", 7]}`)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "/tags/1", violations[0].Path)
+		assert.Equal(t, "type", violations[0].Keyword)
+		assert.NotEmpty(t, violations[0].Message)
+		assert.Nil(t, violations[0].Actual)
+	})
+
+	t.Run("non_validation_error", func(t *testing.T) {
+		assert.Nil(t, Violations(assert.AnError, schemaJSON, nil))
+	})
+}
+
+func TestMissingRequired(t *testing.T) {
+	validator := NewValidator()
+	schemaJSON := json.RawMessage(`{
+		"type": "object",
+		"required": ["name", "address"],
+		"properties": {
+			"name": {"type": "string"},
+			"address": {
+				"type": "object",
+				"required": ["street", "city", "zip/code"],
+				"properties": {"street": {"type": "string"}, "city": {"type": "string"}}
+			},
+			"contacts": {"type": "array", "items": {"type": "object", "required": ["email"]}}
+		}
+	}`)
+
+	missingFor := func(t *testing.T, data string) []string {
+		response := &types.ValidatedResponse{Data: json.RawMessage(data)}
+		err := validator.ValidateResponse(schemaJSON, response)
+		require.Error(t, err)
+		return MissingRequired(err, schemaJSON, response.Data)
+	}
+
+	t.Run("top_level", func(t *testing.T) {
+		assert.Equal(t, []string{"/name", "/address"}, missingFor(t, `{}`))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		missing := missingFor(t, `{"name": "Jane", "address": {"street": "Main St"}}`)
+		assert.Equal(t, []string{"/address/city", "/address/zip~1code"}, missing)
+	})
+
+	t.Run("array_items", func(t *testing.T) {
+		missing := missingFor(t, `{"name": "Jane", "address": {"street": "a", "city": "b", "zip/code": "c"}, "contacts": [{"email": "x"}, {}]}`)
+		assert.Equal(t, []string{"/contacts/1/email"}, missing)
+	})
+
+	t.Run("other_failures", func(t *testing.T) {
+		assert.Nil(t, missingFor(t, `{"name": 7, "address": {"street": "a", "city": "b", "zip/code": "c"}}`))
+		assert.Nil(t, MissingRequired(assert.AnError, schemaJSON, nil))
+	})
+}
+
+func TestNumericViolations(t *testing.T) {
+	validator := NewValidator()
+	schemaJSON := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"price": {"type": "number", "minimum": 10, "maximum": 300},
+			"quantity": {"type": "integer", "multipleOf": 5},
+			"discount": {"type": "number", "exclusiveMaximum": 0.5}
+		}
+	}`)
+
+	violationFor := func(t *testing.T, data string) types.Violation {
+		response := &types.ValidatedResponse{Data: json.RawMessage(data)}
+		err := validator.ValidateResponse(schemaJSON, response)
+		require.Error(t, err)
+		violations := Violations(err, schemaJSON, response.Data)
+		require.Len(t, violations, 1)
+		return violations[0]
+	}
+
+	t.Run("below_minimum", func(t *testing.T) {
+		violation := violationFor(t, `{"price": 2.5}`)
+		assert.Equal(t, "/price", violation.Path)
+		assert.Equal(t, "minimum", violation.Keyword)
+		assert.Equal(t, 2.5, violation.Actual)
+		assert.Equal(t, float64(10), violation.Expected)
+		assert.Equal(t, "value 2.5 at /price is below minimum 10", violation.Message)
+	})
+
+	t.Run("above_maximum", func(t *testing.T) {
+		violation := violationFor(t, `{"price": 350}`)
+		assert.Equal(t, "maximum", violation.Keyword)
+		assert.Equal(t, float64(350), violation.Actual)
+		assert.Equal(t, float64(300), violation.Expected)
+		assert.Equal(t, "value 350 at /price exceeds maximum 300", violation.Message)
+	})
+
+	t.Run("not_multiple_of", func(t *testing.T) {
+		violation := violationFor(t, `{"quantity": 7}`)
+		assert.Equal(t, "multipleOf", violation.Keyword)
+		assert.Equal(t, "value 7 at /quantity is not a multiple of 5", violation.Message)
+	})
+
+	t.Run("exclusive_maximum", func(t *testing.T) {
+		violation := violationFor(t, `{"discount": 0.5}`)
+		assert.Equal(t, "exclusiveMaximum", violation.Keyword)
+		assert.Equal(t, "value 0.5 at /discount must be less than 0.5", violation.Message)
+	})
+}
+
+func TestAllowedValueViolations(t *testing.T) {
+	validator := NewValidator()
+	schemaJSON := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"status": {"type": "string", "enum": ["active", "closed"]},
+			"version": {"const": 2},
+			"priority": {"$ref": "#/$defs/priority"}
+		},
+		"$defs": {"priority": {"enum": [1, 2, 3]}}
+	}`)
+
+	violationFor := func(t *testing.T, data string) types.Violation {
+		response := &types.ValidatedResponse{Data: json.RawMessage(data)}
+		err := validator.ValidateResponse(schemaJSON, response)
+		require.Error(t, err)
+		violations := Violations(err, schemaJSON, response.Data)
+		require.Len(t, violations, 1)
+		return violations[0]
+	}
+
+	t.Run("enum", func(t *testing.T) {
+		violation := violationFor(t, `{"status": "pending"}`)
+		assert.Equal(t, "/status", violation.Path)
+		assert.Equal(t, "enum", violation.Keyword)
+		assert.Equal(t, "pending", violation.Actual)
+		assert.Equal(t, []interface{}{"active", "closed"}, violation.Expected)
+		assert.Equal(t, `value "pending" at /status is not one of ["active", "closed"]`, violation.Message)
+	})
+
+	t.Run("enum_behind_reference", func(t *testing.T) {
+		violation := violationFor(t, `{"priority": 5}`)
+		assert.Equal(t, "/priority", violation.Path)
+		assert.Equal(t, "enum", violation.Keyword)
+		assert.Equal(t, float64(5), violation.Actual)
+		assert.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, violation.Expected)
+		assert.Equal(t, "value 5 at /priority is not one of [1, 2, 3]", violation.Message)
+	})
+
+	t.Run("const", func(t *testing.T) {
+		violation := violationFor(t, `{"version": 1}`)
+		assert.Equal(t, "/version", violation.Path)
+		assert.Equal(t, "const", violation.Keyword)
+		assert.Equal(t, float64(1), violation.Actual)
+		assert.Equal(t, float64(2), violation.Expected)
+		assert.Equal(t, "value 1 at /version must be 2", violation.Message)
+	})
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	t.Run("no_duplicates", func(t *testing.T) {
+		duplicates, err := DuplicateKeys(json.RawMessage(`{"a": 1, "b": {"a": 2}, "c": [{"a": 3}, {"a": 4}]}`))
+		require.NoError(t, err)
+		assert.Empty(t, duplicates)
+	})
+
+	t.Run("nested_duplicates", func(t *testing.T) {
+		duplicates, err := DuplicateKeys(json.RawMessage(`{"a": 1, "a": 2, "b": {"x": [{"y": 1, "y": 2}], "x": null}, "c/d": 1, "c/d": 2}`))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/a", "/b/x/0/y", "/b/x", "/c~1d"}, duplicates)
+	})
+
+	t.Run("invalid_json", func(t *testing.T) {
+		_, err := DuplicateKeys(json.RawMessage(`{"a": `))
+		assert.Error(t, err)
+	})
+}
+
+func TestTopLevelTypeCheck(t *testing.T) {
+	validator := NewValidator()
+	objectSchema := json.RawMessage(`{"type": "object", "required": ["name"]}`)
+
+	validate := func(schemaJSON json.RawMessage, data string) error {
+		return validator.ValidateResponse(schemaJSON, &types.ValidatedResponse{Data: json.RawMessage(data)})
+	}
+
+	t.Run("array_when_object_expected", func(t *testing.T) {
+		err := validate(objectSchema, `[{"name": "Jane"}]`)
+		require.Error(t, err)
+
+		var typeErr *TopLevelTypeError
+		require.ErrorAs(t, err, &typeErr)
+		assert.Equal(t, "array", typeErr.Actual)
+		assert.Equal(t, "validation failed: expected object, got array", err.Error())
+	})
+
+	t.Run("scalar_when_object_expected", func(t *testing.T) {
+		err := validate(objectSchema, `"Jane"`)
+		assert.EqualError(t, err, "validation failed: expected object, got string")
+
+		err = validate(objectSchema, `null`)
+		assert.EqualError(t, err, "validation failed: expected object, got null")
+	})
+
+	t.Run("union_types", func(t *testing.T) {
+		err := validate(json.RawMessage(`{"type": ["object", "array"]}`), `42`)
+		assert.EqualError(t, err, "validation failed: expected object or array, got integer")
+		assert.NoError(t, validate(json.RawMessage(`{"type": ["object", "array"]}`), `[]`))
+	})
+
+	t.Run("integer_satisfies_number", func(t *testing.T) {
+		assert.NoError(t, validate(json.RawMessage(`{"type": "number"}`), `42`))
+		err := validate(json.RawMessage(`{"type": "integer"}`), `4.5`)
+		assert.EqualError(t, err, "validation failed: expected integer, got number")
+	})
+
+	t.Run("matching_type_runs_full_validation", func(t *testing.T) {
+		err := validate(objectSchema, `{}`)
+		require.Error(t, err)
+		var typeErr *TopLevelTypeError
+		assert.False(t, errors.As(err, &typeErr))
+	})
+
+	t.Run("schema_without_type_is_not_checked", func(t *testing.T) {
+		assert.NoError(t, validate(json.RawMessage(`{"minLength": 1}`), `[1]`))
+	})
+
+	t.Run("reported_as_single_violation", func(t *testing.T) {
+		data := json.RawMessage(`[1, 2]`)
+		err := validate(objectSchema, string(data))
+		violations := Violations(err, objectSchema, data)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "/", violations[0].Path)
+		assert.Equal(t, "type", violations[0].Keyword)
+		assert.Equal(t, "array", violations[0].Actual)
+		assert.Equal(t, "object", violations[0].Expected)
+	})
+}
+
+func TestSelfCheck(t *testing.T) {
+	assert.NoError(t, NewValidator().SelfCheck())
+}
+
+func TestNormalize(t *testing.T) {
+	validator := NewValidator()
+
+	schemaJSON := json.RawMessage(`{
+		"type": "object",
+		"required": ["owner"],
+		"properties": {
+			"owner": {"$ref": "#/$defs/person"},
+			"pets": {"type": "array", "items": {"$ref": "#/$defs/pet", "description": "A pet"}}
+		},
+		"$defs": {
+			"person": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}},
+			"pet": {"type": "object", "properties": {"species": {"enum": ["cat", "dog"]}}}
+		}
+	}`)
+
+	t.Run("sorts_keys_and_inlines_local_refs", func(t *testing.T) {
+		normalized, err := validator.Normalize(schemaJSON)
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"properties":{"owner":{"properties":{"name":{"type":"string"}},"required":["name"],"type":"object"},`+
+			`"pets":{"items":{"allOf":[{"properties":{"species":{"enum":["cat","dog"]}},"type":"object"}],"description":"A pet"},"type":"array"}},`+
+			`"required":["owner"],"type":"object"}`, string(normalized))
+	})
+
+	t.Run("stable_across_key_order_and_repeated_runs", func(t *testing.T) {
+		reordered := json.RawMessage(`{"$defs": {"pet": {"properties": {"species": {"enum": ["cat", "dog"]}}, "type": "object"},
+			"person": {"properties": {"name": {"type": "string"}}, "required": ["name"], "type": "object"}},
+			"properties": {"pets": {"items": {"description": "A pet", "$ref": "#/$defs/pet"}, "type": "array"}, "owner": {"$ref": "#/$defs/person"}},
+			"required": ["owner"], "type": "object"}`)
+
+		first, err := validator.Normalize(schemaJSON)
+		require.NoError(t, err)
+		second, err := validator.Normalize(reordered)
+		require.NoError(t, err)
+		again, err := validator.Normalize(first)
+		require.NoError(t, err)
+
+		assert.Equal(t, string(first), string(second))
+		assert.Equal(t, string(first), string(again))
+	})
+
+	t.Run("semantically_equivalent", func(t *testing.T) {
+		normalized, err := validator.Normalize(schemaJSON)
+		require.NoError(t, err)
+
+		documents := []string{
+			`{"owner": {"name": "Jane"}, "pets": [{"species": "cat"}]}`,
+			`{"owner": {}}`,
+			`{"owner": {"name": "Jane"}, "pets": [{"species": "fish"}]}`,
+			`{"pets": []}`,
+		}
+		for _, doc := range documents {
+			response := &types.ValidatedResponse{Data: json.RawMessage(doc)}
+			original := validator.ValidateResponse(schemaJSON, response)
+			result := validator.ValidateResponse(normalized, response)
+			assert.Equal(t, original == nil, result == nil, doc)
+		}
+	})
+
+	t.Run("recursive_refs_kept", func(t *testing.T) {
+		recursive := json.RawMessage(`{"$ref": "#/$defs/node", "$defs": {"node": {"type": "object", "properties": {"child": {"$ref": "#/$defs/node"}}}}}`)
+
+		normalized, err := validator.Normalize(recursive)
+		require.NoError(t, err)
+		assert.Contains(t, string(normalized), `"$ref":"#/$defs/node"`)
+		assert.Contains(t, string(normalized), `"$defs"`)
+		assert.NoError(t, validator.ValidateSchema(normalized))
+	})
+
+	t.Run("invalid_schema_rejected", func(t *testing.T) {
+		_, err := validator.Normalize(json.RawMessage(`{"type": 12}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("exponential_expansion_refused", func(t *testing.T) {
+		_, err := validator.Normalize(doublingSchema(40))
+		assert.ErrorIs(t, err, ErrSchemaTooLarge)
+	})
+}
+
+// doublingSchema returns a schema whose definitions each reference the next
+// one twice, so inlining it takes 2^depth copies of the last definition
+func doublingSchema(depth int) json.RawMessage {
+	defs := make(map[string]interface{}, depth+1)
+	for i := 0; i < depth; i++ {
+		next := map[string]interface{}{"$ref": fmt.Sprintf("#/$defs/d%d", i+1)}
+		defs[fmt.Sprintf("d%d", i)] = map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"a": next, "b": next},
+		}
+	}
+	defs[fmt.Sprintf("d%d", depth)] = map[string]interface{}{"type": "integer", "default": 1}
+
+	schemaBytes, _ := json.Marshal(map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"root": map[string]interface{}{"$ref": "#/$defs/d0"}},
+		"$defs":      defs,
+	})
+	return schemaBytes
+}
+
+func TestValidationTimeout(t *testing.T) {
+	schemaBytes := json.RawMessage(`{"type": "object"}`)
+	response := &types.ValidatedResponse{Data: json.RawMessage(`{"name": "John"}`)}
+
+	original := validateInstance
+	defer func() { validateInstance = original }()
+	validateInstance = func(schema *jsonschema.Schema, data interface{}) error {
+		time.Sleep(50 * time.Millisecond)
+		return original(schema, data)
+	}
+
+	t.Run("slow validation times out", func(t *testing.T) {
+		validator := NewValidator()
+		validator.SetTimeout(5 * time.Millisecond)
+
+		err := validator.ValidateResponse(schemaBytes, response)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrValidationTimeout)
+	})
+
+	t.Run("validation within timeout completes", func(t *testing.T) {
+		validator := NewValidator()
+		validator.SetTimeout(5 * time.Second)
+
+		assert.NoError(t, validator.ValidateResponse(schemaBytes, response))
+	})
+
+	t.Run("zero timeout disables the limit", func(t *testing.T) {
+		validator := NewValidator()
+
+		assert.NoError(t, validator.ValidateResponse(schemaBytes, response))
+	})
+
+	t.Run("timeout applies to namespaced validators", func(t *testing.T) {
+		validator := NewValidator()
+		validator.SetTimeout(5 * time.Millisecond)
+
+		err := validator.WithNamespace("tenant").ValidateResponse(schemaBytes, response)
+		assert.ErrorIs(t, err, ErrValidationTimeout)
+	})
 }
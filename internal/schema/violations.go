@@ -0,0 +1,275 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// Violations breaks a ValidateResponse error into one entry per failed
+// constraint. Array size, uniqueness and numeric range failures are enriched
+// with the actual and required values, which the underlying error messages
+// leave implicit, and enum and const failures with the offending value and
+// the values allowed. A wrong top-level type yields a single violation at "/",
+// and a non-integer literal in strict integer mode one at its path.
+// It returns nil when err did not come from schema validation.
+func Violations(err error, schemaBytes, data json.RawMessage) []types.Violation {
+	var typeErr *TopLevelTypeError
+	if errors.As(err, &typeErr) {
+		return []types.Violation{{
+			Path:     "/",
+			Keyword:  "type",
+			Message:  typeErr.Error(),
+			Actual:   typeErr.Actual,
+			Expected: strings.Join(typeErr.Expected, " or "),
+		}}
+	}
+
+	var literalErr *IntegerLiteralError
+	if errors.As(err, &literalErr) {
+		return []types.Violation{{
+			Path:     pathOrRoot(literalErr.Path),
+			Keyword:  "type",
+			Message:  literalErr.Error(),
+			Actual:   json.Number(literalErr.Literal),
+			Expected: "integer",
+		}}
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+
+	var schemaDoc, instance interface{}
+	_ = json.Unmarshal(schemaBytes, &schemaDoc)
+	_ = json.Unmarshal(data, &instance)
+
+	var violations []types.Violation
+	for _, leaf := range leafErrors(validationErr) {
+		violations = append(violations, newViolation(leaf, schemaDoc, instance))
+	}
+	return violations
+}
+
+// MissingRequired lists the JSON pointer of every property a ValidateResponse
+// error reports as required but absent, e.g. "/address/city", in the order
+// the schema requires them. It returns nil when err did not come from schema
+// validation or nothing required is missing.
+func MissingRequired(err error, schemaBytes, data json.RawMessage) []string {
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+
+	var schemaDoc, instance interface{}
+	_ = json.Unmarshal(schemaBytes, &schemaDoc)
+	_ = json.Unmarshal(data, &instance)
+
+	var missing []string
+	for _, leaf := range leafErrors(validationErr) {
+		if keywordName(leaf.KeywordLocation) != "required" {
+			continue
+		}
+		object, _ := resolvePointer(instance, leaf.InstanceLocation)
+		fields, _ := object.(map[string]interface{})
+		required, _ := keywordValue(schemaDoc, leaf.AbsoluteKeywordLocation).([]interface{})
+		for _, name := range required {
+			name, ok := name.(string)
+			if !ok {
+				continue
+			}
+			if _, present := fields[name]; !present {
+				missing = append(missing, leaf.InstanceLocation+"/"+escapePointer(name))
+			}
+		}
+	}
+	return missing
+}
+
+func newViolation(leaf *jsonschema.ValidationError, schemaDoc, instance interface{}) types.Violation {
+	path := leaf.InstanceLocation
+	if path == "" {
+		path = "/"
+	}
+	violation := types.Violation{
+		Path:    path,
+		Keyword: keywordName(leaf.KeywordLocation),
+		Message: leaf.Message,
+	}
+
+	value, _ := resolvePointer(instance, leaf.InstanceLocation)
+	if violation.Keyword == "enum" || violation.Keyword == "const" {
+		describeAllowed(&violation, value, keywordValue(schemaDoc, leaf.AbsoluteKeywordLocation))
+		return violation
+	}
+	switch value := value.(type) {
+	case []interface{}:
+		describeArray(&violation, value, keywordValue(schemaDoc, leaf.AbsoluteKeywordLocation))
+	case float64:
+		describeNumber(&violation, value, keywordValue(schemaDoc, leaf.AbsoluteKeywordLocation))
+	}
+	return violation
+}
+
+// describeArray fills in array size and uniqueness details
+func describeArray(violation *types.Violation, items []interface{}, keyword interface{}) {
+	switch violation.Keyword {
+	case "minItems", "maxItems":
+		limit, ok := keyword.(float64)
+		if !ok {
+			return
+		}
+		bound := "minimum"
+		if violation.Keyword == "maxItems" {
+			bound = "maximum"
+		}
+		violation.Actual = len(items)
+		violation.Expected = int(limit)
+		violation.Message = fmt.Sprintf("array at %s has %d items, %s is %d", violation.Path, len(items), bound, int(limit))
+	case "uniqueItems":
+		if first, second, ok := findDuplicate(items); ok {
+			violation.DuplicateIndices = []int{first, second}
+			violation.Message = fmt.Sprintf("array at %s has duplicate items at indices %d and %d", violation.Path, first, second)
+		}
+	}
+}
+
+// describeAllowed fills in the value and the allowed values of enum and
+// const failures
+func describeAllowed(violation *types.Violation, value, keyword interface{}) {
+	if keyword == nil {
+		return
+	}
+	violation.Actual = value
+	violation.Expected = keyword
+	if violation.Keyword == "const" {
+		violation.Message = fmt.Sprintf("value %s at %s must be %s", formatJSON(value), violation.Path, formatJSON(keyword))
+		return
+	}
+	allowed, ok := keyword.([]interface{})
+	if !ok {
+		return
+	}
+	values := make([]string, len(allowed))
+	for i, v := range allowed {
+		values[i] = formatJSON(v)
+	}
+	violation.Message = fmt.Sprintf("value %s at %s is not one of [%s]", formatJSON(value), violation.Path, strings.Join(values, ", "))
+}
+
+// formatJSON renders a decoded value as JSON
+func formatJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// describeNumber fills in the value and violated bound of numeric range failures
+func describeNumber(violation *types.Violation, value float64, keyword interface{}) {
+	limit, ok := keyword.(float64)
+	if !ok {
+		return
+	}
+
+	var relation string
+	switch violation.Keyword {
+	case "minimum":
+		relation = "is below minimum"
+	case "maximum":
+		relation = "exceeds maximum"
+	case "exclusiveMinimum":
+		relation = "must be greater than"
+	case "exclusiveMaximum":
+		relation = "must be less than"
+	case "multipleOf":
+		relation = "is not a multiple of"
+	default:
+		return
+	}
+	violation.Actual = value
+	violation.Expected = limit
+	violation.Message = fmt.Sprintf("value %s at %s %s %s", formatNumber(value), violation.Path, relation, formatNumber(limit))
+}
+
+// formatNumber renders a number as it would appear in JSON
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// leafErrors flattens a validation error tree into its most specific causes
+func leafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
+
+// keywordName returns the final keyword of a keyword location
+func keywordName(location string) string {
+	return location[strings.LastIndex(location, "/")+1:]
+}
+
+// keywordValue reads the failing keyword's value from the schema document.
+// Schemas are compiled as a single resource, so the fragment of the absolute
+// keyword location is a pointer into the request's schema.
+func keywordValue(schemaDoc interface{}, absoluteLocation string) interface{} {
+	_, fragment, found := strings.Cut(absoluteLocation, "#")
+	if !found {
+		return nil
+	}
+	value, _ := resolvePointer(schemaDoc, fragment)
+	return value
+}
+
+// resolvePointer follows a JSON pointer through decoded JSON
+func resolvePointer(doc interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	current := doc
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// findDuplicate returns the indices of the first pair of equal items
+func findDuplicate(items []interface{}) (int, int, bool) {
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			if reflect.DeepEqual(items[i], items[j]) {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
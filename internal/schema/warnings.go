@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// CollectWarnings reports advisory issues in a response that already passed
+// validation. Warnings never fail a request; they flag properties the schema
+// marks as deprecated, properties the schema does not declare unless
+// additionalProperties is false and, when the policy ignores formats, values
+// that do not match their "format".
+func (v *Validator) CollectWarnings(schemaBytes json.RawMessage, response *types.ValidatedResponse) ([]string, error) {
+	found, err := v.Warnings(schemaBytes, response.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, warning := range found {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", warning.Path, warning.Message))
+	}
+
+	if len(warnings) > 0 {
+		v.logger.WithComponent("schema_validator").
+			WithFields(map[string]interface{}{
+				"warning_count": len(warnings),
+				"warnings":      warnings,
+			}).
+			Info("Response passed validation with warnings")
+	}
+
+	return warnings, nil
+}
+
+// Warnings returns the advisory issues CollectWarnings reports as structured
+// entries, keyed by "deprecated", "additionalProperties" or "format"
+func (v *Validator) Warnings(schemaBytes, data json.RawMessage) ([]types.Violation, error) {
+	var schemaObj interface{}
+	if err := json.Unmarshal(schemaBytes, &schemaObj); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var responseData interface{}
+	if err := json.Unmarshal(data, &responseData); err != nil {
+		return nil, fmt.Errorf("invalid response JSON: %w", err)
+	}
+
+	var warnings []types.Violation
+	// Asserted formats have already been checked by validation
+	collectWarnings(schemaObj, responseData, "", !v.policy.AssertFormats, &warnings)
+	return warnings, nil
+}
+
+// collectWarnings walks the schema's properties and items alongside the data.
+// checkFormats reports values that do not match their "format" annotation.
+func collectWarnings(schemaNode, data interface{}, path string, checkFormats bool, warnings *[]types.Violation) {
+	schemaMap, ok := schemaNode.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if format, ok := schemaMap["format"].(string); ok && checkFormats {
+		if isFormat, known := jsonschema.Formats[format]; known && !isFormat(data) {
+			*warnings = append(*warnings, types.Violation{
+				Path:    path,
+				Keyword: "format",
+				Message: fmt.Sprintf("value is not a valid %s", format),
+			})
+		}
+	}
+
+	switch value := data.(type) {
+	case map[string]interface{}:
+		properties, _ := schemaMap["properties"].(map[string]interface{})
+		// Only false rejects undeclared properties; true or a schema accepts them
+		closed := schemaMap["additionalProperties"] == false
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := path + "/" + escapePointer(key)
+			propSchema, declared := properties[key]
+			if !declared {
+				if properties != nil && !closed {
+					*warnings = append(*warnings, types.Violation{
+						Path:    childPath,
+						Keyword: "additionalProperties",
+						Message: "property is not declared in schema",
+					})
+				}
+				continue
+			}
+			if propMap, ok := propSchema.(map[string]interface{}); ok {
+				if deprecated, _ := propMap["deprecated"].(bool); deprecated {
+					*warnings = append(*warnings, types.Violation{
+						Path:    childPath,
+						Keyword: "deprecated",
+						Message: "property is deprecated",
+					})
+				}
+			}
+			collectWarnings(propSchema, value[key], childPath, checkFormats, warnings)
+		}
+	case []interface{}:
+		for i, item := range value {
+			collectWarnings(schemaMap["items"], item, path+"/"+strconv.Itoa(i), checkFormats, warnings)
+		}
+	}
+}
+
+// escapePointer escapes a key for use as a JSON pointer token
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,65 @@
+package schema
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestWarnings(t *testing.T) {
+	newValidator := func(policy Policy) *Validator {
+		v := NewValidatorWithLogger(10, logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard}))
+		v.SetPolicy(policy)
+		return v
+	}
+
+	t.Run("unasserted_formats_are_warnings", func(t *testing.T) {
+		schemaJSON := json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"email": {"type": "string", "format": "email"},
+				"events": {"type": "array", "items": {"type": "string", "format": "date-time"}}
+			}
+		}`)
+		data := json.RawMessage(`{"email": "not-an-email", "events": ["2024-01-02T03:04:05Z", "yesterday"]}`)
+
+		warnings, err := newValidator(Policy{}).Warnings(schemaJSON, data)
+		require.NoError(t, err)
+		assert.Equal(t, []types.Violation{
+			{Path: "/email", Keyword: "format", Message: "value is not a valid email"},
+			{Path: "/events/1", Keyword: "format", Message: "value is not a valid date-time"},
+		}, warnings)
+	})
+
+	t.Run("asserted_formats_are_not_repeated", func(t *testing.T) {
+		schemaJSON := json.RawMessage(`{"type": "object", "properties": {"email": {"type": "string", "format": "email"}}}`)
+
+		warnings, err := newValidator(Policy{AssertFormats: true}).Warnings(schemaJSON, json.RawMessage(`{"email": "not-an-email"}`))
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("unknown_formats_are_ignored", func(t *testing.T) {
+		schemaJSON := json.RawMessage(`{"type": "object", "properties": {"sku": {"type": "string", "format": "sku"}}}`)
+
+		warnings, err := newValidator(Policy{}).Warnings(schemaJSON, json.RawMessage(`{"sku": "anything"}`))
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("only_false_additional_properties_is_closed", func(t *testing.T) {
+		data := json.RawMessage(`{"name": "Jane", "extra": "x"}`)
+		for additional, want := range map[string]int{`false`: 0, `true`: 1, `{"type": "string"}`: 1, `{}`: 1} {
+			schemaJSON := json.RawMessage(`{"type": "object", "properties": {"name": {"type": "string"}}, "additionalProperties": ` + additional + `}`)
+
+			warnings, err := newValidator(Policy{}).Warnings(schemaJSON, data)
+			require.NoError(t, err)
+			assert.Len(t, warnings, want, "additionalProperties: %s", additional)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// logLevelRequest is the body of POST /admin/log-level
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// logLevelResponse reports the runtime log level and, after a change, the
+// level it replaced
+type logLevelResponse struct {
+	Level    string `json:"level"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// requireAdmin allows only authenticated callers, and when Admin.Subjects is
+// set only those subjects, through to an admin handler
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := middleware.GetRequestID(r.Context())
+		identity := middleware.GetIdentity(r.Context())
+		if identity == nil {
+			s.writeErrorResponse(w, r, http.StatusUnauthorized, types.ErrorCodeUnauthorized,
+				"Unauthorized", "admin endpoints require an authenticated caller", requestID, nil)
+			return
+		}
+		if subjects := s.config.Admin.Subjects; len(subjects) > 0 && !slices.Contains(subjects, identity.Subject) {
+			s.writeErrorResponse(w, r, http.StatusForbidden, types.ErrorCodeForbidden,
+				"Forbidden", "caller is not an admin", requestID, s.requestLogger(r))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	if s.logLevel == nil {
+		s.writeErrorResponse(w, r, http.StatusNotImplemented, types.ErrorCodeInternalError,
+			"Log level is fixed", "the server was started without an adjustable log level", middleware.GetRequestID(r.Context()), nil)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, logLevelResponse{Level: strings.ToLower(s.logLevel.Level().String())})
+}
+
+// handleSetLogLevel changes the log level of every logger sharing the
+// server's level variable, taking effect on their next log call
+func (s *Server) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logger := s.requestLogger(r)
+	if s.logLevel == nil {
+		s.writeErrorResponse(w, r, http.StatusNotImplemented, types.ErrorCodeInternalError,
+			"Log level is fixed", "the server was started without an adjustable log level", requestID, nil)
+		return
+	}
+
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", err.Error(), requestID, logger)
+		return
+	}
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid log level", err.Error()+"; use debug, info, warn or error", requestID, logger)
+		return
+	}
+
+	previous := s.logLevel.Level()
+	s.logLevel.Set(level)
+	// Warn so the change is recorded whatever the new level
+	logger.WithFields(map[string]interface{}{
+		"previous_level": strings.ToLower(previous.String()),
+		"log_level":      strings.ToLower(level.String()),
+	}).Warn("Log level changed")
+
+	s.writeJSON(w, r, http.StatusOK, logLevelResponse{
+		Level:    strings.ToLower(level.String()),
+		Previous: strings.ToLower(previous.String()),
+	})
+}
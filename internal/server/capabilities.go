@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wcygan/llm-json-parse/internal/responsecache"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// handleCapabilities reports which optional features are enabled. It only
+// reveals feature flags and limits, never addresses or credentials, so it is
+// served without authentication.
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	cfg := s.config
+	engine := schema.Engine()
+	engine.AssertFormats = cfg.Validation.AssertFormats
+	s.writeJSON(w, r, http.StatusOK, types.Capabilities{
+		Streaming:             true,
+		Batch:                 true,
+		BatchMaxPayloads:      cfg.Validation.BatchMaxPayloads,
+		Repair:                cfg.Validation.MaxRepairAttempts > 0,
+		MaxRepairAttempts:     cfg.Validation.MaxRepairAttempts,
+		SchemaRegistry:        true,
+		SharedSchemaRegistry:  cfg.Registry.Store == "redis",
+		SchemaURLRegistration: len(cfg.Registry.FetchAllowlist) > 0,
+		Schemaless:            cfg.Validation.AllowSchemaless,
+		ResponseCache:         cfg.ResponseCache.Backend != "" && cfg.ResponseCache.Backend != responsecache.BackendNone,
+		BackendOverride:       cfg.LLM.BackendOverride.Enabled,
+		ModelFallback:         cfg.LLM.FallbackURL != "",
+		Envelope:              cfg.Output.Envelope,
+		SchemaEngine:          engine,
+	})
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// Sources of a validated query's deadline, as logged in timeout_source
+const (
+	timeoutSourceNone        = "none"
+	timeoutSourceServer      = "write_timeout"
+	timeoutSourceRoute       = "route_timeout"
+	timeoutSourceMaxDuration = "max_request_duration"
+)
+
+// effectiveDeadline reports the deadline that will bound a validated query
+// and which setting imposes it: the request timeout middleware, with the
+// server's write timeout or the route's override, or MaxRequestDuration,
+// whichever ends first. It must be called before MaxRequestDuration is
+// applied to the request context.
+func (s *Server) effectiveDeadline(r *http.Request, start time.Time) (time.Time, string) {
+	deadline, ok := r.Context().Deadline()
+	source := timeoutSourceNone
+	if ok {
+		source = timeoutSourceServer
+		if _, routed := s.config.Server.RouteTimeouts[r.URL.Path]; routed {
+			source = timeoutSourceRoute
+		}
+	}
+	if maxDuration := s.config.Server.MaxRequestDuration; maxDuration > 0 {
+		if capped := start.Add(maxDuration); !ok || capped.Before(deadline) {
+			deadline, source = capped, timeoutSourceMaxDuration
+		}
+	}
+	return deadline, source
+}
+
+// deadlineFields describes the deadline governing a request, with the time
+// left from start, alongside the per-call LLM and validation timeouts that
+// apply within it
+func (s *Server) deadlineFields(deadline time.Time, source string, start time.Time) map[string]interface{} {
+	fields := map[string]interface{}{
+		"timeout_source":        source,
+		"llm_timeout_ms":        s.config.LLM.Timeout.Milliseconds(),
+		"validation_timeout_ms": s.config.Validation.Timeout.Milliseconds(),
+	}
+	if !deadline.IsZero() {
+		fields["effective_timeout_ms"] = deadline.Sub(start).Milliseconds()
+		fields["effective_deadline"] = deadline.UTC().Format(time.RFC3339Nano)
+	}
+	return fields
+}
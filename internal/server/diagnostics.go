@@ -0,0 +1,37 @@
+package server
+
+import "net/http"
+
+// backendDefault names the configured LLM backend in X-LLM-Backend
+const backendDefault = "default"
+
+// backendFallback names LLM.FallbackURL in X-LLM-Backend, once a request
+// has fallen back to it
+const backendFallback = "fallback"
+
+// cacheMiss is the X-Cache value of a response produced by the LLM rather
+// than the response cache
+const cacheMiss = "miss"
+
+// cacheHit is the X-Cache value of a response served from the response cache
+const cacheHit = "hit"
+
+// setDiagnosticHeaders describes how the request was served, when enabled.
+// X-Request-ID is always set by the request ID middleware; the rest come from
+// the request summary and are omitted when the handler never learned them,
+// e.g. for a request rejected before its schema was read.
+func (s *Server) setDiagnosticHeaders(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Output.DiagnosticHeaders {
+		return
+	}
+	summary := summaryFrom(r.Context())
+	if summary.schemaHash != "" {
+		w.Header().Set("X-Schema-Hash", summary.schemaHash)
+	}
+	if summary.backend != "" {
+		w.Header().Set("X-LLM-Backend", summary.backend)
+	}
+	if summary.cacheStatus != "" {
+		w.Header().Set("X-Cache", summary.cacheStatus)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/webhook"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// requestSummary collects what a handler learns about a request so it can be
+// reported in the completion event once the response is written
+type requestSummary struct {
+	requestID  string
+	schemaHash string
+	usage      *types.Usage
+	// backend names the LLM backend that served the request and
+	// cacheStatus whether the response came from the response cache
+	backend     string
+	cacheStatus string
+	// writeFailed marks a response that could not be fully written, which
+	// is a failure whatever status was sent before the write broke
+	writeFailed bool
+	// errorCode is the code of the error the request failed with, if any
+	errorCode string
+	// streamStatus is the status of an event stream's final event; the
+	// stream itself always opens with a 200
+	streamStatus int
+}
+
+type summaryKey struct{}
+
+// summaryFrom returns the request's summary, or a throwaway one when the
+// handler runs without instrumentation so callers never need nil checks
+func summaryFrom(ctx context.Context) *requestSummary {
+	if summary, ok := ctx.Value(summaryKey{}).(*requestSummary); ok {
+		return summary
+	}
+	return &requestSummary{}
+}
+
+// completedStatus returns the status a request completed with: the one
+// written by the handler, or for an event stream that of its final event
+func completedStatus(summary *requestSummary, written int) int {
+	if summary.streamStatus != 0 {
+		return summary.streamStatus
+	}
+	return written
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// emitCompletion queues a completion event for the webhook; it never blocks
+func (s *Server) emitCompletion(summary *requestSummary, status int, latency time.Duration) {
+	s.webhook.Send(webhook.Event{
+		RequestID:  summary.requestID,
+		Outcome:    outcomeOf(summary, status),
+		StatusCode: status,
+		LatencyMs:  latency.Milliseconds(),
+		SchemaHash: summary.schemaHash,
+		Usage:      summary.usage,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// recordFailure counts a request failing with code, both in the stats and in
+// the request's summary
+func (s *Server) recordFailure(r *http.Request, code string) {
+	s.stats.RecordFailure(code)
+	summaryFrom(r.Context()).errorCode = code
+}
+
+// failureCategory returns the error code a completed request failed with,
+// or "" when it succeeded
+func failureCategory(summary *requestSummary, status int) string {
+	if outcomeOf(summary, status) != webhook.OutcomeFailure {
+		return ""
+	}
+	if summary.errorCode != "" {
+		return summary.errorCode
+	}
+	return "HTTP_" + strconv.Itoa(status)
+}
+
+// outcomeOf classifies a completed request as a success or failure
+func outcomeOf(summary *requestSummary, status int) string {
+	if status >= http.StatusBadRequest || summary.writeFailed {
+		return webhook.OutcomeFailure
+	}
+	return webhook.OutcomeSuccess
+}
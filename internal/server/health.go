@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// healthCache reuses a backend health check result for a short TTL so that
+// frequent readiness probes don't each reach the LLM backend. Concurrent
+// probes that miss the cache share a single check.
+type healthCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	checkedAt time.Time
+	err       error
+	now       func() time.Time
+}
+
+func newHealthCache(ttl time.Duration) *healthCache {
+	return &healthCache{
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// check returns the cached result if it is fresh, otherwise runs probe and
+// caches its result; cached reports whether the result came from the cache.
+// A probe cut short by its own context says nothing about the backend, so
+// that failure is returned without being cached.
+func (h *healthCache) check(ctx context.Context, probe func(context.Context) error) (cached bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.checkedAt.IsZero() && h.now().Sub(h.checkedAt) < h.ttl {
+		return true, h.err
+	}
+
+	err = probe(ctx)
+	if err != nil && errors.Is(err, ctx.Err()) {
+		return false, err
+	}
+	h.err = err
+	h.checkedAt = h.now()
+	return false, err
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCache(t *testing.T) {
+	newCache := func() (*healthCache, *time.Time) {
+		h := newHealthCache(time.Minute)
+		now := time.Unix(0, 0)
+		h.now = func() time.Time { return now }
+		return h, &now
+	}
+
+	t.Run("result_reused_until_ttl_expires", func(t *testing.T) {
+		h, now := newCache()
+		calls := 0
+		probe := func(context.Context) error {
+			calls++
+			return errors.New("backend down")
+		}
+
+		cached, err := h.check(context.Background(), probe)
+		assert.False(t, cached)
+		assert.EqualError(t, err, "backend down")
+
+		cached, err = h.check(context.Background(), probe)
+		assert.True(t, cached)
+		assert.EqualError(t, err, "backend down")
+		assert.Equal(t, 1, calls)
+
+		*now = now.Add(time.Minute)
+		cached, _ = h.check(context.Background(), probe)
+		assert.False(t, cached)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("probe_context_errors_not_cached", func(t *testing.T) {
+		h, _ := newCache()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		cached, err := h.check(ctx, func(ctx context.Context) error {
+			return fmt.Errorf("http request: %w", ctx.Err())
+		})
+		assert.False(t, cached)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// The next probe reaches the backend instead of the canceled result
+		cached, err = h.check(context.Background(), func(context.Context) error { return nil })
+		assert.False(t, cached)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// checkSchemaInjection scans a schema for likely prompt-injection phrases
+// under the configured policy, writing a 400 and returning false when the
+// policy rejects it
+func (s *Server) checkSchemaInjection(w http.ResponseWriter, r *http.Request, schemaBytes json.RawMessage, requestID string, logger *logging.Logger) bool {
+	policy := s.config.Validation.SchemaInjection
+	if policy != schema.InjectionWarn && policy != schema.InjectionReject {
+		return true
+	}
+
+	suspects, err := schema.InjectionSuspects(schemaBytes)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to scan schema for prompt injection")
+		return true
+	}
+	if len(suspects) == 0 {
+		return true
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"suspect_paths": suspects,
+		"policy":        policy,
+	}).Warn("Schema contains likely prompt injection")
+	if policy == schema.InjectionReject {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeSchemaInjection,
+			"Schema contains likely prompt injection", "suspicious text at "+strings.Join(suspects, ", "), requestID, logger)
+		return false
+	}
+	return true
+}
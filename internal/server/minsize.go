@@ -0,0 +1,31 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// smallResponseWarning describes how a validated response falls short of the
+// configured minimum size, or returns "" when it does not. A tiny response
+// such as {} often means the model refused or produced nothing useful, even
+// though a permissive schema accepts it. Size is measured without whitespace
+// and properties are counted at the top level of an object.
+func (s *Server) smallResponseWarning(data json.RawMessage) string {
+	minBytes := s.config.Validation.MinResponseBytes
+	minProperties := s.config.Validation.MinResponseProperties
+
+	if minBytes > 0 {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err == nil && compact.Len() < minBytes {
+			return fmt.Sprintf("response is %d bytes, below the minimum of %d", compact.Len(), minBytes)
+		}
+	}
+	if minProperties > 0 {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err == nil && len(object) < minProperties {
+			return fmt.Sprintf("response has %d properties, below the minimum of %d", len(object), minProperties)
+		}
+	}
+	return ""
+}
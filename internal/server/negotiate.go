@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/wcygan/llm-json-parse/internal/transform"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// Representations a response body may take, selected from the Accept header
+// or the configured default
+const (
+	representationJSON    = "json"
+	representationPretty  = "pretty"
+	representationProblem = "problem"
+)
+
+// representation picks the response representation for a request. An
+// explicit Accept of application/json or application/problem+json wins;
+// otherwise, including when Accept is absent or a wildcard, the configured
+// default applies.
+func (s *Server) representation(r *http.Request) string {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "application/problem+json":
+			return representationProblem
+		case "application/json":
+			return representationJSON
+		}
+	}
+	if s.config.Output.DefaultRepresentation == "" {
+		return representationJSON
+	}
+	return s.config.Output.DefaultRepresentation
+}
+
+// writeJSON writes a successful response body in the negotiated
+// representation, returning any error writing it to the client. HTML
+// characters in it are escaped unless configured otherwise.
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	s.setDiagnosticHeaders(w, r)
+	return s.encode(w, s.representation(r), "application/json", status, v, s.config.Output.EscapeHTML)
+}
+
+// writeRawJSON writes an already encoded JSON body byte for byte, ignoring
+// the negotiated representation, whose indentation would change the bytes
+func (s *Server) writeRawJSON(w http.ResponseWriter, r *http.Request, status int, body []byte) error {
+	s.setDiagnosticHeaders(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
+
+// canonicalBody encodes a response body as RFC 8785 canonical JSON
+func canonicalBody(body interface{}) (json.RawMessage, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return transform.CanonicalJSON(encoded)
+}
+
+// writeProblem writes an error body, as problem details when negotiated
+func (s *Server) writeProblem(w http.ResponseWriter, r *http.Request, status int, body interface{}, problem func() *types.ProblemDetails) error {
+	s.setDiagnosticHeaders(w, r)
+	rep := s.representation(r)
+	if rep == representationProblem {
+		return s.encode(w, rep, "application/problem+json", status, problem(), true)
+	}
+	return s.encode(w, rep, "application/json", status, body, true)
+}
+
+func (s *Server) encode(w http.ResponseWriter, rep, contentType string, status int, v interface{}, escapeHTML bool) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	// Encode straight to the client; large bodies are flushed in chunks
+	// rather than buffered again
+	enc := json.NewEncoder(newChunkedWriter(w))
+	enc.SetEscapeHTML(escapeHTML)
+	if rep == representationPretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
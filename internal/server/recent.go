@@ -0,0 +1,88 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"sync"
+
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// recentRequests is a fixed-size ring of the latest completed requests
+type recentRequests struct {
+	mu      sync.Mutex
+	entries []types.RecentRequest
+	next    int
+	full    bool
+}
+
+func newRecentRequests(size int) *recentRequests {
+	return &recentRequests{entries: make([]types.RecentRequest, size)}
+}
+
+// add records a request, overwriting the oldest once the ring is full
+func (rr *recentRequests) add(entry types.RecentRequest) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.entries[rr.next] = entry
+	rr.next = (rr.next + 1) % len(rr.entries)
+	rr.full = rr.full || rr.next == 0
+}
+
+// list returns the recorded requests, oldest first
+func (rr *recentRequests) list() []types.RecentRequest {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if !rr.full {
+		return append([]types.RecentRequest{}, rr.entries[:rr.next]...)
+	}
+	return append(append([]types.RecentRequest{}, rr.entries[rr.next:]...), rr.entries[:rr.next]...)
+}
+
+// recordRecent adds a completed request to the recent-requests ring
+func (s *Server) recordRecent(r *http.Request, summary *requestSummary, status int, entry types.RecentRequest) {
+	entry.RequestID = summary.requestID
+	entry.Method = r.Method
+	entry.Path = r.URL.Path
+	entry.Status = status
+	entry.Outcome = outcomeOf(summary, status)
+	entry.SchemaHash = summary.schemaHash
+	entry.Backend = summary.backend
+	entry.Cache = summary.cacheStatus
+	entry.Usage = summary.usage
+	if s.config.Admin.RecentRequestBodies {
+		if body, ok := middleware.GetRequestBody(r.Context()); ok {
+			entry.Body = string(body)
+		}
+	}
+	s.recent.add(entry)
+}
+
+func (s *Server) handleRecentRequests(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, types.RecentRequests{Requests: s.recent.list()})
+}
+
+// recentRequestsPage renders the recent requests as a plain table
+var recentRequestsPage = template.Must(template.New("requests").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Recent requests</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head>
+<body>
+<h1>Recent requests</h1>
+<table>
+<tr><th>Started</th><th>Request ID</th><th>Method</th><th>Path</th><th>Status</th><th>Outcome</th><th>Latency (ms)</th><th>Schema</th><th>Backend</th><th>Cache</th></tr>
+{{range .}}<tr><td>{{.StartedAt}}</td><td>{{.RequestID}}</td><td>{{.Method}}</td><td>{{.Path}}</td><td>{{.Status}}</td><td>{{.Outcome}}</td><td>{{.LatencyMs}}</td><td>{{.SchemaHash}}</td><td>{{.Backend}}</td><td>{{.Cache}}</td></tr>
+{{if .Body}}<tr><td colspan="10"><pre>{{.Body}}</pre></td></tr>{{end}}{{end}}
+</table>
+</body>
+</html>
+`))
+
+func (s *Server) handleRecentRequestsView(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := recentRequestsPage.Execute(w, s.recent.list()); err != nil {
+		s.requestLogger(r).WithError(err).Warn("Failed to render recent requests")
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// repairMessages extends a conversation with the LLM's invalid response and a
+// request to correct it, so a re-prompt can see its own mistakes. details is
+// used when the failure could not be broken into violations.
+func repairMessages(messages []types.Message, invalid json.RawMessage, violations []types.Violation, details string) []types.Message {
+	problems := make([]string, 0, len(violations))
+	for _, v := range violations {
+		problems = append(problems, v.Path+": "+v.Message)
+	}
+	if len(problems) == 0 {
+		problems = append(problems, details)
+	}
+
+	repaired := make([]types.Message, 0, len(messages)+2)
+	repaired = append(repaired, messages...)
+	return append(repaired,
+		types.Message{Role: "assistant", Content: string(invalid)},
+		types.Message{
+			Role: "user",
+			Content: "That response does not match the required JSON schema:\n- " +
+				strings.Join(problems, "\n- ") + "\nReply again with corrected JSON only.",
+		},
+	)
+}
+
+// repairAllowed reports whether a failed response to req may be re-prompted.
+// With RepairDeterministicOnly set, only requests marked deterministic are.
+func (s *Server) repairAllowed(req *types.ValidatedQueryRequest) bool {
+	return !s.config.Validation.RepairDeterministicOnly || req.Deterministic
+}
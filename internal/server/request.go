@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// fieldError identifies the request field that made a request malformed
+type fieldError struct {
+	Field   string
+	Problem string
+}
+
+func (e *fieldError) Error() string {
+	return e.Field + " " + e.Problem
+}
+
+// decodeQueryRequest decodes a validated query request. The messages array is
+// checked element by element first so a malformed message is reported as a
+// fieldError naming its index and field rather than a generic type error.
+func decodeQueryRequest(body io.Reader) (*types.ValidatedQueryRequest, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if messages, ok := fields["messages"]; ok {
+		if err := checkMessages(messages); err != nil {
+			return nil, err
+		}
+	}
+
+	var req types.ValidatedQueryRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// checkMessages verifies messages is an array of objects with string role and content
+func checkMessages(raw json.RawMessage) error {
+	var messages []json.RawMessage
+	if jsonKind(raw) != '[' || json.Unmarshal(raw, &messages) != nil {
+		return &fieldError{Field: "messages", Problem: "must be an array"}
+	}
+
+	for i, message := range messages {
+		var fields map[string]json.RawMessage
+		if jsonKind(message) != '{' || json.Unmarshal(message, &fields) != nil {
+			return &fieldError{Field: fmt.Sprintf("messages[%d]", i), Problem: "must be an object"}
+		}
+		for _, name := range []string{"role", "content"} {
+			value, ok := fields[name]
+			field := fmt.Sprintf("messages[%d].%s", i, name)
+			if !ok {
+				return &fieldError{Field: field, Problem: "is required"}
+			}
+			if jsonKind(value) != '"' {
+				return &fieldError{Field: field, Problem: "must be a string"}
+			}
+		}
+	}
+	return nil
+}
+
+// checkMessageSizes reports the first message whose content is larger than
+// maxBytes; zero means no limit
+func checkMessageSizes(messages []types.Message, maxBytes int64) *fieldError {
+	if maxBytes <= 0 {
+		return nil
+	}
+	for i, message := range messages {
+		if int64(len(message.Content)) > maxBytes {
+			return &fieldError{
+				Field:   fmt.Sprintf("messages[%d].content", i),
+				Problem: fmt.Sprintf("exceeds %d bytes", maxBytes),
+			}
+		}
+	}
+	return nil
+}
+
+// checkRequestInput validates a request's structured input against its
+// request_schema, writing a 400 and returning false when either is invalid.
+// Requests without a request_schema pass their input through unchecked.
+func (s *Server) checkRequestInput(w http.ResponseWriter, r *http.Request, validator *schema.Validator, req *types.ValidatedQueryRequest, requestID string, logger *logging.Logger) bool {
+	if isEmptySchema(req.RequestSchema) {
+		return true
+	}
+	if isEmptySchema(req.Input) {
+		s.writeFieldError(w, r, &fieldError{Field: "input", Problem: "is required when request_schema is set"}, requestID, logger)
+		return false
+	}
+
+	requestSchema, ok := s.resolveFragments(w, r, req.RequestSchema, requestID, logger)
+	if !ok {
+		return false
+	}
+	if err := validator.ValidateSchema(requestSchema); err != nil {
+		logger.WithError(err).Warn("Request schema validation failed")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+			"Invalid request schema", err.Error(), requestID, logger)
+		return false
+	}
+
+	input := &types.ValidatedResponse{Data: req.Input}
+	if err := validator.ValidateResponse(requestSchema, input); err != nil {
+		if errors.Is(err, schema.ErrValidationTimeout) {
+			s.writeErrorResponse(w, r, http.StatusGatewayTimeout, types.ErrorCodeValidationTimeout,
+				"Request input validation timed out", err.Error(), requestID, logger)
+			return false
+		}
+		errorResp := types.NewErrorResponse(types.ErrorCodeInvalidRequest, "Request input does not match request_schema", err.Error()).
+			WithContext("field", "input").
+			WithContext("violations", schema.Violations(err, requestSchema, req.Input)).
+			WithRequestID(requestID)
+		s.recordFailure(r, errorResp.Code)
+		s.writeProblem(w, r, http.StatusBadRequest, errorResp, func() *types.ProblemDetails {
+			return errorResp.Problem(http.StatusBadRequest, r.URL.Path)
+		})
+		logger.WithError(err).WithFields(map[string]interface{}{
+			"status_code": http.StatusBadRequest,
+		}).Warn("Request input failed validation")
+		return false
+	}
+	// Null rewriting may have changed the input
+	req.Input = input.Data
+	return true
+}
+
+// inputMessage carries a request's structured input to the LLM
+func inputMessage(input json.RawMessage) types.Message {
+	var compact bytes.Buffer
+	// Input was decoded from the request body, so it is valid JSON
+	_ = json.Compact(&compact, input)
+	return types.Message{Role: "user", Content: "Input:\n" + compact.String()}
+}
+
+// jsonKind returns the first significant byte of a JSON value, which
+// identifies its type: '{', '[', '"', 'n', 't', 'f' or a number
+func jsonKind(raw json.RawMessage) byte {
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
+
+// writeFieldError writes a 400 naming the malformed request field
+func (s *Server) writeFieldError(w http.ResponseWriter, r *http.Request, fe *fieldError, requestID string, logger *logging.Logger) {
+	errorResp := types.NewErrorResponse(types.ErrorCodeInvalidRequest, "Invalid request body", fe.Error()).
+		WithContext("field", fe.Field).
+		WithRequestID(requestID)
+	s.recordFailure(r, errorResp.Code)
+
+	s.writeProblem(w, r, http.StatusBadRequest, errorResp, func() *types.ProblemDetails {
+		return errorResp.Problem(http.StatusBadRequest, r.URL.Path)
+	})
+
+	logger.WithFields(map[string]interface{}{
+		"field":       fe.Field,
+		"status_code": http.StatusBadRequest,
+	}).Warn("Malformed request field")
+}
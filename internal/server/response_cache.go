@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/registry"
+	"github.com/wcygan/llm-json-parse/internal/responsecache"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// newResponseCache creates the configured response cache backend, or nil
+// when caching is disabled
+func newResponseCache(cfg config.ResponseCacheConfig) responsecache.ResponseCache {
+	switch cfg.Backend {
+	case responsecache.BackendMemory:
+		return responsecache.NewMemoryCache(cfg.MaxEntries, cfg.TTL)
+	case responsecache.BackendRedis:
+		return responsecache.NewRedisCache(responsecache.RedisConfig{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			Timeout:  cfg.RedisTimeout,
+		}, cfg.TTL)
+	}
+	return nil
+}
+
+// responseCacheKey scopes a request's cache key to its tenant and LLM backend,
+// so neither ever sees a response produced for another, and to any
+// per-request validation preset, whose responses were validated differently
+func (s *Server) responseCacheKey(req *types.ValidatedQueryRequest, tenantID, backend, preset string) string {
+	scope := tenantID + "/" + backend + "/"
+	if preset != "" {
+		scope += "preset:" + preset + "/"
+	}
+	return scope + responsecache.Key(*req, s.config.ResponseCache.OrderSensitiveKeys)
+}
+
+// cachePolicy returns how the response cache treats requests using the schema
+// with the given hash: the policy it was registered with, if any, and
+// otherwise cacheable with the cache's own TTL
+func (s *Server) cachePolicy(schemaHash string) registry.CachePolicy {
+	if schemaHash != "" {
+		if policy, ok := s.registry.CachePolicy(schemaHash); ok {
+			return policy
+		}
+	}
+	return registry.CachePolicy{Cacheable: true}
+}
+
+// cachedResponse looks up validated response data. An unreachable backend is
+// logged and treated as a miss.
+func (s *Server) cachedResponse(ctx context.Context, key string, logger *logging.Logger) (json.RawMessage, bool) {
+	if s.responseCache == nil {
+		return nil, false
+	}
+	data, ok, err := s.responseCache.Get(ctx, key)
+	if err != nil {
+		logger.WithError(err).Warn("Response cache lookup failed")
+		return nil, false
+	}
+	return data, ok
+}
+
+// storeResponse caches validated response data, for ttl when positive and
+// otherwise the cache's TTL. Failures are logged and otherwise ignored, since
+// the response itself is still good.
+func (s *Server) storeResponse(ctx context.Context, key string, data json.RawMessage, ttl time.Duration, logger *logging.Logger) {
+	if s.responseCache == nil {
+		return
+	}
+	var err error
+	if ttl > 0 {
+		err = s.responseCache.SetWithTTL(ctx, key, data, ttl)
+	} else {
+		err = s.responseCache.Set(ctx, key, data)
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Failed to store response in cache")
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// errorVerbosityFull returns error details to clients exactly as logged
+const errorVerbosityFull = "full"
+
+// redactedBackend replaces backend details stripped from client-facing errors
+const redactedBackend = "[llm backend]"
+
+var (
+	// urlPattern matches absolute URLs, e.g. in Go's `Post "http://..."` errors
+	urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"']+`)
+	// addrPattern matches IPv4 and bracketed IPv6 addresses, with optional port,
+	// e.g. in `dial tcp 10.0.0.5:8080` errors
+	addrPattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b|\[[0-9a-fA-F:.]+\](?::\d+)?`)
+)
+
+// backendHosts returns the hosts of every configured LLM backend, longest
+// first so a host is never partially replaced by one of its suffixes
+func (s *Server) backendHosts() []string {
+	backendURLs := []string{s.config.LLM.ServerURL}
+	for _, t := range s.config.Tenants.Tenants {
+		backendURLs = append(backendURLs, t.LLMServerURL)
+	}
+	backendURLs = append(backendURLs, s.config.LLM.BackendOverride.Allowlist...)
+
+	var hosts []string
+	for _, backendURL := range backendURLs {
+		if backendURL == "" {
+			continue
+		}
+		if u, err := url.Parse(backendURL); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host, u.Hostname())
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return len(hosts[i]) > len(hosts[j]) })
+	return hosts
+}
+
+// sanitizeDetails strips LLM backend URLs, hostnames and addresses from error
+// details unless the configured error verbosity is "full"
+func (s *Server) sanitizeDetails(details string) string {
+	if s.config.Output.ErrorVerbosity == errorVerbosityFull {
+		return details
+	}
+	details = urlPattern.ReplaceAllString(details, redactedBackend)
+	for _, host := range s.backendHosts() {
+		details = strings.ReplaceAll(details, host, redactedBackend)
+	}
+	return addrPattern.ReplaceAllString(details, redactedBackend)
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/registry"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// handleRegisterSchema stores a valid schema in the registry and returns its
+// ID. The body is either the schema itself or {"url": ...} naming an
+// allowlisted location to fetch it from. ?cacheable and ?cache_ttl set how
+// the response cache treats requests using the schema; a schema already
+// registered with a different policy is a conflict.
+func (s *Server) handleRegisterSchema(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logger := s.requestLogger(r)
+
+	cachePolicy, hasCachePolicy, err := registrationCachePolicy(r)
+	if err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid cache policy", err.Error(), requestID, logger)
+		return
+	}
+
+	var schemaBytes json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&schemaBytes); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", err.Error(), requestID, logger)
+		return
+	}
+
+	if schemaURL, ok := registrationURL(schemaBytes); ok {
+		if !s.fetcher.Enabled() {
+			s.writeErrorResponse(w, r, http.StatusForbidden, types.ErrorCodeSchemaURLForbidden,
+				"Schema URL not allowed", "registering schemas by URL is disabled", requestID, logger)
+			return
+		}
+		fetched, err := s.fetcher.Fetch(r.Context(), schemaURL)
+		if errors.Is(err, registry.ErrHostNotAllowed) {
+			s.writeErrorResponse(w, r, http.StatusForbidden, types.ErrorCodeSchemaURLForbidden,
+				"Schema URL not allowed", err.Error(), requestID, logger)
+			return
+		}
+		if err != nil {
+			s.writeErrorResponse(w, r, http.StatusBadGateway, types.ErrorCodeSchemaFetchFailed,
+				"Failed to fetch schema", err.Error(), requestID, logger)
+			return
+		}
+		logger.WithFields(map[string]interface{}{
+			"schema_url":        schemaURL,
+			"schema_size_bytes": len(fetched),
+		}).Info("Fetched schema for registration")
+		schemaBytes = fetched
+	}
+	// Fragments are resolved to check the schema compiles, but the schema is
+	// stored as sent so its ID doesn't depend on the fragments' contents
+	resolved, ok := s.resolveFragments(w, r, schemaBytes, requestID, logger)
+	if !ok {
+		return
+	}
+	if err := s.validator.ValidateSchema(resolved); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+			"Invalid JSON schema", err.Error(), requestID, logger)
+		return
+	}
+	if !s.checkSchemaInjection(w, r, resolved, requestID, logger) {
+		return
+	}
+
+	var policy *registry.CachePolicy
+	if hasCachePolicy {
+		policy = &cachePolicy
+	}
+	id, err := s.registry.RegisterWithCachePolicy(schemaBytes, policy)
+	if errors.Is(err, registry.ErrTooLarge) {
+		s.writeErrorResponse(w, r, http.StatusRequestEntityTooLarge, types.ErrorCodeRequestTooLarge,
+			"Schema too large", err.Error(), requestID, logger)
+		return
+	}
+	if errors.Is(err, registry.ErrFull) {
+		s.writeErrorResponse(w, r, http.StatusInsufficientStorage, types.ErrorCodeRegistryFull,
+			"Schema registry full", err.Error(), requestID, logger)
+		return
+	}
+	if errors.Is(err, registry.ErrCachePolicyConflict) {
+		s.writeErrorResponse(w, r, http.StatusConflict, types.ErrorCodeInvalidRequest,
+			"Cache policy conflict", err.Error(), requestID, logger)
+		return
+	}
+	if errors.Is(err, registry.ErrStoreUnavailable) {
+		logger.WithError(err).Error("Failed to share registered schema")
+		s.writeErrorResponse(w, r, http.StatusServiceUnavailable, types.ErrorCodeInternalError,
+			"Schema registry unavailable", err.Error(), requestID, logger)
+		return
+	}
+
+	s.writeJSON(w, r, http.StatusCreated, types.SchemaRegistration{ID: id})
+}
+
+// registrationCachePolicy reads the caching policy a registration asks for.
+// A TTL implies the schema is cacheable; ok is false when neither is given,
+// leaving the schema to global caching.
+func registrationCachePolicy(r *http.Request) (policy registry.CachePolicy, ok bool, err error) {
+	query := r.URL.Query()
+	cacheable, ttl := query.Get("cacheable"), query.Get("cache_ttl")
+	if cacheable == "" && ttl == "" {
+		return registry.CachePolicy{}, false, nil
+	}
+
+	policy.Cacheable = true
+	if cacheable != "" {
+		if policy.Cacheable, err = strconv.ParseBool(cacheable); err != nil {
+			return policy, false, fmt.Errorf("cacheable must be a boolean, got %q", cacheable)
+		}
+	}
+	if ttl != "" {
+		if policy.TTL, err = time.ParseDuration(ttl); err != nil || policy.TTL <= 0 {
+			return policy, false, fmt.Errorf("cache_ttl must be a positive duration, got %q", ttl)
+		}
+		if !policy.Cacheable {
+			return policy, false, errors.New("cache_ttl cannot be set for a schema that is not cacheable")
+		}
+	}
+	return policy, true, nil
+}
+
+// handleGetSchema returns a registered schema exactly as it was registered
+func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	schemaBytes, ok := s.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(schemaBytes)
+}
+
+// handleGetNormalizedSchema returns a registered schema as the gateway
+// validates it, with sorted keys and local references inlined
+func (s *Server) handleGetNormalizedSchema(w http.ResponseWriter, r *http.Request) {
+	schemaBytes, ok := s.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+	schemaBytes, ok = s.resolveFragments(w, r, schemaBytes, middleware.GetRequestID(r.Context()), s.requestLogger(r))
+	if !ok {
+		return
+	}
+
+	normalized, err := s.validator.Normalize(schemaBytes)
+	if errors.Is(err, schema.ErrSchemaTooLarge) {
+		s.writeErrorResponse(w, r, http.StatusUnprocessableEntity, types.ErrorCodeInvalidSchema,
+			"Schema cannot be normalized", err.Error(), middleware.GetRequestID(r.Context()), s.requestLogger(r))
+		return
+	}
+	if err != nil {
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, types.ErrorCodeInternalError,
+			"Failed to normalize schema", err.Error(), middleware.GetRequestID(r.Context()), s.requestLogger(r))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(normalized)
+}
+
+// handleAnalyzeSchema suggests ways to tighten a schema that its sample data
+// shows to be safe. It is an authoring aid: suggestions are never applied.
+func (s *Server) handleAnalyzeSchema(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logger := s.requestLogger(r)
+
+	var req types.SchemaAnalyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", err.Error(), requestID, logger)
+		return
+	}
+	if isEmptySchema(req.Schema) || len(req.Samples) == 0 {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", "schema and at least one sample are required", requestID, logger)
+		return
+	}
+	if maxSamples := s.config.Validation.BatchMaxPayloads; len(req.Samples) > maxSamples {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Too many samples", fmt.Sprintf("an analysis may use at most %d samples, got %d", maxSamples, len(req.Samples)), requestID, logger)
+		return
+	}
+
+	schemaBytes, ok := s.resolveFragments(w, r, req.Schema, requestID, logger)
+	if !ok {
+		return
+	}
+	if err := s.validator.ValidateSchema(schemaBytes); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+			"Invalid JSON schema", err.Error(), requestID, logger)
+		return
+	}
+
+	suggestions, err := schema.Analyze(schemaBytes, req.Samples)
+	if err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid sample", err.Error(), requestID, logger)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, types.SchemaAnalysis{Samples: len(req.Samples), Suggestions: suggestions})
+}
+
+// handleGetSchemaStats reports how data validated against a registered schema
+// fared, through validated queries and the validation endpoints
+func (s *Server) handleGetSchemaStats(w http.ResponseWriter, r *http.Request) {
+	stats, ok := s.registry.Stats(r.PathValue("id"))
+	if !ok {
+		s.writeErrorResponse(w, r, http.StatusNotFound, types.ErrorCodeSchemaNotFound,
+			"Schema not found", "schema "+r.PathValue("id")+" is not registered", middleware.GetRequestID(r.Context()), s.requestLogger(r))
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, stats)
+}
+
+// lookupSchema resolves the {id} path value, writing a 404 if it is unknown
+func (s *Server) lookupSchema(w http.ResponseWriter, r *http.Request) (json.RawMessage, bool) {
+	id := r.PathValue("id")
+	schemaBytes, ok := s.registry.Get(id)
+	if !ok {
+		s.writeErrorResponse(w, r, http.StatusNotFound, types.ErrorCodeSchemaNotFound,
+			"Schema not found", "schema "+id+" is not registered", middleware.GetRequestID(r.Context()), s.requestLogger(r))
+		return nil, false
+	}
+	return schemaBytes, true
+}
+
+// resolveFragments inlines the registered fragments a schema includes,
+// writing a 400 if one is missing or they include each other
+func (s *Server) resolveFragments(w http.ResponseWriter, r *http.Request, schemaBytes json.RawMessage, requestID string, logger *logging.Logger) (json.RawMessage, bool) {
+	resolved, err := schema.ResolveFragments(schemaBytes, s.registry.Get)
+	if err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+			"Invalid schema fragment reference", err.Error(), requestID, logger)
+		return nil, false
+	}
+	return resolved, true
+}
+
+// registrationURL reports whether a registration body is {"url": ...}
+// rather than a schema
+func registrationURL(body json.RawMessage) (string, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || len(fields) != 1 {
+		return "", false
+	}
+	var schemaURL string
+	if err := json.Unmarshal(fields["url"], &schemaURL); err != nil || schemaURL == "" {
+		return "", false
+	}
+	return schemaURL, true
+}
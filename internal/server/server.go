@@ -1,60 +1,425 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/limiter"
 	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/metrics"
 	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/registry"
+	"github.com/wcygan/llm-json-parse/internal/responsecache"
 	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/internal/transform"
+	"github.com/wcygan/llm-json-parse/internal/webhook"
 	"github.com/wcygan/llm-json-parse/pkg/types"
 )
 
 type Server struct {
-	llmClient client.LLMClient
-	validator *schema.Validator
-	logger    *logging.Logger
+	llmClient     client.LLMClient
+	tenantClients map[string]client.LLMClient
+	// backendClients holds a client per allowlisted X-LLM-Backend URL
+	backendClients map[string]client.LLMClient
+	// fallbackClient retries default-backend queries that timed out; nil
+	// when no fallback is configured
+	fallbackClient client.LLMClient
+	validator      *schema.Validator
+	logger         *logging.Logger
+	config         *config.Config
+	stats          *metrics.Stats
+	estimator      client.TokenEstimator
+	health         *healthCache
+	schemaCheck    func() error
+	transport      http.RoundTripper
+	webhook        *webhook.Dispatcher
+	registry       *registry.Registry
+	fetcher        *registry.Fetcher
+	// logLevel is the level shared by the process's loggers, adjusted by
+	// the admin endpoint
+	logLevel *slog.LevelVar
+	// admission is the concurrency limiter whose queue /debug/vars reports
+	admission *limiter.Limiter
+	// responseCache holds validated responses by request key; nil when disabled
+	responseCache responsecache.ResponseCache
+	// results holds /v1/validate reports by schema and data; nil when disabled
+	results *schema.ResultCache
+	// recent holds the latest validated queries for /admin/requests; nil
+	// when disabled
+	recent *recentRequests
+	// errorRates tracks validated query failure rates for /debug/vars; nil
+	// when disabled
+	errorRates *metrics.ErrorWindow
 }
 
 func NewServer(llmClient client.LLMClient) *Server {
-	return &Server{
-		llmClient: llmClient,
-		validator: schema.NewValidator(),
-		logger:    logging.NewLogger(logging.LogConfig{Level: "info", Format: "json"}),
-	}
+	return NewServerFromConfig(llmClient, config.Default(),
+		logging.NewLogger(logging.LogConfig{Level: "info", Format: "json"}))
 }
 
 // NewServerWithCacheSize creates a server with custom schema cache size
 func NewServerWithCacheSize(llmClient client.LLMClient, cacheSize int) *Server {
-	return &Server{
-		llmClient: llmClient,
-		validator: schema.NewValidatorWithCacheSize(cacheSize),
-		logger:    logging.NewLogger(logging.LogConfig{Level: "info", Format: "json"}),
-	}
+	cfg := config.Default()
+	cfg.Cache.MaxSize = cacheSize
+	return NewServerFromConfig(llmClient, cfg,
+		logging.NewLogger(logging.LogConfig{Level: "info", Format: "json"}))
 }
 
 // NewServerWithConfig creates a server with full configuration
 func NewServerWithConfig(llmClient client.LLMClient, cacheSize int, logger *logging.Logger) *Server {
-	return &Server{
-		llmClient: llmClient,
-		validator: schema.NewValidatorWithCacheSize(cacheSize),
-		logger:    logger,
+	cfg := config.Default()
+	cfg.Cache.MaxSize = cacheSize
+	return NewServerFromConfig(llmClient, cfg, logger)
+}
+
+// NewServerFromConfig creates a server from the application configuration,
+// including an LLM client for each tenant with a dedicated backend
+func NewServerFromConfig(llmClient client.LLMClient, cfg *config.Config, logger *logging.Logger) *Server {
+	return NewServerFromConfigWithTransport(llmClient, cfg, logger, client.NewTransport(client.TransportConfig{
+		DNSCacheTTL:         cfg.LLM.DNSCacheTTL,
+		KeepAlive:           cfg.LLM.KeepAlive,
+		MaxIdleConnsPerHost: cfg.LLM.MaxIdleConnsPerHost,
+	}))
+}
+
+// NewServerFromConfigWithTransport creates a server like NewServerFromConfig
+// whose tenant and fallback LLM clients use transport, typically the one
+// llmClient was built with so every backend shares one connection pool
+func NewServerFromConfigWithTransport(llmClient client.LLMClient, cfg *config.Config, logger *logging.Logger, transport http.RoundTripper) *Server {
+	s := &Server{
+		llmClient:      llmClient,
+		tenantClients:  make(map[string]client.LLMClient),
+		backendClients: make(map[string]client.LLMClient),
+		validator:      schema.NewValidatorWithCache(schema.NewSchemaCacheWithPolicy(cfg.Cache.MaxSize, cfg.Cache.EvictionPolicy), logger),
+		logger:         logger,
+		config:         cfg,
+		stats:          metrics.NewStats(),
+		estimator:      client.NewCharTokenEstimator(),
+		health:         newHealthCache(cfg.Health.CacheTTL),
+		registry:       registry.NewRegistry(cfg.Registry.MaxEntries, cfg.Registry.FullPolicy),
+		fetcher: registry.NewFetcher(registry.FetchConfig{
+			AllowedHosts: cfg.Registry.FetchAllowlist,
+			Timeout:      cfg.Registry.FetchTimeout,
+			MaxBytes:     cfg.Registry.FetchMaxBytes,
+		}),
+		transport:     transport,
+		responseCache: newResponseCache(cfg.ResponseCache),
+	}
+	s.validator.SetTimeout(cfg.Validation.Timeout)
+	s.validator.SetPolicy(validationPolicy(cfg.Validation))
+	s.schemaCheck = s.validator.SelfCheck
+	s.registry.SetMaxSchemaBytes(cfg.Registry.MaxSchemaBytes)
+	if cfg.Cache.ResultMaxSize > 0 {
+		s.results = schema.NewResultCache(cfg.Cache.ResultMaxSize, cfg.Cache.ResultTTL)
+	}
+	if cfg.Debug.ErrorRateWindow > 0 {
+		s.errorRates = metrics.NewErrorWindow(cfg.Debug.ErrorRateWindow)
+	}
+	if cfg.Admin.Enabled && cfg.Admin.RecentRequests > 0 {
+		s.recent = newRecentRequests(cfg.Admin.RecentRequests)
+	}
+	if cfg.Registry.Store == "redis" {
+		// Schemas never expire from the shared store; each replica's registry
+		// bounds only its local copies
+		s.registry.SetStore(responsecache.NewRedisCache(responsecache.RedisConfig{
+			Addr:     cfg.Registry.RedisAddr,
+			Password: cfg.Registry.RedisPassword,
+			Timeout:  cfg.Registry.RedisTimeout,
+			Prefix:   "llm-json-parse:schema:",
+		}, 0))
 	}
+	if cfg.LLM.FallbackURL != "" {
+		s.fallbackClient = s.newFallbackClient()
+	}
+	for _, t := range cfg.Tenants.Tenants {
+		if t.LLMServerURL != "" {
+			s.tenantClients[t.ID] = s.newBackendClient(t.LLMServerURL)
+		}
+	}
+	if cfg.Webhook.URL != "" {
+		s.webhook = webhook.NewDispatcher(webhook.Config{
+			URL:           cfg.Webhook.URL,
+			QueueSize:     cfg.Webhook.QueueSize,
+			RetryAttempts: cfg.Webhook.RetryAttempts,
+			RetryDelay:    cfg.Webhook.RetryDelay,
+			Timeout:       cfg.Webhook.Timeout,
+		}, logger)
+	}
+	if cfg.LLM.BackendOverride.Enabled {
+		for _, backendURL := range cfg.LLM.BackendOverride.Allowlist {
+			s.backendClients[normalizeBackendURL(backendURL)] = s.newBackendClient(backendURL)
+		}
+	}
+	return s
+}
+
+// newBackendClient creates an LLM client for an additional backend using the
+// server's LLM timeout and retry settings
+func (s *Server) newBackendClient(baseURL string) client.LLMClient {
+	llmClient := client.NewLlamaServerClientWithTransport(baseURL, s.config.LLM.Timeout, client.RetryConfig{
+		Attempts:       s.config.LLM.RetryAttempts,
+		Delay:          s.config.LLM.RetryDelay,
+		MaxDelay:       s.config.LLM.MaxRetryDelay,
+		Jitter:         s.config.LLM.RetryJitter,
+		EmptyResponses: s.config.LLM.RetryEmptyResponses,
+		DNSFailures:    s.config.LLM.RetryDNSFailures,
+	}, s.transport, s.logger)
+	llmClient.SetExtraRequestFields(s.config.LLM.ExtraRequestFields)
+	llmClient.SetAllowInvalidUTF8(s.config.LLM.AllowInvalidUTF8)
+	return llmClient
+}
+
+// validationPolicy is the schema policy for the given validation settings
+func validationPolicy(v config.ValidationConfig) schema.Policy {
+	return schema.Policy{
+		AssertFormats:     v.AssertFormats,
+		RequireObjectRoot: v.RequireObjectRoot,
+		RejectPermissive:  v.RejectPermissive,
+		Nulls:             v.NullPolicy,
+		StrictIntegers:    v.StrictIntegers,
+	}
+}
+
+// newFallbackClient creates the client for LLM.FallbackURL, which asks for
+// LLM.FallbackModel when one is configured
+func (s *Server) newFallbackClient() client.LLMClient {
+	llmClient := client.NewLlamaServerClientWithTransport(s.config.LLM.FallbackURL, s.config.LLM.Timeout, client.RetryConfig{
+		Attempts:       s.config.LLM.RetryAttempts,
+		Delay:          s.config.LLM.RetryDelay,
+		MaxDelay:       s.config.LLM.MaxRetryDelay,
+		Jitter:         s.config.LLM.RetryJitter,
+		EmptyResponses: s.config.LLM.RetryEmptyResponses,
+		DNSFailures:    s.config.LLM.RetryDNSFailures,
+	}, s.transport, s.logger)
+	fields := make(map[string]json.RawMessage, len(s.config.LLM.ExtraRequestFields)+1)
+	for name, value := range s.config.LLM.ExtraRequestFields {
+		fields[name] = value
+	}
+	if model := s.config.LLM.FallbackModel; model != "" {
+		// Marshalling a string cannot fail
+		fields["model"], _ = json.Marshal(model)
+	}
+	llmClient.SetExtraRequestFields(fields)
+	llmClient.SetAllowInvalidUTF8(s.config.LLM.AllowInvalidUTF8)
+	return llmClient
+}
+
+// requestLogger returns the request-scoped logger, falling back to the server logger
+func (s *Server) requestLogger(r *http.Request) *logging.Logger {
+	if ctxLogger := middleware.GetLogger(r.Context()); ctxLogger != nil {
+		return ctxLogger
+	}
+	return s.logger
+}
+
+// normalizeBackendURL canonicalizes a backend URL for allowlist matching
+func normalizeBackendURL(backendURL string) string {
+	return strings.TrimRight(strings.TrimSpace(backendURL), "/")
+}
+
+// SetTokenEstimator replaces the estimator used to enforce LLM.MaxPromptTokens,
+// e.g. with a tokenizer matching the backend model
+func (s *Server) SetTokenEstimator(estimator client.TokenEstimator) {
+	s.estimator = estimator
+}
+
+// SetLogLevelVar lets the admin endpoint change the level of every logger
+// created with levelVar
+func (s *Server) SetLogLevelVar(levelVar *slog.LevelVar) {
+	s.logLevel = levelVar
+}
+
+// SetAdmissionLimiter reports the admission queue of l on the debug endpoint
+func (s *Server) SetAdmissionLimiter(l *limiter.Limiter) {
+	s.admission = l
+}
+
+// SetSchemaSelfCheck replaces the validator self-check run by /ready
+func (s *Server) SetSchemaSelfCheck(check func() error) {
+	s.schemaCheck = check
 }
 
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /v1/validated-query", s.handleValidatedQuery)
+	mux.HandleFunc("POST /v1/validated-query", s.instrument(s.handleValidatedQuery))
+	mux.HandleFunc("POST /v1/validated-query/stream", s.instrument(s.handleValidatedQueryStream))
+	mux.HandleFunc("POST /v1/validate", s.handleValidate)
+	mux.HandleFunc("POST /v1/validate/batch", s.handleValidateBatch)
 	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /ready", s.handleReady)
+	mux.HandleFunc("GET /v1/capabilities", s.handleCapabilities)
+	mux.HandleFunc("POST /v1/schemas/normalize", s.handleNormalizeSchema)
+	mux.HandleFunc("POST /v1/schemas/analyze", s.handleAnalyzeSchema)
+	mux.HandleFunc("POST /v1/schemas", s.handleRegisterSchema)
+	mux.HandleFunc("GET /v1/schemas/{id}", s.handleGetSchema)
+	mux.HandleFunc("GET /v1/schemas/{id}/normalized", s.handleGetNormalizedSchema)
+	mux.HandleFunc("GET /v1/schemas/{id}/stats", s.handleGetSchemaStats)
+	if s.config.Debug.Enabled {
+		mux.HandleFunc("GET /debug/vars", s.handleDebugVars)
+	}
+	if s.config.Admin.Enabled {
+		mux.HandleFunc("GET /admin/log-level", s.requireAdmin(s.handleGetLogLevel))
+		mux.HandleFunc("POST /admin/log-level", s.requireAdmin(s.handleSetLogLevel))
+		if s.recent != nil {
+			mux.HandleFunc("GET /admin/requests", s.requireAdmin(s.handleRecentRequests))
+			mux.HandleFunc("GET /admin/requests/view", s.requireAdmin(s.handleRecentRequestsView))
+		}
+	}
+}
+
+// instrument wraps a handler so it is reflected in the request counters,
+// gets a summary for diagnostic headers and, when configured, is reported to
+// the completion webhook and kept among the recent requests
+func (s *Server) instrument(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.stats.RequestStarted()
+		defer s.stats.RequestFinished()
+
+		summary := &requestSummary{requestID: middleware.GetRequestID(r.Context())}
+		r = r.WithContext(context.WithValue(r.Context(), summaryKey{}, summary))
+		if s.webhook == nil && s.recent == nil && s.errorRates == nil {
+			next(w, r)
+			return
+		}
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		latency := time.Since(start)
+		status := completedStatus(summary, recorder.status)
+		if s.webhook != nil {
+			s.emitCompletion(summary, status, latency)
+		}
+		if s.errorRates != nil {
+			s.errorRates.Record(failureCategory(summary, status))
+		}
+		if s.recent != nil {
+			s.recordRecent(r, summary, status, types.RecentRequest{
+				StartedAt: start.UTC().Format(time.RFC3339Nano),
+				LatencyMs: latency.Milliseconds(),
+			})
+		}
+	}
+}
+
+// Close releases background resources, waiting for queued webhook events to
+// be delivered until ctx is done
+func (s *Server) Close(ctx context.Context) error {
+	if s.webhook == nil {
+		return nil
+	}
+	return s.webhook.Close(ctx)
+}
+
+func (s *Server) handleDebugVars(w http.ResponseWriter, r *http.Request) {
+	if token := s.config.Debug.Token; token != "" {
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			s.writeErrorResponse(w, r, http.StatusUnauthorized, types.ErrorCodeUnauthorized,
+				"Unauthorized", "a valid debug token is required", middleware.GetRequestID(r.Context()), nil)
+			return
+		}
+	}
+
+	size, hits, misses := s.validator.CacheStats()
+	snapshot := s.stats.Snapshot(metrics.CacheSnapshot{
+		Size:   size,
+		Hits:   hits,
+		Misses: misses,
+	})
+	if s.admission.Enabled() {
+		queue := s.admission.QueueStats()
+		snapshot.Queue = &queue
+	}
+	if s.errorRates != nil {
+		rates := s.errorRates.Snapshot()
+		snapshot.ErrorRates = &rates
+	}
+
+	s.writeJSON(w, r, http.StatusOK, snapshot)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, r, "OK")
+}
+
+// writeProbe answers a successful health probe in plain text. GET routes
+// also serve HEAD, which monitors use to probe without a body, so HEAD
+// requests get only the headers.
+func writeProbe(w http.ResponseWriter, r *http.Request, body string) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	if r.Method != http.MethodHead {
+		w.Write([]byte(body))
+	}
+}
+
+// handleReady reports whether requests can be served: the schema validator
+// must pass its self-check and the LLM backend must be healthy. The backend
+// check is cached per Health.CacheTTL; the local self-check runs every probe.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	logger := s.requestLogger(r)
+
+	if err := s.schemaCheck(); err != nil {
+		logger.WithError(err).Error("Schema validator self-check failed")
+		s.writeErrorResponse(w, r, http.StatusServiceUnavailable, types.ErrorCodeInternalError,
+			"Schema validator unavailable", err.Error(), middleware.GetRequestID(r.Context()), nil)
+		return
+	}
+
+	cached, err := s.health.check(r.Context(), s.llmClient.HealthCheck)
+	if err != nil {
+		logger.WithError(err).WithFields(map[string]interface{}{
+			"health_cached": cached,
+		}).Warn("LLM backend not ready")
+		s.writeErrorResponse(w, r, http.StatusServiceUnavailable, types.ErrorCodeLLMError,
+			"LLM backend unavailable", err.Error(), middleware.GetRequestID(r.Context()), nil)
+		return
+	}
+
+	writeProbe(w, r, "READY")
+}
+
+// handleNormalizeSchema returns the posted schema as the gateway validates it,
+// with sorted keys and local references inlined
+func (s *Server) handleNormalizeSchema(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logger := s.requestLogger(r)
+
+	var schemaBytes json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&schemaBytes); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", err.Error(), requestID, logger)
+		return
+	}
+
+	schemaBytes, ok := s.resolveFragments(w, r, schemaBytes, requestID, logger)
+	if !ok {
+		return
+	}
+
+	normalized, err := s.validator.Normalize(schemaBytes)
+	if err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+			"Invalid schema", err.Error(), requestID, logger)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(normalized)
 }
 
 func (s *Server) handleValidatedQuery(w http.ResponseWriter, r *http.Request) {
@@ -67,61 +432,498 @@ func (s *Server) handleValidatedQuery(w http.ResponseWriter, r *http.Request) {
 	if requestID == "" {
 		requestID = s.generateRequestID()
 	}
+	summary := summaryFrom(r.Context())
+	summary.requestID = requestID
 
 	requestLogger = requestLogger.WithComponent("validated_query_handler")
 
-	var req types.ValidatedQueryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	// Record which timeout governs the request, so a 504 can be traced to it
+	handlerStart := time.Now()
+	deadline, timeoutSource := s.effectiveDeadline(r, handlerStart)
+	requestLogger.WithFields(s.deadlineFields(deadline, timeoutSource, handlerStart)).Debug("Effective request deadline")
+
+	// One deadline bounds every LLM call, retry, re-prompt and validation
+	if maxDuration := s.config.Server.MaxRequestDuration; maxDuration > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), maxDuration)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	validator := s.validator
+	llmClient := s.llmClient
+	summary.backend = backendDefault
+	t := middleware.GetTenant(r.Context())
+	if t != nil {
+		if !t.Allow() {
+			requestLogger.Warn("Tenant rate limit exceeded")
+			s.writeErrorResponse(w, r, http.StatusTooManyRequests, types.ErrorCodeRateLimited,
+				"Rate limit exceeded", "tenant "+t.ID+" exceeded its request limit", requestID, requestLogger)
+			return
+		}
+		validator = s.validator.WithNamespace(t.ID)
+		if tenantClient, ok := s.tenantClients[t.ID]; ok {
+			llmClient = tenantClient
+			summary.backend = "tenant:" + t.ID
+		}
+	}
+
+	// A strictness preset named by the client replaces the server's for
+	// this request only
+	validation := s.config.Validation
+	preset := r.Header.Get("X-Validation-Preset")
+	if preset != "" {
+		if err := validation.ApplyPreset(preset); err != nil {
+			requestLogger.WithFields(map[string]interface{}{"validation_preset": preset}).Warn("Unknown validation preset")
+			s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+				"Invalid validation preset", err.Error(), requestID, requestLogger)
+			return
+		}
+		validator = validator.WithPolicy(validationPolicy(validation))
+		requestLogger = requestLogger.WithFields(map[string]interface{}{"validation_preset": preset})
+	}
+
+	// Trusted mode: an allowlisted X-LLM-Backend overrides any tenant backend.
+	// The header is ignored entirely unless the override is enabled.
+	if backendURL := r.Header.Get("X-LLM-Backend"); backendURL != "" && s.config.LLM.BackendOverride.Enabled {
+		backendClient, ok := s.backendClients[normalizeBackendURL(backendURL)]
+		if !ok {
+			requestLogger.WithFields(map[string]interface{}{"llm_backend": backendURL}).Warn("LLM backend not allowlisted")
+			s.writeErrorResponse(w, r, http.StatusForbidden, types.ErrorCodeBackendForbidden,
+				"LLM backend not allowed", "backend "+backendURL+" is not in the allowlist", requestID, requestLogger)
+			return
+		}
+		llmClient = backendClient
+		summary.backend = backendURL
+		requestLogger = requestLogger.WithFields(map[string]interface{}{"llm_backend": backendURL})
+	}
+
+	req, err := decodeQueryRequest(r.Body)
+	if err != nil {
+		var fe *fieldError
+		if errors.As(err, &fe) {
+			s.writeFieldError(w, r, fe, requestID, requestLogger)
+			return
+		}
 		requestLogger.WithError(err).Warn("Failed to decode request body")
-		s.writeErrorResponse(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
 			"Invalid request body", err.Error(), requestID, requestLogger)
 		return
 	}
+	if fe := checkMessageSizes(req.Messages, s.config.Server.MaxMessageBytes); fe != nil {
+		s.writeFieldError(w, r, fe, requestID, requestLogger)
+		return
+	}
 
-	// Validate schema
-	schemaValidationStart := time.Now()
-	if err := s.validator.ValidateSchema(req.Schema); err != nil {
-		requestLogger.WithError(err).WithDuration(time.Since(schemaValidationStart)).Warn("Schema validation failed")
-		s.writeErrorResponse(w, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
-			"Invalid JSON schema", err.Error(), requestID, requestLogger)
+	// Event stream clients see each repair attempt before the final response,
+	// and on the streaming endpoint the LLM's output as it is generated. The
+	// stream opens only once the body is read, since the server may close an
+	// unread body when the response starts.
+	var stream, deltas *eventStream
+	if wantsEventStream(r) || streamsDeltas(r) {
+		stream = newEventStream(w)
+		defer func() {
+			if err := stream.finish(); err != nil {
+				summary.writeFailed = true
+			}
+			summary.streamStatus = stream.status
+		}()
+		w = stream
+		if streamsDeltas(r) {
+			deltas = stream
+		}
+	}
+
+	keyCase := s.config.Output.KeyCase
+	if req.KeyCase != "" {
+		if !transform.ValidKeyCase(req.KeyCase) {
+			requestLogger.WithFields(map[string]interface{}{"key_case": req.KeyCase}).Warn("Unsupported key case")
+			s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+				"Invalid request body", "key_case must be \"camel\" or \"snake\"", requestID, requestLogger)
+			return
+		}
+		keyCase = req.KeyCase
+	}
+
+	if !s.checkRequestInput(w, r, validator, req, requestID, requestLogger) {
 		return
 	}
-	requestLogger.WithDuration(time.Since(schemaValidationStart)).Debug("Schema validation successful")
+	if !isEmptySchema(req.Input) {
+		req.Messages = append(req.Messages, inputMessage(req.Input))
+	}
 
-	// Send LLM request
-	llmRequestStart := time.Now()
-	requestLogger.WithOperation("llm_request").Info("Sending structured query to LLM")
-	response, err := s.llmClient.SendStructuredQuery(r.Context(), req.Messages, req.Schema)
-	llmDuration := time.Since(llmRequestStart)
+	if maxTokens := s.config.LLM.MaxPromptTokens; maxTokens > 0 {
+		if estimated := s.estimator.EstimateTokens(req.Messages); estimated > maxTokens {
+			requestLogger.WithFields(map[string]interface{}{
+				"estimated_tokens":  estimated,
+				"max_prompt_tokens": maxTokens,
+			}).Warn("Prompt exceeds token limit")
+			s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodePromptTooLarge,
+				"Prompt too large", fmt.Sprintf("estimated %d prompt tokens exceeds limit of %d", estimated, maxTokens),
+				requestID, requestLogger)
+			return
+		}
+	}
 
-	if err != nil {
-		requestLogger.WithError(err).WithDuration(llmDuration).Error("LLM request failed")
-		s.writeErrorResponse(w, http.StatusInternalServerError, types.ErrorCodeLLMError,
-			"LLM service error", err.Error(), requestID, requestLogger)
+	if req.SchemaID != "" {
+		if !isEmptySchema(req.Schema) {
+			s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+				"Invalid request body", "provide either schema or schema_id, not both", requestID, requestLogger)
+			return
+		}
+		registered, ok := s.registry.Get(req.SchemaID)
+		if !ok {
+			s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeSchemaNotFound,
+				"Unknown schema ID", "schema "+req.SchemaID+" is not registered", requestID, requestLogger)
+			return
+		}
+		req.Schema = registered
+	}
+
+	schemaless := isEmptySchema(req.Schema)
+	if schemaless {
+		if !validation.AllowSchemaless {
+			requestLogger.Warn("Request is missing a schema")
+			s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+				"Missing JSON schema", "a schema is required", requestID, requestLogger)
+			return
+		}
+		req.Schema = nil
+	} else {
+		summary.schemaHash = schema.Hash(req.Schema)
+	}
+
+	if t != nil && !t.SchemaAllowed(schema.Hash(req.Schema)) {
+		requestLogger.Warn("Schema not in tenant allowlist")
+		s.writeErrorResponse(w, r, http.StatusForbidden, types.ErrorCodeSchemaForbidden,
+			"Schema not allowed", "schema "+schema.Hash(req.Schema)+" is not allowlisted for tenant "+t.ID, requestID, requestLogger)
 		return
 	}
-	requestLogger.WithDuration(llmDuration).WithFields(map[string]interface{}{
-		"response_size_bytes": len(response.Data),
-	}).Info("LLM request successful")
 
-	// Validate response
-	responseValidationStart := time.Now()
-	if err := s.validator.ValidateResponse(req.Schema, response); err != nil {
+	// Validate schema
+	if !schemaless {
+		resolved, ok := s.resolveFragments(w, r, req.Schema, requestID, requestLogger)
+		if !ok {
+			return
+		}
+		req.Schema = resolved
+
+		schemaValidationStart := time.Now()
+		if err := validator.ValidateSchema(req.Schema); err != nil {
+			requestLogger.WithError(err).WithDuration(time.Since(schemaValidationStart)).Warn("Schema validation failed")
+			s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+				"Invalid JSON schema", err.Error(), requestID, requestLogger)
+			return
+		}
+		requestLogger.WithDuration(time.Since(schemaValidationStart)).Debug("Schema validation successful")
+
+		if !s.checkSchemaInjection(w, r, req.Schema, requestID, requestLogger) {
+			return
+		}
+	}
+
+	// Callers that handle retries themselves can opt out of gateway retries
+	llmCtx := r.Context()
+	if noRetry, _ := strconv.ParseBool(r.Header.Get("X-No-Retry")); noRetry {
+		llmCtx = client.WithoutRetry(llmCtx)
+	}
+
+	// The initial call, HTTP retries and repair re-prompts share one budget
+	budget := client.NewCallBudget(s.config.LLM.MaxCallsPerRequest)
+	llmCtx = client.WithCallBudget(llmCtx, budget)
+
+	// A cached response was validated when stored, so it skips the LLM
+	// entirely. Schemas registered as not cacheable bypass the cache both ways.
+	cachePolicy := s.cachePolicy(summary.schemaHash)
+	tenantID := ""
+	if t != nil {
+		tenantID = t.ID
+	}
+	cacheKey := s.responseCacheKey(req, tenantID, summary.backend, preset)
+	summary.cacheStatus = cacheMiss
+
+	// The LLM may be shown a hinted copy of the schema; validation below
+	// always uses req.Schema
+	llmSchema := req.Schema
+	if s.config.LLM.SchemaHints && !schemaless {
+		hinted, err := schema.AddFormatHints(req.Schema)
+		if err != nil {
+			requestLogger.WithError(err).Warn("Failed to add schema hints")
+		} else {
+			llmSchema = hinted
+		}
+	}
+
+	messages := req.Messages
+	var response *types.ValidatedResponse
+	var llmOutput json.RawMessage
+	var warnings []string
+	var cached json.RawMessage
+	hit := false
+	if cachePolicy.Cacheable {
+		cached, hit = s.cachedResponse(r.Context(), cacheKey, requestLogger)
+	}
+	if hit {
+		requestLogger.Debug("Serving response from cache")
+		summary.cacheStatus = cacheHit
+		response = &types.ValidatedResponse{Data: cached}
+		llmOutput = cached
+	}
+	for attempt := 0; !hit; attempt++ {
+		// Send LLM request
+		llmRequestStart := time.Now()
+		requestLogger.WithOperation("llm_request").Info("Sending structured query to LLM")
+		var err error
+		var streamed bool
+		response, streamed, err = sendQuery(llmCtx, llmClient, messages, llmSchema, deltas)
+		// A default-backend timeout moves the rest of the request to the
+		// fallback backend while the request itself still has time. Output
+		// already streamed to the client cannot be taken back, so a timeout
+		// after the first delta is not retried.
+		if err != nil && s.fallbackClient != nil && summary.backend == backendDefault &&
+			client.IsTimeout(err) && r.Context().Err() == nil && !streamed {
+			requestLogger.WithError(err).WithDuration(time.Since(llmRequestStart)).Warn("LLM request timed out, falling back")
+			llmClient = s.fallbackClient
+			summary.backend = backendFallback
+			response, _, err = sendQuery(llmCtx, llmClient, messages, llmSchema, deltas)
+		}
+		llmDuration := time.Since(llmRequestStart)
+
+		if err != nil {
+			requestLogger.WithError(err).WithDuration(llmDuration).WithFields(map[string]interface{}{
+				"llm_calls": budget.Used(),
+			}).Error("LLM request failed")
+			if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+				s.writeErrorResponse(w, r, http.StatusGatewayTimeout, types.ErrorCodeTimeout,
+					"Request exceeded maximum duration", err.Error(), requestID,
+					requestLogger.WithFields(map[string]interface{}{"timeout_source": timeoutSource}))
+				return
+			}
+			s.stats.RecordLLMError()
+			if errors.Is(err, client.ErrEmptyResponse) {
+				s.writeErrorResponse(w, r, http.StatusBadGateway, types.ErrorCodeLLMEmptyResponse,
+					"LLM returned an empty response", err.Error(), requestID, requestLogger)
+				return
+			}
+			s.writeErrorResponse(w, r, http.StatusInternalServerError, types.ErrorCodeLLMError,
+				"LLM service error", err.Error(), requestID, requestLogger)
+			return
+		}
+		summary.usage = response.Usage
+		llmOutput = response.Data
+		requestLogger.WithDuration(llmDuration).WithFields(map[string]interface{}{
+			"response_size_bytes": len(response.Data),
+		}).Info("LLM request successful")
+
+		// Scan for duplicate keys before decoding hides all but the last
+		warnings = nil
+		if policy := validation.DuplicateKeys; policy == schema.DuplicateKeysWarn || policy == schema.DuplicateKeysReject {
+			duplicates, err := schema.DuplicateKeys(response.Data)
+			if err != nil {
+				requestLogger.WithError(err).Warn("Failed to scan response for duplicate keys")
+			} else if len(duplicates) > 0 {
+				requestLogger.WithFields(map[string]interface{}{
+					"duplicate_keys": duplicates,
+					"policy":         policy,
+				}).Warn("LLM response contains duplicate keys")
+				if policy == schema.DuplicateKeysReject {
+					s.writeErrorResponse(w, r, http.StatusUnprocessableEntity, types.ErrorCodeDuplicateKeys,
+						"Response contains duplicate keys", "duplicate keys at "+strings.Join(duplicates, ", "), requestID, requestLogger)
+					return
+				}
+				for _, path := range duplicates {
+					warnings = append(warnings, path+": duplicate key, last value kept")
+				}
+			}
+		}
+
+		// Validate response
+		if schemaless {
+			// The client has already ensured the content is valid JSON
+			requestLogger.Debug("Schemaless pass-through, skipping response validation")
+			break
+		}
+
+		responseValidationStart := time.Now()
+		if err := validator.ValidateResponse(req.Schema, response); err != nil {
+			validationDuration := time.Since(responseValidationStart)
+			if errors.Is(err, schema.ErrValidationTimeout) {
+				requestLogger.WithError(err).WithDuration(validationDuration).Warn("Response validation timed out")
+				s.writeErrorResponse(w, r, http.StatusGatewayTimeout, types.ErrorCodeValidationTimeout, "Response validation timed out", err.Error(), requestID, requestLogger)
+				return
+			}
+			requestLogger.WithError(err).WithDuration(validationDuration).Warn("Response validation failed")
+			violations := schema.Violations(err, req.Schema, response.Data)
+
+			// Re-prompt with the validation error while attempts and calls remain
+			if attempt < validation.MaxRepairAttempts && budget.Remaining() != 0 && s.repairAllowed(req) {
+				requestLogger.WithFields(map[string]interface{}{
+					"repair_attempt":            attempt + 1,
+					"repair_attempts_remaining": validation.MaxRepairAttempts - attempt - 1,
+					"llm_calls_remaining":       budget.Remaining(),
+				}).Info("Re-prompting LLM to repair invalid response")
+				messages = repairMessages(messages, response.Data, violations, err.Error())
+				if stream != nil {
+					stream.event(eventAttempt, types.RepairAttempt{
+						Attempt:    attempt + 1,
+						Message:    fmt.Sprintf("attempt %d failed: %s; retrying", attempt+1, err),
+						Violations: violations,
+					})
+				}
+				continue
+			}
+			s.registry.RecordValidation(summary.schemaHash, false, len(response.Data))
+			missing := schema.MissingRequired(err, req.Schema, response.Data)
+			s.writeValidationError(w, r, "Schema validation failed", err.Error(), response.Data, violations, missing, summary.schemaHash, req.Schema, attempt, requestID, requestLogger)
+			return
+		}
 		validationDuration := time.Since(responseValidationStart)
-		requestLogger.WithError(err).WithDuration(validationDuration).Warn("Response validation failed")
-		s.writeValidationError(w, "Schema validation failed", err.Error(), response.Data, requestID, requestLogger)
+		requestLogger.WithDuration(validationDuration).Debug("Response validation successful")
+		s.registry.RecordValidation(summary.schemaHash, true, len(response.Data))
+
+		if validation.ReportWarnings {
+			collected, err := validator.CollectWarnings(req.Schema, response)
+			if err != nil {
+				// Warnings are advisory; never fail a valid response over them
+				requestLogger.WithError(err).Warn("Failed to collect validation warnings")
+			}
+			warnings = append(warnings, collected...)
+		}
+		break
+	}
+	if !hit && cachePolicy.Cacheable {
+		s.storeResponse(r.Context(), cacheKey, response.Data, cachePolicy.TTL, requestLogger)
+	}
+	if warning := s.smallResponseWarning(response.Data); warning != "" {
+		requestLogger.WithFields(map[string]interface{}{
+			"response_size_bytes": len(response.Data),
+			"warning":             warning,
+		}).Warn("Validated response is suspiciously small")
+		warnings = append(warnings, warning)
+	}
+	if validation.ReportWarnings && len(warnings) > 0 {
+		w.Header().Set("X-Validation-Warnings", strings.Join(warnings, "; "))
+	}
+
+	// Transformations are listed in the order they are applied to the data
+	transformations := []types.Transformation{}
+	if !bytes.Equal(response.Data, llmOutput) {
+		transformations = append(transformations, types.Transformation{
+			Name: types.TransformationNulls, Detail: validation.NullPolicy,
+		})
+	}
+
+	data := response.Data
+	if validation.ApplyDefaults && !schemaless {
+		filled, err := schema.ApplyDefaults(req.Schema, data)
+		if err != nil {
+			requestLogger.WithError(err).Error("Failed to apply schema defaults")
+			s.writeErrorResponse(w, r, http.StatusInternalServerError, types.ErrorCodeInternalError,
+				"Failed to apply schema defaults", err.Error(), requestID, requestLogger)
+			return
+		}
+		data = filled
+		transformations = append(transformations, types.Transformation{Name: types.TransformationDefaults})
+	}
+
+	// Null rewriting and defaults re-encode data with sorted keys
+	if s.config.Output.PreserveKeyOrder && !bytes.Equal(data, llmOutput) {
+		ordered, err := transform.PreserveKeyOrder(llmOutput, data)
+		if err != nil {
+			requestLogger.WithError(err).Error("Failed to restore response key order")
+			s.writeErrorResponse(w, r, http.StatusInternalServerError, types.ErrorCodeInternalError,
+				"Failed to transform response", err.Error(), requestID, requestLogger)
+			return
+		}
+		data = ordered
+		transformations = append(transformations, types.Transformation{Name: types.TransformationKeyOrder})
+	}
+
+	// Rekey only after validation, which runs against the schema's original key case
+	if keyCase != transform.KeyCaseNone {
+		rekeyed, err := transform.RekeyJSON(data, keyCase)
+		if err != nil {
+			requestLogger.WithError(err).Error("Failed to transform response keys")
+			s.writeErrorResponse(w, r, http.StatusInternalServerError, types.ErrorCodeInternalError,
+				"Failed to transform response", err.Error(), requestID, requestLogger)
+			return
+		}
+		data = rekeyed
+		transformations = append(transformations, types.Transformation{Name: types.TransformationKeyCase, Detail: keyCase})
+	}
+
+	completion := map[string]interface{}{}
+	if cost, ok := client.PriceTable(s.config.LLM.Prices).EstimateCost(response.Model, response.Usage); ok {
+		completion["llm_model"] = response.Model
+		completion["estimated_cost_usd"] = cost
+		if s.config.Output.CostHeader {
+			w.Header().Set("X-Estimated-Cost", strconv.FormatFloat(cost, 'f', -1, 64))
+		}
+	}
+
+	var body interface{} = data
+	if s.config.Output.Envelope {
+		envelope := types.ResponseEnvelope{Data: data, Usage: response.Usage, Model: response.Model}
+		if s.config.Output.RequestIDInBody {
+			envelope.RequestID = requestID
+		}
+		if s.config.Output.ReportTransformations {
+			envelope.Metadata = &types.EnvelopeMetadata{Transformations: transformations}
+		}
+		body = envelope
+	}
+
+	var writeErr error
+	if s.config.Output.Canonical {
+		canonical, err := canonicalBody(body)
+		if err != nil {
+			requestLogger.WithError(err).Error("Failed to canonicalize response")
+			s.writeErrorResponse(w, r, http.StatusInternalServerError, types.ErrorCodeInternalError,
+				"Failed to canonicalize response", err.Error(), requestID, requestLogger)
+			return
+		}
+		writeErr = s.writeRawJSON(w, r, http.StatusOK, canonical)
+	} else {
+		writeErr = s.writeJSON(w, r, http.StatusOK, body)
+	}
+	if writeErr != nil {
+		s.recordWriteFailure(r, writeErr, summary, requestLogger)
 		return
 	}
-	validationDuration := time.Since(responseValidationStart)
-	requestLogger.WithDuration(validationDuration).Debug("Response validation successful")
 
-	// Success - return validated response
-	requestLogger.WithFields(map[string]interface{}{
+	// Success - the validated response reached the client
+	completion["total_duration_ms"] = time.Since(middleware.GetStartTime(r.Context())).Milliseconds()
+	requestLogger.WithFields(completion).Info("Validated query completed successfully")
+}
+
+// recordWriteFailure logs a response body that could not be fully written
+// and counts the request as failed. A client that went away mid-response is
+// reported as a disconnect rather than a server fault.
+func (s *Server) recordWriteFailure(r *http.Request, err error, summary *requestSummary, logger *logging.Logger) {
+	summary.writeFailed = true
+	code := types.ErrorCodeWriteFailed
+	message := "Failed to write response"
+	if isClientDisconnect(r.Context(), err) {
+		code = types.ErrorCodeClientDisconnected
+		message = "Client disconnected before response was written"
+	}
+	s.recordFailure(r, code)
+	logger.WithError(err).WithFields(map[string]interface{}{
+		"error_code":        code,
 		"total_duration_ms": time.Since(middleware.GetStartTime(r.Context())).Milliseconds(),
-	}).Info("Validated query completed successfully")
+	}).Warn(message)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response.Data)
+// isClientDisconnect reports whether a write failed because the client
+// cancelled the request or closed the connection
+func isClientDisconnect(ctx context.Context, err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// isEmptySchema reports whether the request omitted its schema
+func isEmptySchema(schemaBytes json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(schemaBytes)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
 }
 
 // generateRequestID creates a unique request identifier
@@ -131,13 +933,19 @@ func (s *Server) generateRequestID() string {
 	return hex.EncodeToString(bytes)
 }
 
-// writeErrorResponse writes a standardized error response
-func (s *Server) writeErrorResponse(w http.ResponseWriter, status int, code, message, details string, requestID string, logger *logging.Logger) {
-	errorResp := types.NewErrorResponse(code, message, details).WithRequestID(requestID)
+// writeErrorResponse writes a standardized error response. LLM error details
+// are sanitized for the client; the log keeps them in full.
+func (s *Server) writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, code, message, details string, requestID string, logger *logging.Logger) {
+	clientDetails := details
+	if code == types.ErrorCodeLLMError {
+		clientDetails = s.sanitizeDetails(details)
+	}
+	errorResp := types.NewErrorResponse(code, message, clientDetails).WithRequestID(requestID)
+	s.recordFailure(r, code)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(errorResp)
+	s.writeProblem(w, r, status, errorResp, func() *types.ProblemDetails {
+		return errorResp.Problem(status, r.URL.Path)
+	})
 
 	if logger != nil {
 		logger.WithFields(map[string]interface{}{
@@ -148,24 +956,46 @@ func (s *Server) writeErrorResponse(w http.ResponseWriter, status int, code, mes
 	}
 }
 
-// writeValidationError writes a standardized validation error response
-func (s *Server) writeValidationError(w http.ResponseWriter, message, details string, responseData json.RawMessage, requestID string, logger *logging.Logger) {
+// Values of Output.ErrorSchema that add the applied schema to 422 errors
+const (
+	errorSchemaID   = "id"
+	errorSchemaFull = "full"
+)
+
+// writeValidationError writes a standardized validation error response,
+// reporting how many repair re-prompts preceded the final failure
+func (s *Server) writeValidationError(w http.ResponseWriter, r *http.Request, message, details string, responseData json.RawMessage, violations []types.Violation, missingRequired []string, schemaID string, schemaBytes json.RawMessage, repairAttempts int, requestID string, logger *logging.Logger) {
 	validationErr := types.NewValidationError(message, details, responseData).
-		WithValidationContext("endpoint", "/v1/validated-query")
+		WithValidationContext("endpoint", r.URL.Path).
+		WithValidationContext("repair_attempts", repairAttempts)
+	// The applied schema helps clients that sent only a schema_id, at the
+	// cost of larger error bodies
+	switch s.config.Output.ErrorSchema {
+	case errorSchemaFull:
+		validationErr = validationErr.WithValidationContext("schema", schemaBytes)
+		fallthrough
+	case errorSchemaID:
+		validationErr = validationErr.WithValidationContext("schema_id", schemaID)
+	}
+	validationErr.Violations = violations
+	validationErr.MissingRequired = missingRequired
+	s.recordFailure(r, validationErr.Code)
 
 	if requestID != "" {
 		validationErr.RequestID = requestID
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnprocessableEntity)
-	json.NewEncoder(w).Encode(validationErr)
+	s.writeProblem(w, r, http.StatusUnprocessableEntity, validationErr, func() *types.ProblemDetails {
+		return validationErr.Problem(http.StatusUnprocessableEntity, r.URL.Path)
+	})
 
 	if logger != nil {
 		logger.WithFields(map[string]interface{}{
 			"status_code":        http.StatusUnprocessableEntity,
 			"validation_details": details,
+			"violation_count":    len(violations),
 			"response_size":      len(responseData),
+			"repair_attempts":    repairAttempts,
 		}).Warn(message)
 	}
 }
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// Server-sent event names of a streamed validated query
+const (
+	eventAttempt = "attempt"
+	eventDelta   = "delta"
+	eventResult  = "result"
+	eventError   = "error"
+)
+
+// streamDeltasKey marks a request made to /v1/validated-query/stream
+type streamDeltasKey struct{}
+
+// handleValidatedQueryStream answers a validated query as server-sent events
+// whatever its Accept header, forwarding the LLM's output as "delta" events
+// while it is generated. Validation still runs once the output is complete,
+// so output that fails it ends the stream with an "error" event carrying the
+// ValidationError rather than a 422, since the 200 has already been sent.
+func (s *Server) handleValidatedQueryStream(w http.ResponseWriter, r *http.Request) {
+	s.handleValidatedQuery(w, r.WithContext(context.WithValue(r.Context(), streamDeltasKey{}, true)))
+}
+
+// streamsDeltas reports whether the request came through
+// handleValidatedQueryStream
+func streamsDeltas(r *http.Request) bool {
+	streaming, _ := r.Context().Value(streamDeltasKey{}).(bool)
+	return streaming
+}
+
+// sendQuery queries the LLM. With a delta stream, the completion is streamed
+// from the backend and each piece forwarded as a "delta" event as it arrives;
+// streamed reports whether any was, even when the query then failed.
+func sendQuery(ctx context.Context, llmClient client.LLMClient, messages []types.Message, schema json.RawMessage, deltas *eventStream) (response *types.ValidatedResponse, streamed bool, err error) {
+	if deltas == nil {
+		response, err = llmClient.SendStructuredQuery(ctx, messages, schema)
+		return response, false, err
+	}
+	stream, err := llmClient.SendStructuredQueryStream(ctx, messages, schema)
+	if err != nil {
+		return nil, false, err
+	}
+	response, err = client.CollectStream(stream, func(content string) {
+		streamed = true
+		deltas.event(eventDelta, types.ContentDelta{Content: content})
+	})
+	return response, streamed, err
+}
+
+// wantsEventStream reports whether the client asked for server-sent events
+func wantsEventStream(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(mediaType), "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// eventStream sends a validated query as server-sent events. The stream is
+// opened with a 200 at once so progress events reach the client while the
+// query runs; the handler's own response is captured and sent as the final
+// "result" event, or an "error" event when its status is a failure.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return &eventStream{w: w, flusher: flusher, header: make(http.Header), status: http.StatusOK}
+}
+
+// Header returns the headers of the final response, which are never sent
+// since the stream's headers already were
+func (e *eventStream) Header() http.Header {
+	return e.header
+}
+
+func (e *eventStream) WriteHeader(status int) {
+	e.status = status
+}
+
+func (e *eventStream) Write(p []byte) (int, error) {
+	return e.body.Write(p)
+}
+
+// event sends one event with v encoded as its data
+func (e *eventStream) event(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return e.send(name, data)
+}
+
+// finish sends the captured response as the final event
+func (e *eventStream) finish() error {
+	name := eventResult
+	if e.status >= http.StatusBadRequest {
+		name = eventError
+	}
+	return e.send(name, bytes.TrimSpace(e.body.Bytes()))
+}
+
+// send writes an event, one data line per line of data, and flushes it
+func (e *eventStream) send(name string, data []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "event: %s\n", name)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+	if _, err := e.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return nil
+}
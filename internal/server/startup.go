@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+)
+
+// ErrPlaceholderLLMURL is returned by CheckStartup in strict mode when the
+// LLM server URL looks like a default or placeholder
+var ErrPlaceholderLLMURL = errors.New("LLM server URL looks like a placeholder")
+
+// placeholderHostWords mark hostnames nobody would deploy a backend under
+var placeholderHostWords = []string{"example", "placeholder", "changeme", "your-", "todo"}
+
+// placeholderReason explains why an LLM server URL looks like a default or
+// placeholder rather than a real backend, or returns "" if it does not
+func placeholderReason(serverURL string) string {
+	if serverURL == config.Default().LLM.ServerURL {
+		return "it is the built-in default"
+	}
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" {
+		return "it is not an absolute URL"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Sprintf("scheme %q is not http or https", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, tld := range []string{".invalid", ".example", ".test"} {
+		if strings.HasSuffix(host, tld) {
+			return "host " + host + " uses a reserved test domain"
+		}
+	}
+	for _, word := range placeholderHostWords {
+		if strings.Contains(host, word) {
+			return "host " + host + " looks like a placeholder"
+		}
+	}
+	return ""
+}
+
+// CheckStartup looks for misconfiguration that basic validation accepts but
+// that would make every LLM request fail, such as a placeholder LLM server
+// URL. Problems are logged as warnings; with Server.StrictStartup they are
+// returned so the caller can refuse to start.
+func CheckStartup(cfg *config.Config, logger *logging.Logger) error {
+	reason := placeholderReason(cfg.LLM.ServerURL)
+	if reason == "" {
+		return nil
+	}
+
+	logger.WithComponent("startup").WithFields(map[string]interface{}{
+		"llm_server_url": cfg.LLM.ServerURL,
+		"reason":         reason,
+		"strict_startup": cfg.Server.StrictStartup,
+	}).Warn("LLM server URL looks like a default or placeholder; set LLM_SERVER_URL to the real backend")
+
+	if cfg.Server.StrictStartup {
+		return fmt.Errorf("%w: %s", ErrPlaceholderLLMURL, reason)
+	}
+	return nil
+}
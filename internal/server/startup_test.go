@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+)
+
+func TestPlaceholderReason(t *testing.T) {
+	placeholders := []string{
+		config.Default().LLM.ServerURL,
+		"http://llm.example.com:8080",
+		"http://backend.invalid",
+		"https://your-llm-host",
+		"llm-server:8080",
+		"ftp://llm:8080",
+	}
+	for _, serverURL := range placeholders {
+		assert.NotEmpty(t, placeholderReason(serverURL), serverURL)
+	}
+
+	real := []string{"http://llm:8080", "https://llm.internal.corp", "http://10.0.0.5:8080", "http://localhost:9000"}
+	for _, serverURL := range real {
+		assert.Empty(t, placeholderReason(serverURL), serverURL)
+	}
+}
+
+func TestCheckStartup(t *testing.T) {
+	newLogger := func() (*logging.Logger, *bytes.Buffer) {
+		var buf bytes.Buffer
+		return logging.NewLogger(logging.LogConfig{Level: "info", Format: "json", Output: &buf}), &buf
+	}
+
+	t.Run("placeholder_warns", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.LLM.ServerURL = "http://llm.example.com"
+		logger, buf := newLogger()
+
+		require.NoError(t, CheckStartup(cfg, logger))
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &entry))
+		assert.Equal(t, "WARN", entry["level"])
+		assert.Equal(t, "http://llm.example.com", entry["llm_server_url"])
+		assert.Contains(t, entry["msg"], "placeholder")
+	})
+
+	t.Run("placeholder_fails_in_strict_mode", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Server.StrictStartup = true
+		logger, buf := newLogger()
+
+		err := CheckStartup(cfg, logger)
+		assert.ErrorIs(t, err, ErrPlaceholderLLMURL)
+		assert.Contains(t, err.Error(), "built-in default")
+		assert.Contains(t, buf.String(), "placeholder")
+	})
+
+	t.Run("real_url_passes_strict_mode", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.LLM.ServerURL = "http://llm:8080"
+		cfg.Server.StrictStartup = true
+		logger, buf := newLogger()
+
+		assert.NoError(t, CheckStartup(cfg, logger))
+		assert.Empty(t, buf.String())
+	})
+}
@@ -0,0 +1,38 @@
+package server
+
+import "net/http"
+
+// streamChunkSize is how much of a large response body is written between
+// flushes
+const streamChunkSize = 32 << 10
+
+// chunkedWriter writes large bodies in chunks, flushing after each so clients
+// start receiving data (with chunked transfer encoding) before the whole body
+// is sent. Bodies of at most one chunk pass through in a single write.
+type chunkedWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newChunkedWriter(w http.ResponseWriter) *chunkedWriter {
+	flusher, _ := w.(http.Flusher)
+	return &chunkedWriter{w: w, flusher: flusher}
+}
+
+func (c *chunkedWriter) Write(p []byte) (int, error) {
+	if c.flusher == nil || len(p) <= streamChunkSize {
+		return c.w.Write(p)
+	}
+
+	written := 0
+	for written < len(p) {
+		end := min(written+streamChunkSize, len(p))
+		n, err := c.w.Write(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		c.flusher.Flush()
+	}
+	return written, nil
+}
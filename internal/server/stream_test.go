@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+)
+
+// flushCounter is a ResponseWriter that counts flushes and discards the body
+type flushCounter struct {
+	header  http.Header
+	written int
+	flushes int
+}
+
+func (f *flushCounter) Header() http.Header         { return f.header }
+func (f *flushCounter) WriteHeader(int)             {}
+func (f *flushCounter) Write(p []byte) (int, error) { f.written += len(p); return len(p), nil }
+func (f *flushCounter) Flush()                      { f.flushes++ }
+
+// largeResponse builds a compact JSON object of roughly size bytes
+func largeResponse(size int) json.RawMessage {
+	return json.RawMessage(`{"text":"` + strings.Repeat("x", size) + `"}`)
+}
+
+func TestChunkedWriter(t *testing.T) {
+	s := &Server{config: config.Default()}
+	req := httptest.NewRequest("POST", "/v1/validated-query", nil)
+
+	t.Run("large_body_flushed_incrementally", func(t *testing.T) {
+		data := largeResponse(1 << 20)
+		rec := httptest.NewRecorder()
+		counter := &flushCounter{header: http.Header{}}
+
+		s.writeJSON(counter, req, http.StatusOK, data)
+		s.writeJSON(rec, req, http.StatusOK, data)
+
+		assert.Equal(t, len(data)+1, counter.written) // Encode appends a newline
+		assert.GreaterOrEqual(t, counter.flushes, len(data)/streamChunkSize)
+		assert.True(t, rec.Flushed)
+		assert.JSONEq(t, string(data), rec.Body.String())
+	})
+
+	t.Run("small_body_written_once", func(t *testing.T) {
+		counter := &flushCounter{header: http.Header{}}
+		s.writeJSON(counter, req, http.StatusOK, json.RawMessage(`{"name":"Jane"}`))
+		assert.Zero(t, counter.flushes)
+	})
+
+	t.Run("bounded_memory", func(t *testing.T) {
+		data := largeResponse(4 << 20)
+		result := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.writeJSON(&flushCounter{header: http.Header{}}, req, http.StatusOK, data)
+			}
+		})
+		// The encoder's single compacted copy is the only full-size buffer
+		require.NotZero(t, result.N)
+		assert.Less(t, result.AllocedBytesPerOp(), int64(2*len(data)))
+	})
+}
+
+func BenchmarkWriteLargeResponse(b *testing.B) {
+	s := &Server{config: config.Default()}
+	req := httptest.NewRequest("POST", "/v1/validated-query", nil)
+	data := largeResponse(4 << 20)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.writeJSON(&flushCounter{header: http.Header{}}, req, http.StatusOK, data)
+	}
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// Output formats for /v1/validate
+const (
+	validateFormatText = "text"
+	validateFormatCI   = "ci"
+)
+
+// handleValidate checks data against a schema without querying the LLM. The
+// default text format is for people; ?format=ci returns a ValidationReport
+// for tooling. Both report a failed validation with 200, since the request
+// itself succeeded.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logger := s.requestLogger(r)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = validateFormatText
+	}
+	if format != validateFormatText && format != validateFormatCI {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid format", fmt.Sprintf("format must be %q or %q, got %q", validateFormatText, validateFormatCI, format), requestID, logger)
+		return
+	}
+
+	var req types.ValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", err.Error(), requestID, logger)
+		return
+	}
+	if isEmptySchema(req.Schema) || len(req.Data) == 0 {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", "schema and data are required", requestID, logger)
+		return
+	}
+	schemaID := schema.Hash(req.Schema)
+	schemaBytes, ok := s.resolveFragments(w, r, req.Schema, requestID, logger)
+	if !ok {
+		return
+	}
+	req.Schema = schemaBytes
+	if err := s.validator.ValidateSchema(req.Schema); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+			"Invalid JSON schema", err.Error(), requestID, logger)
+		return
+	}
+
+	report, err := s.validationReport(req)
+	if errors.Is(err, schema.ErrValidationTimeout) {
+		s.writeErrorResponse(w, r, http.StatusGatewayTimeout, types.ErrorCodeValidationTimeout,
+			"Validation timed out", err.Error(), requestID, logger)
+		return
+	}
+	s.registry.RecordValidation(schemaID, report.Valid, len(req.Data))
+
+	if format == validateFormatCI {
+		s.writeJSON(w, r, http.StatusOK, report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(formatReportText(report)))
+}
+
+// handleValidateBatch checks each payload against one schema without
+// querying the LLM. The schema is compiled once and shared through the
+// validator's cache; payloads are validated concurrently, up to the
+// configured limit, and reported in request order.
+func (s *Server) handleValidateBatch(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logger := s.requestLogger(r)
+
+	var req types.ValidateBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", err.Error(), requestID, logger)
+		return
+	}
+	if isEmptySchema(req.Schema) || len(req.Payloads) == 0 {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Invalid request body", "schema and at least one payload are required", requestID, logger)
+		return
+	}
+	if maxPayloads := s.config.Validation.BatchMaxPayloads; len(req.Payloads) > maxPayloads {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidRequest,
+			"Too many payloads", fmt.Sprintf("a batch may hold at most %d payloads, got %d", maxPayloads, len(req.Payloads)), requestID, logger)
+		return
+	}
+	schemaID := schema.Hash(req.Schema)
+	schemaBytes, ok := s.resolveFragments(w, r, req.Schema, requestID, logger)
+	if !ok {
+		return
+	}
+	req.Schema = schemaBytes
+	if err := s.validator.ValidateSchema(req.Schema); err != nil {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, types.ErrorCodeInvalidSchema,
+			"Invalid JSON schema", err.Error(), requestID, logger)
+		return
+	}
+
+	reports := make([]*types.ValidationReport, len(req.Payloads))
+	errs := make([]error, len(req.Payloads))
+	sem := make(chan struct{}, s.config.Validation.BatchConcurrency)
+	var wg sync.WaitGroup
+	for i, payload := range req.Payloads {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, payload json.RawMessage) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			reports[i], errs[i] = s.validationReport(types.ValidateRequest{Schema: req.Schema, Data: payload})
+		}(i, payload)
+	}
+	wg.Wait()
+
+	resp := types.ValidateBatchResponse{
+		SchemaHash: schema.Hash(req.Schema),
+		Results:    make([]types.BatchPayloadReport, len(reports)),
+	}
+	for i, report := range reports {
+		if errs[i] != nil {
+			s.writeErrorResponse(w, r, http.StatusGatewayTimeout, types.ErrorCodeValidationTimeout,
+				"Validation timed out", fmt.Sprintf("payload %d: %v", i, errs[i]), requestID, logger)
+			return
+		}
+		if report.Valid {
+			resp.Valid++
+		} else {
+			resp.Invalid++
+		}
+		resp.Results[i] = types.BatchPayloadReport{Index: i, ValidationReport: *report}
+	}
+	for i, report := range reports {
+		s.registry.RecordValidation(schemaID, report.Valid, len(req.Payloads[i]))
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"payloads": len(req.Payloads),
+		"valid":    resp.Valid,
+		"invalid":  resp.Invalid,
+	}).Info("Batch validation completed")
+	s.writeJSON(w, r, http.StatusOK, resp)
+}
+
+// validationReport validates req.Data and gathers its errors and warnings in
+// a stable order: errors before warnings, then by path, keyword and message.
+// The only error returned is a validation timeout. Reports are served from
+// the result cache when enabled; timeouts are never cached.
+func (s *Server) validationReport(req types.ValidateRequest) (*types.ValidationReport, error) {
+	if s.results == nil {
+		return s.buildValidationReport(req)
+	}
+	key := schema.ResultKey(req.Schema, req.Data)
+	if report, ok := s.results.Get(key); ok {
+		return report, nil
+	}
+	report, err := s.buildValidationReport(req)
+	if err != nil {
+		return nil, err
+	}
+	s.results.Set(key, report)
+	return report, nil
+}
+
+// buildValidationReport validates req.Data against req.Schema
+func (s *Server) buildValidationReport(req types.ValidateRequest) (*types.ValidationReport, error) {
+	report := &types.ValidationReport{
+		Valid:      true,
+		SchemaHash: schema.Hash(req.Schema),
+		Results:    []types.ValidationResult{},
+	}
+
+	data := &types.ValidatedResponse{Data: req.Data}
+	if err := s.validator.ValidateResponse(req.Schema, data); err != nil {
+		if errors.Is(err, schema.ErrValidationTimeout) {
+			return nil, err
+		}
+		report.Valid = false
+		violations := schema.Violations(err, req.Schema, req.Data)
+		if len(violations) == 0 {
+			// The data could not be decoded, so there is no schema location
+			violations = []types.Violation{{Path: "/", Keyword: "json", Message: err.Error()}}
+		}
+		for _, v := range violations {
+			report.Results = append(report.Results, newValidationResult(types.SeverityError, v))
+		}
+	}
+
+	// Warnings are advisory and never affect validity
+	warnings, _ := s.validator.Warnings(req.Schema, req.Data)
+	for _, v := range warnings {
+		report.Results = append(report.Results, newValidationResult(types.SeverityWarning, v))
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Severity != b.Severity {
+			return a.Severity == types.SeverityError
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Keyword != b.Keyword {
+			return a.Keyword < b.Keyword
+		}
+		return a.Message < b.Message
+	})
+	for _, result := range report.Results {
+		if result.Severity == types.SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	return report, nil
+}
+
+func newValidationResult(severity string, v types.Violation) types.ValidationResult {
+	return types.ValidationResult{
+		Severity: severity,
+		Path:     v.Path,
+		Keyword:  v.Keyword,
+		Message:  v.Message,
+		Actual:   v.Actual,
+		Expected: v.Expected,
+	}
+}
+
+// formatReportText renders a report for people, one finding per line
+func formatReportText(report *types.ValidationReport) string {
+	var b strings.Builder
+	if report.Valid {
+		b.WriteString("VALID")
+	} else {
+		b.WriteString("INVALID")
+	}
+	fmt.Fprintf(&b, ": %d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+	for _, result := range report.Results {
+		fmt.Fprintf(&b, "%s %s [%s] %s\n", result.Severity, result.Path, result.Keyword, result.Message)
+	}
+	return b.String()
+}
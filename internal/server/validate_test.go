@@ -0,0 +1,50 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+)
+
+func TestValidationResultCache(t *testing.T) {
+	validate := func(s *Server, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleValidate(rec, httptest.NewRequest("POST", "/v1/validate?format=ci", strings.NewReader(body)))
+		return rec
+	}
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+
+	t.Run("identical_validation_served_from_cache", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Cache.ResultMaxSize = 10
+		s := NewServerFromConfig(staticLLMClient{}, cfg, logger)
+		body := `{"schema": {"type": "object", "required": ["name"]}, "data": {}}`
+
+		first := validate(s, body)
+		second := validate(s, body)
+		require.Equal(t, http.StatusOK, second.Code)
+		assert.JSONEq(t, first.Body.String(), second.Body.String())
+
+		hits, misses := s.results.Stats()
+		assert.Equal(t, int64(1), hits)
+		assert.Equal(t, int64(1), misses)
+
+		// Different data against the same schema is validated afresh
+		validate(s, `{"schema": {"type": "object", "required": ["name"]}, "data": {"name": "Ada"}}`)
+		_, misses = s.results.Stats()
+		assert.Equal(t, int64(2), misses)
+	})
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		s := NewServerFromConfig(staticLLMClient{}, config.Default(), logger)
+		assert.Nil(t, s.results)
+		assert.Equal(t, http.StatusOK, validate(s, `{"schema": {"type": "object"}, "data": {}}`).Code)
+	})
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/metrics"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// staticLLMClient answers every query with the same data
+type staticLLMClient struct {
+	data string
+}
+
+func (c staticLLMClient) SendStructuredQuery(ctx context.Context, messages []types.Message, schema json.RawMessage) (*types.ValidatedResponse, error) {
+	return &types.ValidatedResponse{Data: json.RawMessage(c.data)}, nil
+}
+
+func (c staticLLMClient) SendStructuredQueryStream(ctx context.Context, messages []types.Message, schema json.RawMessage) (<-chan client.StreamDelta, error) {
+	deltas := make(chan client.StreamDelta, 1)
+	deltas <- client.StreamDelta{Content: c.data}
+	close(deltas)
+	return deltas, nil
+}
+
+func (c staticLLMClient) HealthCheck(ctx context.Context) error { return nil }
+
+// brokenWriter fails every body write with err, as a dropped connection does
+type brokenWriter struct {
+	header http.Header
+	err    error
+}
+
+func (b *brokenWriter) Header() http.Header         { return b.header }
+func (b *brokenWriter) WriteHeader(int)             {}
+func (b *brokenWriter) Write(p []byte) (int, error) { return 0, b.err }
+
+func TestResponseWriteFailure(t *testing.T) {
+	requestBody := `{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Hi"}]}`
+
+	run := func(t *testing.T, ctx context.Context, writeErr error) (string, map[string]int64) {
+		var logs bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "info", Format: "json", Output: &logs})
+		s := NewServerFromConfig(staticLLMClient{data: `{"name": "Jane"}`}, config.Default(), logger)
+
+		req := httptest.NewRequest("POST", "/v1/validated-query", strings.NewReader(requestBody)).WithContext(ctx)
+		s.handleValidatedQuery(&brokenWriter{header: http.Header{}, err: writeErr}, req)
+
+		return logs.String(), s.stats.Snapshot(metrics.CacheSnapshot{}).FailuresByCode
+	}
+
+	t.Run("broken_pipe_logged_as_disconnect", func(t *testing.T) {
+		logs, failures := run(t, context.Background(), fmt.Errorf("write tcp: %w", syscall.EPIPE))
+
+		assert.Contains(t, logs, "Client disconnected before response was written")
+		assert.NotContains(t, logs, "Validated query completed successfully")
+		assert.Equal(t, int64(1), failures[types.ErrorCodeClientDisconnected])
+	})
+
+	t.Run("cancelled_request_logged_as_disconnect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		logs, failures := run(t, ctx, errors.New("i/o error"))
+
+		assert.Contains(t, logs, "Client disconnected before response was written")
+		assert.Equal(t, int64(1), failures[types.ErrorCodeClientDisconnected])
+	})
+
+	t.Run("other_write_error_logged_as_failure", func(t *testing.T) {
+		logs, failures := run(t, context.Background(), errors.New("i/o error"))
+
+		assert.Contains(t, logs, "Failed to write response")
+		assert.NotContains(t, logs, "Validated query completed successfully")
+		assert.Equal(t, int64(1), failures[types.ErrorCodeWriteFailed])
+	})
+}
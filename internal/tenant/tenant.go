@@ -0,0 +1,105 @@
+package tenant
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/config"
+)
+
+// Tenant holds the resolved configuration and runtime state for one tenant
+type Tenant struct {
+	config.TenantConfig
+
+	allowlist map[string]bool
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+	now         func() time.Time
+}
+
+// Registry resolves tenant IDs to their configuration
+type Registry struct {
+	header   string
+	required bool
+	tenants  map[string]*Tenant
+}
+
+// NewRegistry creates a tenant registry from configuration
+func NewRegistry(cfg config.TenantsConfig) *Registry {
+	r := &Registry{
+		header:   cfg.Header,
+		required: cfg.Required,
+		tenants:  make(map[string]*Tenant, len(cfg.Tenants)),
+	}
+	for _, tc := range cfg.Tenants {
+		r.tenants[tc.ID] = newTenant(tc)
+	}
+	return r
+}
+
+func newTenant(cfg config.TenantConfig) *Tenant {
+	t := &Tenant{
+		TenantConfig: cfg,
+		now:          time.Now,
+	}
+	if len(cfg.SchemaAllowlist) > 0 {
+		t.allowlist = make(map[string]bool, len(cfg.SchemaAllowlist))
+		for _, hash := range cfg.SchemaAllowlist {
+			t.allowlist[hash] = true
+		}
+	}
+	return t
+}
+
+// Enabled reports whether any tenants are configured
+func (r *Registry) Enabled() bool {
+	return len(r.tenants) > 0
+}
+
+// Header returns the request header that carries the tenant ID
+func (r *Registry) Header() string {
+	return r.header
+}
+
+// Required reports whether requests without a tenant ID are rejected
+func (r *Registry) Required() bool {
+	return r.required
+}
+
+// Lookup returns the tenant registered under the given ID
+func (r *Registry) Lookup(id string) (*Tenant, bool) {
+	t, ok := r.tenants[id]
+	return t, ok
+}
+
+// Allow reports whether the tenant may issue another request in the current
+// one-minute window. A zero RequestsPerMinute disables the limit.
+func (t *Tenant) Allow() bool {
+	if t.RequestsPerMinute <= 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if now.Sub(t.windowStart) >= time.Minute {
+		t.windowStart = now
+		t.windowCount = 0
+	}
+	if t.windowCount >= t.RequestsPerMinute {
+		return false
+	}
+	t.windowCount++
+	return true
+}
+
+// SchemaAllowed reports whether the schema with the given hash may be used
+func (t *Tenant) SchemaAllowed(hash string) bool {
+	if t.allowlist == nil {
+		return true
+	}
+	return t.allowlist[hash]
+}
@@ -0,0 +1,69 @@
+package tenant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wcygan/llm-json-parse/internal/config"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("disabled_without_tenants", func(t *testing.T) {
+		registry := NewRegistry(config.TenantsConfig{Header: "X-Tenant-ID"})
+		assert.False(t, registry.Enabled())
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		registry := NewRegistry(config.TenantsConfig{
+			Header:  "X-Tenant-ID",
+			Tenants: []config.TenantConfig{{ID: "acme", LLMServerURL: "http://acme-llm:8080"}},
+		})
+
+		assert.True(t, registry.Enabled())
+		assert.Equal(t, "X-Tenant-ID", registry.Header())
+
+		tenant, ok := registry.Lookup("acme")
+		assert.True(t, ok)
+		assert.Equal(t, "http://acme-llm:8080", tenant.LLMServerURL)
+
+		_, ok = registry.Lookup("globex")
+		assert.False(t, ok)
+	})
+}
+
+func TestTenantAllow(t *testing.T) {
+	t.Run("unlimited_by_default", func(t *testing.T) {
+		tenant := newTenant(config.TenantConfig{ID: "acme"})
+		for i := 0; i < 100; i++ {
+			assert.True(t, tenant.Allow())
+		}
+	})
+
+	t.Run("limits_per_minute_window", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		tenant := newTenant(config.TenantConfig{ID: "acme", RequestsPerMinute: 2})
+		tenant.now = func() time.Time { return now }
+
+		assert.True(t, tenant.Allow())
+		assert.True(t, tenant.Allow())
+		assert.False(t, tenant.Allow())
+
+		// A new window resets the count
+		now = now.Add(time.Minute)
+		assert.True(t, tenant.Allow())
+	})
+}
+
+func TestTenantSchemaAllowed(t *testing.T) {
+	t.Run("empty_allowlist_permits_all", func(t *testing.T) {
+		tenant := newTenant(config.TenantConfig{ID: "acme"})
+		assert.True(t, tenant.SchemaAllowed("any"))
+	})
+
+	t.Run("allowlist_restricts_schemas", func(t *testing.T) {
+		tenant := newTenant(config.TenantConfig{ID: "acme", SchemaAllowlist: []string{"abc123"}})
+		assert.True(t, tenant.SchemaAllowed("abc123"))
+		assert.False(t, tenant.SchemaAllowed("def456"))
+	})
+}
@@ -0,0 +1,65 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	canonical := func(t *testing.T, data string) string {
+		out, err := CanonicalJSON(json.RawMessage(data))
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	t.Run("equivalent_documents_identical", func(t *testing.T) {
+		a := canonical(t, `{"b": [1, 2.50, {"y": true, "x": null}], "a": "<tag> & \u00e9"}`)
+		b := canonical(t, "{\n  \"a\": \"<tag> & é\",\n  \"b\": [1.0, 25e-1, {\"x\": null, \"y\": true}]\n}")
+		assert.Equal(t, `{"a":"<tag> & é","b":[1,2.5,{"x":null,"y":true}]}`, a)
+		assert.Equal(t, a, b)
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, a, canonical(t, `{"a": "<tag> & é", "b": [1, 2.5, {"y": true, "x": null}]}`))
+		}
+	})
+
+	t.Run("keys_sorted_by_utf16_code_units", func(t *testing.T) {
+		// RFC 8785 section 3.2.3
+		out := canonical(t, `{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh",
+			"1": "One", "\ud83d\ude00": "Emoji: Grinning Face", "\u0080": "Control", "\u00f6": "Latin Small Letter O With Diaeresis"}`)
+		want := "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+			"\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"
+		assert.Equal(t, want, out)
+	})
+
+	t.Run("numbers_in_ecmascript_form", func(t *testing.T) {
+		// RFC 8785 appendix B
+		cases := map[string]string{
+			`-0`:                     `0`,
+			`5e-324`:                 `5e-324`,
+			`1.7976931348623157e308`: `1.7976931348623157e+308`,
+			`9007199254740992`:       `9007199254740992`,
+			`295147905179352830000`:  `295147905179352830000`,
+			`1e21`:                   `1e+21`,
+			`0.000001`:               `0.000001`,
+			`1e-7`:                   `1e-7`,
+			`333333333.33333329`:     `333333333.3333333`,
+			`-1.5E+3`:                `-1500`,
+		}
+		for in, want := range cases {
+			assert.Equal(t, want, canonical(t, in), in)
+		}
+	})
+
+	t.Run("strings_minimally_escaped", func(t *testing.T) {
+		out := canonical(t, `"\"\\\b\f\n\r\t\u001f\/<\u2028"`)
+		assert.Equal(t, `"\"\\\b\f\n\r\t\u001f/<`+"\u2028"+`"`, out)
+	})
+
+	t.Run("invalid_json_rejected", func(t *testing.T) {
+		_, err := CanonicalJSON(json.RawMessage(`{"a":`))
+		assert.Error(t, err)
+	})
+}
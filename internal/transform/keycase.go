@@ -0,0 +1,155 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Key cases accepted by RekeyJSON
+const (
+	KeyCaseNone  = ""
+	KeyCaseCamel = "camel"
+	KeyCaseSnake = "snake"
+)
+
+// ValidKeyCase reports whether keyCase names a supported key case
+func ValidKeyCase(keyCase string) bool {
+	switch keyCase {
+	case KeyCaseNone, KeyCaseCamel, KeyCaseSnake:
+		return true
+	}
+	return false
+}
+
+// RekeyJSON recursively renames every object key in data to the given case,
+// leaving values untouched and preserving key order. Keys that collide after
+// conversion (e.g. "user_id" and "userId") are both kept, so the last one wins
+// for most decoders.
+func RekeyJSON(data json.RawMessage, keyCase string) (json.RawMessage, error) {
+	var convert func(string) string
+	switch keyCase {
+	case KeyCaseNone:
+		return data, nil
+	case KeyCaseCamel:
+		convert = ToCamelCase
+	case KeyCaseSnake:
+		convert = ToSnakeCase
+	default:
+		return nil, fmt.Errorf("unsupported key case %q", keyCase)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	if err := rekey(dec, &buf, convert); err != nil {
+		return nil, fmt.Errorf("rekey JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// rekey copies one JSON value from dec to buf, converting object keys
+func rekey(dec *json.Decoder, buf *bytes.Buffer, convert func(string) string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return writeJSON(buf, tok)
+	}
+
+	switch delim {
+	case '{':
+		buf.WriteByte('{')
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(buf, convert(keyTok.(string))); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := rekey(dec, buf, convert); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case '[':
+		buf.WriteByte('[')
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := rekey(dec, buf, convert); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	}
+
+	// Consume the closing delimiter
+	_, err = dec.Token()
+	return err
+}
+
+// writeJSON encodes v to buf without HTML escaping, leaving that choice to
+// the encoder that writes the response
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	// Drop the newline Encode appends
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// ToCamelCase converts a snake_case key to camelCase. Leading underscores are
+// kept so that keys such as "_id" survive unchanged.
+func ToCamelCase(key string) string {
+	var b strings.Builder
+	upperNext := false
+	for i, r := range key {
+		if r == '_' && i > 0 && strings.TrimLeft(key[:i], "_") != "" {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ToSnakeCase converts a camelCase or PascalCase key to snake_case, treating
+// runs of capitals as a single word ("userID" becomes "user_id")
+func ToSnakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyConversion(t *testing.T) {
+	t.Run("to_camel_case", func(t *testing.T) {
+		cases := map[string]string{
+			"first_name":     "firstName",
+			"home_address_1": "homeAddress1",
+			"already":        "already",
+			"_id":            "_id",
+			"alreadyCamel":   "alreadyCamel",
+		}
+		for in, want := range cases {
+			assert.Equal(t, want, ToCamelCase(in), in)
+		}
+	})
+
+	t.Run("to_snake_case", func(t *testing.T) {
+		cases := map[string]string{
+			"firstName":     "first_name",
+			"userID":        "user_id",
+			"HTTPServer":    "http_server",
+			"homeAddress1":  "home_address1",
+			"already_snake": "already_snake",
+		}
+		for in, want := range cases {
+			assert.Equal(t, want, ToSnakeCase(in), in)
+		}
+	})
+}
+
+func TestRekeyJSON(t *testing.T) {
+	snake := json.RawMessage(`{"first_name":"Jane","home_address":{"zip_code":"12345"},"phone_numbers":[{"is_primary":true,"raw_value":"555"}],"login_count":12345678901234567890}`)
+	camel := `{"firstName":"Jane","homeAddress":{"zipCode":"12345"},"phoneNumbers":[{"isPrimary":true,"rawValue":"555"}],"loginCount":12345678901234567890}`
+
+	t.Run("snake_to_camel", func(t *testing.T) {
+		out, err := RekeyJSON(snake, KeyCaseCamel)
+		require.NoError(t, err)
+		assert.Equal(t, camel, string(out))
+	})
+
+	t.Run("camel_back_to_snake", func(t *testing.T) {
+		out, err := RekeyJSON(json.RawMessage(camel), KeyCaseSnake)
+		require.NoError(t, err)
+		assert.Equal(t, string(snake), string(out))
+	})
+
+	t.Run("values_are_not_rekeyed", func(t *testing.T) {
+		out, err := RekeyJSON(json.RawMessage(`["snake_value",{"a_b":"c_d"}]`), KeyCaseCamel)
+		require.NoError(t, err)
+		assert.Equal(t, `["snake_value",{"aB":"c_d"}]`, string(out))
+	})
+
+	t.Run("none_is_passthrough", func(t *testing.T) {
+		out, err := RekeyJSON(snake, KeyCaseNone)
+		require.NoError(t, err)
+		assert.Equal(t, string(snake), string(out))
+	})
+
+	t.Run("unsupported_case", func(t *testing.T) {
+		_, err := RekeyJSON(snake, "kebab")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,148 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// keyOrder records the key order of one JSON value: the keys of an object in
+// the order they first appear, or the elements of an array
+type keyOrder struct {
+	keys   []string
+	fields map[string]*keyOrder
+	items  []*keyOrder
+}
+
+// PreserveKeyOrder re-encodes data with every object's keys in the order they
+// appear at the same position in reference, typically the LLM output data
+// was derived from. Keys absent from reference, such as filled-in defaults,
+// follow in sorted order. Values are taken from data unchanged.
+func PreserveKeyOrder(reference, data json.RawMessage) (json.RawMessage, error) {
+	refDec := json.NewDecoder(bytes.NewReader(reference))
+	refDec.UseNumber()
+	order, err := readKeyOrder(refDec)
+	if err != nil {
+		return nil, fmt.Errorf("read key order: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeOrdered(&buf, value, order); err != nil {
+		return nil, fmt.Errorf("encode JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readKeyOrder reads one JSON value from dec and returns its key order. Only
+// objects and arrays have one; scalars return nil.
+func readKeyOrder(dec *json.Decoder) (*keyOrder, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil, nil
+	}
+
+	order := &keyOrder{}
+	switch delim {
+	case '{':
+		order.fields = map[string]*keyOrder{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			child, err := readKeyOrder(dec)
+			if err != nil {
+				return nil, err
+			}
+			// As with decoding, the last duplicate's value wins, but the key
+			// keeps its first position
+			if _, seen := order.fields[key]; !seen {
+				order.keys = append(order.keys, key)
+			}
+			order.fields[key] = child
+		}
+	case '[':
+		for dec.More() {
+			child, err := readKeyOrder(dec)
+			if err != nil {
+				return nil, err
+			}
+			order.items = append(order.items, child)
+		}
+	}
+
+	// Consume the closing delimiter
+	_, err = dec.Token()
+	return order, err
+}
+
+// writeOrdered encodes value to buf, ordering object keys by order
+func writeOrdered(buf *bytes.Buffer, value interface{}, order *keyOrder) error {
+	if order == nil {
+		order = &keyOrder{}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for _, key := range order.keys {
+			if _, ok := v[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+		var extra []string
+		for key := range v {
+			if _, ok := order.fields[key]; !ok {
+				extra = append(extra, key)
+			}
+		}
+		sort.Strings(extra)
+		keys = append(keys, extra...)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeOrdered(buf, v[key], order.fields[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			var itemOrder *keyOrder
+			if i < len(order.items) {
+				itemOrder = order.items[i]
+			}
+			if err := writeOrdered(buf, item, itemOrder); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		return writeJSON(buf, v)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreserveKeyOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference string
+		data      string
+		want      string
+	}{
+		{
+			name:      "restores_reference_order",
+			reference: `{"zeta": 1, "alpha": {"y": true, "x": false}, "mid": "m"}`,
+			data:      `{"alpha":{"x":false,"y":true},"mid":"m","zeta":1}`,
+			want:      `{"zeta":1,"alpha":{"y":true,"x":false},"mid":"m"}`,
+		},
+		{
+			name:      "new_keys_follow_sorted",
+			reference: `{"b": null, "a": 1}`,
+			data:      `{"a":1,"b":"default","d":4,"c":3}`,
+			want:      `{"b":"default","a":1,"c":3,"d":4}`,
+		},
+		{
+			name:      "arrays_matched_by_index",
+			reference: `[{"q": 1, "p": 2}, {"s": 1, "r": 2}]`,
+			data:      `[{"p":2,"q":1},{"r":2,"s":1},{"u":1,"t":2}]`,
+			want:      `[{"q":1,"p":2},{"s":1,"r":2},{"t":2,"u":1}]`,
+		},
+		{
+			name:      "removed_keys_and_numbers",
+			reference: `{"big": 12345678901234567890, "gone": null, "f": 1.50}`,
+			data:      `{"big":12345678901234567890,"f":1.50}`,
+			want:      `{"big":12345678901234567890,"f":1.50}`,
+		},
+		{
+			name:      "duplicate_keys_keep_first_position",
+			reference: `{"a": 1, "b": 2, "a": 3}`,
+			data:      `{"a":3,"b":2}`,
+			want:      `{"a":3,"b":2}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PreserveKeyOrder(json.RawMessage(tt.reference), json.RawMessage(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	t.Run("invalid_json", func(t *testing.T) {
+		_, err := PreserveKeyOrder(json.RawMessage(`{`), json.RawMessage(`{}`))
+		assert.Error(t, err)
+		_, err = PreserveKeyOrder(json.RawMessage(`{}`), json.RawMessage(`{`))
+		assert.Error(t, err)
+	})
+}
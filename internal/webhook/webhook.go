@@ -0,0 +1,153 @@
+// Package webhook delivers request completion events to an external URL
+// without blocking the request path.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// Event summarizes a completed request
+type Event struct {
+	RequestID  string       `json:"request_id"`
+	Outcome    string       `json:"outcome"`
+	StatusCode int          `json:"status_code"`
+	LatencyMs  int64        `json:"latency_ms"`
+	SchemaHash string       `json:"schema_hash,omitempty"`
+	Usage      *types.Usage `json:"usage,omitempty"`
+	Timestamp  string       `json:"timestamp"`
+}
+
+// Outcomes reported in Event.Outcome
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Config controls delivery. QueueSize bounds the events buffered for
+// delivery; RetryAttempts is the number of retries after the first POST.
+type Config struct {
+	URL           string
+	QueueSize     int
+	RetryAttempts int
+	RetryDelay    time.Duration
+	Timeout       time.Duration
+}
+
+// Dispatcher posts events to the webhook from a background worker. Events
+// are dropped rather than blocking when the queue is full.
+type Dispatcher struct {
+	cfg    Config
+	client *http.Client
+	logger *logging.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan Event
+	done   chan struct{}
+}
+
+// NewDispatcher creates a dispatcher and starts its delivery worker
+func NewDispatcher(cfg Config, logger *logging.Logger) *Dispatcher {
+	d := &Dispatcher{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger.WithComponent("webhook"),
+		queue:  make(chan Event, cfg.QueueSize),
+		done:   make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Send queues an event for delivery, reporting false if it was dropped
+// because the queue is full or the dispatcher is closed
+func (d *Dispatcher) Send(event Event) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return false
+	}
+
+	select {
+	case d.queue <- event:
+		return true
+	default:
+		d.logger.WithFields(map[string]interface{}{
+			"request_id": event.RequestID,
+			"queue_size": d.cfg.QueueSize,
+		}).Warn("Webhook queue full, dropping event")
+		return false
+	}
+}
+
+// Close stops accepting events and waits for queued events to be delivered
+// or for ctx to be done
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for event := range d.queue {
+		d.deliver(event)
+	}
+}
+
+// deliver posts an event, retrying failures up to the configured attempts
+func (d *Dispatcher) deliver(event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.WithError(err).Error("Failed to encode webhook event")
+		return
+	}
+
+	maxAttempts := d.cfg.RetryAttempts + 1
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = d.post(body)
+		if err == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			time.Sleep(d.cfg.RetryDelay)
+		}
+	}
+
+	d.logger.WithError(err).WithFields(map[string]interface{}{
+		"request_id": event.RequestID,
+		"attempts":   maxAttempts,
+	}).Warn("Webhook delivery failed")
+}
+
+func (d *Dispatcher) post(body []byte) error {
+	resp, err := d.client.Post(d.cfg.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+)
+
+func newTestDispatcher(url string, queueSize int) *Dispatcher {
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	return NewDispatcher(Config{
+		URL:           url,
+		QueueSize:     queueSize,
+		RetryAttempts: 2,
+		RetryDelay:    time.Millisecond,
+		Timeout:       time.Second,
+	}, logger)
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("delivers_events", func(t *testing.T) {
+		received := make(chan Event, 1)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event Event
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+			received <- event
+		}))
+		defer receiver.Close()
+
+		d := newTestDispatcher(receiver.URL, 10)
+		assert.True(t, d.Send(Event{RequestID: "req-1", Outcome: OutcomeSuccess, StatusCode: 200}))
+		require.NoError(t, d.Close(context.Background()))
+
+		event := <-received
+		assert.Equal(t, "req-1", event.RequestID)
+		assert.Equal(t, OutcomeSuccess, event.Outcome)
+	})
+
+	t.Run("retries_failed_deliveries", func(t *testing.T) {
+		var calls int32
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer receiver.Close()
+
+		d := newTestDispatcher(receiver.URL, 10)
+		d.Send(Event{RequestID: "req-1"})
+		require.NoError(t, d.Close(context.Background()))
+
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("drops_events_when_queue_full", func(t *testing.T) {
+		release := make(chan struct{})
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer receiver.Close()
+
+		d := newTestDispatcher(receiver.URL, 1)
+		// The worker takes the first event and blocks, the second fills the queue
+		assert.True(t, d.Send(Event{RequestID: "req-1"}))
+		require.Eventually(t, func() bool { return len(d.queue) == 0 }, time.Second, time.Millisecond)
+		assert.True(t, d.Send(Event{RequestID: "req-2"}))
+		assert.False(t, d.Send(Event{RequestID: "req-3"}))
+
+		close(release)
+		require.NoError(t, d.Close(context.Background()))
+	})
+
+	t.Run("send_after_close_is_dropped", func(t *testing.T) {
+		d := newTestDispatcher("http://127.0.0.1:0", 1)
+		require.NoError(t, d.Close(context.Background()))
+		assert.False(t, d.Send(Event{RequestID: "req-1"}))
+	})
+}
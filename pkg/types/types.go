@@ -13,11 +13,174 @@ type Message struct {
 type ValidatedQueryRequest struct {
 	Schema   json.RawMessage `json:"schema"`
 	Messages []Message       `json:"messages"`
+	// KeyCase overrides the configured output key case ("camel" or "snake")
+	KeyCase string `json:"key_case,omitempty"`
+	// SchemaID refers to a registered schema, used in place of Schema
+	SchemaID string `json:"schema_id,omitempty"`
+	// Deterministic marks the schema as expecting a deterministic answer,
+	// which a repair re-prompt can converge on; see RepairDeterministicOnly
+	Deterministic bool `json:"deterministic,omitempty"`
+	// Input is structured data for the LLM, sent after the messages. When
+	// RequestSchema is set, Input is required and must validate against it
+	// before the LLM is called.
+	Input         json.RawMessage `json:"input,omitempty"`
+	RequestSchema json.RawMessage `json:"request_schema,omitempty"`
+}
+
+// ValidateRequest asks for data to be checked against a schema without
+// querying the LLM
+type ValidateRequest struct {
+	Schema json.RawMessage `json:"schema"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// ValidateBatchRequest asks for several payloads to be checked against one
+// schema without querying the LLM
+type ValidateBatchRequest struct {
+	Schema   json.RawMessage   `json:"schema"`
+	Payloads []json.RawMessage `json:"payloads"`
+}
+
+// ValidateBatchResponse is the result of /v1/validate/batch. Results holds
+// one report per payload, in request order.
+type ValidateBatchResponse struct {
+	SchemaHash string               `json:"schema_hash"`
+	Valid      int                  `json:"valid"`
+	Invalid    int                  `json:"invalid"`
+	Results    []BatchPayloadReport `json:"results"`
+}
+
+// BatchPayloadReport is the ValidationReport for the payload at Index
+type BatchPayloadReport struct {
+	Index int `json:"index"`
+	ValidationReport
+}
+
+// ValidationReport is the machine-readable result of /v1/validate?format=ci.
+// Its field names are stable so CI tooling can diff reports across runs.
+type ValidationReport struct {
+	Valid      bool               `json:"valid"`
+	SchemaHash string             `json:"schema_hash"`
+	Errors     int                `json:"errors"`
+	Warnings   int                `json:"warnings"`
+	Results    []ValidationResult `json:"results"`
+}
+
+// ValidationResult is one finding in a ValidationReport. Severity is
+// "error" for schema violations and "warning" for advisory issues.
+type ValidationResult struct {
+	Severity string      `json:"severity"`
+	Path     string      `json:"path"`
+	Keyword  string      `json:"keyword"`
+	Message  string      `json:"message"`
+	Actual   interface{} `json:"actual,omitempty"`
+	Expected interface{} `json:"expected,omitempty"`
+}
+
+// Severities of a ValidationResult
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// SchemaRegistration is returned when a schema is registered
+type SchemaRegistration struct {
+	ID string `json:"id"`
+}
+
+// SchemaAnalyzeRequest asks for ways to tighten a schema, judged against
+// samples of data the schema is expected to accept
+type SchemaAnalyzeRequest struct {
+	Schema  json.RawMessage   `json:"schema"`
+	Samples []json.RawMessage `json:"samples"`
+}
+
+// SchemaAnalysis is the result of /v1/schemas/analyze
+type SchemaAnalysis struct {
+	Samples     int                `json:"samples"`
+	Suggestions []SchemaSuggestion `json:"suggestions"`
+}
+
+// SchemaSuggestion is one way to tighten a schema. Path is the JSON pointer
+// of the data concerned, with "*" for every array item; Suggested is the
+// proposed keyword value, when there is one.
+type SchemaSuggestion struct {
+	Path      string      `json:"path"`
+	Keyword   string      `json:"keyword"`
+	Message   string      `json:"message"`
+	Suggested interface{} `json:"suggested,omitempty"`
+}
+
+// SchemaStats reports how data validated against a registered schema fared.
+// AvgResponseBytes is the mean size of the validated data.
+type SchemaStats struct {
+	ID               string  `json:"id"`
+	Validations      int64   `json:"validations"`
+	Valid            int64   `json:"valid"`
+	Invalid          int64   `json:"invalid"`
+	PassRate         float64 `json:"pass_rate"`
+	AvgResponseBytes float64 `json:"avg_response_bytes"`
+}
+
+// Capabilities is the result of /v1/capabilities: the optional features this
+// gateway instance has enabled, so clients can adapt without trial and error
+type Capabilities struct {
+	Streaming             bool `json:"streaming"`
+	Batch                 bool `json:"batch"`
+	BatchMaxPayloads      int  `json:"batch_max_payloads"`
+	Repair                bool `json:"repair"`
+	MaxRepairAttempts     int  `json:"max_repair_attempts"`
+	SchemaRegistry        bool `json:"schema_registry"`
+	SharedSchemaRegistry  bool `json:"shared_schema_registry"`
+	SchemaURLRegistration bool `json:"schema_url_registration"`
+	Schemaless            bool `json:"schemaless"`
+	ResponseCache         bool `json:"response_cache"`
+	BackendOverride       bool `json:"backend_override"`
+	ModelFallback         bool `json:"model_fallback"`
+	Envelope              bool `json:"envelope"`
+	// SchemaEngine identifies the validator, to reconcile deployments that
+	// validate the same data differently
+	SchemaEngine SchemaEngine `json:"schema_engine"`
+}
+
+// SchemaEngine describes the JSON Schema implementation and how it is
+// configured. DefaultDraft is the meta-schema URL of the draft assumed for
+// schemas without "$schema".
+type SchemaEngine struct {
+	Library       string `json:"library"`
+	Version       string `json:"version"`
+	DefaultDraft  string `json:"default_draft"`
+	AssertFormats bool   `json:"assert_formats"`
+}
+
+// RecentRequests is the result of /admin/requests: the latest validated
+// queries, oldest first
+type RecentRequests struct {
+	Requests []RecentRequest `json:"requests"`
+}
+
+// RecentRequest describes one completed validated query. Body holds the
+// request body only when body capture is enabled.
+type RecentRequest struct {
+	RequestID  string `json:"request_id"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	Outcome    string `json:"outcome"`
+	StartedAt  string `json:"started_at"`
+	LatencyMs  int64  `json:"latency_ms"`
+	SchemaHash string `json:"schema_hash,omitempty"`
+	Backend    string `json:"backend,omitempty"`
+	Cache      string `json:"cache,omitempty"`
+	Usage      *Usage `json:"usage,omitempty"`
+	Body       string `json:"body,omitempty"`
 }
 
 type LLMRequest struct {
 	Messages       []Message       `json:"messages"`
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// Stream asks the backend to send the completion as server-sent events
+	Stream bool `json:"stream,omitempty"`
 }
 
 type ResponseFormat struct {
@@ -32,19 +195,78 @@ type JSONSchema struct {
 }
 
 type LLMResponse struct {
+	Model   string   `json:"model,omitempty"`
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Usage reports the tokens consumed by an LLM call, when the backend provides it
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 type Choice struct {
 	Message Message `json:"message"`
 }
 
+// RepairAttempt is the "attempt" event streamed to clients that accept
+// text/event-stream when a response fails validation and is re-prompted
+type RepairAttempt struct {
+	Attempt    int         `json:"attempt"`
+	Message    string      `json:"message"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// ContentDelta is the "delta" event streamed by /v1/validated-query/stream
+// with each piece of LLM output as it is generated, before validation
+type ContentDelta struct {
+	Content string `json:"content"`
+}
+
 // ValidatedResponse represents a structured response from LLM validation
 type ValidatedResponse struct {
 	Data     json.RawMessage   `json:"data"`
 	Metadata *ResponseMetadata `json:"metadata,omitempty"`
+	Usage    *Usage            `json:"usage,omitempty"`
+	// Model is the model the backend reports having answered with
+	Model string `json:"model,omitempty"`
 }
 
+// ResponseEnvelope wraps validated data when envelope mode is enabled
+type ResponseEnvelope struct {
+	Data      json.RawMessage `json:"data"`
+	RequestID string          `json:"request_id,omitempty"`
+	Usage     *Usage          `json:"usage,omitempty"`
+	// Model is the model the backend reports having answered with, which
+	// differs from the usual one after a fallback
+	Model    string            `json:"model,omitempty"`
+	Metadata *EnvelopeMetadata `json:"metadata,omitempty"`
+}
+
+// EnvelopeMetadata describes how the enveloped data differs from what the
+// LLM returned
+type EnvelopeMetadata struct {
+	Transformations []Transformation `json:"transformations"`
+}
+
+// Transformation is one change applied to validated data, listed in the
+// order it was applied. Detail carries its setting, such as the null policy
+// or key case, when it has one.
+type Transformation struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Transformation names reported in EnvelopeMetadata
+const (
+	TransformationNulls    = "null_rewrite"
+	TransformationDefaults = "defaults"
+	TransformationKeyOrder = "key_order"
+	TransformationKeyCase  = "key_case"
+)
+
 // ResponseMetadata contains optional metadata about the validation
 type ResponseMetadata struct {
 	SchemaHash     string `json:"schema_hash,omitempty"`
@@ -62,27 +284,81 @@ type ErrorResponse struct {
 	RequestID string                 `json:"request_id,omitempty"`
 }
 
-// ValidationError represents schema validation failures with response data
+// ValidationError represents schema validation failures with response data.
+// MissingRequired lists the JSON pointer of each required property absent
+// from the response.
 type ValidationError struct {
-	Error     string                 `json:"error"`
-	Message   string                 `json:"message"`
-	Code      string                 `json:"code"`
-	Details   string                 `json:"details"`
-	Response  json.RawMessage        `json:"response,omitempty"`
-	Context   map[string]interface{} `json:"context,omitempty"`
-	Timestamp string                 `json:"timestamp"`
-	RequestID string                 `json:"request_id,omitempty"`
+	Error           string                 `json:"error"`
+	Message         string                 `json:"message"`
+	Code            string                 `json:"code"`
+	Details         string                 `json:"details"`
+	Response        json.RawMessage        `json:"response,omitempty"`
+	Violations      []Violation            `json:"violations,omitempty"`
+	MissingRequired []string               `json:"missing_required,omitempty"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+	Timestamp       string                 `json:"timestamp"`
+	RequestID       string                 `json:"request_id,omitempty"`
+}
+
+// Violation describes a single schema constraint the response failed.
+// Path is a JSON pointer into the response and Keyword the failing schema
+// keyword. Actual and Expected carry the measured and required values for
+// count constraints such as minItems.
+type Violation struct {
+	Path             string      `json:"path"`
+	Keyword          string      `json:"keyword"`
+	Message          string      `json:"message"`
+	Actual           interface{} `json:"actual,omitempty"`
+	Expected         interface{} `json:"expected,omitempty"`
+	DuplicateIndices []int       `json:"duplicate_indices,omitempty"`
+}
+
+// ProblemDetails is an RFC 7807 (application/problem+json) error body. Code
+// and the remaining members are extensions carrying the same information as
+// ErrorResponse and ValidationError.
+type ProblemDetails struct {
+	Type            string                 `json:"type"`
+	Title           string                 `json:"title"`
+	Status          int                    `json:"status"`
+	Detail          string                 `json:"detail,omitempty"`
+	Instance        string                 `json:"instance,omitempty"`
+	Code            string                 `json:"code"`
+	Response        json.RawMessage        `json:"response,omitempty"`
+	Violations      []Violation            `json:"violations,omitempty"`
+	MissingRequired []string               `json:"missing_required,omitempty"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+	Timestamp       string                 `json:"timestamp"`
+	RequestID       string                 `json:"request_id,omitempty"`
 }
 
 // Error codes for consistent error handling
 const (
-	ErrorCodeInvalidRequest   = "INVALID_REQUEST"
-	ErrorCodeInvalidSchema    = "INVALID_SCHEMA"
-	ErrorCodeLLMError         = "LLM_ERROR"
-	ErrorCodeValidationFailed = "VALIDATION_FAILED"
-	ErrorCodeInternalError    = "INTERNAL_ERROR"
-	ErrorCodeTimeout          = "TIMEOUT"
-	ErrorCodeRateLimited      = "RATE_LIMITED"
+	ErrorCodeInvalidRequest     = "INVALID_REQUEST"
+	ErrorCodeInvalidSchema      = "INVALID_SCHEMA"
+	ErrorCodeLLMError           = "LLM_ERROR"
+	ErrorCodeLLMEmptyResponse   = "LLM_EMPTY_RESPONSE"
+	ErrorCodeValidationFailed   = "VALIDATION_FAILED"
+	ErrorCodeInternalError      = "INTERNAL_ERROR"
+	ErrorCodeTimeout            = "TIMEOUT"
+	ErrorCodeRateLimited        = "RATE_LIMITED"
+	ErrorCodeUnknownTenant      = "UNKNOWN_TENANT"
+	ErrorCodeSchemaForbidden    = "SCHEMA_FORBIDDEN"
+	ErrorCodeUnauthorized       = "UNAUTHORIZED"
+	ErrorCodeForbidden          = "FORBIDDEN"
+	ErrorCodePromptTooLarge     = "PROMPT_TOO_LARGE"
+	ErrorCodeOverloaded         = "OVERLOADED"
+	ErrorCodeBackendForbidden   = "BACKEND_FORBIDDEN"
+	ErrorCodeRegistryFull       = "REGISTRY_FULL"
+	ErrorCodeSchemaNotFound     = "SCHEMA_NOT_FOUND"
+	ErrorCodeValidationTimeout  = "VALIDATION_TIMEOUT"
+	ErrorCodeSchemaURLForbidden = "SCHEMA_URL_FORBIDDEN"
+	ErrorCodeSchemaFetchFailed  = "SCHEMA_FETCH_FAILED"
+	ErrorCodeDuplicateKeys      = "DUPLICATE_KEYS"
+	ErrorCodeSchemaInjection    = "SCHEMA_INJECTION"
+	ErrorCodeRequestTooLarge    = "REQUEST_TOO_LARGE"
+	// Recorded in metrics only; the client is gone so no body is sent
+	ErrorCodeClientDisconnected = "CLIENT_DISCONNECTED"
+	ErrorCodeWriteFailed        = "WRITE_FAILED"
 )
 
 // NewErrorResponse creates a standardized error response
@@ -131,3 +407,38 @@ func (e *ValidationError) WithValidationContext(key string, value interface{}) *
 	e.Context[key] = value
 	return e
 }
+
+// Problem converts an error response to problem details for the given
+// status and request path
+func (e *ErrorResponse) Problem(status int, instance string) *ProblemDetails {
+	return &ProblemDetails{
+		Type:      "about:blank",
+		Title:     e.Message,
+		Status:    status,
+		Detail:    e.Details,
+		Instance:  instance,
+		Code:      e.Code,
+		Context:   e.Context,
+		Timestamp: e.Timestamp,
+		RequestID: e.RequestID,
+	}
+}
+
+// Problem converts a validation error to problem details for the given
+// status and request path
+func (e *ValidationError) Problem(status int, instance string) *ProblemDetails {
+	return &ProblemDetails{
+		Type:            "about:blank",
+		Title:           e.Message,
+		Status:          status,
+		Detail:          e.Details,
+		Instance:        instance,
+		Code:            e.Code,
+		Response:        e.Response,
+		Violations:      e.Violations,
+		MissingRequired: e.MissingRequired,
+		Context:         e.Context,
+		Timestamp:       e.Timestamp,
+		RequestID:       e.RequestID,
+	}
+}
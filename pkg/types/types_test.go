@@ -97,6 +97,36 @@ func TestValidationError(t *testing.T) {
 	})
 }
 
+func TestProblemDetails(t *testing.T) {
+	t.Run("from_error_response", func(t *testing.T) {
+		errResp := NewErrorResponse(ErrorCodeInvalidSchema, "Invalid JSON schema", "bad type").
+			WithContext("field", "schema").
+			WithRequestID("req-1")
+
+		problem := errResp.Problem(400, "/v1/validated-query")
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Invalid JSON schema", problem.Title)
+		assert.Equal(t, 400, problem.Status)
+		assert.Equal(t, "bad type", problem.Detail)
+		assert.Equal(t, "/v1/validated-query", problem.Instance)
+		assert.Equal(t, ErrorCodeInvalidSchema, problem.Code)
+		assert.Equal(t, "schema", problem.Context["field"])
+		assert.Equal(t, "req-1", problem.RequestID)
+	})
+
+	t.Run("from_validation_error", func(t *testing.T) {
+		responseData := json.RawMessage(`{"age": "old"}`)
+		validationErr := NewValidationError("Schema validation failed", "wrong type", responseData)
+		validationErr.Violations = []Violation{{Path: "/age", Keyword: "type", Message: "expected integer"}}
+
+		problem := validationErr.Problem(422, "/v1/validated-query")
+		assert.Equal(t, 422, problem.Status)
+		assert.Equal(t, ErrorCodeValidationFailed, problem.Code)
+		assert.Equal(t, responseData, problem.Response)
+		assert.Len(t, problem.Violations, 1)
+	})
+}
+
 func TestErrorCodes(t *testing.T) {
 	t.Run("error_codes_defined", func(t *testing.T) {
 		assert.Equal(t, "INVALID_REQUEST", ErrorCodeInvalidRequest)
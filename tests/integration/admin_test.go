@@ -0,0 +1,188 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/auth"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of a server
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	cfg := config.Default()
+	cfg.Admin.Enabled = true
+	cfg.Admin.Subjects = []string{"oncall"}
+	cfg.Auth = config.AuthConfig{Mode: "static", Tokens: []config.StaticToken{
+		{Token: "admin-token", Subject: "oncall"},
+		{Token: "user-token", Subject: "ci"},
+	}}
+
+	var logs syncBuffer
+	levelVar := new(slog.LevelVar)
+	logger := logging.NewLogger(logging.LogConfig{Level: "info", Format: "json", Output: &logs, LevelVar: levelVar})
+	srv := server.NewServerFromConfig(mocks.NewMockLLMClient(), cfg, logger)
+	srv.SetLogLevelVar(levelVar)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(middleware.Authentication(auth.New(cfg.Auth))(mux))
+	defer testServer.Close()
+
+	send := func(t *testing.T, method, path, token, body string) (int, []byte) {
+		req, err := http.NewRequest(method, testServer.URL+path, bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, respBody
+	}
+	validate := func(t *testing.T) {
+		status, _ := send(t, http.MethodPost, "/v1/validate", "user-token", `{"schema": {"type": "object"}, "data": {}}`)
+		require.Equal(t, http.StatusOK, status)
+	}
+	const debugLine = "Response validation successful"
+
+	t.Run("rejects_unauthorized_callers", func(t *testing.T) {
+		status, _ := send(t, http.MethodPost, "/admin/log-level", "", `{"level": "debug"}`)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		status, _ = send(t, http.MethodPost, "/admin/log-level", "user-token", `{"level": "debug"}`)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, slog.LevelInfo, levelVar.Level())
+	})
+
+	t.Run("rejects_unknown_level", func(t *testing.T) {
+		status, _ := send(t, http.MethodPost, "/admin/log-level", "admin-token", `{"level": "verbose"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, slog.LevelInfo, levelVar.Level())
+	})
+
+	t.Run("debug_logs_appear_after_lowering", func(t *testing.T) {
+		validate(t)
+		assert.NotContains(t, logs.String(), debugLine)
+
+		status, body := send(t, http.MethodPost, "/admin/log-level", "admin-token", `{"level": "debug"}`)
+		require.Equal(t, http.StatusOK, status)
+		var resp map[string]string
+		require.NoError(t, json.Unmarshal(body, &resp))
+		assert.Equal(t, map[string]string{"level": "debug", "previous": "info"}, resp)
+
+		validate(t)
+		assert.Contains(t, logs.String(), debugLine)
+
+		status, body = send(t, http.MethodGet, "/admin/log-level", "admin-token", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"level": "debug"}`, string(body))
+	})
+}
+
+func TestAdminRecentRequests(t *testing.T) {
+	cfg := config.Default()
+	cfg.Admin.Enabled = true
+	cfg.Admin.RecentRequests = 3
+	cfg.Admin.RecentRequestBodies = true
+	cfg.Auth = config.AuthConfig{Mode: "static", Tokens: []config.StaticToken{{Token: "admin-token", Subject: "oncall"}}}
+
+	mockClient := mocks.NewMockLLMClient()
+	mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+		&types.ValidatedResponse{Data: json.RawMessage(`{"name": "Ada"}`)}, nil)
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mockClient, cfg, logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	handler := middleware.Authentication(auth.New(cfg.Auth))(
+		middleware.RequestLogging(logger)(middleware.BufferBody(0, false)(mux)))
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	send := func(t *testing.T, method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, testServer.URL+path, bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	queries := []struct {
+		body   string
+		status int
+	}{
+		{`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "first"}]}`, http.StatusOK},
+		{`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "second"}]}`, http.StatusOK},
+		{`{"schema": {"type": "object"}, "messages": `, http.StatusBadRequest},
+		{`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "fourth"}]}`, http.StatusOK},
+	}
+	var requestIDs []string
+	for _, q := range queries {
+		resp := send(t, http.MethodPost, "/v1/validated-query", q.body)
+		require.Equal(t, q.status, resp.StatusCode)
+		requestIDs = append(requestIDs, resp.Header.Get("X-Request-ID"))
+	}
+
+	t.Run("latest_requests_in_order", func(t *testing.T) {
+		resp := send(t, http.MethodGet, "/admin/requests", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var recent types.RecentRequests
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&recent))
+
+		// The buffer holds three, so the first query has been dropped
+		require.Len(t, recent.Requests, 3)
+		for i, entry := range recent.Requests {
+			assert.Equal(t, requestIDs[i+1], entry.RequestID)
+			assert.Equal(t, queries[i+1].status, entry.Status)
+			assert.Equal(t, queries[i+1].body, entry.Body)
+			assert.Equal(t, "/v1/validated-query", entry.Path)
+		}
+		assert.Equal(t, "success", recent.Requests[0].Outcome)
+		assert.Equal(t, "failure", recent.Requests[1].Outcome)
+		assert.NotEmpty(t, recent.Requests[2].SchemaHash)
+	})
+
+	t.Run("html_view", func(t *testing.T) {
+		resp := send(t, http.MethodGet, "/admin/requests/view", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), requestIDs[3])
+		assert.NotContains(t, string(body), requestIDs[0])
+	})
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestBackendOverride(t *testing.T) {
+	defaultBackend, defaultCalls := newMockLLMBackend(t, `{"name": "from-default"}`)
+	allowedBackend, allowedCalls := newMockLLMBackend(t, `{"name": "from-allowed"}`)
+	otherBackend, otherCalls := newMockLLMBackend(t, `{"name": "from-other"}`)
+
+	setup := func(t *testing.T, enabled bool) *httptest.Server {
+		cfg := config.Default()
+		cfg.LLM.ServerURL = defaultBackend.URL
+		cfg.LLM.BackendOverride = config.BackendOverrideConfig{
+			Enabled:   enabled,
+			Allowlist: []string{allowedBackend.URL + "/"},
+		}
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithLogger(cfg.LLM.ServerURL, cfg.LLM.Timeout, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	send := func(t *testing.T, testServer *httptest.Server, backendURL string) (*http.Response, map[string]interface{}) {
+		body := []byte(`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Who are you?"}]}`)
+		req, err := http.NewRequest("POST", testServer.URL+"/v1/validated-query", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-LLM-Backend", backendURL)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+		return resp, decoded
+	}
+
+	t.Run("allowlisted_backend_used", func(t *testing.T) {
+		testServer := setup(t, true)
+
+		resp, body := send(t, testServer, allowedBackend.URL)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "from-allowed", body["name"])
+		assert.Equal(t, int32(1), atomic.LoadInt32(allowedCalls))
+	})
+
+	t.Run("non_allowlisted_backend_rejected", func(t *testing.T) {
+		testServer := setup(t, true)
+
+		resp, body := send(t, testServer, otherBackend.URL)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, types.ErrorCodeBackendForbidden, body["code"])
+		assert.Equal(t, int32(0), atomic.LoadInt32(otherCalls))
+	})
+
+	t.Run("disabled_ignores_header", func(t *testing.T) {
+		testServer := setup(t, false)
+		before := atomic.LoadInt32(allowedCalls)
+
+		resp, body := send(t, testServer, allowedBackend.URL)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "from-default", body["name"])
+		assert.Equal(t, int32(1), atomic.LoadInt32(defaultCalls))
+		assert.Equal(t, before, atomic.LoadInt32(allowedCalls))
+	})
+}
@@ -0,0 +1,207 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// scriptedReply is one backend response: a failing status or message content
+type scriptedReply struct {
+	status  int
+	content string
+}
+
+// newScriptedBackend serves replies in order, repeating the last one, and
+// records the messages of every call
+func newScriptedBackend(t *testing.T, replies ...scriptedReply) (*httptest.Server, *int32, func() [][]types.Message) {
+	var calls int32
+	var mu sync.Mutex
+	var received [][]types.Message
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.LLMRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		received = append(received, req.Messages)
+		mu.Unlock()
+
+		n := int(atomic.AddInt32(&calls, 1))
+		reply := replies[len(replies)-1]
+		if n <= len(replies) {
+			reply = replies[n-1]
+		}
+		if reply.status != 0 {
+			w.WriteHeader(reply.status)
+			return
+		}
+		json.NewEncoder(w).Encode(types.LLMResponse{
+			Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: reply.content}}},
+		})
+	}))
+	t.Cleanup(backend.Close)
+	return backend, &calls, func() [][]types.Message {
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+}
+
+func TestRequestCallBudget(t *testing.T) {
+	setup := func(t *testing.T, backendURL string, maxCalls, repairAttempts int) *httptest.Server {
+		cfg := config.Default()
+		cfg.LLM.MaxCallsPerRequest = maxCalls
+		cfg.Validation.MaxRepairAttempts = repairAttempts
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithRetry(backendURL, 5*time.Second,
+			client.RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	requestBody := []byte(`{
+		"schema": {"type": "object", "required": ["name", "age"], "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}},
+		"messages": [{"role": "user", "content": "Give me a person"}]
+	}`)
+
+	post := func(t *testing.T, testServer *httptest.Server) (int, []byte) {
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, body
+	}
+
+	// A flaky backend whose first answer misses a required field
+	replies := []scriptedReply{
+		{status: http.StatusServiceUnavailable},
+		{content: `{"name": "Jane"}`},
+		{status: http.StatusServiceUnavailable},
+		{content: `{"name": "Jane", "age": 30}`},
+	}
+
+	t.Run("retries_and_repair_within_budget", func(t *testing.T) {
+		backend, calls, received := newScriptedBackend(t, replies...)
+		testServer := setup(t, backend.URL, 5, 2)
+
+		status, body := post(t, testServer)
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"name": "Jane", "age": 30}`, string(body))
+		assert.Equal(t, int32(4), atomic.LoadInt32(calls))
+
+		// The repair re-prompt carries the invalid answer and the validation error
+		repair := received()[3]
+		require.Len(t, repair, 3)
+		assert.Equal(t, "assistant", repair[1].Role)
+		assert.Equal(t, `{"name": "Jane"}`, repair[1].Content)
+		assert.Equal(t, "user", repair[2].Role)
+		assert.Contains(t, repair[2].Content, "age")
+	})
+
+	t.Run("budget_caps_total_calls", func(t *testing.T) {
+		backend, calls, _ := newScriptedBackend(t, replies...)
+		testServer := setup(t, backend.URL, 3, 2)
+
+		status, body := post(t, testServer)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+		var errResp types.ErrorResponse
+		require.NoError(t, json.Unmarshal(body, &errResp))
+		assert.Contains(t, errResp.Details, client.ErrCallBudgetExhausted.Error())
+	})
+
+	t.Run("exhausted_budget_skips_repair", func(t *testing.T) {
+		backend, calls, _ := newScriptedBackend(t, replies[1])
+		testServer := setup(t, backend.URL, 1, 3)
+
+		status, body := post(t, testServer)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+		var validationErr types.ValidationError
+		require.NoError(t, json.Unmarshal(body, &validationErr))
+		assert.Equal(t, float64(0), validationErr.Context["repair_attempts"])
+	})
+
+	t.Run("repair_attempts_bounded_without_budget", func(t *testing.T) {
+		backend, calls, _ := newScriptedBackend(t, replies[1])
+		testServer := setup(t, backend.URL, 0, 2)
+
+		status, body := post(t, testServer)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+		var validationErr types.ValidationError
+		require.NoError(t, json.Unmarshal(body, &validationErr))
+		assert.Equal(t, float64(2), validationErr.Context["repair_attempts"])
+	})
+}
+
+func TestEmptyChoicesResponse(t *testing.T) {
+	setup := func(t *testing.T, backendURL string, retryEmpty bool) *httptest.Server {
+		cfg := config.Default()
+		cfg.LLM.RetryEmptyResponses = retryEmpty
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithRetry(backendURL, 5*time.Second,
+			client.RetryConfig{Attempts: 2, Delay: time.Millisecond, EmptyResponses: retryEmpty}, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	var calls int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		json.NewEncoder(w).Encode(types.LLMResponse{Choices: []types.Choice{}})
+	}))
+	defer backend.Close()
+
+	post := func(t *testing.T, testServer *httptest.Server) (int, types.ErrorResponse) {
+		body := []byte(`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Hello"}]}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var errResp types.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		return resp.StatusCode, errResp
+	}
+
+	for _, retryEmpty := range []bool{false, true} {
+		name := "retry_off"
+		wantCalls := int32(1)
+		if retryEmpty {
+			name, wantCalls = "retry_on", 3
+		}
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			status, errResp := post(t, setup(t, backend.URL, retryEmpty))
+			assert.Equal(t, http.StatusBadGateway, status)
+			assert.Equal(t, types.ErrorCodeLLMEmptyResponse, errResp.Code)
+			assert.Equal(t, wantCalls, atomic.LoadInt32(&calls))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestCanonicalOutput(t *testing.T) {
+	query := func(t *testing.T, canonical bool, data string) []byte {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(data)}, nil)
+
+		cfg := config.Default()
+		cfg.Output.Canonical = canonical
+		cfg.Output.DefaultRepresentation = "pretty"
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		body := `{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Describe"}]}`
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return respBody
+	}
+
+	// The same data as two LLM runs might emit it
+	first := `{"name": "Ada", "scores": [1.50, 2], "meta": {"z": "<b>", "a": 1e2}}`
+	second := "{\n  \"meta\": {\"a\": 100, \"z\": \"<b>\"},\n  \"scores\": [1.5, 2.0],\n  \"name\": \"Ada\"\n}"
+
+	t.Run("equivalent_data_byte_identical", func(t *testing.T) {
+		a := query(t, true, first)
+		b := query(t, true, second)
+		assert.Equal(t, `{"meta":{"a":100,"z":"<b>"},"name":"Ada","scores":[1.5,2]}`, string(a))
+		assert.Equal(t, a, b)
+	})
+
+	t.Run("disabled_keeps_representation", func(t *testing.T) {
+		a := query(t, false, first)
+		b := query(t, false, second)
+		assert.NotEqual(t, a, b)
+		assert.JSONEq(t, string(a), string(b))
+	})
+}
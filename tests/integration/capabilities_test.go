@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestCapabilities(t *testing.T) {
+	fetch := func(t *testing.T, cfg *config.Config) types.Capabilities {
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mocks.NewMockLLMClient(), cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		resp, err := http.Get(testServer.URL + "/v1/capabilities")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var caps types.Capabilities
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&caps))
+		return caps
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg := config.Default()
+		caps := fetch(t, cfg)
+
+		assert.True(t, caps.Streaming)
+		assert.True(t, caps.Batch)
+		assert.Equal(t, cfg.Validation.BatchMaxPayloads, caps.BatchMaxPayloads)
+		assert.True(t, caps.SchemaRegistry)
+		assert.False(t, caps.Repair)
+		assert.False(t, caps.SharedSchemaRegistry)
+		assert.False(t, caps.SchemaURLRegistration)
+		assert.False(t, caps.Schemaless)
+		assert.False(t, caps.ResponseCache)
+		assert.False(t, caps.BackendOverride)
+		assert.False(t, caps.ModelFallback)
+		assert.False(t, caps.Envelope)
+
+		assert.Equal(t, "github.com/santhosh-tekuri/jsonschema/v5", caps.SchemaEngine.Library)
+		assert.NotEmpty(t, caps.SchemaEngine.Version)
+		assert.NotEmpty(t, caps.SchemaEngine.DefaultDraft)
+		assert.Equal(t, cfg.Validation.AssertFormats, caps.SchemaEngine.AssertFormats)
+	})
+
+	t.Run("reflects_enabled_features", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Validation.MaxRepairAttempts = 2
+		cfg.Validation.AllowSchemaless = true
+		cfg.Registry.FetchAllowlist = []string{"schemas.example.com"}
+		cfg.ResponseCache.Backend = "memory"
+		cfg.LLM.BackendOverride.Enabled = true
+		cfg.LLM.FallbackURL = "http://small-llm:8080"
+		cfg.Output.Envelope = true
+		caps := fetch(t, cfg)
+
+		assert.True(t, caps.Repair)
+		assert.Equal(t, 2, caps.MaxRepairAttempts)
+		assert.True(t, caps.Schemaless)
+		assert.True(t, caps.SchemaURLRegistration)
+		assert.True(t, caps.ResponseCache)
+		assert.True(t, caps.BackendOverride)
+		assert.True(t, caps.ModelFallback)
+		assert.True(t, caps.Envelope)
+	})
+}
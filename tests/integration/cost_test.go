@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestCostEstimates(t *testing.T) {
+	setup := func(t *testing.T, header bool) (*httptest.Server, *bytes.Buffer) {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(&types.ValidatedResponse{
+			Data:  json.RawMessage(`{"name": "Jane"}`),
+			Model: "gemma-3-4b",
+			Usage: &types.Usage{PromptTokens: 2000, CompletionTokens: 500, TotalTokens: 2500},
+		}, nil)
+
+		cfg := config.Default()
+		cfg.LLM.Prices = map[string]config.ModelPrice{
+			"gemma-3-4b": {InputPerMillion: 0.5, OutputPerMillion: 2},
+		}
+		cfg.Output.CostHeader = header
+
+		var logs bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "info", Format: "json", Output: &logs})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer, &logs
+	}
+
+	requestBody := []byte(`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Hi"}]}`)
+	// 2000 * $0.50/M + 500 * $2/M
+	const expectedCost = 0.002
+
+	t.Run("cost_returned_in_header", func(t *testing.T) {
+		testServer, _ := setup(t, true)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		cost, err := strconv.ParseFloat(resp.Header.Get("X-Estimated-Cost"), 64)
+		require.NoError(t, err)
+		assert.InDelta(t, expectedCost, cost, 1e-12)
+	})
+
+	t.Run("cost_logged_without_header", func(t *testing.T) {
+		testServer, logs := setup(t, false)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Empty(t, resp.Header.Get("X-Estimated-Cost"))
+
+		var completion map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			if entry["msg"] == "Validated query completed successfully" {
+				completion = entry
+			}
+		}
+		require.NotNil(t, completion)
+		assert.Equal(t, "gemma-3-4b", completion["llm_model"])
+		assert.InDelta(t, expectedCost, completion["estimated_cost_usd"], 1e-12)
+	})
+}
@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestEffectiveDeadlineLogged(t *testing.T) {
+	// deadlineLog sends one validated query and returns its deadline log entry
+	deadlineLog := func(t *testing.T, configure func(*config.Config)) map[string]interface{} {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "Ada"}`)}, nil)
+
+		cfg := config.Default()
+		configure(cfg)
+		var logs syncBuffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "debug", Format: "json", Output: &logs})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+		testServer := httptest.NewServer(middleware.RequestTimeoutWithRoutes(cfg.Server.WriteTimeout, cfg.Server.RouteTimeouts)(mux))
+		defer testServer.Close()
+
+		body := []byte(`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Who?"}]}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			if entry["msg"] == "Effective request deadline" {
+				return entry
+			}
+		}
+		t.Fatal("no effective deadline logged")
+		return nil
+	}
+
+	t.Run("server_write_timeout", func(t *testing.T) {
+		entry := deadlineLog(t, func(cfg *config.Config) {})
+		assert.Equal(t, "write_timeout", entry["timeout_source"])
+		assert.InDelta(t, 30000, entry["effective_timeout_ms"], 1000)
+	})
+
+	t.Run("route_override", func(t *testing.T) {
+		entry := deadlineLog(t, func(cfg *config.Config) {
+			cfg.Server.RouteTimeouts = map[string]time.Duration{"/v1/validated-query": 90 * time.Second}
+		})
+		assert.Equal(t, "route_timeout", entry["timeout_source"])
+		assert.InDelta(t, 90000, entry["effective_timeout_ms"], 1000)
+	})
+
+	t.Run("shorter_max_request_duration", func(t *testing.T) {
+		entry := deadlineLog(t, func(cfg *config.Config) {
+			cfg.Server.RouteTimeouts = map[string]time.Duration{"/v1/validated-query": 90 * time.Second}
+			cfg.Server.MaxRequestDuration = 5 * time.Second
+		})
+		assert.Equal(t, "max_request_duration", entry["timeout_source"])
+		assert.InDelta(t, 5000, entry["effective_timeout_ms"], 1000)
+		assert.NotEmpty(t, entry["effective_deadline"])
+	})
+}
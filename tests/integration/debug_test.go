@@ -0,0 +1,196 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/limiter"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/metrics"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestDebugVarsEndpoint(t *testing.T) {
+	newDebugServer := func(t *testing.T, debug config.DebugConfig) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "John"}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Debug = debug
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	query := func(t *testing.T, url string, schema string) {
+		body, err := json.Marshal(types.ValidatedQueryRequest{
+			Schema:   json.RawMessage(schema),
+			Messages: []types.Message{{Role: "user", Content: "Tell me about John"}},
+		})
+		require.NoError(t, err)
+		resp, err := http.Post(url+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	t.Run("reports_error_rates_over_window", func(t *testing.T) {
+		testServer := newDebugServer(t, config.DebugConfig{Enabled: true, ErrorRateWindow: 5 * time.Minute})
+
+		validSchema := `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`
+		for i := 0; i < 3; i++ {
+			query(t, testServer.URL, validSchema)
+		}
+		query(t, testServer.URL, `{"type":"object","required":["age"]}`)
+
+		resp, err := http.Get(testServer.URL + "/debug/vars")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var snapshot metrics.Snapshot
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshot))
+		require.NotNil(t, snapshot.ErrorRates)
+		assert.Equal(t, float64(300), snapshot.ErrorRates.WindowSeconds)
+		assert.Equal(t, int64(4), snapshot.ErrorRates.Requests)
+		assert.Equal(t, int64(1), snapshot.ErrorRates.Failures)
+		assert.InDelta(t, 0.25, snapshot.ErrorRates.FailureRate, 1e-9)
+		assert.Equal(t, map[string]float64{types.ErrorCodeValidationFailed: 0.25}, snapshot.ErrorRates.RatesByCategory)
+	})
+
+	t.Run("reports_counters_after_traffic", func(t *testing.T) {
+		testServer := newDebugServer(t, config.DebugConfig{Enabled: true, Token: "secret"})
+
+		validSchema := `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`
+		query(t, testServer.URL, validSchema)
+		query(t, testServer.URL, validSchema)
+		query(t, testServer.URL, `{"type":"invalid_type"}`)
+		query(t, testServer.URL, `{"type":"object","required":["age"]}`)
+
+		req, err := http.NewRequest("GET", testServer.URL+"/debug/vars", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var vars map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&vars))
+
+		for _, key := range []string{"total_requests", "in_flight", "llm_errors", "failures_by_code", "cache"} {
+			assert.Contains(t, vars, key)
+		}
+		assert.Equal(t, float64(4), vars["total_requests"])
+		assert.Equal(t, float64(0), vars["in_flight"])
+
+		failures := vars["failures_by_code"].(map[string]interface{})
+		assert.Equal(t, float64(1), failures[types.ErrorCodeInvalidSchema])
+		assert.Equal(t, float64(1), failures[types.ErrorCodeValidationFailed])
+
+		cache := vars["cache"].(map[string]interface{})
+		for _, key := range []string{"size", "hits", "misses", "hit_rate"} {
+			assert.Contains(t, cache, key)
+		}
+		assert.Greater(t, cache["hits"], float64(0))
+	})
+
+	t.Run("requires_token", func(t *testing.T) {
+		testServer := newDebugServer(t, config.DebugConfig{Enabled: true, Token: "secret"})
+
+		resp, err := http.Get(testServer.URL + "/debug/vars")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		testServer := newDebugServer(t, config.DebugConfig{})
+
+		resp, err := http.Get(testServer.URL + "/debug/vars")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestAdmissionQueueMetrics(t *testing.T) {
+	release := make(chan time.Time)
+	mockClient := mocks.NewMockLLMClient()
+	mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+		&types.ValidatedResponse{Data: json.RawMessage(`{"name": "John"}`)}, nil).WaitUntil(release)
+
+	cfg := config.Default()
+	cfg.Debug = config.DebugConfig{Enabled: true}
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mockClient, cfg, logger)
+	admission := limiter.NewLimiter(1, nil)
+	srv.SetAdmissionLimiter(admission)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(middleware.ConcurrencyLimit(admission, "X-Priority", "/debug/vars")(mux))
+	defer testServer.Close()
+
+	queue := func(t *testing.T) metrics.QueueSnapshot {
+		resp, err := http.Get(testServer.URL + "/debug/vars")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var snapshot metrics.Snapshot
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshot))
+		require.NotNil(t, snapshot.Queue)
+		return *snapshot.Queue
+	}
+
+	// One request holds the only slot while three more queue behind it
+	done := make(chan int, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			body := `{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Hi"}]}`
+			resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+			if err != nil {
+				done <- 0
+				return
+			}
+			resp.Body.Close()
+			done <- resp.StatusCode
+		}()
+	}
+	require.Eventually(t, func() bool { return admission.Queued() == 3 }, time.Second, time.Millisecond)
+
+	saturated := queue(t)
+	assert.Equal(t, 3, saturated.Depth)
+	assert.Equal(t, 3, saturated.MaxDepth)
+
+	close(release)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, http.StatusOK, <-done)
+	}
+
+	drained := queue(t)
+	assert.Equal(t, 0, drained.Depth)
+	assert.Equal(t, 3, drained.MaxDepth)
+	assert.Equal(t, int64(3), drained.WaitSeconds.Count)
+	assert.NotEmpty(t, drained.WaitSeconds.Buckets)
+}
@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestSchemaDefaults(t *testing.T) {
+	setup := func(t *testing.T, apply bool) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "Jane", "role": "admin"}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Validation.ApplyDefaults = apply
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	requestBody := []byte(`{
+		"schema": {"type": "object", "properties": {
+			"name": {"type": "string"},
+			"role": {"type": "string", "default": "member"},
+			"active": {"type": "boolean", "default": true}
+		}},
+		"messages": [{"role": "user", "content": "Give me a user"}]
+	}`)
+
+	query := func(t *testing.T, testServer *httptest.Server) map[string]interface{} {
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	t.Run("enabled_fills_missing_fields", func(t *testing.T) {
+		body := query(t, setup(t, true))
+		assert.Equal(t, true, body["active"])
+		assert.Equal(t, "admin", body["role"])
+	})
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		body := query(t, setup(t, false))
+		assert.NotContains(t, body, "active")
+	})
+}
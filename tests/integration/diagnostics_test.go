@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestDiagnosticHeaders(t *testing.T) {
+	schemaJSON := `{"type": "object", "required": ["name"]}`
+
+	setup := func(t *testing.T, enabled bool, data string) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(data)}, nil)
+
+		cfg := config.Default()
+		cfg.Output.DiagnosticHeaders = enabled
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(middleware.RequestLogging(logger)(mux))
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	post := func(t *testing.T, testServer *httptest.Server) *http.Response {
+		body := `{"schema": ` + schemaJSON + `, "messages": [{"role": "user", "content": "Name?"}]}`
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("present_when_enabled", func(t *testing.T) {
+		resp := post(t, setup(t, true, `{"name": "Jane"}`))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+		assert.Equal(t, schema.Hash(json.RawMessage(schemaJSON)), resp.Header.Get("X-Schema-Hash"))
+		assert.Equal(t, "default", resp.Header.Get("X-LLM-Backend"))
+		assert.Equal(t, "miss", resp.Header.Get("X-Cache"))
+	})
+
+	t.Run("present_on_errors", func(t *testing.T) {
+		resp := post(t, setup(t, true, `{"age": 30}`))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, schema.Hash(json.RawMessage(schemaJSON)), resp.Header.Get("X-Schema-Hash"))
+		assert.Equal(t, "default", resp.Header.Get("X-LLM-Backend"))
+	})
+
+	t.Run("absent_when_disabled", func(t *testing.T) {
+		resp := post(t, setup(t, false, `{"name": "Jane"}`))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+		assert.Empty(t, resp.Header.Get("X-Schema-Hash"))
+		assert.Empty(t, resp.Header.Get("X-LLM-Backend"))
+		assert.Empty(t, resp.Header.Get("X-Cache"))
+	})
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestDuplicateKeyPolicy(t *testing.T) {
+	setup := func(t *testing.T, policy string) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "Jane", "age": 30, "name": "John"}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Validation.DuplicateKeys = policy
+		cfg.Validation.ReportWarnings = true
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	requestBody := []byte(`{
+		"schema": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}},
+		"messages": [{"role": "user", "content": "Give me a person"}]
+	}`)
+
+	post := func(t *testing.T, testServer *httptest.Server) (*http.Response, []byte) {
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	t.Run("allow_keeps_last_value", func(t *testing.T) {
+		resp, body := post(t, setup(t, "allow"))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Validation-Warnings"))
+
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &data))
+		assert.Equal(t, "John", data["name"])
+	})
+
+	t.Run("warn_reports_duplicate", func(t *testing.T) {
+		resp, _ := post(t, setup(t, "warn"))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("X-Validation-Warnings"), "/name: duplicate key")
+	})
+
+	t.Run("reject_fails_request", func(t *testing.T) {
+		resp, body := post(t, setup(t, "reject"))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+		var errResp types.ErrorResponse
+		require.NoError(t, json.Unmarshal(body, &errResp))
+		assert.Equal(t, types.ErrorCodeDuplicateKeys, errResp.Code)
+		assert.Contains(t, errResp.Details, "/name")
+	})
+}
@@ -0,0 +1,62 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestMaxRequestDuration(t *testing.T) {
+	// A slow, failing backend: unbounded retries would take about a second
+	var calls int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	cfg := config.Default()
+	cfg.Server.MaxRequestDuration = 250 * time.Millisecond
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	llmClient := client.NewLlamaServerClientWithRetry(backend.URL, 5*time.Second,
+		client.RetryConfig{Attempts: 9, Delay: 10 * time.Millisecond}, logger)
+	srv := server.NewServerFromConfig(llmClient, cfg, logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	requestBody := []byte(`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Hello"}]}`)
+	start := time.Now()
+	resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	elapsed := time.Since(start)
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Less(t, elapsed, 500*time.Millisecond)
+	assert.Greater(t, atomic.LoadInt32(&calls), int32(1), "retries should run until the deadline")
+
+	var errResp types.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, types.ErrorCodeTimeout, errResp.Code)
+	assert.Equal(t, "Request exceeded maximum duration", errResp.Message)
+}
@@ -302,50 +302,27 @@ func TestConcurrentRequests(t *testing.T) {
 
 	// Send multiple concurrent requests (fewer with real LLM for performance)
 	numRequests := 3
-	results := make(chan int, numRequests)
-
 	logger.LogConcurrentTestStart(numRequests)
 
-	startTime := time.Now()
-	for i := 0; i < numRequests; i++ {
-		go func(requestID int) {
-			resp, err := http.Post(
-				gatewayServer.URL+"/v1/validated-query",
-				"application/json",
-				bytes.NewReader(reqBody),
-			)
-			if err != nil {
-				results <- 0
-				return
-			}
-			defer resp.Body.Close()
-			results <- resp.StatusCode
-		}(i)
-	}
+	result := utils.RunConcurrent(t, numRequests, func(i int) (int, error) {
+		resp, err := http.Post(
+			gatewayServer.URL+"/v1/validated-query",
+			"application/json",
+			bytes.NewReader(reqBody),
+		)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	})
 
 	logger.LogStep(3, "Collecting results from concurrent requests")
 
-	// Wait for all requests to complete (longer timeout for real LLM)
-	successCount := 0
-	failedCount := 0
-	timeout := time.After(30 * time.Second)
-
-	for i := 0; i < numRequests; i++ {
-		select {
-		case status := <-results:
-			if status == http.StatusOK || status == http.StatusUnprocessableEntity {
-				// Both OK and validation errors are considered successful for concurrency testing
-				successCount++
-			} else {
-				failedCount++
-			}
-		case <-timeout:
-			t.Fatal("Timeout waiting for concurrent requests")
-		}
-	}
-
-	totalDuration := time.Since(startTime)
-	logger.LogConcurrentTestResult(successCount, failedCount, totalDuration)
+	// Both OK and validation errors are considered successful for concurrency testing
+	successCount := result.Count(http.StatusOK, http.StatusUnprocessableEntity)
+	failedCount := numRequests - successCount
+	logger.LogConcurrentTestResult(successCount, failedCount, result.Duration)
 
 	assert.Equal(t, numRequests, successCount, "All concurrent requests should complete")
 	logger.LogValidation(successCount == numRequests, fmt.Sprintf("All %d concurrent requests completed", numRequests))
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	setup := func(t *testing.T, envelope, requestIDInBody bool) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{
+				Data:  json.RawMessage(`{"name": "Jane"}`),
+				Usage: &types.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			}, nil)
+
+		cfg := config.Default()
+		cfg.Output.Envelope = envelope
+		cfg.Output.RequestIDInBody = requestIDInBody
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(middleware.RequestLogging(logger)(mux))
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	post := func(t *testing.T, testServer *httptest.Server) (*http.Response, []byte) {
+		req, err := http.NewRequest("POST", testServer.URL+"/v1/validated-query", bytes.NewReader([]byte(
+			`{"schema": {"type": "object", "required": ["name"]}, "messages": [{"role": "user", "content": "Name?"}]}`)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "req-envelope-1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	t.Run("bare_data_by_default", func(t *testing.T) {
+		resp, body := post(t, setup(t, false, false))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "req-envelope-1", resp.Header.Get("X-Request-ID"))
+		assert.JSONEq(t, `{"name": "Jane"}`, string(body))
+	})
+
+	t.Run("envelope_without_request_id", func(t *testing.T) {
+		_, body := post(t, setup(t, true, false))
+		assert.JSONEq(t, `{"data": {"name": "Jane"}, "usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`, string(body))
+	})
+
+	t.Run("envelope_with_request_id", func(t *testing.T) {
+		resp, body := post(t, setup(t, true, true))
+		assert.Equal(t, "req-envelope-1", resp.Header.Get("X-Request-ID"))
+
+		var envelope types.ResponseEnvelope
+		require.NoError(t, json.Unmarshal(body, &envelope))
+		assert.Equal(t, "req-envelope-1", envelope.RequestID)
+		assert.JSONEq(t, `{"name": "Jane"}`, string(envelope.Data))
+	})
+}
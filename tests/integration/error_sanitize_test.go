@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+)
+
+func TestErrorSanitization(t *testing.T) {
+	// A closed backend makes every call fail with a dial error naming its address
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backendURL := backend.URL
+	backend.Close()
+	backendHost := mustHost(t, backendURL)
+
+	setup := func(t *testing.T, verbosity string) (*httptest.Server, *bytes.Buffer) {
+		cfg := config.Default()
+		cfg.LLM.ServerURL = backendURL
+		cfg.Output.ErrorVerbosity = verbosity
+
+		var logs bytes.Buffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: &logs})
+		llmClient := client.NewLlamaServerClientWithLogger(cfg.LLM.ServerURL, cfg.LLM.Timeout, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer, &logs
+	}
+
+	send := func(t *testing.T, testServer *httptest.Server) (int, string) {
+		body := []byte(`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Hello"}]}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(respBody)
+	}
+
+	t.Run("sanitized_hides_backend", func(t *testing.T) {
+		testServer, logs := setup(t, "sanitized")
+
+		status, body := send(t, testServer)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Contains(t, body, "LLM_ERROR")
+		assert.NotContains(t, body, backendURL)
+		assert.NotContains(t, body, backendHost)
+		assert.Contains(t, body, "[llm backend]")
+
+		// Server logs keep the full detail
+		assert.Contains(t, logs.String(), backendHost)
+	})
+
+	t.Run("sanitized_ready_hides_backend", func(t *testing.T) {
+		testServer, _ := setup(t, "sanitized")
+
+		resp, err := http.Get(testServer.URL + "/ready")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.NotContains(t, string(body), backendHost)
+	})
+
+	t.Run("full_keeps_backend", func(t *testing.T) {
+		testServer, _ := setup(t, "full")
+
+		status, body := send(t, testServer)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Contains(t, body, backendHost)
+	})
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return u.Host
+}
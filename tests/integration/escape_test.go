@@ -0,0 +1,60 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestHTMLEscaping(t *testing.T) {
+	query := func(t *testing.T, escapeHTML bool, keyCase string) string {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"link_url": "https://example.com/?a=1&b=2", "snippet": "if a < b {}"}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Output.EscapeHTML = escapeHTML
+		cfg.Output.KeyCase = keyCase
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		body := `{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Link?"}]}`
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return strings.TrimSpace(string(respBody))
+	}
+
+	t.Run("escaped_by_default", func(t *testing.T) {
+		assert.Equal(t, `{"link_url":"https://example.com/?a=1\u0026b=2","snippet":"if a \u003c b {}"}`, query(t, true, ""))
+	})
+
+	t.Run("disabled_keeps_characters", func(t *testing.T) {
+		assert.Equal(t, `{"link_url":"https://example.com/?a=1&b=2","snippet":"if a < b {}"}`, query(t, false, ""))
+	})
+
+	t.Run("transformed_data_follows_setting", func(t *testing.T) {
+		assert.Equal(t, `{"linkUrl":"https://example.com/?a=1&b=2","snippet":"if a < b {}"}`, query(t, false, "camel"))
+		assert.Equal(t, `{"linkUrl":"https://example.com/?a=1\u0026b=2","snippet":"if a \u003c b {}"}`, query(t, true, "camel"))
+	})
+}
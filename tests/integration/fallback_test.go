@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestModelFallback(t *testing.T) {
+	// The fallback backend answers at once, naming the model it was asked for
+	var fallbackCalls int32
+	var requestedModel atomic.Value
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fallbackCalls, 1)
+		var body map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		var model string
+		json.Unmarshal(body["model"], &model)
+		requestedModel.Store(model)
+		json.NewEncoder(w).Encode(types.LLMResponse{
+			Model:   model,
+			Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: `{"name": "Ada"}`}}},
+		})
+	}))
+	defer fallback.Close()
+
+	setup := func(t *testing.T, primary http.HandlerFunc) *httptest.Server {
+		backend := httptest.NewServer(primary)
+		t.Cleanup(backend.Close)
+
+		cfg := config.Default()
+		cfg.LLM.Timeout = 100 * time.Millisecond
+		cfg.LLM.RetryAttempts = 0
+		cfg.LLM.FallbackURL = fallback.URL
+		cfg.LLM.FallbackModel = "gemma-3-1b"
+		cfg.Output.Envelope = true
+		cfg.Output.DiagnosticHeaders = true
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithLogger(backend.URL, cfg.LLM.Timeout, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	query := func(t *testing.T, testServer *httptest.Server) (*http.Response, []byte) {
+		body := []byte(`{"schema": {"type": "object", "required": ["name"]}, "messages": [{"role": "user", "content": "Who?"}]}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, respBody
+	}
+
+	t.Run("primary_timeout_falls_back", func(t *testing.T) {
+		atomic.StoreInt32(&fallbackCalls, 0)
+		testServer := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(300 * time.Millisecond):
+			}
+		})
+
+		resp, body := query(t, testServer)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+		assert.Equal(t, "fallback", resp.Header.Get("X-LLM-Backend"))
+
+		var envelope types.ResponseEnvelope
+		require.NoError(t, json.Unmarshal(body, &envelope))
+		assert.JSONEq(t, `{"name": "Ada"}`, string(envelope.Data))
+		assert.Equal(t, "gemma-3-1b", envelope.Model)
+		assert.Equal(t, "gemma-3-1b", requestedModel.Load())
+		assert.Equal(t, int32(1), atomic.LoadInt32(&fallbackCalls))
+	})
+
+	t.Run("timeout_after_streamed_output_does_not_fall_back", func(t *testing.T) {
+		atomic.StoreInt32(&fallbackCalls, 0)
+		testServer := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(`data: {"choices": [{"delta": {"content": "{\"na"}}]}` + "\n\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(300 * time.Millisecond):
+			}
+		})
+
+		body := `{"schema": {"type": "object", "required": ["name"]}, "messages": [{"role": "user", "content": "Who?"}]}`
+		resp, err := http.Post(testServer.URL+"/v1/validated-query/stream", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		events := readEvents(t, resp.Body)
+
+		// The client already has part of the primary's output, so the
+		// fallback's answer would not continue it
+		require.Len(t, events, 2)
+		assert.Equal(t, "delta", events[0].name)
+		assert.Equal(t, "error", events[1].name)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&fallbackCalls))
+	})
+
+	t.Run("other_failures_do_not_fall_back", func(t *testing.T) {
+		atomic.StoreInt32(&fallbackCalls, 0)
+		testServer := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		resp, _ := query(t, testServer)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&fallbackCalls))
+	})
+}
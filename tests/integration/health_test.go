@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestReadyEndpoint(t *testing.T) {
+	setup := func(t *testing.T, ttl time.Duration, opts ...func(*server.Server)) (*mocks.MockLLMClient, *httptest.Server) {
+		mockClient := mocks.NewMockLLMClient()
+		cfg := config.Default()
+		cfg.Health.CacheTTL = ttl
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		for _, opt := range opts {
+			opt(srv)
+		}
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return mockClient, testServer
+	}
+
+	probe := func(t *testing.T, testServer *httptest.Server) int {
+		resp, err := http.Get(testServer.URL + "/ready")
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("repeated_probes_within_ttl_check_backend_once", func(t *testing.T) {
+		mockClient, testServer := setup(t, time.Minute)
+		mockClient.On("HealthCheck", mock.Anything).Return(nil)
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, probe(t, testServer))
+		}
+		mockClient.AssertNumberOfCalls(t, "HealthCheck", 1)
+	})
+
+	t.Run("outage_detected_after_ttl", func(t *testing.T) {
+		mockClient, testServer := setup(t, 20*time.Millisecond)
+		mockClient.On("HealthCheck", mock.Anything).Return(nil).Once()
+		mockClient.On("HealthCheck", mock.Anything).Return(errors.New("connection refused"))
+
+		assert.Equal(t, http.StatusOK, probe(t, testServer))
+		assert.Equal(t, http.StatusOK, probe(t, testServer))
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, http.StatusServiceUnavailable, probe(t, testServer))
+		mockClient.AssertNumberOfCalls(t, "HealthCheck", 2)
+	})
+
+	t.Run("validator_failure_is_not_ready", func(t *testing.T) {
+		mockClient, testServer := setup(t, time.Minute, func(srv *server.Server) {
+			srv.SetSchemaSelfCheck(func() error { return errors.New("compile canary schema: broken") })
+		})
+		mockClient.On("HealthCheck", mock.Anything).Return(nil)
+
+		assert.Equal(t, http.StatusServiceUnavailable, probe(t, testServer))
+		mockClient.AssertNotCalled(t, "HealthCheck", mock.Anything)
+	})
+
+	t.Run("zero_ttl_checks_every_probe", func(t *testing.T) {
+		mockClient, testServer := setup(t, 0)
+		mockClient.On("HealthCheck", mock.Anything).Return(nil)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, probe(t, testServer))
+		}
+		mockClient.AssertNumberOfCalls(t, "HealthCheck", 3)
+	})
+}
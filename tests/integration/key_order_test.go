@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestPreserveKeyOrder(t *testing.T) {
+	query := func(t *testing.T, preserve bool) string {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"zeta": 1, "alpha": {"y": null, "x": 2}}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Validation.ApplyDefaults = true
+		cfg.Validation.NullPolicy = "strip"
+		cfg.Output.PreserveKeyOrder = preserve
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		body := `{"schema": {
+			"type": "object",
+			"properties": {
+				"zeta": {"type": "integer"},
+				"alpha": {"type": "object", "properties": {"y": {"type": "string"}, "x": {"type": "integer"}}},
+				"mid": {"type": "string", "default": "m"}
+			}
+		}, "messages": [{"role": "user", "content": "Describe"}]}`
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return strings.TrimSpace(string(respBody))
+	}
+
+	t.Run("enabled_keeps_llm_order", func(t *testing.T) {
+		assert.Equal(t, `{"zeta":1,"alpha":{"x":2},"mid":"m"}`, query(t, true))
+	})
+
+	t.Run("disabled_sorts_rewritten_keys", func(t *testing.T) {
+		assert.Equal(t, `{"alpha":{"x":2},"mid":"m","zeta":1}`, query(t, false))
+	})
+}
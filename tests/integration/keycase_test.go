@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestOutputKeyCase(t *testing.T) {
+	setup := func(t *testing.T, keyCase string) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"first_name": "Jane", "home_address": {"zip_code": "12345"}}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Output.KeyCase = keyCase
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	// The schema uses the LLM's snake_case keys; validation runs before rekeying
+	schema := `{"type": "object", "required": ["first_name"], "properties": {"first_name": {"type": "string"}, "home_address": {"type": "object", "required": ["zip_code"]}}}`
+
+	post := func(t *testing.T, testServer *httptest.Server, body string) (int, map[string]interface{}) {
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+		return resp.StatusCode, decoded
+	}
+
+	t.Run("configured_camel_case", func(t *testing.T) {
+		testServer := setup(t, "camel")
+
+		status, body := post(t, testServer, `{"schema": `+schema+`, "messages": [{"role": "user", "content": "Person"}]}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "Jane", body["firstName"])
+		assert.Equal(t, map[string]interface{}{"zipCode": "12345"}, body["homeAddress"])
+		assert.NotContains(t, body, "first_name")
+	})
+
+	t.Run("request_overrides_config", func(t *testing.T) {
+		testServer := setup(t, "")
+
+		status, body := post(t, testServer, `{"schema": `+schema+`, "messages": [{"role": "user", "content": "Person"}], "key_case": "camel"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "Jane", body["firstName"])
+	})
+
+	t.Run("unchanged_by_default", func(t *testing.T) {
+		testServer := setup(t, "")
+
+		status, body := post(t, testServer, `{"schema": `+schema+`, "messages": [{"role": "user", "content": "Person"}]}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "Jane", body["first_name"])
+	})
+
+	t.Run("unsupported_key_case_rejected", func(t *testing.T) {
+		testServer := setup(t, "")
+
+		status, body := post(t, testServer, `{"schema": `+schema+`, "messages": [{"role": "user", "content": "Person"}], "key_case": "kebab"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, types.ErrorCodeInvalidRequest, body["code"])
+	})
+}
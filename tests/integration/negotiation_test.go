@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestDefaultRepresentation(t *testing.T) {
+	setup := func(t *testing.T, representation string) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "Jane"}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Output.DefaultRepresentation = representation
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	validQuery := `{"schema": {"type": "object", "required": ["name"]}, "messages": [{"role": "user", "content": "Name?"}]}`
+	invalidQuery := `{"schema": {"type": "invalid"}, "messages": [{"role": "user", "content": "Name?"}]}`
+
+	post := func(t *testing.T, testServer *httptest.Server, accept, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest("POST", testServer.URL+"/v1/validated-query", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, respBody
+	}
+
+	t.Run("json_default_omitted_accept", func(t *testing.T) {
+		testServer := setup(t, "json")
+
+		resp, body := post(t, testServer, "", invalidQuery)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var errResp types.ErrorResponse
+		require.NoError(t, json.Unmarshal(body, &errResp))
+		assert.Equal(t, types.ErrorCodeInvalidSchema, errResp.Code)
+	})
+
+	t.Run("problem_default_omitted_accept", func(t *testing.T) {
+		testServer := setup(t, "problem")
+
+		resp, body := post(t, testServer, "", invalidQuery)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+		var problem types.ProblemDetails
+		require.NoError(t, json.Unmarshal(body, &problem))
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, types.ErrorCodeInvalidSchema, problem.Code)
+		assert.Equal(t, "/v1/validated-query", problem.Instance)
+		assert.NotEmpty(t, problem.Title)
+	})
+
+	t.Run("problem_default_wildcard_accept", func(t *testing.T) {
+		testServer := setup(t, "problem")
+
+		resp, _ := post(t, testServer, "*/*", invalidQuery)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("problem_default_success_stays_json", func(t *testing.T) {
+		testServer := setup(t, "problem")
+
+		resp, body := post(t, testServer, "", validQuery)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.JSONEq(t, `{"name": "Jane"}`, string(body))
+	})
+
+	t.Run("pretty_default_omitted_accept", func(t *testing.T) {
+		testServer := setup(t, "pretty")
+
+		resp, body := post(t, testServer, "", validQuery)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\n  \"name\": \"Jane\"\n}\n", string(body))
+	})
+
+	t.Run("explicit_json_accept_wins", func(t *testing.T) {
+		testServer := setup(t, "pretty")
+
+		_, body := post(t, testServer, "application/json", validQuery)
+		assert.Equal(t, "{\"name\":\"Jane\"}\n", string(body))
+
+		testServer = setup(t, "problem")
+		resp, _ := post(t, testServer, "application/json", invalidQuery)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("explicit_problem_accept_wins", func(t *testing.T) {
+		testServer := setup(t, "json")
+
+		resp, body := post(t, testServer, "application/problem+json", invalidQuery)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+		assert.True(t, strings.Contains(string(body), `"status":400`))
+	})
+}
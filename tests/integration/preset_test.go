@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestPerRequestValidationPreset(t *testing.T) {
+	setup := func(t *testing.T, assertFormats bool) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"email": "not-an-email"}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Validation.AssertFormats = assertFormats
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	query := func(t *testing.T, testServer *httptest.Server, preset string) int {
+		body := []byte(`{
+			"schema": {"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object",
+				"properties": {"email": {"type": "string", "format": "email"}}},
+			"messages": [{"role": "user", "content": "Give me an email"}]
+		}`)
+		req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/validated-query", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if preset != "" {
+			req.Header.Set("X-Validation-Preset", preset)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("strict_asserts_formats_for_that_request_only", func(t *testing.T) {
+		testServer := setup(t, false)
+
+		assert.Equal(t, http.StatusOK, query(t, testServer, ""))
+		assert.Equal(t, http.StatusUnprocessableEntity, query(t, testServer, "strict"))
+		assert.Equal(t, http.StatusOK, query(t, testServer, ""))
+	})
+
+	t.Run("lenient_leaves_formats_to_the_schema_for_that_request_only", func(t *testing.T) {
+		testServer := setup(t, true)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, query(t, testServer, ""))
+		assert.Equal(t, http.StatusOK, query(t, testServer, "lenient"))
+		assert.Equal(t, http.StatusUnprocessableEntity, query(t, testServer, ""))
+	})
+
+	t.Run("unknown_preset_rejected", func(t *testing.T) {
+		testServer := setup(t, false)
+
+		assert.Equal(t, http.StatusBadRequest, query(t, testServer, "paranoid"))
+	})
+}
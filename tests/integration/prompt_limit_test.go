@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+// wordEstimator counts one token per whitespace-separated word
+type wordEstimator struct{}
+
+func (wordEstimator) EstimateTokens(messages []types.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += len(strings.Fields(msg.Content))
+	}
+	return total
+}
+
+func TestMaxPromptTokens(t *testing.T) {
+	setup := func(t *testing.T, maxTokens int) (*server.Server, *mocks.MockLLMClient, *httptest.Server) {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "John"}`)}, nil).Maybe()
+
+		cfg := config.Default()
+		cfg.LLM.MaxPromptTokens = maxTokens
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return srv, mockClient, testServer
+	}
+
+	send := func(t *testing.T, url, content string) (*http.Response, map[string]interface{}) {
+		body, err := json.Marshal(types.ValidatedQueryRequest{
+			Schema:   json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`),
+			Messages: []types.Message{{Role: "user", Content: content}},
+		})
+		require.NoError(t, err)
+		resp, err := http.Post(url+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var decoded map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp, decoded
+	}
+
+	t.Run("prompt_at_limit_is_sent", func(t *testing.T) {
+		_, mockClient, testServer := setup(t, 10)
+
+		// 40 characters estimate to exactly 10 tokens
+		resp, _ := send(t, testServer.URL, strings.Repeat("a", 40))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockClient.AssertNumberOfCalls(t, "SendStructuredQuery", 1)
+	})
+
+	t.Run("prompt_over_limit_is_rejected_before_llm_call", func(t *testing.T) {
+		_, mockClient, testServer := setup(t, 10)
+
+		// 41 characters estimate to 11 tokens
+		resp, body := send(t, testServer.URL, strings.Repeat("a", 41))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, types.ErrorCodePromptTooLarge, body["code"])
+		assert.Contains(t, body["details"], "estimated 11 prompt tokens exceeds limit of 10")
+		mockClient.AssertNotCalled(t, "SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		_, _, testServer := setup(t, 0)
+
+		resp, _ := send(t, testServer.URL, strings.Repeat("a", 10000))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("custom_estimator", func(t *testing.T) {
+		srv, _, testServer := setup(t, 3)
+		srv.SetTokenEstimator(wordEstimator{})
+
+		resp, _ := send(t, testServer.URL, "three short words")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, _ = send(t, testServer.URL, "now there are four")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestMaxMessageBytes(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+		&types.ValidatedResponse{Data: json.RawMessage(`{"name": "John"}`)}, nil).Maybe()
+
+	cfg := config.Default()
+	cfg.Server.MaxMessageBytes = 64
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mockClient, cfg, logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	send := func(t *testing.T, messages []types.Message) (*http.Response, types.ErrorResponse) {
+		body, err := json.Marshal(types.ValidatedQueryRequest{
+			Schema:   json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`),
+			Messages: messages,
+		})
+		require.NoError(t, err)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var errorResp types.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errorResp)
+		return resp, errorResp
+	}
+
+	t.Run("oversized_message_is_rejected_by_index", func(t *testing.T) {
+		resp, errorResp := send(t, []types.Message{
+			{Role: "system", Content: "Extract the name."},
+			{Role: "user", Content: strings.Repeat("a", 65)},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, types.ErrorCodeInvalidRequest, errorResp.Code)
+		assert.Equal(t, "messages[1].content", errorResp.Context["field"])
+		assert.Equal(t, "messages[1].content exceeds 64 bytes", errorResp.Details)
+		mockClient.AssertNotCalled(t, "SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("message_at_limit_is_sent", func(t *testing.T) {
+		resp, _ := send(t, []types.Message{{Role: "user", Content: strings.Repeat("a", 64)}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,444 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	setup := func(t *testing.T, maxEntries int, policy string) (*mocks.MockLLMClient, *httptest.Server) {
+		mockClient := mocks.NewMockLLMClient()
+		cfg := config.Default()
+		cfg.Registry.MaxEntries = maxEntries
+		cfg.Registry.FullPolicy = policy
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return mockClient, testServer
+	}
+
+	register := func(t *testing.T, testServer *httptest.Server, schemaJSON string) (int, string) {
+		resp, err := http.Post(testServer.URL+"/v1/schemas", "application/json", bytes.NewReader([]byte(schemaJSON)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var registration types.SchemaRegistration
+		if resp.StatusCode == http.StatusCreated {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&registration))
+		}
+		return resp.StatusCode, registration.ID
+	}
+
+	getStatus := func(t *testing.T, testServer *httptest.Server, id string) int {
+		resp, err := http.Get(testServer.URL + "/v1/schemas/" + id)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	schemaN := func(i int) string {
+		return fmt.Sprintf(`{"type": "object", "title": "schema-%d"}`, i)
+	}
+
+	t.Run("query_by_registered_schema_id", func(t *testing.T) {
+		mockClient, testServer := setup(t, 10, "reject")
+		status, id := register(t, testServer, `{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`)
+		require.Equal(t, http.StatusCreated, status)
+
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "Jane"}`)}, nil)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json",
+			bytes.NewReader([]byte(`{"schema_id": "`+id+`", "messages": [{"role": "user", "content": "Name?"}]}`)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = http.Post(testServer.URL+"/v1/validated-query", "application/json",
+			bytes.NewReader([]byte(`{"schema_id": "unknown", "messages": [{"role": "user", "content": "Name?"}]}`)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("reject_mode_returns_507_when_full", func(t *testing.T) {
+		_, testServer := setup(t, 2, "reject")
+		for i := 0; i < 2; i++ {
+			status, _ := register(t, testServer, schemaN(i))
+			require.Equal(t, http.StatusCreated, status)
+		}
+
+		status, _ := register(t, testServer, schemaN(2))
+		assert.Equal(t, http.StatusInsufficientStorage, status)
+
+		// Re-registering an existing schema still succeeds
+		status, _ = register(t, testServer, schemaN(0))
+		assert.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("oversized_schema_returns_413", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Registry.MaxSchemaBytes = 64
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mocks.NewMockLLMClient(), cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+
+		status, _ := register(t, testServer, `{"type": "object", "description": "`+strings.Repeat("x", 64)+`"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+		status, _ = register(t, testServer, schemaN(0))
+		assert.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("lru_mode_evicts_oldest_when_full", func(t *testing.T) {
+		_, testServer := setup(t, 2, "lru")
+		var ids []string
+		for i := 0; i < 3; i++ {
+			status, id := register(t, testServer, schemaN(i))
+			require.Equal(t, http.StatusCreated, status)
+			ids = append(ids, id)
+		}
+
+		assert.Equal(t, http.StatusNotFound, getStatus(t, testServer, ids[0]))
+		assert.Equal(t, http.StatusOK, getStatus(t, testServer, ids[1]))
+		assert.Equal(t, http.StatusOK, getStatus(t, testServer, ids[2]))
+	})
+
+	t.Run("normalized_view", func(t *testing.T) {
+		_, testServer := setup(t, 10, "reject")
+		_, id := register(t, testServer, `{"type": "object", "properties": {"id": {"$ref": "#/$defs/id"}}, "$defs": {"id": {"type": "integer"}}}`)
+
+		resp, err := http.Get(testServer.URL + "/v1/schemas/" + id + "/normalized")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"properties":{"id":{"type":"integer"}},"type":"object"}`, string(body))
+	})
+
+	t.Run("invalid_schema_not_registered", func(t *testing.T) {
+		_, testServer := setup(t, 10, "reject")
+		status, _ := register(t, testServer, `{"type": 12}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("stats_reflect_validations", func(t *testing.T) {
+		mockClient, testServer := setup(t, 10, "reject")
+		_, id := register(t, testServer, `{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`)
+
+		post := func(path, body string) {
+			resp, err := http.Post(testServer.URL+path, "application/json", bytes.NewReader([]byte(body)))
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+		schemaJSON := `{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`
+		post("/v1/validate", `{"schema": `+schemaJSON+`, "data": {"name": "Ada"}}`)
+		post("/v1/validate", `{"schema": `+schemaJSON+`, "data": {"name": 7}}`)
+		post("/v1/validate/batch", `{"schema": `+schemaJSON+`, "payloads": [{"name": "Bo"}, {}]}`)
+
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"name": "Cy"}`)}, nil).Once()
+		post("/v1/validated-query", `{"schema_id": "`+id+`", "messages": [{"role": "user", "content": "Name?"}]}`)
+
+		resp, err := http.Get(testServer.URL + "/v1/schemas/" + id + "/stats")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var stats types.SchemaStats
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+		assert.Equal(t, id, stats.ID)
+		assert.Equal(t, int64(5), stats.Validations)
+		assert.Equal(t, int64(3), stats.Valid)
+		assert.Equal(t, int64(2), stats.Invalid)
+		assert.InDelta(t, 0.6, stats.PassRate, 1e-9)
+		assert.Greater(t, stats.AvgResponseBytes, 0.0)
+
+		missing, err := http.Get(testServer.URL + "/v1/schemas/unknown/stats")
+		require.NoError(t, err)
+		missing.Body.Close()
+		assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+	})
+}
+
+func TestSchemaRegistrationByURL(t *testing.T) {
+	schemaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "object", "required": ["name"]}`))
+	}))
+	defer schemaServer.Close()
+
+	setup := func(t *testing.T, allowlist []string) *httptest.Server {
+		cfg := config.Default()
+		cfg.Registry.FetchAllowlist = allowlist
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mocks.NewMockLLMClient(), cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	registerURL := func(t *testing.T, testServer *httptest.Server, schemaURL string) *http.Response {
+		body, err := json.Marshal(map[string]string{"url": schemaURL})
+		require.NoError(t, err)
+		resp, err := http.Post(testServer.URL+"/v1/schemas", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("allowlisted_url_is_fetched", func(t *testing.T) {
+		testServer := setup(t, []string{"127.0.0.1"})
+		resp := registerURL(t, testServer, schemaServer.URL+"/person.json")
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var registration types.SchemaRegistration
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&registration))
+		require.NotEmpty(t, registration.ID)
+
+		getResp, err := http.Get(testServer.URL + "/v1/schemas/" + registration.ID)
+		require.NoError(t, err)
+		defer getResp.Body.Close()
+		body, err := io.ReadAll(getResp.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type": "object", "required": ["name"]}`, string(body))
+	})
+
+	t.Run("non_allowlisted_url_is_rejected", func(t *testing.T) {
+		testServer := setup(t, []string{"schemas.example.com"})
+		resp := registerURL(t, testServer, schemaServer.URL+"/person.json")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		var errResp types.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.Equal(t, types.ErrorCodeSchemaURLForbidden, errResp.Code)
+	})
+
+	t.Run("fetching_disabled_without_allowlist", func(t *testing.T) {
+		testServer := setup(t, nil)
+		resp := registerURL(t, testServer, schemaServer.URL+"/person.json")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestSchemaFragments(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mockClient, config.Default(), logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	post := func(t *testing.T, path, body string) (int, []byte) {
+		resp, err := http.Post(testServer.URL+path, "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, respBody
+	}
+	register := func(t *testing.T, schemaJSON string) string {
+		status, body := post(t, "/v1/schemas", schemaJSON)
+		require.Equal(t, http.StatusCreated, status, string(body))
+		var registration types.SchemaRegistration
+		require.NoError(t, json.Unmarshal(body, &registration))
+		return registration.ID
+	}
+
+	addressID := register(t, `{
+		"type": "object",
+		"required": ["city", "country"],
+		"properties": {"city": {"type": "string"}, "country": {"$ref": "#/$defs/country"}},
+		"$defs": {"country": {"type": "string", "minLength": 2, "maxLength": 2}}
+	}`)
+	moneyID := register(t, `{
+		"$defs": {
+			"money": {
+				"type": "object",
+				"required": ["amount", "currency"],
+				"properties": {"amount": {"type": "number", "minimum": 0}, "currency": {"enum": ["USD", "EUR"]}}
+			}
+		}
+	}`)
+	orderSchema := `{
+		"type": "object",
+		"required": ["ship_to", "total"],
+		"properties": {
+			"ship_to": {"$ref": "registry:` + addressID + `"},
+			"total": {"$ref": "registry:` + moneyID + `#/$defs/money", "description": "Order total"}
+		}
+	}`
+
+	validate := func(t *testing.T, data string) types.ValidationReport {
+		status, body := post(t, "/v1/validate?format=ci", `{"schema": `+orderSchema+`, "data": `+data+`}`)
+		require.Equal(t, http.StatusOK, status, string(body))
+		var report types.ValidationReport
+		require.NoError(t, json.Unmarshal(body, &report))
+		return report
+	}
+
+	t.Run("composed_schema_accepts_valid_data", func(t *testing.T) {
+		report := validate(t, `{"ship_to": {"city": "Paris", "country": "FR"}, "total": {"amount": 12.5, "currency": "EUR"}}`)
+		assert.True(t, report.Valid, report.Results)
+	})
+
+	t.Run("composed_schema_enforces_both_fragments", func(t *testing.T) {
+		report := validate(t, `{"ship_to": {"city": "Paris", "country": "France"}, "total": {"amount": -1, "currency": "GBP"}}`)
+		assert.False(t, report.Valid)
+		var paths []string
+		for _, result := range report.Results {
+			paths = append(paths, result.Path)
+		}
+		assert.ElementsMatch(t, []string{"/ship_to/country", "/total/amount", "/total/currency"}, paths)
+	})
+
+	t.Run("registered_composition_used_by_query", func(t *testing.T) {
+		orderID := register(t, orderSchema)
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"ship_to": {"city": "Oslo"}, "total": {"amount": 3, "currency": "USD"}}`)}, nil).Once()
+
+		status, body := post(t, "/v1/validated-query", `{"schema_id": "`+orderID+`", "messages": [{"role": "user", "content": "Order?"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Contains(t, string(body), "country")
+	})
+
+	t.Run("unknown_fragment_rejected", func(t *testing.T) {
+		status, body := post(t, "/v1/schemas", `{"type": "object", "properties": {"a": {"$ref": "registry:missing"}}}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, string(body), "unknown schema fragment")
+	})
+}
+
+func TestSchemaAnalysis(t *testing.T) {
+	cfg := config.Default()
+	cfg.Validation.BatchMaxPayloads = 3
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mocks.NewMockLLMClient(), cfg, logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	analyze := func(t *testing.T, body string) (int, types.SchemaAnalysis) {
+		resp, err := http.Post(testServer.URL+"/v1/schemas/analyze", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var analysis types.SchemaAnalysis
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&analysis))
+		}
+		return resp.StatusCode, analysis
+	}
+
+	t.Run("suggests_tightening", func(t *testing.T) {
+		status, analysis := analyze(t, `{
+			"schema": {"type": "object", "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}},
+			"samples": [{"name": "Ada", "age": 36}, {"name": "Alan", "age": 41}]
+		}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 2, analysis.Samples)
+
+		var got []string
+		for _, s := range analysis.Suggestions {
+			got = append(got, s.Path+" "+s.Keyword)
+		}
+		assert.Equal(t, []string{"/ additionalProperties", "/age required", "/name required"}, got)
+	})
+
+	t.Run("rejects_invalid_requests", func(t *testing.T) {
+		status, _ := analyze(t, `{"schema": {"type": "object"}, "samples": []}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = analyze(t, `{"schema": {"type": "object"}, "samples": [{}, {}, {}, {}]}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = analyze(t, `{"schema": {"type": 12}, "samples": [{}]}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
+
+func TestErrorSchemaContext(t *testing.T) {
+	setup := func(t *testing.T, errorSchema string) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{}`)}, nil)
+
+		cfg := config.Default()
+		cfg.Output.ErrorSchema = errorSchema
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	// query registers the schema and sends a validated query referring to it
+	// by ID, which the mocked LLM answers with data missing "name"
+	query := func(t *testing.T, testServer *httptest.Server) (string, types.ValidationError) {
+		resp, err := http.Post(testServer.URL+"/v1/schemas", "application/json",
+			bytes.NewReader([]byte(`{"type": "object", "required": ["name"]}`)))
+		require.NoError(t, err)
+		var registration types.SchemaRegistration
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&registration))
+		resp.Body.Close()
+
+		body := `{"schema_id": "` + registration.ID + `", "messages": [{"role": "user", "content": "Name someone"}]}`
+		resp, err = http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+		var validationErr types.ValidationError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationErr))
+		return registration.ID, validationErr
+	}
+
+	t.Run("omitted_by_default", func(t *testing.T) {
+		_, validationErr := query(t, setup(t, "none"))
+		assert.NotContains(t, validationErr.Context, "schema_id")
+		assert.NotContains(t, validationErr.Context, "schema")
+	})
+
+	t.Run("id", func(t *testing.T) {
+		id, validationErr := query(t, setup(t, "id"))
+		assert.Equal(t, id, validationErr.Context["schema_id"])
+		assert.NotContains(t, validationErr.Context, "schema")
+	})
+
+	t.Run("full", func(t *testing.T) {
+		id, validationErr := query(t, setup(t, "full"))
+		assert.Equal(t, id, validationErr.Context["schema_id"])
+		assert.Equal(t, map[string]interface{}{"type": "object", "required": []interface{}{"name"}}, validationErr.Context["schema"])
+	})
+}
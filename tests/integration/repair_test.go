@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+)
+
+func TestRepairDeterministicOnly(t *testing.T) {
+	setup := func(t *testing.T, backendURL string) *httptest.Server {
+		cfg := config.Default()
+		cfg.Validation.MaxRepairAttempts = 1
+		cfg.Validation.RepairDeterministicOnly = true
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithLogger(backendURL, 5*time.Second, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	post := func(t *testing.T, testServer *httptest.Server, deterministic bool) int {
+		flag := ""
+		if deterministic {
+			flag = `"deterministic": true,`
+		}
+		body := []byte(`{` + flag + `
+			"schema": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}},
+			"messages": [{"role": "user", "content": "Give me a name"}]
+		}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The first answer misses the required field; a re-prompt fixes it
+	replies := []scriptedReply{
+		{content: `{"title": "Jane"}`},
+		{content: `{"name": "Jane"}`},
+	}
+
+	t.Run("deterministic_request_is_repaired", func(t *testing.T) {
+		backend, calls, _ := newScriptedBackend(t, replies...)
+		testServer := setup(t, backend.URL)
+
+		assert.Equal(t, http.StatusOK, post(t, testServer, true))
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+
+	t.Run("other_request_fails_at_once", func(t *testing.T) {
+		backend, calls, _ := newScriptedBackend(t, replies...)
+		testServer := setup(t, backend.URL)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, post(t, testServer, false))
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+}
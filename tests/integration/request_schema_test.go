@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestRequestSchema(t *testing.T) {
+	setup := func(t *testing.T) (*httptest.Server, *mocks.MockLLMClient) {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"summary": "A short order"}`)}, nil)
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, config.Default(), logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer, mockClient
+	}
+
+	query := func(t *testing.T, testServer *httptest.Server, input string) (int, []byte) {
+		body := `{
+			"request_schema": {"type": "object", "required": ["items"], "properties": {"items": {"type": "array", "minItems": 1}}},
+			"input": ` + input + `,
+			"schema": {"type": "object", "required": ["summary"], "properties": {"summary": {"type": "string"}}},
+			"messages": [{"role": "user", "content": "Summarize this order"}]
+		}`
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, respBody
+	}
+
+	t.Run("input_failing_request_schema_is_rejected", func(t *testing.T) {
+		testServer, mockClient := setup(t)
+
+		status, body := query(t, testServer, `{"items": []}`)
+		require.Equal(t, http.StatusBadRequest, status)
+
+		var errResp types.ErrorResponse
+		require.NoError(t, json.Unmarshal(body, &errResp))
+		assert.Equal(t, types.ErrorCodeInvalidRequest, errResp.Code)
+		assert.Equal(t, "input", errResp.Context["field"])
+		assert.NotEmpty(t, errResp.Context["violations"])
+		mockClient.AssertNotCalled(t, "SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("missing_input_is_rejected", func(t *testing.T) {
+		testServer, _ := setup(t)
+
+		status, body := query(t, testServer, `null`)
+		require.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, string(body), "input is required when request_schema is set")
+	})
+
+	t.Run("passing_both_schemas", func(t *testing.T) {
+		testServer, mockClient := setup(t)
+
+		status, body := query(t, testServer, `{"items": ["tea", "scones"]}`)
+		require.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"summary": "A short order"}`, string(body))
+
+		// The input follows the messages as its own user message
+		messages := mockClient.Calls[0].Arguments.Get(1).([]types.Message)
+		require.Len(t, messages, 2)
+		assert.Equal(t, types.Message{Role: "user", Content: "Input:\n" + `{"items":["tea","scones"]}`}, messages[1])
+	})
+}
@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+func TestResponseCache(t *testing.T) {
+	setup := func(t *testing.T, backend string) (*httptest.Server, *int32) {
+		llm, calls, _ := newScriptedBackend(t, scriptedReply{content: `{"name": "Ada"}`})
+
+		cfg := config.Default()
+		cfg.ResponseCache.Backend = backend
+		cfg.Output.DiagnosticHeaders = true
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithLogger(llm.URL, 5*time.Second, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer, calls
+	}
+
+	send := func(t *testing.T, testServer *httptest.Server, content string) (*http.Response, string) {
+		body := []byte(`{"schema": {"type": "object", "required": ["name"]}, "messages": [{"role": "user", "content": "` + content + `"}]}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(respBody)
+	}
+
+	t.Run("memory_serves_repeat_from_cache", func(t *testing.T) {
+		testServer, calls := setup(t, "memory")
+
+		first, firstBody := send(t, testServer, "Who?")
+		assert.Equal(t, http.StatusOK, first.StatusCode)
+		assert.Equal(t, "miss", first.Header.Get("X-Cache"))
+
+		second, secondBody := send(t, testServer, "Who?")
+		assert.Equal(t, http.StatusOK, second.StatusCode)
+		assert.Equal(t, "hit", second.Header.Get("X-Cache"))
+		assert.JSONEq(t, firstBody, secondBody)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+		// A different prompt is a different key
+		third, _ := send(t, testServer, "Who else?")
+		assert.Equal(t, "miss", third.Header.Get("X-Cache"))
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+
+	t.Run("none_always_calls_llm", func(t *testing.T) {
+		testServer, calls := setup(t, "none")
+
+		send(t, testServer, "Who?")
+		resp, _ := send(t, testServer, "Who?")
+		assert.Equal(t, "miss", resp.Header.Get("X-Cache"))
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+
+	t.Run("registered_policy_selects_schemas", func(t *testing.T) {
+		testServer, calls := setup(t, "memory")
+
+		register := func(t *testing.T, query, schemaBody string) string {
+			resp, err := http.Post(testServer.URL+"/v1/schemas"+query, "application/json", strings.NewReader(schemaBody))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+			var registration types.SchemaRegistration
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&registration))
+			return registration.ID
+		}
+		creative := register(t, "?cacheable=false", `{"type": "object", "description": "a character"}`)
+		lookup := register(t, "?cache_ttl=1m", `{"type": "object", "description": "a record"}`)
+
+		sendID := func(t *testing.T, id string) *http.Response {
+			body := `{"schema_id": "` + id + `", "messages": [{"role": "user", "content": "Who?"}]}`
+			resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			return resp
+		}
+
+		sendID(t, creative)
+		assert.Equal(t, "miss", sendID(t, creative).Header.Get("X-Cache"))
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+		sendID(t, lookup)
+		assert.Equal(t, "hit", sendID(t, lookup).Header.Get("X-Cache"))
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	})
+
+	t.Run("invalid_policy_rejected", func(t *testing.T) {
+		testServer, _ := setup(t, "memory")
+
+		for _, query := range []string{"?cacheable=maybe", "?cache_ttl=soon", "?cacheable=false&cache_ttl=1m"} {
+			resp, err := http.Post(testServer.URL+"/v1/schemas"+query, "application/json", strings.NewReader(`{"type": "object"}`))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+
+	t.Run("changed_policy_conflicts", func(t *testing.T) {
+		testServer, _ := setup(t, "memory")
+
+		statuses := []int{}
+		for _, query := range []string{"?cacheable=false", "?cacheable=true", ""} {
+			resp, err := http.Post(testServer.URL+"/v1/schemas"+query, "application/json", strings.NewReader(`{"type": "object"}`))
+			require.NoError(t, err)
+			resp.Body.Close()
+			statuses = append(statuses, resp.StatusCode)
+		}
+		assert.Equal(t, []int{http.StatusCreated, http.StatusConflict, http.StatusCreated}, statuses)
+	})
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// newFlakyLLMBackend starts an OpenAI-compatible backend that fails with 503
+// for the first failures calls and answers with content afterwards
+func newFlakyLLMBackend(t *testing.T, failures int, content string) (*httptest.Server, *int32) {
+	var calls int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(atomic.AddInt32(&calls, 1)) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(types.LLMResponse{
+			Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: content}}},
+		})
+	}))
+	t.Cleanup(backend.Close)
+	return backend, &calls
+}
+
+func TestNoRetryHeader(t *testing.T) {
+	setup := func(t *testing.T) (*httptest.Server, *int32) {
+		backend, calls := newFlakyLLMBackend(t, 1, `{"name": "John"}`)
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithRetry(backend.URL, 5*time.Second,
+			client.RetryConfig{Attempts: 3, Delay: time.Millisecond}, logger)
+		srv := server.NewServerFromConfig(llmClient, config.Default(), logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer, calls
+	}
+
+	send := func(t *testing.T, url string, noRetry bool) *http.Response {
+		body, err := json.Marshal(types.ValidatedQueryRequest{
+			Schema:   json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`),
+			Messages: []types.Message{{Role: "user", Content: "Tell me about John"}},
+		})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest("POST", url+"/v1/validated-query", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if noRetry {
+			req.Header.Set("X-No-Retry", "true")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("header_forces_single_attempt", func(t *testing.T) {
+		testServer, calls := setup(t)
+
+		resp := send(t, testServer.URL, true)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("configured_retries_apply_without_header", func(t *testing.T) {
+		testServer, calls := setup(t)
+
+		resp := send(t, testServer.URL, false)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+}
@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestSchemaHints(t *testing.T) {
+	const schemaJSON = `{"type": "object", "required": ["status"], "properties": {"status": {"type": "string", "enum": ["active", "closed"]}}}`
+
+	setup := func(t *testing.T, hints bool, llmOutput string) (*httptest.Server, *json.RawMessage) {
+		var sent json.RawMessage
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(2).(json.RawMessage)
+		}).Return(&types.ValidatedResponse{Data: json.RawMessage(llmOutput)}, nil)
+
+		cfg := config.Default()
+		cfg.LLM.SchemaHints = hints
+		cfg.Validation.MaxRepairAttempts = 0
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer, &sent
+	}
+
+	query := func(t *testing.T, testServer *httptest.Server) int {
+		body := []byte(`{"schema": ` + schemaJSON + `, "messages": [{"role": "user", "content": "Status?"}]}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	sentDescription := func(t *testing.T, sent json.RawMessage) interface{} {
+		var decoded struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		}
+		require.NoError(t, json.Unmarshal(sent, &decoded))
+		return decoded.Properties["status"]["description"]
+	}
+
+	t.Run("llm_sees_hints", func(t *testing.T) {
+		testServer, sent := setup(t, true, `{"status": "active"}`)
+
+		assert.Equal(t, http.StatusOK, query(t, testServer))
+		assert.Equal(t, `Must be one of: "active", "closed".`, sentDescription(t, *sent))
+	})
+
+	t.Run("validation_uses_original_schema", func(t *testing.T) {
+		testServer, sent := setup(t, true, `{"status": "pending"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, query(t, testServer))
+		assert.NotNil(t, sentDescription(t, *sent))
+	})
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		testServer, sent := setup(t, false, `{"status": "active"}`)
+
+		assert.Equal(t, http.StatusOK, query(t, testServer))
+		assert.Nil(t, sentDescription(t, *sent))
+	})
+}
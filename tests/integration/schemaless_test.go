@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestSchemalessPassThrough(t *testing.T) {
+	setup := func(t *testing.T, allow bool) (*mocks.MockLLMClient, *httptest.Server) {
+		mockClient := mocks.NewMockLLMClient()
+		cfg := config.Default()
+		cfg.Validation.AllowSchemaless = allow
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return mockClient, testServer
+	}
+
+	requestBody := []byte(`{"messages":[{"role":"user","content":"Give me any JSON"}]}`)
+
+	t.Run("schemaless_allowed_returns_raw_json", func(t *testing.T) {
+		mockClient, testServer := setup(t, true)
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, json.RawMessage(nil)).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`{"anything": ["goes", 42]}`)}, nil)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, []interface{}{"goes", float64(42)}, body["anything"])
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("null_schema_treated_as_missing", func(t *testing.T) {
+		mockClient, testServer := setup(t, true)
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, json.RawMessage(nil)).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(`[1, 2, 3]`)}, nil)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json",
+			bytes.NewReader([]byte(`{"schema": null, "messages":[{"role":"user","content":"List"}]}`)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("schemaless_disallowed_returns_400", func(t *testing.T) {
+		mockClient, testServer := setup(t, false)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, types.ErrorCodeInvalidSchema, body["code"])
+		assert.Equal(t, "Missing JSON schema", body["message"])
+		mockClient.AssertNotCalled(t, "SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -208,6 +210,37 @@ func TestHealthEndpoint(t *testing.T) {
 	assert.Equal(t, "OK", body)
 }
 
+func TestHealthEndpointsHEAD(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	mockClient.On("HealthCheck", mock.Anything).Return(nil)
+	srv := server.NewServer(mockClient)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	for _, path := range []string{"/health", "/ready"} {
+		t.Run(strings.TrimPrefix(path, "/"), func(t *testing.T) {
+			resp, err := http.Head(testServer.URL + path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Empty(t, body)
+
+			// The handler itself writes no body either
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, path, nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Empty(t, rr.Body.String())
+		})
+	}
+}
+
 func TestInvalidJSONRequest(t *testing.T) {
 	mockClient := mocks.NewMockLLMClient()
 	srv := server.NewServer(mockClient)
@@ -228,3 +261,154 @@ func TestInvalidJSONRequest(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 }
+
+func TestArrayViolationDetails(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+		&types.ValidatedResponse{Data: json.RawMessage(`{"tags": []}`)}, nil)
+
+	srv := server.NewServer(mockClient)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	requestBody := []byte(`{
+		"schema": {"type": "object", "properties": {"tags": {"type": "array", "minItems": 1}}},
+		"messages": [{"role": "user", "content": "Tag this"}]
+	}`)
+	resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var validationErr types.ValidationError
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationErr))
+	require.Len(t, validationErr.Violations, 1)
+
+	violation := validationErr.Violations[0]
+	assert.Equal(t, "/tags", violation.Path)
+	assert.Equal(t, "minItems", violation.Keyword)
+	assert.Equal(t, float64(0), violation.Actual)
+	assert.Equal(t, float64(1), violation.Expected)
+}
+
+func TestMissingRequiredDetails(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+		&types.ValidatedResponse{Data: json.RawMessage(`{"user": {"name": "Jane"}}`)}, nil)
+
+	srv := server.NewServer(mockClient)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	requestBody := []byte(`{
+		"schema": {
+			"type": "object",
+			"required": ["user", "id"],
+			"properties": {"user": {"type": "object", "required": ["name", "email"]}}
+		},
+		"messages": [{"role": "user", "content": "Describe a user"}]
+	}`)
+	resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var validationErr types.ValidationError
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationErr))
+	assert.ElementsMatch(t, []string{"/id", "/user/email"}, validationErr.MissingRequired)
+}
+
+func TestNormalizeSchemaEndpoint(t *testing.T) {
+	srv := server.NewServer(mocks.NewMockLLMClient())
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	t.Run("returns_normalized_schema", func(t *testing.T) {
+		resp, err := http.Post(testServer.URL+"/v1/schemas/normalize", "application/json",
+			bytes.NewReader([]byte(`{"type": "object", "properties": {"id": {"$ref": "#/$defs/id"}}, "$defs": {"id": {"type": "integer"}}}`)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"properties":{"id":{"type":"integer"}},"type":"object"}`, string(body))
+	})
+
+	t.Run("rejects_invalid_schema", func(t *testing.T) {
+		resp, err := http.Post(testServer.URL+"/v1/schemas/normalize", "application/json",
+			bytes.NewReader([]byte(`{"type": 12}`)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestMalformedMessages(t *testing.T) {
+	srv := server.NewServer(mocks.NewMockLLMClient())
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedField string
+		expectedError string
+	}{
+		{
+			name:          "wrong_typed_role",
+			body:          `{"schema": {"type": "object"}, "messages": [{"role": 123, "content": "hi"}]}`,
+			expectedField: "messages[0].role",
+			expectedError: "messages[0].role must be a string",
+		},
+		{
+			name:          "missing_content",
+			body:          `{"schema": {"type": "object"}, "messages": [{"role": "system", "content": "x"}, {"role": "user"}]}`,
+			expectedField: "messages[1].content",
+			expectedError: "messages[1].content is required",
+		},
+		{
+			name:          "non_array_messages",
+			body:          `{"schema": {"type": "object"}, "messages": {"role": "user", "content": "hi"}}`,
+			expectedField: "messages",
+			expectedError: "messages must be an array",
+		},
+		{
+			name:          "non_object_message",
+			body:          `{"schema": {"type": "object"}, "messages": ["hi"]}`,
+			expectedField: "messages[0]",
+			expectedError: "messages[0] must be an object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader([]byte(tt.body)))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+			var errorResp types.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorResp))
+			assert.Equal(t, types.ErrorCodeInvalidRequest, errorResp.Code)
+			assert.Equal(t, tt.expectedError, errorResp.Details)
+			assert.Equal(t, tt.expectedField, errorResp.Context["field"])
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/internal/webhook"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// sseEvent is one parsed server-sent event
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents parses a server-sent event stream until it ends
+func readEvents(t *testing.T, body io.Reader) []sseEvent {
+	var events []sseEvent
+	var current sseEvent
+	var data []string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			current.data = strings.Join(data, "\n")
+			events = append(events, current)
+			current, data = sseEvent{}, nil
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
+
+func TestRepairProgressEvents(t *testing.T) {
+	setup := func(t *testing.T, backendURL string) *httptest.Server {
+		cfg := config.Default()
+		cfg.Validation.MaxRepairAttempts = 2
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithLogger(backendURL, 5*time.Second, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	stream := func(t *testing.T, testServer *httptest.Server) []sseEvent {
+		body := []byte(`{
+			"schema": {"type": "object", "required": ["name", "age"], "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}},
+			"messages": [{"role": "user", "content": "Give me a person"}]
+		}`)
+		req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/validated-query", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return readEvents(t, resp.Body)
+	}
+
+	names := func(events []sseEvent) []string {
+		var result []string
+		for _, e := range events {
+			result = append(result, e.name)
+		}
+		return result
+	}
+
+	t.Run("fail_then_succeed", func(t *testing.T) {
+		backend, _, _ := newScriptedBackend(t,
+			scriptedReply{content: `{"name": "Jane"}`},
+			scriptedReply{content: `{"name": "Jane", "age": 30}`},
+		)
+		events := stream(t, setup(t, backend.URL))
+		require.Equal(t, []string{"attempt", "result"}, names(events))
+
+		var attempt types.RepairAttempt
+		require.NoError(t, json.Unmarshal([]byte(events[0].data), &attempt))
+		assert.Equal(t, 1, attempt.Attempt)
+		assert.Contains(t, attempt.Message, "attempt 1 failed")
+		assert.Contains(t, attempt.Message, "retrying")
+		require.NotEmpty(t, attempt.Violations)
+		assert.Equal(t, "required", attempt.Violations[0].Keyword)
+
+		assert.JSONEq(t, `{"name": "Jane", "age": 30}`, events[1].data)
+	})
+
+	t.Run("attempts_exhausted", func(t *testing.T) {
+		backend, _, _ := newScriptedBackend(t, scriptedReply{content: `{"name": "Jane"}`})
+		events := stream(t, setup(t, backend.URL))
+		require.Equal(t, []string{"attempt", "attempt", "error"}, names(events))
+
+		var validationErr types.ValidationError
+		require.NoError(t, json.Unmarshal([]byte(events[2].data), &validationErr))
+		assert.Equal(t, types.ErrorCodeValidationFailed, validationErr.Code)
+		assert.Equal(t, []string{"/age"}, validationErr.MissingRequired)
+	})
+}
+
+func TestValidatedQueryStream(t *testing.T) {
+	// The backend streams content in two pieces, as llama-server does with
+	// stream: true
+	setup := func(t *testing.T, content string, cfg *config.Config) *httptest.Server {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req types.LLMRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			assert.True(t, req.Stream)
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			half := len(content) / 2
+			for _, piece := range []string{content[:half], content[half:]} {
+				chunk, _ := json.Marshal(map[string]interface{}{
+					"choices": []map[string]interface{}{{"delta": map[string]string{"content": piece}}},
+				})
+				w.Write([]byte("data: " + string(chunk) + "\n\n"))
+				w.(http.Flusher).Flush()
+			}
+			w.Write([]byte("data: [DONE]\n\n"))
+		}))
+		t.Cleanup(backend.Close)
+
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		llmClient := client.NewLlamaServerClientWithLogger(backend.URL, 5*time.Second, logger)
+		srv := server.NewServerFromConfig(llmClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	stream := func(t *testing.T, testServer *httptest.Server) []sseEvent {
+		body := `{
+			"schema": {"type": "object", "required": ["name", "age"], "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}},
+			"messages": [{"role": "user", "content": "Give me a person"}]
+		}`
+		resp, err := http.Post(testServer.URL+"/v1/validated-query/stream", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return readEvents(t, resp.Body)
+	}
+
+	deltaContent := func(t *testing.T, events []sseEvent) string {
+		var content string
+		for _, e := range events {
+			if e.name != "delta" {
+				continue
+			}
+			var delta types.ContentDelta
+			require.NoError(t, json.Unmarshal([]byte(e.data), &delta))
+			content += delta.Content
+		}
+		return content
+	}
+
+	t.Run("deltas_then_result", func(t *testing.T) {
+		content := `{"name": "Jane", "age": 30}`
+		events := stream(t, setup(t, content, config.Default()))
+		require.Len(t, events, 3)
+		assert.Equal(t, "delta", events[0].name)
+		assert.Equal(t, "delta", events[1].name)
+		assert.Equal(t, content, deltaContent(t, events))
+
+		assert.Equal(t, "result", events[2].name)
+		assert.JSONEq(t, content, events[2].data)
+	})
+
+	t.Run("invalid_output_ends_with_error_event", func(t *testing.T) {
+		content := `{"name": "Jane"}`
+		events := stream(t, setup(t, content, config.Default()))
+		require.Len(t, events, 3)
+		assert.Equal(t, content, deltaContent(t, events))
+
+		last := events[len(events)-1]
+		assert.Equal(t, "error", last.name)
+		var validationErr types.ValidationError
+		require.NoError(t, json.Unmarshal([]byte(last.data), &validationErr))
+		assert.Equal(t, types.ErrorCodeValidationFailed, validationErr.Code)
+		assert.Equal(t, []string{"/age"}, validationErr.MissingRequired)
+		assert.Equal(t, "/v1/validated-query/stream", validationErr.Context["endpoint"])
+	})
+
+	t.Run("error_event_recorded_as_failure", func(t *testing.T) {
+		events := make(chan webhook.Event, 1)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event webhook.Event
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+			events <- event
+		}))
+		defer receiver.Close()
+
+		cfg := config.Default()
+		cfg.Webhook.URL = receiver.URL
+		cfg.Webhook.RetryDelay = time.Millisecond
+		stream(t, setup(t, `{"name": "Jane"}`, cfg))
+
+		// The stream opened with a 200, but its final event was a 422
+		select {
+		case event := <-events:
+			assert.Equal(t, webhook.OutcomeFailure, event.Outcome)
+			assert.Equal(t, http.StatusUnprocessableEntity, event.StatusCode)
+		case <-time.After(2 * time.Second):
+			t.Fatal("webhook did not receive an event")
+		}
+	})
+}
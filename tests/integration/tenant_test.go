@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/client"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/middleware"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/internal/tenant"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+)
+
+// newMockLLMBackend starts an OpenAI-compatible backend that always answers
+// with the given content and counts the requests it receives
+func newMockLLMBackend(t *testing.T, content string) (*httptest.Server, *int32) {
+	var calls int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.LLMResponse{
+			Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: content}}},
+		})
+	}))
+	t.Cleanup(backend.Close)
+	return backend, &calls
+}
+
+func TestTenantIsolation(t *testing.T) {
+	backendA, callsA := newMockLLMBackend(t, `{"name": "from-a"}`)
+	backendB, callsB := newMockLLMBackend(t, `{"name": "from-b"}`)
+	defaultBackend, defaultCalls := newMockLLMBackend(t, `{"name": "from-default"}`)
+
+	personSchema := json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`)
+	otherSchema := json.RawMessage(`{"type":"object","properties":{"title":{"type":"string"}}}`)
+
+	cfg := config.Default()
+	cfg.LLM.ServerURL = defaultBackend.URL
+	cfg.Tenants.Tenants = []config.TenantConfig{
+		{ID: "tenant-a", LLMServerURL: backendA.URL},
+		{ID: "tenant-b", LLMServerURL: backendB.URL, RequestsPerMinute: 2, SchemaAllowlist: []string{schema.Hash(personSchema)}},
+	}
+
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	llmClient := client.NewLlamaServerClientWithLogger(cfg.LLM.ServerURL, cfg.LLM.Timeout, logger)
+	srv := server.NewServerFromConfig(llmClient, cfg, logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+
+	handler := middleware.RequestLogging(logger)(
+		middleware.TenantResolution(tenant.NewRegistry(cfg.Tenants))(mux),
+	)
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	send := func(tenantID string, schemaJSON json.RawMessage) (*http.Response, map[string]interface{}) {
+		body, err := json.Marshal(types.ValidatedQueryRequest{
+			Schema:   schemaJSON,
+			Messages: []types.Message{{Role: "user", Content: "Who are you?"}},
+		})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest("POST", testServer.URL+"/v1/validated-query", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if tenantID != "" {
+			req.Header.Set("X-Tenant-ID", tenantID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var decoded map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp, decoded
+	}
+
+	t.Run("routes_each_tenant_to_its_backend", func(t *testing.T) {
+		resp, body := send("tenant-a", personSchema)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "from-a", body["name"])
+
+		resp, body = send("tenant-b", personSchema)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "from-b", body["name"])
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(callsA))
+		assert.Equal(t, int32(1), atomic.LoadInt32(callsB))
+		assert.Equal(t, int32(0), atomic.LoadInt32(defaultCalls))
+	})
+
+	t.Run("requests_without_tenant_use_default_backend", func(t *testing.T) {
+		resp, body := send("", personSchema)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "from-default", body["name"])
+	})
+
+	t.Run("unknown_tenant_is_rejected", func(t *testing.T) {
+		resp, body := send("tenant-c", personSchema)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, types.ErrorCodeUnknownTenant, body["code"])
+	})
+
+	t.Run("schema_outside_allowlist_is_rejected", func(t *testing.T) {
+		resp, body := send("tenant-b", otherSchema)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, types.ErrorCodeSchemaForbidden, body["code"])
+	})
+
+	t.Run("tenant_rate_limit_is_enforced", func(t *testing.T) {
+		// tenant-b has used its two requests for this minute in the subtests above
+		resp, body := send("tenant-b", personSchema)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, types.ErrorCodeRateLimited, body["code"])
+
+		// tenant-a is unaffected by tenant-b's limit
+		resp, _ = send("tenant-a", personSchema)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestReportTransformations(t *testing.T) {
+	setup := func(t *testing.T, llmOutput string, configure func(*config.Config)) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(llmOutput)}, nil)
+
+		cfg := config.Default()
+		cfg.Output.Envelope = true
+		cfg.Output.ReportTransformations = true
+		configure(cfg)
+		require.NoError(t, cfg.Validate())
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	requestBody := []byte(`{
+		"schema": {"type": "object", "properties": {
+			"user_name": {"type": "string"},
+			"nick_name": {"type": "string"},
+			"user_role": {"type": "string", "default": "member"}
+		}},
+		"messages": [{"role": "user", "content": "Give me a user"}]
+	}`)
+
+	query := func(t *testing.T, testServer *httptest.Server) types.ResponseEnvelope {
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var envelope types.ResponseEnvelope
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&envelope))
+		require.NotNil(t, envelope.Metadata)
+		return envelope
+	}
+
+	t.Run("lists_enabled_transformers_in_order", func(t *testing.T) {
+		envelope := query(t, setup(t, `{"user_name": "Jane", "nick_name": null}`, func(cfg *config.Config) {
+			cfg.Validation.NullPolicy = "strip"
+			cfg.Validation.ApplyDefaults = true
+			cfg.Output.PreserveKeyOrder = true
+			cfg.Output.KeyCase = "camel"
+		}))
+
+		assert.Equal(t, []types.Transformation{
+			{Name: types.TransformationNulls, Detail: "strip"},
+			{Name: types.TransformationDefaults},
+			{Name: types.TransformationKeyOrder},
+			{Name: types.TransformationKeyCase, Detail: "camel"},
+		}, envelope.Metadata.Transformations)
+		assert.JSONEq(t, `{"userName": "Jane", "userRole": "member"}`, string(envelope.Data))
+	})
+
+	t.Run("only_transformers_that_ran", func(t *testing.T) {
+		envelope := query(t, setup(t, `{"user_name": "Jane"}`, func(cfg *config.Config) {
+			cfg.Validation.NullPolicy = "strip"
+			cfg.Output.KeyCase = "camel"
+		}))
+
+		// Nulls are only rewritten when they would otherwise fail validation
+		assert.Equal(t, []types.Transformation{
+			{Name: types.TransformationKeyCase, Detail: "camel"},
+		}, envelope.Metadata.Transformations)
+	})
+
+	t.Run("empty_when_none_ran", func(t *testing.T) {
+		envelope := query(t, setup(t, `{"user_name": "Jane"}`, func(cfg *config.Config) {}))
+		assert.Empty(t, envelope.Metadata.Transformations)
+		assert.JSONEq(t, `{"user_name": "Jane"}`, string(envelope.Data))
+	})
+}
@@ -0,0 +1,216 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mockClient, config.Default(), logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	schemaJSON := `{
+		"type": "object",
+		"required": ["name", "email"],
+		"properties": {
+			"name": {"type": "string"},
+			"email": {"type": "string"},
+			"age": {"type": "integer", "maximum": 150},
+			"tags": {"type": "array", "minItems": 2},
+			"nickname": {"type": "string", "deprecated": true}
+		}
+	}`
+	// Missing email, age too large, too few tags, a deprecated and an undeclared property
+	invalidData := `{"name": "Jane", "age": 200, "tags": ["a"], "nickname": "JJ", "extra": true}`
+
+	post := func(t *testing.T, query, data string) (*http.Response, []byte) {
+		body := `{"schema": ` + schemaJSON + `, "data": ` + data + `}`
+		resp, err := http.Post(testServer.URL+"/v1/validate"+query, "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, respBody
+	}
+
+	t.Run("ci_format_reports_every_violation", func(t *testing.T) {
+		resp, body := post(t, "?format=ci", invalidData)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var report types.ValidationReport
+		require.NoError(t, json.Unmarshal(body, &report))
+		assert.False(t, report.Valid)
+		assert.Equal(t, 3, report.Errors)
+		assert.Equal(t, 2, report.Warnings)
+
+		var summary [][3]string
+		for _, result := range report.Results {
+			summary = append(summary, [3]string{result.Severity, result.Path, result.Keyword})
+		}
+		assert.Equal(t, [][3]string{
+			{"error", "/", "required"},
+			{"error", "/age", "maximum"},
+			{"error", "/tags", "minItems"},
+			{"warning", "/extra", "additionalProperties"},
+			{"warning", "/nickname", "deprecated"},
+		}, summary)
+		assert.Equal(t, float64(200), report.Results[1].Actual)
+		assert.Equal(t, float64(150), report.Results[1].Expected)
+	})
+
+	t.Run("ci_format_is_stable", func(t *testing.T) {
+		_, first := post(t, "?format=ci", invalidData)
+		for i := 0; i < 5; i++ {
+			_, again := post(t, "?format=ci", invalidData)
+			assert.Equal(t, string(first), string(again))
+		}
+
+		// Field names are part of the contract
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(first, &raw))
+		assert.ElementsMatch(t, []string{"valid", "schema_hash", "errors", "warnings", "results"}, keys(raw))
+		assert.Equal(t, schema.Hash(json.RawMessage(schemaJSON)), raw["schema_hash"])
+		result := raw["results"].([]interface{})[1].(map[string]interface{})
+		assert.ElementsMatch(t, []string{"severity", "path", "keyword", "message", "actual", "expected"}, keys(result))
+	})
+
+	t.Run("ci_format_valid_data", func(t *testing.T) {
+		_, body := post(t, "?format=ci", `{"name": "Jane", "email": "jane@example.com"}`)
+		assert.JSONEq(t, `{"valid": true, "schema_hash": "`+schema.Hash(json.RawMessage(schemaJSON))+`", "errors": 0, "warnings": 0, "results": []}`, string(body))
+	})
+
+	t.Run("text_format_by_default", func(t *testing.T) {
+		resp, body := post(t, "", invalidData)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+		assert.Contains(t, string(body), "INVALID: 3 error(s), 2 warning(s)")
+		assert.Contains(t, string(body), "error /age [maximum] value 200 at /age exceeds maximum 150")
+	})
+
+	t.Run("unknown_format_rejected", func(t *testing.T) {
+		resp, _ := post(t, "?format=xml", invalidData)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestValidateBatchEndpoint(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	cfg := config.Default()
+	cfg.Validation.BatchMaxPayloads = 10
+	cfg.Validation.BatchConcurrency = 3
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mockClient, cfg, logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	schemaJSON := `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string"},
+			"age": {"type": "integer", "maximum": 150}
+		}
+	}`
+
+	post := func(t *testing.T, body string) (*http.Response, []byte) {
+		resp, err := http.Post(testServer.URL+"/v1/validate/batch", "application/json", bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, respBody
+	}
+
+	t.Run("per_payload_results_in_order", func(t *testing.T) {
+		payloads := []string{
+			`{"name": "Jane", "age": 30}`,
+			`{"age": 30}`,
+			`{"name": "Bob"}`,
+			`{"name": "Old", "age": 200}`,
+			`[1, 2, 3]`,
+			`{"name": "Ann", "age": 1}`,
+		}
+		want := []struct {
+			valid    bool
+			keywords []string
+		}{
+			{true, nil},
+			{false, []string{"required"}},
+			{true, nil},
+			{false, []string{"maximum"}},
+			{false, []string{"type"}},
+			{true, nil},
+		}
+
+		resp, body := post(t, `{"schema": `+schemaJSON+`, "payloads": [`+strings.Join(payloads, ",")+`]}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+		var result types.ValidateBatchResponse
+		require.NoError(t, json.Unmarshal(body, &result))
+		assert.Equal(t, schema.Hash(json.RawMessage(schemaJSON)), result.SchemaHash)
+		assert.Equal(t, 3, result.Valid)
+		assert.Equal(t, 3, result.Invalid)
+		require.Len(t, result.Results, len(payloads))
+		for i, report := range result.Results {
+			assert.Equal(t, i, report.Index)
+			assert.Equal(t, want[i].valid, report.Valid, "payload %d", i)
+			var keywords []string
+			for _, r := range report.Results {
+				if r.Severity == types.SeverityError {
+					keywords = append(keywords, r.Keyword)
+				}
+			}
+			assert.Equal(t, want[i].keywords, keywords, "payload %d", i)
+		}
+	})
+
+	t.Run("never_calls_llm", func(t *testing.T) {
+		resp, _ := post(t, `{"schema": `+schemaJSON+`, "payloads": [{"name": "Jane"}]}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockClient.AssertNotCalled(t, "SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects_bad_requests", func(t *testing.T) {
+		tooMany := strings.TrimSuffix(strings.Repeat(`{"name": "x"},`, 11), ",")
+		for name, body := range map[string]string{
+			"no_payloads":    `{"schema": ` + schemaJSON + `, "payloads": []}`,
+			"no_schema":      `{"payloads": [{"name": "x"}]}`,
+			"too_many":       `{"schema": ` + schemaJSON + `, "payloads": [` + tooMany + `]}`,
+			"invalid_schema": `{"schema": {"type": "nope"}, "payloads": [{"name": "x"}]}`,
+		} {
+			resp, _ := post(t, body)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, name)
+		}
+	})
+}
+
+func keys(m map[string]interface{}) []string {
+	var result []string
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestValidationWarnings(t *testing.T) {
+	setup := func(t *testing.T, report bool, data string) *httptest.Server {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(data)}, nil)
+
+		cfg := config.Default()
+		cfg.Validation.ReportWarnings = report
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return testServer
+	}
+
+	requestBody := []byte(`{
+		"schema": {"type": "object", "properties": {"name": {"type": "string"}, "nickname": {"type": "string", "deprecated": true}}},
+		"messages": [{"role": "user", "content": "Give me a contact"}]
+	}`)
+
+	t.Run("deprecated_property_reported_as_warning", func(t *testing.T) {
+		testServer := setup(t, true, `{"name": "Jane", "nickname": "JJ"}`)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		warnings := resp.Header.Get("X-Validation-Warnings")
+		assert.Equal(t, "/nickname: property is deprecated", warnings)
+	})
+
+	t.Run("no_header_for_clean_response", func(t *testing.T) {
+		testServer := setup(t, true, `{"name": "Jane"}`)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Validation-Warnings"))
+	})
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		testServer := setup(t, false, `{"name": "Jane", "nickname": "JJ"}`)
+
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Validation-Warnings"))
+	})
+}
+
+func TestUnassertedFormatWarnings(t *testing.T) {
+	mockClient := mocks.NewMockLLMClient()
+	mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+		&types.ValidatedResponse{Data: json.RawMessage(`{"email": "not-an-email", "created_at": "yesterday"}`)}, nil)
+
+	cfg := config.Default()
+	cfg.Validation.ReportWarnings = true
+	cfg.Validation.AssertFormats = false
+	logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+	srv := server.NewServerFromConfig(mockClient, cfg, logger)
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	body := []byte(`{
+		"schema": {"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object",
+			"properties": {"email": {"type": "string", "format": "email"}, "created_at": {"type": "string", "format": "date-time"}}},
+		"messages": [{"role": "user", "content": "Give me a contact"}]
+	}`)
+	resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/created_at: value is not a valid date-time; /email: value is not a valid email", resp.Header.Get("X-Validation-Warnings"))
+}
+
+func TestSmallResponseWarning(t *testing.T) {
+	query := func(t *testing.T, data string) (*http.Response, string) {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(
+			&types.ValidatedResponse{Data: json.RawMessage(data)}, nil)
+
+		cfg := config.Default()
+		cfg.Validation.ReportWarnings = true
+		cfg.Validation.MinResponseProperties = 1
+		var logs syncBuffer
+		logger := logging.NewLogger(logging.LogConfig{Level: "warn", Format: "json", Output: &logs})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		// A permissive schema accepts an empty object
+		body := []byte(`{"schema": {"type": "object"}, "messages": [{"role": "user", "content": "Summarize"}]}`)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp, logs.String()
+	}
+
+	t.Run("empty_object_warns", func(t *testing.T) {
+		resp, logs := query(t, `{ }`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "response has 0 properties, below the minimum of 1", resp.Header.Get("X-Validation-Warnings"))
+		assert.Contains(t, logs, "Validated response is suspiciously small")
+	})
+
+	t.Run("substantive_response_does_not_warn", func(t *testing.T) {
+		resp, logs := query(t, `{"summary": "All good"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Validation-Warnings"))
+		assert.NotContains(t, logs, "suspiciously small")
+	})
+}
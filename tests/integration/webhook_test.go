@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wcygan/llm-json-parse/internal/config"
+	"github.com/wcygan/llm-json-parse/internal/logging"
+	"github.com/wcygan/llm-json-parse/internal/schema"
+	"github.com/wcygan/llm-json-parse/internal/server"
+	"github.com/wcygan/llm-json-parse/internal/webhook"
+	"github.com/wcygan/llm-json-parse/pkg/types"
+	"github.com/wcygan/llm-json-parse/tests/mocks"
+)
+
+func TestCompletionWebhook(t *testing.T) {
+	schemaJSON := json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`)
+	requestBody, err := json.Marshal(types.ValidatedQueryRequest{
+		Schema:   schemaJSON,
+		Messages: []types.Message{{Role: "user", Content: "Who are you?"}},
+	})
+	require.NoError(t, err)
+
+	setup := func(t *testing.T, webhookURL string) (*server.Server, *httptest.Server) {
+		mockClient := mocks.NewMockLLMClient()
+		mockClient.On("SendStructuredQuery", mock.Anything, mock.Anything, mock.Anything).Return(&types.ValidatedResponse{
+			Data:  json.RawMessage(`{"name": "gateway"}`),
+			Usage: &types.Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17},
+		}, nil)
+
+		cfg := config.Default()
+		cfg.Webhook.URL = webhookURL
+		cfg.Webhook.RetryDelay = time.Millisecond
+		logger := logging.NewLogger(logging.LogConfig{Level: "error", Format: "json", Output: io.Discard})
+		srv := server.NewServerFromConfig(mockClient, cfg, logger)
+		mux := http.NewServeMux()
+		srv.RegisterRoutes(mux)
+
+		testServer := httptest.NewServer(mux)
+		t.Cleanup(testServer.Close)
+		return srv, testServer
+	}
+
+	t.Run("receives_event_after_request", func(t *testing.T) {
+		events := make(chan webhook.Event, 1)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event webhook.Event
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+			events <- event
+		}))
+		defer receiver.Close()
+
+		_, testServer := setup(t, receiver.URL)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		select {
+		case event := <-events:
+			assert.NotEmpty(t, event.RequestID)
+			assert.Equal(t, webhook.OutcomeSuccess, event.Outcome)
+			assert.Equal(t, http.StatusOK, event.StatusCode)
+			assert.Equal(t, schema.Hash(schemaJSON), event.SchemaHash)
+			require.NotNil(t, event.Usage)
+			assert.Equal(t, 17, event.Usage.TotalTokens)
+		case <-time.After(2 * time.Second):
+			t.Fatal("webhook did not receive an event")
+		}
+	})
+
+	t.Run("webhook_failure_does_not_affect_response", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer receiver.Close()
+
+		srv, testServer := setup(t, receiver.URL)
+		resp, err := http.Post(testServer.URL+"/v1/validated-query", "application/json", bytes.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "gateway", body["name"])
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		assert.NoError(t, srv.Close(ctx))
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/wcygan/llm-json-parse/internal/client"
 	"github.com/wcygan/llm-json-parse/pkg/types"
 )
 
@@ -20,6 +21,19 @@ func (m *MockLLMClient) SendStructuredQuery(ctx context.Context, messages []type
 	return args.Get(0).(*types.ValidatedResponse), args.Error(1)
 }
 
+func (m *MockLLMClient) SendStructuredQueryStream(ctx context.Context, messages []types.Message, schema json.RawMessage) (<-chan client.StreamDelta, error) {
+	args := m.Called(ctx, messages, schema)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(<-chan client.StreamDelta), args.Error(1)
+}
+
+func (m *MockLLMClient) HealthCheck(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func NewMockLLMClient() *MockLLMClient {
 	return &MockLLMClient{}
 }
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultConcurrentTimeout bounds how long RunConcurrent waits for all
+// operations to finish
+const DefaultConcurrentTimeout = 30 * time.Second
+
+// ConcurrentResult summarizes the operations run by RunConcurrent. Statuses
+// and Errors are indexed by operation; an operation that returned an error
+// has its error in Errors and whatever status it returned in Statuses.
+type ConcurrentResult struct {
+	Statuses []int
+	Errors   []error
+	// ByStatus counts the operations that succeeded with each status
+	ByStatus map[int]int
+	// Failed counts the operations that returned an error
+	Failed   int
+	Duration time.Duration
+}
+
+// Count returns how many operations succeeded with any of the given statuses
+func (r ConcurrentResult) Count(statuses ...int) int {
+	total := 0
+	for _, status := range statuses {
+		total += r.ByStatus[status]
+	}
+	return total
+}
+
+// RunConcurrent starts n operations at once, calling fn with each index, and
+// waits up to DefaultConcurrentTimeout for them to finish. fn typically sends
+// a request and returns its status code.
+func RunConcurrent(t *testing.T, n int, fn func(i int) (int, error)) ConcurrentResult {
+	t.Helper()
+	return RunConcurrentWithTimeout(t, n, DefaultConcurrentTimeout, fn)
+}
+
+// RunConcurrentWithTimeout is RunConcurrent with a custom timeout. The test
+// fails immediately if the operations do not all finish in time.
+func RunConcurrentWithTimeout(t *testing.T, n int, timeout time.Duration, fn func(i int) (int, error)) ConcurrentResult {
+	t.Helper()
+
+	result := ConcurrentResult{
+		Statuses: make([]int, n),
+		Errors:   make([]error, n),
+		ByStatus: make(map[int]int),
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result.Statuses[i], result.Errors[i] = fn(i)
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("timed out after %v waiting for %d concurrent operations", timeout, n)
+	}
+	result.Duration = time.Since(start)
+
+	for i, err := range result.Errors {
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		result.ByStatus[result.Statuses[i]]++
+	}
+	return result
+}
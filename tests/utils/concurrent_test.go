@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunConcurrent(t *testing.T) {
+	t.Run("collects_statuses_by_index", func(t *testing.T) {
+		var running, peak int32
+		result := RunConcurrent(t, 6, func(i int) (int, error) {
+			now := atomic.AddInt32(&running, 1)
+			for {
+				seen := atomic.LoadInt32(&peak)
+				if now <= seen || atomic.CompareAndSwapInt32(&peak, seen, now) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+
+			switch {
+			case i == 5:
+				return 0, errors.New("connection refused")
+			case i%2 == 0:
+				return http.StatusOK, nil
+			default:
+				return http.StatusUnprocessableEntity, nil
+			}
+		})
+
+		assert.Equal(t, []int{200, 422, 200, 422, 200, 0}, result.Statuses)
+		assert.EqualError(t, result.Errors[5], "connection refused")
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, map[int]int{200: 3, 422: 2}, result.ByStatus)
+		assert.Equal(t, 5, result.Count(http.StatusOK, http.StatusUnprocessableEntity))
+		assert.Greater(t, atomic.LoadInt32(&peak), int32(1), "operations should overlap")
+		assert.Greater(t, result.Duration, time.Duration(0))
+	})
+
+	t.Run("zero_operations", func(t *testing.T) {
+		result := RunConcurrent(t, 0, func(i int) (int, error) { return http.StatusOK, nil })
+		assert.Empty(t, result.Statuses)
+		assert.Zero(t, result.Count(http.StatusOK))
+	})
+}